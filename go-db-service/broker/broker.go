@@ -13,5 +13,7 @@ type BrokerConfig struct {
 
 type Consumer interface {
 	Read(topic string, batchSize int) ([]byte, error)
-	RunWorker(ctx context.Context, handler func(ctx context.Context, iocs []models.IoCDto))
+	RunWorker(ctx context.Context, handler func(ctx context.Context, iocs []models.IoCDto) error) error
+	Wait(ctx context.Context) error
+	Close() error
 }
@@ -6,9 +6,9 @@ import (
 	"awesomeProject/pkg/logger"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/streadway/amqp"
-	"log"
 	"sync"
 	"time"
 )
@@ -16,13 +16,21 @@ import (
 const retryCount = 5
 const pause = 5
 
+const (
+	consumerTag     = "go-db-service"
+	prefetchBatches = 2           // Сколько пачек сообщений брокер отдает без подтверждения
+	flushInterval   = time.Second // Как часто отдавать в обработку неполную пачку
+)
+
 // IoCDto - структура данных для обработки
 
 // RabbitMQConsumer - реализация Consumer для RabbitMQ
 type RabbitMQConsumer struct {
 	logger  logger.CustomZapLogger
+	conn    *amqp.Connection
 	channel *amqp.Channel
 	config  broker.BrokerConfig
+	done    chan struct{} // Закрывается, когда RunWorker обработал все прочитанные сообщения
 }
 
 // NewRabbitMQConsumer - конструктор RabbitMQConsumer
@@ -51,9 +59,11 @@ retry:
 	}
 
 	return &RabbitMQConsumer{
+		conn:    conn,
 		channel: ch,
 		config:  config,
 		logger:  logger,
+		done:    make(chan struct{}),
 	}, nil
 }
 
@@ -94,11 +104,20 @@ func (c *RabbitMQConsumer) Read(batchSize int) ([]byte, error) {
 	return data, nil
 }
 
+// RunWorker - читает сообщения пачками по BatchSize и передает их в handler.
+// Сообщения подтверждаются только после успешной обработки пачки; при ошибке пачка возвращается
+// в очередь, а повторно не прошедшая - отклоняется (уходит в dead letter exchange, если он настроен).
+// После отмены ctx консьюмер перестает получать сообщения, обрабатывает уже прочитанные и
+// подтверждает их; дождаться этого можно через Wait.
 func (c *RabbitMQConsumer) RunWorker(ctx context.Context, handler func(ctx context.Context, iocs []models.IoCDto) error) error {
+	// Неподтвержденных сообщений не больше двух пачек: остальные остаются в очереди
+	if err := c.channel.Qos(c.config.BatchSize*prefetchBatches, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
 	msgs, err := c.channel.Consume(
 		c.config.Topic,
-		"",
-		true,
+		consumerTag,
+		false,
 		false,
 		false,
 		false,
@@ -108,46 +127,107 @@ func (c *RabbitMQConsumer) RunWorker(ctx context.Context, handler func(ctx conte
 		return fmt.Errorf("failed to register a consumer: %w", err)
 	}
 
+	// Прочитанные пачки дописываются и после отмены ctx, поэтому обработчик получает контекст без отмены
+	handlerCtx := context.WithoutCancel(ctx)
 	wg := &sync.WaitGroup{}
+	flush := func(batch []models.IoCDto, deliveries []amqp.Delivery) {
+		if len(deliveries) == 0 {
+			return
+		}
+		c.logger.Info(fmt.Sprintf("Read %d IoCDto", len(batch)))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.handle(handlerCtx, handler, batch, deliveries)
+		}()
+	}
 
 	go func() {
+		defer close(c.done)
 		defer wg.Wait()
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		stop := ctx.Done()
+		var batch []models.IoCDto
+		var deliveries []amqp.Delivery
 		for {
 			select {
-			case <-ctx.Done():
-				log.Println("Shutting down gracefully...")
-				return
-			default:
-				var batch []models.IoCDto
-				for i := 0; i < c.config.BatchSize; i++ {
-					select {
-					case <-ctx.Done():
-						return
-					case msg, ok := <-msgs:
-						if !ok {
-							return
-						}
-						var ioc models.IoCDto
-						if err := json.Unmarshal(msg.Body, &ioc); err != nil {
-							log.Printf("Error decoding message: %v", err)
-							continue
-						}
-						batch = append(batch, ioc)
+			case <-stop:
+				// Брокер закроет msgs, когда отдаст уже доставленные сообщения
+				c.logger.Info("Stopping consumer, finishing in-flight messages")
+				stop = nil
+				if err := c.channel.Cancel(consumerTag, false); err != nil {
+					c.logger.Error(fmt.Sprintf("Failed to cancel consumer: %v", err))
+				}
+			case msg, ok := <-msgs:
+				if !ok {
+					flush(batch, deliveries)
+					c.logger.Info("Consumer stopped")
+					return
+				}
+				var ioc models.IoCDto
+				if err := json.Unmarshal(msg.Body, &ioc); err != nil {
+					c.logger.Error(fmt.Sprintf("Error decoding message: %v", err))
+					if err := msg.Reject(false); err != nil {
+						c.logger.Error(fmt.Sprintf("Failed to reject message: %v", err))
 					}
+					continue
 				}
-				if len(batch) > 0 {
-					c.logger.Info(fmt.Sprintf("Read %d IoCDto", len(batch)))
-					wg.Add(1)
-					go func(batch []models.IoCDto) {
-						defer wg.Done()
-						if err := handler(ctx, batch); err != nil {
-							log.Printf("Handler error: %v", err)
-						}
-					}(batch)
+				batch = append(batch, ioc)
+				deliveries = append(deliveries, msg)
+				if len(batch) >= c.config.BatchSize {
+					flush(batch, deliveries)
+					batch, deliveries = nil, nil
 				}
+			case <-ticker.C:
+				// Неполная пачка не ждет следующих сообщений дольше flushInterval
+				flush(batch, deliveries)
+				batch, deliveries = nil, nil
 			}
 		}
 	}()
 
 	return nil
 }
+
+// handle - обработка пачки и подтверждение ее сообщений
+func (c *RabbitMQConsumer) handle(ctx context.Context, handler func(ctx context.Context, iocs []models.IoCDto) error, batch []models.IoCDto, deliveries []amqp.Delivery) {
+	err := handler(ctx, batch)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Handler error: %v", err))
+	}
+	for _, msg := range deliveries {
+		var ackErr error
+		if err != nil {
+			ackErr = msg.Nack(false, !msg.Redelivered)
+		} else {
+			ackErr = msg.Ack(false)
+		}
+		if ackErr != nil {
+			// Неподтвержденное сообщение брокер доставит повторно
+			c.logger.Error(fmt.Sprintf("Failed to acknowledge message: %v", ackErr))
+		}
+	}
+}
+
+// Wait - ждет, пока консьюмер после отмены контекста обработает и подтвердит прочитанные сообщения
+func (c *RabbitMQConsumer) Wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close - закрывает канал и соединение; неподтвержденные сообщения брокер вернет в очередь
+func (c *RabbitMQConsumer) Close() error {
+	if err := c.channel.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		return err
+	}
+	if err := c.conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/importer"
+	"awesomeProject/pkg/logger"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// runImport - подкоманда import: разбирает файлы IoC и пишет их напрямую в хранилище, минуя брокер.
+// Пример: main import -format csv -source ir-2025-01 -columns value=indicator dump.csv
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "csv | ndjson | stix | misp (по умолчанию определяется по расширению)")
+	source := flags.String("source", "", "источник для строк без source")
+	iocType := flags.String("type", "", "тип для строк без type")
+	columns := flags.String("columns", "", "маппинг колонок CSV: value=indicator,type=kind")
+	batchID := flags.String("batch-id", "", "идентификатор импорта (по умолчанию генерируется)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s import [flags] file...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	columnMap, err := importer.ParseColumns(*columns)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *batchID == "" {
+		// Все файлы одного запуска помечаются одним идентификатором
+		*batchID = uuid.NewString()
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %s\n", err)
+		return 1
+	}
+	appLogger := logger.NewCustomZapLogger((*logger.LoggerConfig)(&cfg.LoggerConfig))
+	storageImpl := newStorage(cfg, appLogger)
+	defer storageImpl.Close()
+	serviceImpl := newService(cfg, storageImpl, appLogger)
+	setupAllowlist(cfg, serviceImpl, appLogger)
+	setupTenants(cfg, serviceImpl, appLogger)
+	if enricher := setupGeoIP(cfg, serviceImpl, appLogger); enricher != nil {
+		defer enricher.Close()
+	}
+
+	// Импорт из консоли тоже попадает в журнал аудита, от имени пользователя ОС
+	auditCtx, stopAudit := context.WithCancel(context.Background())
+	auditLog := newAuditLog(cfg, storageImpl, appLogger)
+	go auditLog.Run(auditCtx)
+	defer auditLog.Wait()
+	defer stopAudit()
+	// Импорт из консоли, как и брокер, может писать IoC с арендатором из файла
+	actorCtx := auth.WithAdmin(auth.WithIdentity(context.Background(), "cli:"+cliUser()))
+
+	exitCode := 0
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	for _, path := range flags.Args() {
+		fileFormat := *format
+		if fileFormat == "" {
+			fileFormat = detectFormat(path)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+			continue
+		}
+		opts := importer.Options{
+			Format:  fileFormat,
+			Source:  *source,
+			Type:    *iocType,
+			Columns: columnMap,
+			BatchID: *batchID,
+		}
+		result, err := serviceImpl.Import(context.Background(), file, opts)
+		file.Close()
+		auditLog.Record(actorCtx, audit.MethodCLIImport, struct {
+			File string `json:"file"`
+			importer.Options
+		}{File: path, Options: opts}, result.Accepted, err)
+
+		_ = encoder.Encode(struct {
+			File string `json:"file"`
+			importer.Result
+		}{File: path, Result: result})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// cliUser - пользователь ОС, запустивший импорт
+func cliUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// detectFormat - формат по расширению файла
+func detectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return importer.FormatCSV
+	case ".ndjson", ".jsonl":
+		return importer.FormatNDJSON
+	default:
+		name := strings.ToLower(filepath.Base(path))
+		if strings.Contains(name, "misp") {
+			return importer.FormatMISP
+		}
+		return importer.FormatSTIX
+	}
+}
@@ -3,20 +3,39 @@ package main
 import (
 	"awesomeProject/broker/rabbitmq"
 	"awesomeProject/config"
+	"awesomeProject/internal/alerts"
+	"awesomeProject/internal/allowlist"
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/cache"
+	"awesomeProject/internal/executor"
+	"awesomeProject/internal/geoip"
+	"awesomeProject/internal/misp"
+	"awesomeProject/internal/pipeline"
 	"awesomeProject/internal/service"
+	"awesomeProject/internal/spool"
 	"awesomeProject/internal/storage"
+	"awesomeProject/internal/tenant"
 	"awesomeProject/internal/transport"
 	"awesomeProject/pkg/logger"
 	"awesomeProject/server"
 	"context"
 	"fmt"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 )
 
 func main() {
+	// Подкоманды запускаются без серверов и брокера
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+
 	// Загружаем конфигурацию
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -32,15 +51,13 @@ func main() {
 	appLogger.Info("Profiler started at :6060/debug/pprof/")
 
 	// Подключение к базе данных
-	connStr := cfg.DBConfig.ConnStr()
-	storageImpl, err := storage.NewClickHouseStorage(connStr, appLogger)
-	if err != nil {
-		appLogger.Fatal("Error connecting to database", zap.Error(err))
-	}
-	err = storageImpl.ApplyHardcodedMigration()
-	if err != nil {
-		appLogger.Fatal("Error performing migration", zap.Error(err))
-	}
+	storageImpl := newStorage(cfg, appLogger)
+
+	// Журнал аудита пишется до остановки серверов, поэтому у него свой контекст
+	auditCtx, stopAudit := context.WithCancel(context.Background())
+	defer stopAudit()
+	auditLog := newAuditLog(cfg, storageImpl, appLogger)
+	go auditLog.Run(auditCtx)
 
 	// Инициализация брокера
 	broker, err := rabbitmq.NewRabbitMQConsumer(cfg.BrokerConfig, *appLogger)
@@ -48,17 +65,80 @@ func main() {
 		appLogger.Fatal("Error creating rabbitmq consumer", zap.Error(err))
 	}
 
+	// Фоновые задачи (MISP фид, отправка вебхуков) останавливаются при завершении сервиса
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
 	// Инициализация сервиса
-	serviceImpl := service.NewService(*appLogger, storageImpl)
-	err = broker.RunWorker(context.Background(), serviceImpl.UnaryStore)
+	serviceImpl := newService(cfg, storageImpl, appLogger)
+
+	// Allowlist и правила оповещений подключаются до запуска консьюмера, чтобы не пропустить первые пачки
+	setupAllowlist(cfg, serviceImpl, appLogger)
+	tenants := setupTenants(cfg, serviceImpl, appLogger)
+	go serviceImpl.RunAllowlistRefresh(bgCtx, cfg.Allowlist.Refresh)
+	if err := serviceImpl.ReloadLifecycle(context.Background()); err != nil {
+		appLogger.Fatal("Error loading IoC lifecycle", zap.Error(err))
+	}
+	go serviceImpl.RunLifecycleRefresh(bgCtx, cfg.Lifecycle.Refresh)
+	if enricher := setupGeoIP(cfg, serviceImpl, appLogger); enricher != nil {
+		go enricher.Run(bgCtx, cfg.GeoIP.ReloadInterval)
+	}
+	closeCache := setupCache(cfg, serviceImpl, appLogger)
+	defer closeCache()
+
+	// Пачки, отложенные при недоступности хранилища (в том числе до перезапуска), дописываются в фоне
+	ingestSpool := setupSpool(cfg, serviceImpl, appLogger)
+	if ingestSpool != nil {
+		go serviceImpl.RunSpoolReplay(bgCtx, cfg.Spool.RetryInterval)
+	}
+
+	var deliveryLog *alerts.DeliveryLog
+	if cfg.AlertsConfig.RulesFile != "" {
+		deliveryLog = newAlerts(bgCtx, cfg, serviceImpl, appLogger)
+		defer deliveryLog.Close()
+	}
+
+	// Пачки из брокера проходят шаги обогащения перед записью; сообщения подтверждаются после коммита
+	storeBatch := serviceImpl.UnaryStoreSync
+	var enrichment *pipeline.Pipeline
+	if cfg.Pipeline.StagesFile != "" {
+		enrichment = newPipeline(cfg, appLogger)
+		storeBatch = enrichment.Handler(serviceImpl.UnaryStoreSync)
+	}
+	storeBatch = auditLog.Handler(audit.MethodBrokerIngest, "broker:"+cfg.BrokerConfig.Topic,
+		map[string]string{"topic": cfg.BrokerConfig.Topic}, storeBatch)
+	// Брокер - внутренний источник, он пишет IoC с арендатором из самих сообщений
+	brokerCtx, stopBroker := context.WithCancel(auth.WithAdmin(context.Background()))
+	defer stopBroker()
+	err = broker.RunWorker(brokerCtx, storeBatch)
 	if err != nil {
 		appLogger.Fatal("Error running worker", zap.Error(err))
 		return
 	}
 
-	// HTTP сервер
+	// Клиенты HTTP API; в gRPC по тем же токенам определяется клиент для аудита
+	authenticator, err := auth.NewAuthenticator(cfg.AuthConfig.Clients)
+	if err != nil {
+		appLogger.Fatal("Invalid AUTH_CLIENTS", zap.Error(err))
+	}
+	if err := authenticator.SetAdmins(cfg.AuthConfig.Admins); err != nil {
+		appLogger.Fatal("Invalid AUTH_ADMINS", zap.Error(err))
+	}
+	if !authenticator.Enabled() {
+		appLogger.Warn("AUTH_CLIENTS is empty, HTTP API will reject all requests")
+	}
+
+	// gRPC сервер
 	handler := transport.NewHandler(serviceImpl, *appLogger)
-	srv := server.NewServer(handler, *appLogger)
+	handler.SetAudit(auditLog)
+	limits, err := transport.NewLimits(cfg.Limits.Rates, cfg.Limits.Streams, cfg.Limits.MaxRows, *appLogger)
+	if err != nil {
+		appLogger.Fatal("Invalid API limits", zap.Error(err))
+	}
+	// Арендатор и ограничения выбираются по уже определенному клиенту, поэтому их перехватчики идут после аутентификации
+	srv := server.NewServer(handler, *appLogger,
+		grpc.ChainUnaryInterceptor(authenticator.UnaryInterceptor(), tenants.UnaryInterceptor(), limits.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(authenticator.StreamInterceptor(), tenants.StreamInterceptor(), limits.StreamInterceptor()))
 
 	// Асинхронный запуск HTTP сервера
 	go func() {
@@ -68,14 +148,280 @@ func main() {
 	}()
 	appLogger.Info("Server is running", zap.String("port", cfg.ServerConfig.Port))
 
-	// Ожидание завершения работы
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	// HTTP сервер для выгрузок и TAXII
+	httpHandler := transport.NewHTTPHandler(serviceImpl, authenticator, tenants, limits, *appLogger)
+	httpHandler.SetAudit(auditLog)
+
+	// MISP фид: фоновая инкрементальная генерация и раздача по HTTP
+	if cfg.MISPConfig.FeedDir != "" {
+		feedGenerator := misp.NewFeedGenerator(cfg.MISPConfig.FeedDir, serviceImpl, *appLogger)
+		go feedGenerator.Run(bgCtx, cfg.MISPConfig.FeedInterval)
+		httpHandler.ServeMISPFeed(cfg.MISPConfig.FeedDir)
+	}
+	if deliveryLog != nil {
+		httpHandler.ServeAlertDeliveries(deliveryLog)
+	}
+	if enrichment != nil {
+		httpHandler.ServePipelineStats(enrichment)
+	}
+	httpHandler.ServeExecutorStats()
+	httpHandler.ServeAuditStats()
+	if ingestSpool != nil {
+		httpHandler.ServeSpoolStats()
+	}
+	if cfg.Cache.TTLs != "" {
+		httpHandler.ServeCacheStats()
+	}
+
+	httpSrv := server.NewHTTPServer(httpHandler, *appLogger)
+	if err := httpSrv.Start(":" + cfg.ServerConfig.HTTPPort); err != nil {
+		appLogger.Fatal("Failed to start http server", zap.Error(err))
+	}
+	appLogger.Info("HTTP server is running", zap.String("port", cfg.ServerConfig.HTTPPort))
 
-	// Завершаем работу сервера
-	if err := srv.Shutdown(context.Background()); err != nil {
+	// Ожидание завершения работы: SIGTERM присылают Docker и Kubernetes, SIGINT - консоль
+	quit := make(chan os.Signal, 2)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	sig := <-quit
+	appLogger.Info("Shutting down", zap.String("signal", sig.String()), zap.Duration("timeout", cfg.Shutdown.Timeout))
+	go func() {
+		<-quit
+		appLogger.Fatal("Second signal received, exiting without graceful shutdown")
+	}()
+
+	// Вся остановка укладывается в SHUTDOWN_TIMEOUT; после него оставшиеся вызовы обрываются
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancelShutdown()
+
+	// Сначала перестаем принимать данные: брокер дописывает и подтверждает прочитанные пачки
+	stopBroker()
+	if err := broker.Wait(shutdownCtx); err != nil {
+		appLogger.Error("Broker batches were not finished before shutdown timeout, they will be redelivered", zap.Error(err))
+	}
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		appLogger.Error("HTTP server shutdown failed", zap.Error(err))
+	}
+	// Подписки бесконечны, поэтому закрываются сразу: клиенты возобновят их с курсором на другой реплике
+	serviceImpl.CloseSubscriptions()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		appLogger.Error("Server shutdown failed", zap.Error(err))
 	}
+
+	// Дожидаемся задач, уже принятых в пулы, и останавливаем фоновые задачи
+	if err := serviceImpl.Drain(shutdownCtx); err != nil {
+		appLogger.Error("Service tasks did not finish before shutdown timeout", zap.Error(err))
+	}
+	if err := broker.Close(); err != nil {
+		appLogger.Error("Failed to close broker connection", zap.Error(err))
+	}
+	stopBackground()
+	if ingestSpool != nil {
+		if err := ingestSpool.Close(); err != nil {
+			appLogger.Error("Failed to close spool", zap.Error(err))
+		}
+	}
+	stopAudit()
+	auditLog.Wait()
+	if dropped := auditLog.Dropped(); dropped > 0 {
+		appLogger.Warn("Audit records were not stored", zap.Int64("dropped", dropped))
+	}
+	if err := storageImpl.Close(); err != nil {
+		appLogger.Error("Failed to close database connection", zap.Error(err))
+	}
 	appLogger.Info("Server shutdown successfully")
 }
+
+// storageBackend - хранилище IoC, выбранное DB_BACKEND, вместе с журналом аудита
+type storageBackend interface {
+	service.Storage
+	audit.Writer
+	SetAuditRetention(ctx context.Context, days int) error
+	Close() error
+}
+
+// newStorage - подключение к базе данных с накатыванием миграций
+func newStorage(cfg config.Config, appLogger *logger.CustomZapLogger) storageBackend {
+	connStr := cfg.DBConfig.ConnStr()
+	switch cfg.DBConfig.Backend {
+	case config.BackendMemory:
+		appLogger.Warn("Using in-memory storage, IoCs are lost on restart")
+		return storage.NewMemoryStorage(appLogger)
+	case config.BackendPostgres:
+		storageImpl, err := storage.NewPostgresStorage(connStr, appLogger)
+		if err != nil {
+			appLogger.Fatal("Error connecting to database", zap.Error(err))
+		}
+		if err := storageImpl.Migrate(context.Background()); err != nil {
+			appLogger.Fatal("Error performing migration", zap.Error(err))
+		}
+		return storageImpl
+	}
+
+	storageImpl, err := storage.NewClickHouseStorage(connStr, appLogger)
+	if err != nil {
+		appLogger.Fatal("Error connecting to database", zap.Error(err))
+	}
+	storageImpl.SetAsyncInsert(cfg.DBConfig.AsyncInsertMaxRows)
+	err = storageImpl.ApplyHardcodedMigration()
+	if err != nil {
+		appLogger.Fatal("Error performing migration", zap.Error(err))
+	}
+	return storageImpl
+}
+
+// newService - сервис с пулами задач по классам операций
+func newService(cfg config.Config, storageImpl storageBackend, appLogger *logger.CustomZapLogger) *service.Service {
+	exec, err := executor.New(executor.Config{
+		Workers: map[executor.Class]int{
+			executor.Interactive: cfg.Executor.InteractiveWorkers,
+			executor.Write:       cfg.Executor.WriteWorkers,
+			executor.Bulk:        cfg.Executor.BulkWorkers,
+		},
+		QueueSize:    cfg.Executor.QueueSize,
+		QueueTimeout: cfg.Executor.QueueTimeout,
+	}, *appLogger)
+	if err != nil {
+		appLogger.Fatal("Invalid executor configuration", zap.Error(err))
+	}
+	return service.NewService(*appLogger, storageImpl, exec)
+}
+
+// newAuditLog - журнал аудита и срок его хранения
+func newAuditLog(cfg config.Config, storageImpl storageBackend, appLogger *logger.CustomZapLogger) *audit.Log {
+	if err := storageImpl.SetAuditRetention(context.Background(), cfg.Audit.RetentionDays); err != nil {
+		appLogger.Fatal("Error setting audit retention", zap.Error(err))
+	}
+	return audit.NewLog(storageImpl, *appLogger)
+}
+
+// newAlerts - загрузка правил оповещений и запуск отправки вебхуков
+func newAlerts(ctx context.Context, cfg config.Config, serviceImpl *service.Service, appLogger *logger.CustomZapLogger) *alerts.DeliveryLog {
+	rules, err := alerts.LoadRules(cfg.AlertsConfig.RulesFile)
+	if err != nil {
+		appLogger.Fatal("Error loading alert rules", zap.Error(err))
+	}
+	deliveryLog, err := alerts.NewDeliveryLog(cfg.AlertsConfig.DeliveryLog)
+	if err != nil {
+		appLogger.Fatal("Error opening alert delivery log", zap.Error(err))
+	}
+	engine, err := alerts.NewEngine(rules, deliveryLog, *appLogger)
+	if err != nil {
+		appLogger.Fatal("Invalid alert rules", zap.Error(err))
+	}
+	engine.Run(ctx)
+	serviceImpl.SetAlerter(engine)
+	appLogger.Info("Alert rules loaded", zap.Int("rules", engine.Rules()), zap.String("file", cfg.AlertsConfig.RulesFile))
+	return deliveryLog
+}
+
+// newPipeline - загрузка шагов обогащения; ошибка в конфигурации не дает запустить сервис
+func newPipeline(cfg config.Config, appLogger *logger.CustomZapLogger) *pipeline.Pipeline {
+	stages, err := pipeline.LoadConfig(cfg.Pipeline.StagesFile)
+	if err != nil {
+		appLogger.Fatal("Error loading pipeline stages", zap.Error(err))
+	}
+	enrichment, err := pipeline.New(stages, *appLogger)
+	if err != nil {
+		appLogger.Fatal("Invalid pipeline stages", zap.Error(err))
+	}
+	appLogger.Info("Enrichment pipeline loaded", zap.Int("stages", enrichment.Stages()), zap.String("file", cfg.Pipeline.StagesFile))
+	return enrichment
+}
+
+// setupAllowlist - политика allowlist, список популярных доменов и записи из хранилища
+func setupAllowlist(cfg config.Config, serviceImpl *service.Service, appLogger *logger.CustomZapLogger) {
+	policy, err := allowlist.ParsePolicy(cfg.Allowlist.Policy)
+	if err != nil {
+		appLogger.Fatal("Invalid ALLOWLIST_POLICY", zap.Error(err))
+	}
+	var top []string
+	if cfg.Allowlist.TopNFile != "" {
+		if top, err = allowlist.LoadTopN(cfg.Allowlist.TopNFile, cfg.Allowlist.TopNSize); err != nil {
+			appLogger.Fatal("Error loading allowlist top-N list", zap.Error(err))
+		}
+	}
+	serviceImpl.SetAllowlist(allowlist.New(policy, top))
+	if err := serviceImpl.ReloadAllowlist(context.Background()); err != nil {
+		appLogger.Fatal("Error loading allowlist", zap.Error(err))
+	}
+	appLogger.Info("Allowlist loaded", zap.String("policy", policy), zap.Int("topN", len(top)))
+}
+
+// setupTenants - арендаторы клиентов и квоты арендаторов
+func setupTenants(cfg config.Config, serviceImpl *service.Service, appLogger *logger.CustomZapLogger) *tenant.Resolver {
+	resolver, err := tenant.NewResolver(cfg.Tenant.Clients)
+	if err != nil {
+		appLogger.Fatal("Invalid TENANT_CLIENTS", zap.Error(err))
+	}
+	quotas, err := tenant.ParseQuotas(cfg.Tenant.Quotas)
+	if err != nil {
+		appLogger.Fatal("Invalid TENANT_QUOTAS", zap.Error(err))
+	}
+	serviceImpl.SetTenantQuotas(quotas)
+	return resolver
+}
+
+// setupCache - кэш запросов перед хранилищем: в Redis, если он задан, иначе в памяти процесса.
+// Возвращает функцию закрытия соединений с Redis.
+func setupCache(cfg config.Config, serviceImpl *service.Service, appLogger *logger.CustomZapLogger) func() {
+	if cfg.Cache.TTLs == "" {
+		return func() {}
+	}
+	ttls, err := cache.ParseTTLs(cfg.Cache.TTLs)
+	if err != nil {
+		appLogger.Fatal("Invalid CACHE_TTLS", zap.Error(err))
+	}
+	var backend cache.Backend = cache.NewLRU(cfg.Cache.MaxBytes)
+	closeBackend := func() {}
+	if cfg.Cache.RedisAddr != "" {
+		redisBackend, err := cache.NewRedis(context.Background(), &redis.Options{
+			Addr:     cfg.Cache.RedisAddr,
+			Password: cfg.Cache.RedisPassword,
+			DB:       cfg.Cache.RedisDB,
+		}, "go-db-service:cache:")
+		if err != nil {
+			appLogger.Fatal("Error connecting to cache Redis", zap.Error(err))
+		}
+		backend = redisBackend
+		closeBackend = func() {
+			if err := redisBackend.Close(); err != nil {
+				appLogger.Error("Failed to close cache Redis connection", zap.Error(err))
+			}
+		}
+	}
+	serviceImpl.SetCache(cache.New(backend, ttls, *appLogger), cfg.Cache.LoadMaxRows)
+	appLogger.Info("Query cache enabled", zap.String("ttls", cfg.Cache.TTLs), zap.Bool("redis", cfg.Cache.RedisAddr != ""))
+	return closeBackend
+}
+
+// setupSpool - открывает spool пачек для записи при недоступности хранилища; nil - spool выключен
+func setupSpool(cfg config.Config, serviceImpl *service.Service, appLogger *logger.CustomZapLogger) *spool.Spool {
+	if cfg.Spool.Dir == "" {
+		appLogger.Warn("SPOOL_DIR is empty, batches will not be kept while storage is unavailable")
+		return nil
+	}
+	ingestSpool, err := spool.Open(spool.Config{
+		Dir:          cfg.Spool.Dir,
+		SegmentBytes: cfg.Spool.SegmentBytes,
+		MaxBytes:     cfg.Spool.MaxBytes,
+	}, *appLogger)
+	if err != nil {
+		appLogger.Fatal("Error opening spool", zap.Error(err))
+	}
+	serviceImpl.SetSpool(ingestSpool)
+	return ingestSpool
+}
+
+// setupGeoIP - открывает локальные базы GeoIP/ASN, если они заданы; nil - обогащение выключено
+func setupGeoIP(cfg config.Config, serviceImpl *service.Service, appLogger *logger.CustomZapLogger) *geoip.Enricher {
+	if cfg.GeoIP.CityDB == "" && cfg.GeoIP.ASNDB == "" {
+		return nil
+	}
+	enricher, err := geoip.NewEnricher(cfg.GeoIP.CityDB, cfg.GeoIP.ASNDB, *appLogger)
+	if err != nil {
+		appLogger.Fatal("Error opening GeoIP databases", zap.Error(err))
+	}
+	serviceImpl.SetGeoIP(enricher)
+	appLogger.Info("GeoIP enrichment enabled", zap.String("cityDB", cfg.GeoIP.CityDB), zap.String("asnDB", cfg.GeoIP.ASNDB))
+	return enricher
+}
@@ -3,10 +3,12 @@ package config
 import (
 	"awesomeProject/broker"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the application configuration
@@ -15,18 +17,146 @@ type Config struct {
 	DBConfig     DBConfig
 	ServerConfig ServerConfig
 	BrokerConfig broker.BrokerConfig
+	AuthConfig   AuthConfig
+	MISPConfig   MISPConfig
+	AlertsConfig AlertsConfig
+	Allowlist    AllowlistConfig
+	GeoIP        GeoIPConfig
+	Pipeline     PipelineConfig
+	Lifecycle    LifecycleConfig
+	Audit        AuditConfig
+	Tenant       TenantConfig
+	Limits       LimitsConfig
+	Executor     ExecutorConfig
+	Shutdown     ShutdownConfig
+	Spool        SpoolConfig
+	Cache        CacheConfig
 }
 
 type ServerConfig struct {
-	Port string
+	Port     string
+	HTTPPort string
 }
 
+// Хранилища IoC, выбираются DB_BACKEND
+const (
+	BackendClickHouse = "clickhouse"
+	BackendPostgres   = "postgres"
+	BackendMemory     = "memory"
+)
+
 type DBConfig struct {
+	Backend    string // clickhouse, postgres или memory
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	SSLMode string // sslmode PostgreSQL
+
+	AsyncInsertMaxRows int // Пачки IoC не больше этого размера пишутся через async_insert; 0 - выключено
+	MaxOpenConns       int // Соединений с ClickHouse; по умолчанию - сумма пулов задач и запас на фоновые задачи
+}
+
+// backgroundConns - соединения для работы вне пулов задач: журнал аудита, spool, обновление allowlist и lifecycle
+const backgroundConns = 4
+
+// AuthConfig - клиенты HTTP API (выгрузки, TAXII) в формате "name:token,name2:token2"
+// и клиенты из них, которым доступны административные вызовы ("name,name2")
+type AuthConfig struct {
+	Clients string
+	Admins  string
+}
+
+// MISPConfig - генерация MISP фида; пустой FeedDir отключает фид
+type MISPConfig struct {
+	FeedDir      string
+	FeedInterval time.Duration
+}
+
+// AlertsConfig - правила оповещений; пустой RulesFile отключает оповещения
+type AlertsConfig struct {
+	RulesFile   string
+	DeliveryLog string
+}
+
+// AllowlistConfig - политика allowlist при записи и список популярных доменов (Majestic Million)
+type AllowlistConfig struct {
+	Policy   string
+	TopNFile string
+	TopNSize int
+	Refresh  time.Duration
+}
+
+// LifecycleConfig - как часто перечитывать статусы IoC, измененные другими репликами
+type LifecycleConfig struct {
+	Refresh time.Duration
+}
+
+// AuditConfig - срок хранения журнала аудита в днях; 0 - бессрочно
+type AuditConfig struct {
+	RetentionDays int
+}
+
+// TenantConfig - арендаторы клиентов из AUTH_CLIENTS ("client:unit-a|unit-b,...")
+// и лимиты числа собственных IoC арендаторов ("unit-a:1000000,...")
+type TenantConfig struct {
+	Clients string
+	Quotas  string
+}
+
+// LimitsConfig - ограничения клиентов gRPC API и HTTP выгрузок по правилам "*=…,Method=…,client@Method=…":
+// частота вызовов (вызовов в секунду:емкость корзины), одновременные стримы и строки в ответе
+type LimitsConfig struct {
+	Rates   string
+	Streams string
+	MaxRows string
+}
+
+// ExecutorConfig - одновременные задачи хранилища по классам операций и очередь ожидания
+type ExecutorConfig struct {
+	InteractiveWorkers int
+	WriteWorkers       int
+	BulkWorkers        int
+	QueueSize          int
+	QueueTimeout       time.Duration
+}
+
+// ShutdownConfig - сколько всего может длиться остановка, после чего оставшиеся вызовы обрываются
+type ShutdownConfig struct {
+	Timeout time.Duration
+}
+
+// SpoolConfig - каталог для пачек, которые не удалось записать в хранилище; пусто - spool выключен
+type SpoolConfig struct {
+	Dir           string
+	SegmentBytes  int64         // Размер файла сегмента
+	MaxBytes      int64         // Предел неотправленных данных на диске; 0 - без ограничения
+	RetryInterval time.Duration // Пауза между попытками дописать пачки после ошибки хранилища
+}
+
+// CacheConfig - кэш запросов перед ClickHouse: TTL по методам Storage ("*=30s,UnaryLoad=10s"),
+// пусто - кэш выключен. Без RedisAddr кэш хранится в памяти процесса.
+type CacheConfig struct {
+	TTLs          string
+	MaxBytes      int64 // Размер кэша в памяти процесса
+	LoadMaxRows   int64 // Load кэшируется, пока offset+limit не больше этого значения
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+}
+
+// PipelineConfig - файл с шагами обогащения пачек из брокера; пусто - пайплайн выключен
+type PipelineConfig struct {
+	StagesFile string
+}
+
+// GeoIPConfig - локальные базы MaxMind (GeoLite2 City/Country и ASN) для обогащения IP IoC
+type GeoIPConfig struct {
+	CityDB         string
+	ASNDB          string
+	ReloadInterval time.Duration
 }
 
 type LoggerConfig struct {
@@ -39,17 +169,57 @@ type LoggerConfig struct {
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (Config, error) {
 	bSize, _ := strconv.Atoi(getEnv("BATCH_SIZE", "100"))
+	feedInterval, err := time.ParseDuration(getEnv("MISP_FEED_INTERVAL", "10m"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid MISP_FEED_INTERVAL: %w", err)
+	}
+	topNSize, _ := strconv.Atoi(getEnv("ALLOWLIST_TOPN_SIZE", "10000"))
+	allowlistRefresh, err := time.ParseDuration(getEnv("ALLOWLIST_REFRESH", "1m"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ALLOWLIST_REFRESH: %w", err)
+	}
+	lifecycleRefresh, err := time.ParseDuration(getEnv("LIFECYCLE_REFRESH", "1m"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid LIFECYCLE_REFRESH: %w", err)
+	}
+	auditRetention, err := strconv.Atoi(getEnv("AUDIT_RETENTION_DAYS", "365"))
+	if err != nil || auditRetention < 0 {
+		return Config{}, fmt.Errorf("invalid AUDIT_RETENTION_DAYS: %q", os.Getenv("AUDIT_RETENTION_DAYS"))
+	}
+	dbCfg, err := loadDBConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	executorCfg, err := loadExecutorConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	if dbCfg.MaxOpenConns == 0 {
+		// Задача из пула не должна ждать соединения после того, как дождалась места в пуле
+		dbCfg.MaxOpenConns = executorCfg.InteractiveWorkers + executorCfg.WriteWorkers + executorCfg.BulkWorkers + backgroundConns
+	}
+	spoolCfg, err := loadSpoolConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	cacheCfg, err := loadCacheConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	shutdownTimeout, err := time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil || shutdownTimeout <= 0 {
+		return Config{}, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %q", os.Getenv("SHUTDOWN_TIMEOUT"))
+	}
+	geoipReload, err := time.ParseDuration(getEnv("GEOIP_RELOAD_INTERVAL", "1m"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid GEOIP_RELOAD_INTERVAL: %w", err)
+	}
 	config := Config{
 		ServerConfig: ServerConfig{
-			Port: getEnv("SERVER_PORT", ":8080"),
-		},
-		DBConfig: DBConfig{
-			DBHost:     getEnv("DB_HOST", "localhost"),
-			DBPort:     getEnv("DB_PORT", "5432"),
-			DBUser:     getEnv("DB_USER", "user"),
-			DBPassword: getEnv("DB_PASSWORD", "password"),
-			DBName:     getEnv("DB_NAME", "app_db"),
+			Port:     getEnv("SERVER_PORT", ":8080"),
+			HTTPPort: getEnv("HTTP_PORT", "8081"),
 		},
+		DBConfig: dbCfg,
 		LoggerConfig: LoggerConfig{
 			LogLevel:    getEnv("LOG_LEVEL", "debug"),
 			NodeIP:      getEnv("NODE_IP", "127.0.0.1"),
@@ -62,6 +232,53 @@ func LoadConfig() (Config, error) {
 			Topic:      getEnv("TOPIC", "ioc.normalized.queue"),
 			BrokerAddr: getEnv("BROKER_ADDR", "localhost:9092"),
 		},
+		AuthConfig: AuthConfig{
+			Clients: getEnv("AUTH_CLIENTS", ""),
+			Admins:  getEnv("AUTH_ADMINS", ""),
+		},
+		MISPConfig: MISPConfig{
+			FeedDir:      getEnv("MISP_FEED_DIR", "misp-feed"),
+			FeedInterval: feedInterval,
+		},
+		AlertsConfig: AlertsConfig{
+			RulesFile:   getEnv("ALERT_RULES_FILE", ""),
+			DeliveryLog: getEnv("ALERT_DELIVERY_LOG", "alert-deliveries.log"),
+		},
+		Allowlist: AllowlistConfig{
+			Policy:   getEnv("ALLOWLIST_POLICY", "drop"),
+			TopNFile: getEnv("ALLOWLIST_TOPN_FILE", ""),
+			TopNSize: topNSize,
+			Refresh:  allowlistRefresh,
+		},
+		GeoIP: GeoIPConfig{
+			CityDB:         getEnv("GEOIP_CITY_DB", ""),
+			ASNDB:          getEnv("GEOIP_ASN_DB", ""),
+			ReloadInterval: geoipReload,
+		},
+		Pipeline: PipelineConfig{
+			StagesFile: getEnv("PIPELINE_STAGES_FILE", ""),
+		},
+		Lifecycle: LifecycleConfig{
+			Refresh: lifecycleRefresh,
+		},
+		Audit: AuditConfig{
+			RetentionDays: auditRetention,
+		},
+		Tenant: TenantConfig{
+			Clients: getEnv("TENANT_CLIENTS", ""),
+			Quotas:  getEnv("TENANT_QUOTAS", ""),
+		},
+		Limits: LimitsConfig{
+			Rates:   getEnv("RATE_LIMITS", "*=50:100,StreamLoad=2:4,Export=1:2,Import=1:2"),
+			Streams: getEnv("STREAM_LIMITS", "*=4"),
+			MaxRows: getEnv("MAX_RESULT_ROWS", "Load=10000"),
+		},
+		Executor: executorCfg,
+		Shutdown: ShutdownConfig{
+			Timeout: shutdownTimeout,
+		},
+		Spool: spoolCfg,
+		Cache: cacheCfg,
 	}
 	return config, nil
 }
@@ -73,14 +290,19 @@ func (cfg Config) String() string {
 	// ServerConfig
 	sb.WriteString(fmt.Sprintf("ServerConfig:\n"))
 	sb.WriteString(fmt.Sprintf("  Port: %s\n", cfg.ServerConfig.Port))
+	sb.WriteString(fmt.Sprintf("  HTTPPort: %s\n", cfg.ServerConfig.HTTPPort))
 
 	// DBConfig
 	sb.WriteString(fmt.Sprintf("DBConfig:\n"))
+	sb.WriteString(fmt.Sprintf("  Backend: %s\n", cfg.DBConfig.Backend))
 	sb.WriteString(fmt.Sprintf("  DBHost: %s\n", cfg.DBConfig.DBHost))
 	sb.WriteString(fmt.Sprintf("  DBPort: %s\n", cfg.DBConfig.DBPort))
 	sb.WriteString(fmt.Sprintf("  DBUser: %s\n", cfg.DBConfig.DBUser))
 	sb.WriteString(fmt.Sprintf("  DBPassword: %s\n", cfg.DBConfig.DBPassword))
 	sb.WriteString(fmt.Sprintf("  DBName: %s\n", cfg.DBConfig.DBName))
+	sb.WriteString(fmt.Sprintf("  SSLMode: %s\n", cfg.DBConfig.SSLMode))
+	sb.WriteString(fmt.Sprintf("  AsyncInsertMaxRows: %d\n", cfg.DBConfig.AsyncInsertMaxRows))
+	sb.WriteString(fmt.Sprintf("  MaxOpenConns: %d\n", cfg.DBConfig.MaxOpenConns))
 
 	// LoggerConfig
 	sb.WriteString(fmt.Sprintf("LoggerConfig:\n"))
@@ -95,16 +317,209 @@ func (cfg Config) String() string {
 	sb.WriteString(fmt.Sprintf("  Topic: %s\n", cfg.BrokerConfig.Topic))
 	sb.WriteString(fmt.Sprintf("  BrokerAddr: %s\n", cfg.BrokerConfig.BrokerAddr))
 
+	// AuthConfig (без токенов)
+	sb.WriteString(fmt.Sprintf("AuthConfig:\n"))
+	sb.WriteString(fmt.Sprintf("  Clients: %d\n", len(strings.FieldsFunc(cfg.AuthConfig.Clients, func(r rune) bool { return r == ',' }))))
+	sb.WriteString(fmt.Sprintf("  Admins: %s\n", cfg.AuthConfig.Admins))
+
+	// MISPConfig
+	sb.WriteString(fmt.Sprintf("MISPConfig:\n"))
+	sb.WriteString(fmt.Sprintf("  FeedDir: %s\n", cfg.MISPConfig.FeedDir))
+	sb.WriteString(fmt.Sprintf("  FeedInterval: %s\n", cfg.MISPConfig.FeedInterval))
+
+	// AlertsConfig
+	sb.WriteString(fmt.Sprintf("AlertsConfig:\n"))
+	sb.WriteString(fmt.Sprintf("  RulesFile: %s\n", cfg.AlertsConfig.RulesFile))
+	sb.WriteString(fmt.Sprintf("  DeliveryLog: %s\n", cfg.AlertsConfig.DeliveryLog))
+
+	// AllowlistConfig
+	sb.WriteString(fmt.Sprintf("Allowlist:\n"))
+	sb.WriteString(fmt.Sprintf("  Policy: %s\n", cfg.Allowlist.Policy))
+	sb.WriteString(fmt.Sprintf("  TopNFile: %s\n", cfg.Allowlist.TopNFile))
+	sb.WriteString(fmt.Sprintf("  TopNSize: %d\n", cfg.Allowlist.TopNSize))
+	sb.WriteString(fmt.Sprintf("  Refresh: %s\n", cfg.Allowlist.Refresh))
+
+	// GeoIPConfig
+	sb.WriteString(fmt.Sprintf("GeoIP:\n"))
+	sb.WriteString(fmt.Sprintf("  CityDB: %s\n", cfg.GeoIP.CityDB))
+	sb.WriteString(fmt.Sprintf("  ASNDB: %s\n", cfg.GeoIP.ASNDB))
+	sb.WriteString(fmt.Sprintf("  ReloadInterval: %s\n", cfg.GeoIP.ReloadInterval))
+
+	// PipelineConfig
+	sb.WriteString(fmt.Sprintf("Pipeline:\n"))
+	sb.WriteString(fmt.Sprintf("  StagesFile: %s\n", cfg.Pipeline.StagesFile))
+
+	// LifecycleConfig
+	sb.WriteString(fmt.Sprintf("Lifecycle:\n"))
+	sb.WriteString(fmt.Sprintf("  Refresh: %s\n", cfg.Lifecycle.Refresh))
+
+	// AuditConfig
+	sb.WriteString(fmt.Sprintf("Audit:\n"))
+	sb.WriteString(fmt.Sprintf("  RetentionDays: %d\n", cfg.Audit.RetentionDays))
+
+	// TenantConfig
+	sb.WriteString(fmt.Sprintf("Tenant:\n"))
+	sb.WriteString(fmt.Sprintf("  Clients: %s\n", cfg.Tenant.Clients))
+	sb.WriteString(fmt.Sprintf("  Quotas: %s\n", cfg.Tenant.Quotas))
+
+	// LimitsConfig
+	sb.WriteString(fmt.Sprintf("Limits:\n"))
+	sb.WriteString(fmt.Sprintf("  Rates: %s\n", cfg.Limits.Rates))
+	sb.WriteString(fmt.Sprintf("  Streams: %s\n", cfg.Limits.Streams))
+	sb.WriteString(fmt.Sprintf("  MaxRows: %s\n", cfg.Limits.MaxRows))
+
+	// ExecutorConfig
+	sb.WriteString(fmt.Sprintf("Executor:\n"))
+	sb.WriteString(fmt.Sprintf("  InteractiveWorkers: %d\n", cfg.Executor.InteractiveWorkers))
+	sb.WriteString(fmt.Sprintf("  WriteWorkers: %d\n", cfg.Executor.WriteWorkers))
+	sb.WriteString(fmt.Sprintf("  BulkWorkers: %d\n", cfg.Executor.BulkWorkers))
+	sb.WriteString(fmt.Sprintf("  QueueSize: %d\n", cfg.Executor.QueueSize))
+	sb.WriteString(fmt.Sprintf("  QueueTimeout: %s\n", cfg.Executor.QueueTimeout))
+
+	// ShutdownConfig
+	sb.WriteString(fmt.Sprintf("Shutdown:\n"))
+	sb.WriteString(fmt.Sprintf("  Timeout: %s\n", cfg.Shutdown.Timeout))
+
+	// SpoolConfig
+	sb.WriteString(fmt.Sprintf("Spool:\n"))
+	sb.WriteString(fmt.Sprintf("  Dir: %s\n", cfg.Spool.Dir))
+	sb.WriteString(fmt.Sprintf("  SegmentBytes: %d\n", cfg.Spool.SegmentBytes))
+	sb.WriteString(fmt.Sprintf("  MaxBytes: %d\n", cfg.Spool.MaxBytes))
+	sb.WriteString(fmt.Sprintf("  RetryInterval: %s\n", cfg.Spool.RetryInterval))
+
+	// CacheConfig
+	sb.WriteString(fmt.Sprintf("Cache:\n"))
+	sb.WriteString(fmt.Sprintf("  TTLs: %s\n", cfg.Cache.TTLs))
+	sb.WriteString(fmt.Sprintf("  MaxBytes: %d\n", cfg.Cache.MaxBytes))
+	sb.WriteString(fmt.Sprintf("  LoadMaxRows: %d\n", cfg.Cache.LoadMaxRows))
+	sb.WriteString(fmt.Sprintf("  RedisAddr: %s\n", cfg.Cache.RedisAddr))
+	sb.WriteString(fmt.Sprintf("  RedisDB: %d\n", cfg.Cache.RedisDB))
+
 	return sb.String()
 }
 
-func (cfg *DBConfig) ConnStr() string {
-	return fmt.Sprintf("tcp://%s:%s?username=%s&password=%s&database=%s",
-		cfg.DBHost, cfg.DBPort,
-		url.QueryEscape(cfg.DBUser),
-		url.QueryEscape(cfg.DBPassword),
-		cfg.DBName)
+// loadSpoolConfig - каталог и размеры spool; MaxBytes = 0 снимает ограничение
+func loadSpoolConfig() (SpoolConfig, error) {
+	cfg := SpoolConfig{Dir: getEnv("SPOOL_DIR", "spool")}
+	segmentBytes, err := strconv.ParseInt(getEnv("SPOOL_SEGMENT_BYTES", "67108864"), 10, 64)
+	if err != nil || segmentBytes <= 0 {
+		return SpoolConfig{}, fmt.Errorf("invalid SPOOL_SEGMENT_BYTES: %q", os.Getenv("SPOOL_SEGMENT_BYTES"))
+	}
+	maxBytes, err := strconv.ParseInt(getEnv("SPOOL_MAX_BYTES", "1073741824"), 10, 64)
+	if err != nil || maxBytes < 0 {
+		return SpoolConfig{}, fmt.Errorf("invalid SPOOL_MAX_BYTES: %q", os.Getenv("SPOOL_MAX_BYTES"))
+	}
+	retryInterval, err := time.ParseDuration(getEnv("SPOOL_RETRY_INTERVAL", "5s"))
+	if err != nil || retryInterval <= 0 {
+		return SpoolConfig{}, fmt.Errorf("invalid SPOOL_RETRY_INTERVAL: %q", os.Getenv("SPOOL_RETRY_INTERVAL"))
+	}
+	cfg.SegmentBytes, cfg.MaxBytes, cfg.RetryInterval = segmentBytes, maxBytes, retryInterval
+	return cfg, nil
+}
+
+// loadCacheConfig - TTL и размеры кэша запросов
+func loadCacheConfig() (CacheConfig, error) {
+	cfg := CacheConfig{
+		TTLs:          getEnv("CACHE_TTLS", "*=30s,UnaryLoad=10s,SourceReport=5m"),
+		RedisAddr:     getEnv("CACHE_REDIS_ADDR", ""),
+		RedisPassword: getEnv("CACHE_REDIS_PASSWORD", ""),
+	}
+	var err error
+	if cfg.MaxBytes, err = strconv.ParseInt(getEnv("CACHE_MAX_BYTES", "67108864"), 10, 64); err != nil || cfg.MaxBytes <= 0 {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_MAX_BYTES: %q", os.Getenv("CACHE_MAX_BYTES"))
+	}
+	if cfg.LoadMaxRows, err = strconv.ParseInt(getEnv("CACHE_LOAD_MAX_ROWS", "1000"), 10, 64); err != nil || cfg.LoadMaxRows < 0 {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_LOAD_MAX_ROWS: %q", os.Getenv("CACHE_LOAD_MAX_ROWS"))
+	}
+	if cfg.RedisDB, err = strconv.Atoi(getEnv("CACHE_REDIS_DB", "0")); err != nil || cfg.RedisDB < 0 {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_REDIS_DB: %q", os.Getenv("CACHE_REDIS_DB"))
+	}
+	return cfg, nil
+}
 
+// loadExecutorConfig - размеры пулов задач; все значения должны быть положительными
+func loadExecutorConfig() (ExecutorConfig, error) {
+	var cfg ExecutorConfig
+	for _, field := range []struct {
+		key, def string
+		value    *int
+	}{
+		{"WORKERS_INTERACTIVE", "8", &cfg.InteractiveWorkers},
+		{"WORKERS_WRITE", "4", &cfg.WriteWorkers},
+		{"WORKERS_BULK", "4", &cfg.BulkWorkers},
+		{"TASK_QUEUE_SIZE", "100", &cfg.QueueSize},
+	} {
+		value, err := strconv.Atoi(getEnv(field.key, field.def))
+		if err != nil || value <= 0 {
+			return ExecutorConfig{}, fmt.Errorf("invalid %s: %q", field.key, os.Getenv(field.key))
+		}
+		*field.value = value
+	}
+	for _, field := range []struct {
+		key, def string
+		value    *time.Duration
+	}{
+		{"TASK_QUEUE_TIMEOUT", "5s", &cfg.QueueTimeout},
+	} {
+		value, err := time.ParseDuration(getEnv(field.key, field.def))
+		if err != nil || value <= 0 {
+			return ExecutorConfig{}, fmt.Errorf("invalid %s: %q", field.key, os.Getenv(field.key))
+		}
+		*field.value = value
+	}
+	return cfg, nil
+}
+
+// loadDBConfig - хранилище IoC; порт по умолчанию зависит от хранилища
+func loadDBConfig() (DBConfig, error) {
+	backend := getEnv("DB_BACKEND", BackendClickHouse)
+	defaultPort := "9000"
+	switch backend {
+	case BackendClickHouse:
+	case BackendPostgres:
+		defaultPort = "5432"
+	case BackendMemory:
+	default:
+		return DBConfig{}, fmt.Errorf("invalid DB_BACKEND: %q, expected %s, %s or %s", backend, BackendClickHouse, BackendPostgres, BackendMemory)
+	}
+	asyncInsertMaxRows, err := strconv.Atoi(getEnv("DB_ASYNC_INSERT_MAX_ROWS", "0"))
+	if err != nil || asyncInsertMaxRows < 0 {
+		return DBConfig{}, fmt.Errorf("invalid DB_ASYNC_INSERT_MAX_ROWS: %q", os.Getenv("DB_ASYNC_INSERT_MAX_ROWS"))
+	}
+	maxOpenConns, err := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "0"))
+	if err != nil || maxOpenConns < 0 {
+		return DBConfig{}, fmt.Errorf("invalid DB_MAX_OPEN_CONNS: %q", os.Getenv("DB_MAX_OPEN_CONNS"))
+	}
+	return DBConfig{
+		Backend:    backend,
+		DBHost:     getEnv("DB_HOST", "localhost"),
+		DBPort:     getEnv("DB_PORT", defaultPort),
+		DBUser:     getEnv("DB_USER", "user"),
+		DBPassword: getEnv("DB_PASSWORD", "password"),
+		DBName:     getEnv("DB_NAME", "app_db"),
+		SSLMode:    getEnv("DB_SSLMODE", "disable"),
+
+		AsyncInsertMaxRows: asyncInsertMaxRows,
+		MaxOpenConns:       maxOpenConns,
+	}, nil
+}
+
+// ConnStr - DSN выбранного хранилища: нативный протокол ClickHouse или PostgreSQL
+func (cfg *DBConfig) ConnStr() string {
+	dsn := url.URL{
+		Scheme: "clickhouse",
+		User:   url.UserPassword(cfg.DBUser, cfg.DBPassword),
+		Host:   net.JoinHostPort(cfg.DBHost, cfg.DBPort),
+		Path:   "/" + cfg.DBName,
+	}
+	switch {
+	case cfg.Backend == BackendPostgres:
+		dsn.Scheme = "postgres"
+		dsn.RawQuery = url.Values{"sslmode": {cfg.SSLMode}}.Encode()
+	case cfg.MaxOpenConns > 0:
+		dsn.RawQuery = url.Values{"max_open_conns": {strconv.Itoa(cfg.MaxOpenConns)}}.Encode()
+	}
+	return dsn.String()
 }
 
 // getEnv gets an environment variable or returns a default value
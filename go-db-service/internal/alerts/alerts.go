@@ -0,0 +1,272 @@
+package alerts
+
+import (
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	deliveryQueueSize  = 1000 // Очередь срабатываний на отправку
+	deliveryWorkers    = 4
+	defaultMaxAttempts = 5
+	initialBackoff     = time.Second
+	maxBackoff         = time.Minute
+	requestTimeout     = 10 * time.Second
+
+	// Заголовки запроса вебхука
+	SignatureHeader = "X-IoC-Signature" // sha256=<hex HMAC-SHA256(secret, timestamp + "." + body)>
+	TimestampHeader = "X-IoC-Timestamp" // unix время отправки, входит в подпись
+	DeliveryHeader  = "X-IoC-Delivery"  // id доставки, одинаковый для всех попыток
+)
+
+// Rule - правило оповещения из файла правил
+type Rule struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Match       string  `json:"match"`            // Выражение над полями IoC, см. ParseExpr
+	Tenant      string  `json:"tenant,omitempty"` // Арендатор правила; без арендатора правило видит только общие IoC
+	Webhook     Webhook `json:"webhook"`
+	MaxAttempts int     `json:"max_attempts,omitempty"` // 0 - по умолчанию 5
+	Disabled    bool    `json:"disabled,omitempty"`
+}
+
+// Webhook - адрес и секрет для подписи; секрет можно взять из переменной окружения
+type Webhook struct {
+	URL       string `json:"url"`
+	Secret    string `json:"secret,omitempty"`
+	SecretEnv string `json:"secret_env,omitempty"`
+}
+
+// Payload - тело запроса вебхука
+type Payload struct {
+	DeliveryID string          `json:"delivery_id"`
+	RuleID     string          `json:"rule_id"`
+	RuleName   string          `json:"rule_name"`
+	FiredAt    time.Time       `json:"fired_at"`
+	IoCs       []models.IoCDto `json:"iocs"`
+}
+
+type compiledRule struct {
+	Rule
+	expr   Expr
+	secret []byte
+}
+
+type delivery struct {
+	rule    *compiledRule
+	payload Payload
+}
+
+// LoadRules - читает правила из JSON файла (массив Rule)
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Engine - проверяет записанные пачки IoC по правилам и отправляет вебхуки.
+// Отправка асинхронная: запись в хранилище не ждет получателей.
+type Engine struct {
+	rules  []*compiledRule
+	log    *DeliveryLog
+	client *http.Client
+	queue  chan delivery
+	logger logger.CustomZapLogger
+
+	backoff time.Duration // Задержка перед первым повтором, дальше удваивается до maxBackoff
+}
+
+// NewEngine - компилирует правила; ошибка в любом правиле не дает запустить сервис с неполным набором
+func NewEngine(rules []Rule, log *DeliveryLog, logger logger.CustomZapLogger) (*Engine, error) {
+	engine := &Engine{
+		log:    log,
+		client: &http.Client{Timeout: requestTimeout},
+		queue:  make(chan delivery, deliveryQueueSize),
+		logger: logger,
+
+		backoff: initialBackoff,
+	}
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("alert rule %q has no id", rule.Name)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("duplicate alert rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.Disabled {
+			continue
+		}
+		if rule.Tenant != tenant.Shared {
+			if err := tenant.Validate(rule.Tenant); err != nil {
+				return nil, fmt.Errorf("alert rule %s: %w", rule.ID, err)
+			}
+		}
+		expr, err := ParseExpr(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %s: invalid match: %w", rule.ID, err)
+		}
+		if rule.Webhook.URL == "" {
+			return nil, fmt.Errorf("alert rule %s: webhook url is required", rule.ID)
+		}
+		secret := rule.Webhook.Secret
+		if rule.Webhook.SecretEnv != "" {
+			secret = os.Getenv(rule.Webhook.SecretEnv)
+		}
+		if rule.MaxAttempts <= 0 {
+			rule.MaxAttempts = defaultMaxAttempts
+		}
+		engine.rules = append(engine.rules, &compiledRule{Rule: rule, expr: expr, secret: []byte(secret)})
+	}
+	return engine, nil
+}
+
+// Rules - количество активных правил
+func (e *Engine) Rules() int {
+	return len(e.rules)
+}
+
+// Run - запускает отправку вебхуков до отмены контекста
+func (e *Engine) Run(ctx context.Context) {
+	for i := 0; i < deliveryWorkers; i++ {
+		go e.worker(ctx)
+	}
+}
+
+// Evaluate - проверяет пачку по всем правилам; на каждое сработавшее правило уходит один вебхук.
+// Правилу видны только IoC, доступные его арендатору и не скрытые для него suppressed (может быть nil).
+func (e *Engine) Evaluate(iocs []models.IoCDto, suppressed func(viewer string, ioc models.IoCDto) bool) {
+	for _, rule := range e.rules {
+		var matched []models.IoCDto
+		for _, ioc := range iocs {
+			if !tenant.Visible(rule.Tenant, ioc.Tenant) || (suppressed != nil && suppressed(rule.Tenant, ioc)) {
+				continue
+			}
+			if rule.expr.Eval(ioc) {
+				matched = append(matched, ioc)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		d := delivery{rule: rule, payload: Payload{
+			DeliveryID: uuid.NewString(),
+			RuleID:     rule.ID,
+			RuleName:   rule.Name,
+			FiredAt:    time.Now().UTC(),
+			IoCs:       matched,
+		}}
+		select {
+		case e.queue <- d:
+		default:
+			e.logger.Error("Alert delivery queue is full, dropping notification", zap.String("rule", rule.ID), zap.Int("iocs", len(matched)))
+			e.log.Record(DeliveryRecord{DeliveryID: d.payload.DeliveryID, RuleID: rule.ID, URL: rule.Webhook.URL, IoCs: len(matched), Status: StatusDropped, Error: "delivery queue is full"})
+		}
+	}
+}
+
+func (e *Engine) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-e.queue:
+			e.deliver(ctx, d)
+		}
+	}
+}
+
+// deliver - отправка с повторами по сетевым ошибкам, 429 и 5xx с экспоненциальной задержкой
+func (e *Engine) deliver(ctx context.Context, d delivery) {
+	body, err := json.Marshal(d.payload)
+	if err != nil {
+		e.logger.Error("Failed to marshal alert payload", zap.String("rule", d.rule.ID), zap.Error(err))
+		return
+	}
+
+	backoff := e.backoff
+	for attempt := 1; attempt <= d.rule.MaxAttempts; attempt++ {
+		record := DeliveryRecord{DeliveryID: d.payload.DeliveryID, RuleID: d.rule.ID, URL: d.rule.Webhook.URL, Attempt: attempt, IoCs: len(d.payload.IoCs)}
+		statusCode, err := e.send(ctx, d, body)
+		record.StatusCode = statusCode
+
+		retryable := err != nil || statusCode == http.StatusTooManyRequests || statusCode >= 500
+		switch {
+		case err == nil && statusCode < 300:
+			record.Status = StatusDelivered
+		case err != nil:
+			record.Status, record.Error = StatusFailed, err.Error()
+		default:
+			record.Status, record.Error = StatusFailed, fmt.Sprintf("unexpected status %d", statusCode)
+		}
+		if record.Status == StatusFailed && (!retryable || attempt == d.rule.MaxAttempts) {
+			record.Status = StatusAbandoned
+		}
+		e.log.Record(record)
+
+		if record.Status != StatusFailed {
+			if record.Status == StatusAbandoned {
+				e.logger.Error("Alert webhook delivery abandoned", zap.String("rule", d.rule.ID), zap.String("delivery", d.payload.DeliveryID), zap.Int("attempt", attempt), zap.String("error", record.Error))
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (e *Engine) send(ctx context.Context, d delivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.rule.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, d.payload.DeliveryID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(d.rule.secret, timestamp, body))
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// Sign - подпись тела вебхука, получатель сверяет ее со своим секретом
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
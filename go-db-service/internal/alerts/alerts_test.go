@@ -0,0 +1,166 @@
+package alerts
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestEngine - движок без воркеров: срабатывания остаются в очереди
+func newTestEngine(t *testing.T, rules ...Rule) *Engine {
+	t.Helper()
+	log, err := NewDeliveryLog("")
+	if err != nil {
+		t.Fatalf("delivery log: %v", err)
+	}
+	engine, err := NewEngine(rules, log, *logger.NewNop())
+	if err != nil {
+		t.Fatalf("engine: %v", err)
+	}
+	return engine
+}
+
+// queued - значения IoC в очереди отправки по правилам
+func queued(e *Engine) map[string]string {
+	result := make(map[string]string)
+	for len(e.queue) > 0 {
+		d := <-e.queue
+		var values []string
+		for _, ioc := range d.payload.IoCs {
+			values = append(values, ioc.Value)
+		}
+		sort.Strings(values)
+		result[d.rule.ID] = strings.Join(values, ",")
+	}
+	return result
+}
+
+func TestEvaluateRespectsRuleTenant(t *testing.T) {
+	webhook := Webhook{URL: "http://receiver.invalid/hook"}
+	engine := newTestEngine(t,
+		Rule{ID: "shared", Match: `type == "domain"`, Webhook: webhook},
+		Rule{ID: "unit-a", Match: `type == "domain"`, Tenant: "unit-a", Webhook: webhook},
+		Rule{ID: "unit-b", Match: `type == "domain"`, Tenant: "unit-b", Webhook: webhook},
+	)
+	iocs := []models.IoCDto{
+		{Type: models.TypeDomain, Value: "feed.example.com"},
+		{Type: models.TypeDomain, Value: "a.example.com", Tenant: "unit-a"},
+		{Type: models.TypeDomain, Value: "fp.example.com"},
+	}
+	// unit-a пометила общий fp.example.com как false_positive
+	suppressed := func(viewer string, ioc models.IoCDto) bool {
+		return viewer == "unit-a" && ioc.Value == "fp.example.com"
+	}
+
+	engine.Evaluate(iocs, suppressed)
+	got := queued(engine)
+	want := map[string]string{
+		"shared": "feed.example.com,fp.example.com",
+		"unit-a": "a.example.com,feed.example.com",
+		"unit-b": "feed.example.com,fp.example.com",
+	}
+	for id, values := range want {
+		if got[id] != values {
+			t.Fatalf("rule %s: got %q, want %q", id, got[id], values)
+		}
+	}
+}
+
+func TestNewEngineRejectsInvalidTenant(t *testing.T) {
+	log, _ := NewDeliveryLog("")
+	_, err := NewEngine([]Rule{{ID: "bad", Match: `type == "ip"`, Tenant: "Unit A", Webhook: Webhook{URL: "http://receiver.invalid"}}}, log, *logger.NewNop())
+	if err == nil {
+		t.Fatal("rule with invalid tenant accepted")
+	}
+}
+
+// receiver - получатель вебхуков, отвечающий кодами из statuses по очереди
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		want     []string // Статусы попыток в журнале
+	}{
+		{"delivered", nil, 3, []string{StatusDelivered}},
+		{"retry on 5xx", []int{http.StatusInternalServerError, http.StatusBadGateway}, 3, []string{StatusFailed, StatusFailed, StatusDelivered}},
+		{"retry on 429", []int{http.StatusTooManyRequests}, 3, []string{StatusFailed, StatusDelivered}},
+		{"abandon on 4xx", []int{http.StatusBadRequest, http.StatusOK}, 3, []string{StatusAbandoned}},
+		{"abandon after max attempts", []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}, 2, []string{StatusFailed, StatusAbandoned}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recv := &receiver{statuses: tt.statuses}
+			server := httptest.NewServer(recv)
+			defer server.Close()
+
+			engine := newTestEngine(t, Rule{ID: "rule", Name: "Rule", Match: `type == "ip"`, MaxAttempts: tt.attempts, Webhook: Webhook{URL: server.URL, Secret: "s3cret"}})
+			engine.backoff = time.Millisecond
+			engine.Evaluate([]models.IoCDto{{Type: models.TypeIP, Value: "192.0.2.10"}}, nil)
+			engine.deliver(context.Background(), <-engine.queue)
+
+			records := engine.log.Recent(10)
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Status)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("attempts: got %v, want %v", got, tt.want)
+			}
+			if len(recv.requests) != len(tt.want) {
+				t.Fatalf("receiver got %d requests, want %d", len(recv.requests), len(tt.want))
+			}
+
+			var payload Payload
+			if err := json.Unmarshal(recv.bodies[0], &payload); err != nil {
+				t.Fatalf("payload: %v", err)
+			}
+			if payload.RuleID != "rule" || len(payload.IoCs) != 1 || payload.IoCs[0].Value != "192.0.2.10" {
+				t.Fatalf("payload: %+v", payload)
+			}
+			for i, req := range recv.requests {
+				timestamp := req.Header.Get(TimestampHeader)
+				mac := hmac.New(sha256.New, []byte("s3cret"))
+				mac.Write([]byte(timestamp + "." + string(recv.bodies[i])))
+				if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get(SignatureHeader) != want {
+					t.Fatalf("attempt %d: signature %q, want %q", i+1, req.Header.Get(SignatureHeader), want)
+				}
+				if req.Header.Get(DeliveryHeader) != payload.DeliveryID {
+					t.Fatalf("attempt %d: delivery id %q, want %q", i+1, req.Header.Get(DeliveryHeader), payload.DeliveryID)
+				}
+			}
+		})
+	}
+}
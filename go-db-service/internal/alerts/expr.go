@@ -0,0 +1,334 @@
+package alerts
+
+import (
+	"awesomeProject/models"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Выражения правил:
+//
+//	expr       := and ("or" and)*
+//	and        := unary ("and" unary)*
+//	unary      := "not" unary | "(" expr ")" | comparison
+//	comparison := field ("==" | "!=" | "contains" | "matches") string | field "in" "[" string ("," string)* "]"
+//	field      := id | source | type | value | tags | additional_data.<key>
+//
+// Сравнения регистронезависимые (хранилище пишет значения в нижнем регистре).
+// Для tags условие выполняется, если подходит хотя бы один тег; contains для tags - наличие тега,
+// для остальных полей - подстрока. Пример: tags contains "apt" or (source == "feodotracker" and type == "ip")
+
+// Expr - разобранное выражение правила
+type Expr interface {
+	Eval(ioc models.IoCDto) bool
+}
+
+type orExpr struct{ left, right Expr }
+
+func (e orExpr) Eval(ioc models.IoCDto) bool { return e.left.Eval(ioc) || e.right.Eval(ioc) }
+
+type andExpr struct{ left, right Expr }
+
+func (e andExpr) Eval(ioc models.IoCDto) bool { return e.left.Eval(ioc) && e.right.Eval(ioc) }
+
+type notExpr struct{ inner Expr }
+
+func (e notExpr) Eval(ioc models.IoCDto) bool { return !e.inner.Eval(ioc) }
+
+type comparison struct {
+	field  string
+	key    string // ключ additional_data
+	op     string
+	values []string
+	re     *regexp.Regexp
+}
+
+func (c comparison) Eval(ioc models.IoCDto) bool {
+	if c.field == "tags" {
+		if c.op == "!=" {
+			for _, tag := range ioc.Tags {
+				if strings.EqualFold(tag, c.values[0]) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, tag := range ioc.Tags {
+			if c.matchValue(tag, true) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var actual string
+	switch c.field {
+	case "id":
+		actual = ioc.ID
+	case "source":
+		actual = ioc.Source
+	case "type":
+		actual = ioc.Type
+	case "value":
+		actual = ioc.Value
+	case "additional_data":
+		actual = ioc.AdditionalData[c.key]
+	}
+	if c.op == "!=" {
+		return !strings.EqualFold(actual, c.values[0])
+	}
+	return c.matchValue(actual, false)
+}
+
+// matchValue - сравнение одного значения; для тегов contains означает точное совпадение тега
+func (c comparison) matchValue(actual string, tag bool) bool {
+	switch c.op {
+	case "==":
+		return strings.EqualFold(actual, c.values[0])
+	case "contains":
+		if tag {
+			return strings.EqualFold(actual, c.values[0])
+		}
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(c.values[0]))
+	case "matches":
+		return c.re.MatchString(actual)
+	case "in":
+		for _, value := range c.values {
+			if strings.EqualFold(actual, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseExpr - разбирает выражение правила
+func ParseExpr(input string) (Expr, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota // Конец выражения, его возвращает peek после последнего токена
+	tokenIdent
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		ch := rune(input[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			value, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, pos: i})
+			i = end + 1
+		case strings.HasPrefix(input[i:], "==") || strings.HasPrefix(input[i:], "!="):
+			tokens = append(tokens, token{kind: tokenSymbol, text: input[i : i+2], pos: i})
+			i += 2
+		case strings.ContainsRune("()[],", ch):
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(ch), pos: i})
+			i++
+		case isIdentRune(ch):
+			start := i
+			for i < len(input) && (isIdentRune(rune(input[i])) || input[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", ch, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentRune(ch rune) bool {
+	return ch == '_' || ch == '-' || ch < unicode.MaxASCII && (unicode.IsLetter(ch) || unicode.IsDigit(ch))
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokenEnd, text: "end of expression", pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword - следующий токен является идентификатором name (без учета регистра)
+func (p *parser) keyword(name string) bool {
+	if !p.done() && p.peek().kind == tokenIdent && strings.EqualFold(p.peek().text, name) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if t := p.next(); t.kind != tokenSymbol || t.text != symbol {
+		return fmt.Errorf("expected %q, got %q", symbol, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner: inner}, nil
+	}
+	if t := p.peek(); t.kind == tokenSymbol && t.text == "(" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	fieldToken := p.next()
+	if fieldToken.kind != tokenIdent {
+		return nil, fmt.Errorf("expected field, got %q", fieldToken.text)
+	}
+	c := comparison{field: strings.ToLower(fieldToken.text)}
+	if key, ok := strings.CutPrefix(c.field, "additional_data."); ok && key != "" {
+		c.field, c.key = "additional_data", fieldToken.text[len("additional_data."):]
+	}
+	switch c.field {
+	case "id", "source", "type", "value", "tags", "additional_data":
+	default:
+		return nil, fmt.Errorf("unknown field %q", fieldToken.text)
+	}
+	if c.field == "additional_data" && c.key == "" {
+		return nil, fmt.Errorf("additional_data requires a key, e.g. additional_data.malware")
+	}
+
+	opToken := p.next()
+	c.op = strings.ToLower(opToken.text)
+	switch {
+	case opToken.kind == tokenSymbol && (c.op == "==" || c.op == "!="),
+		opToken.kind == tokenIdent && (c.op == "contains" || c.op == "matches"):
+		value := p.next()
+		if value.kind != tokenString {
+			return nil, fmt.Errorf("expected string after %s, got %q", opToken.text, value.text)
+		}
+		c.values = []string{value.text}
+	case opToken.kind == tokenIdent && c.op == "in":
+		if err := p.expectSymbol("["); err != nil {
+			return nil, err
+		}
+		for {
+			value := p.next()
+			if value.kind != tokenString {
+				return nil, fmt.Errorf("expected string in list, got %q", value.text)
+			}
+			c.values = append(c.values, value.text)
+			if t := p.peek(); t.kind == tokenSymbol && t.text == "," {
+				p.next()
+				continue
+			}
+			break
+		}
+		if err := p.expectSymbol("]"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator %q", opToken.text)
+	}
+
+	if c.op == "matches" {
+		re, err := regexp.Compile("(?i)" + c.values[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp %q: %v", c.values[0], err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
@@ -0,0 +1,108 @@
+package alerts
+
+import (
+	"awesomeProject/models"
+	"strings"
+	"testing"
+)
+
+func TestExprEval(t *testing.T) {
+	ioc := models.IoCDto{
+		ID:             "ioc-1",
+		Source:         "feodotracker",
+		Type:           models.TypeIP,
+		Value:          "192.0.2.10",
+		Tags:           []string{"botnet", "APT28"},
+		AdditionalData: map[string]string{"malware": "Emotet", "port": "443"},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// Приоритет: and связывает сильнее or
+		{`type == "domain" or source == "feodotracker" and value == "192.0.2.10"`, true},
+		{`type == "domain" or source == "other" and value == "192.0.2.10"`, false},
+		{`(type == "domain" or source == "feodotracker") and value == "192.0.2.11"`, false},
+		{`type == "domain" and source == "other" or type == "ip"`, true},
+
+		// not и скобки
+		{`not type == "domain"`, true},
+		{`not not type == "domain"`, false},
+		{`not (type == "ip" and source == "feodotracker")`, false},
+		{`NOT type == "IP"`, false},
+
+		// in
+		{`source in ["urlhaus", "FeodoTracker"]`, true},
+		{`source in ["urlhaus"]`, false},
+		{`type in ["ip"] and not source in ["urlhaus", "threatfox"]`, true},
+
+		// contains, matches и регистр
+		{`value contains "0.2."`, true},
+		{`source contains "TRACKER"`, true},
+		{`value matches "^192\\.0\\.2\\.[0-9]+$"`, true},
+		{`source matches "^tracker"`, false},
+		{`id == "IOC-1"`, true},
+
+		// tags: contains - наличие тега целиком, != - ни один тег не равен значению
+		{`tags contains "apt28"`, true},
+		{`tags contains "apt"`, false},
+		{`tags == "botnet"`, true},
+		{`tags != "botnet"`, false},
+		{`tags != "phishing"`, true},
+		{`tags in ["phishing", "botnet"]`, true},
+		{`tags matches "^apt[0-9]+$"`, true},
+
+		// additional_data.<key>: ключ сохраняет регистр, отсутствующий ключ - пустая строка
+		{`additional_data.malware == "emotet"`, true},
+		{`additional_data.port in ["80", "443"]`, true},
+		{`additional_data.Malware == "emotet"`, false},
+		{`additional_data.missing == ""`, true},
+		{`additional_data.missing != "x"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := expr.Eval(ioc); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		err  string
+	}{
+		{"unterminated string", `source == "feodo`, "unterminated string at position 10"},
+		{"escaped quote at end", `source == "feodo\"`, "unterminated string"},
+		{"missing operand", `source ==`, `expected string after ==, got "end of expression"`},
+		{"missing right side of and", `type == "ip" and`, `expected field, got "end of expression"`},
+		{"empty not", `not`, `expected field, got "end of expression"`},
+		{"empty expression", ``, `expected field, got "end of expression"`},
+		{"unknown field", `country == "ru"`, `unknown field "country"`},
+		{"additional_data without key", `additional_data == "x"`, "additional_data requires a key"},
+		{"unknown operator", `source like "feodo"`, `unknown operator "like"`},
+		{"unclosed parenthesis", `(type == "ip"`, `expected ")", got "end of expression"`},
+		{"unclosed list", `type in ["ip", "domain"`, `expected "]", got "end of expression"`},
+		{"list without strings", `type in [ip]`, `expected string in list, got "ip"`},
+		{"trailing tokens", `type == "ip" "domain"`, `unexpected "domain" at position 13`},
+		{"invalid regexp", `value matches "("`, "invalid regexp"},
+		{"unexpected character", `type = "ip"`, "unexpected character '=' at position 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpr(tt.expr)
+			if err == nil {
+				t.Fatalf("%q parsed without error", tt.expr)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const recentDeliveries = 500 // Сколько последних записей журнала держать в памяти для HTTP API
+
+// Статусы попыток доставки
+const (
+	StatusDelivered = "delivered" // Получатель ответил 2xx
+	StatusFailed    = "failed"    // Попытка не удалась, будет повтор
+	StatusAbandoned = "abandoned" // Попытки исчерпаны или получатель ответил 4xx
+	StatusDropped   = "dropped"   // Очередь отправки переполнена
+)
+
+// DeliveryRecord - запись журнала доставки, одна на попытку
+type DeliveryRecord struct {
+	Time       time.Time `json:"time"`
+	DeliveryID string    `json:"delivery_id"`
+	RuleID     string    `json:"rule_id"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	IoCs       int       `json:"iocs"`
+	Status     string    `json:"status"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DeliveryLog - журнал доставок: дописывается в файл (NDJSON) и хранит последние записи в памяти
+type DeliveryLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	recent []DeliveryRecord
+}
+
+// NewDeliveryLog - журнал с записью в path; пустой path - только в памяти
+func NewDeliveryLog(path string) (*DeliveryLog, error) {
+	l := &DeliveryLog{}
+	if path == "" {
+		return l, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open delivery log: %w", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// Record - добавляет запись в журнал
+func (l *DeliveryLog) Record(record DeliveryRecord) {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.recent = append(l.recent, record)
+	if len(l.recent) > recentDeliveries {
+		l.recent = l.recent[len(l.recent)-recentDeliveries:]
+	}
+	if l.file != nil {
+		if line, err := json.Marshal(record); err == nil {
+			_, _ = l.file.Write(append(line, '\n'))
+		}
+	}
+}
+
+// Recent - последние записи журнала, новые в конце
+func (l *DeliveryLog) Recent(limit int) []DeliveryRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := l.recent
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return append([]DeliveryRecord(nil), records...)
+}
+
+// Close - закрывает файл журнала
+func (l *DeliveryLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,338 @@
+package allowlist
+
+import (
+	"awesomeProject/internal/domains"
+	"awesomeProject/models"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Tag - тег IoC, совпавших с allowlist при политиках tag и hide
+const Tag = "allowlisted"
+
+// Политики обработки IoC из allowlist при записи
+const (
+	PolicyDrop = "drop" // Не записывать
+	PolicyTag  = "tag"  // Записать с тегом allowlisted
+	PolicyHide = "hide" // Записать с тегом и скрыть из выдачи
+)
+
+// matchKey - ключ additional_data с причиной совпадения
+const matchKey = "allowlist_match"
+
+var (
+	ErrInvalidEntry = errors.New("invalid allowlist entry")
+	ErrNotFound     = errors.New("allowlist entry not found")
+)
+
+// ParsePolicy - проверка политики из конфигурации
+func ParsePolicy(policy string) (string, error) {
+	switch policy = strings.ToLower(strings.TrimSpace(policy)); policy {
+	case PolicyDrop, PolicyTag, PolicyHide:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown allowlist policy %q, expected drop, tag or hide", policy)
+}
+
+// NormalizeEntry - приводит запись к каноничному виду; пустой kind определяется по значению:
+// "*.google.com" - suffix, "10.0.0.0/8" - cidr, остальное - value
+func NormalizeEntry(entry models.AllowlistEntry) (models.AllowlistEntry, error) {
+	value := strings.ToLower(strings.TrimSpace(entry.Value))
+	if value == "" {
+		return entry, fmt.Errorf("%w: empty value", ErrInvalidEntry)
+	}
+	if entry.Kind == "" {
+		switch {
+		case strings.HasPrefix(value, "*.") || strings.HasPrefix(value, "."):
+			entry.Kind = models.AllowlistSuffix
+		case strings.Contains(value, "/"):
+			entry.Kind = models.AllowlistCIDR
+		default:
+			entry.Kind = models.AllowlistValue
+		}
+	}
+
+	switch entry.Kind = strings.ToLower(entry.Kind); entry.Kind {
+	case models.AllowlistValue:
+	case models.AllowlistSuffix:
+		value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(value, "*"), "."), ".")
+		if value == "" || strings.ContainsAny(value, "/:*") {
+			return entry, fmt.Errorf("%w: invalid domain suffix %q", ErrInvalidEntry, entry.Value)
+		}
+	case models.AllowlistCIDR:
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return entry, fmt.Errorf("%w: %v", ErrInvalidEntry, err)
+		}
+		value = prefix.Masked().String()
+	default:
+		return entry, fmt.Errorf("%w: unknown kind %q", ErrInvalidEntry, entry.Kind)
+	}
+	entry.Value = value
+	return entry, nil
+}
+
+// Matcher - неизменяемый индекс записей allowlist
+type Matcher struct {
+	values   map[string]string // значение -> id записи
+	suffixes map[string]string // домен -> id записи
+	prefixes []prefixEntry
+	top      map[string]struct{}
+}
+
+type prefixEntry struct {
+	prefix netip.Prefix
+	id     string
+}
+
+// NewMatcher - индекс по нормализованным записям и списку популярных доменов
+func NewMatcher(entries []models.AllowlistEntry, top []string) *Matcher {
+	m := &Matcher{
+		values:   make(map[string]string),
+		suffixes: make(map[string]string),
+		top:      make(map[string]struct{}, len(top)),
+	}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case models.AllowlistValue:
+			m.values[entry.Value] = entry.ID
+		case models.AllowlistSuffix:
+			m.suffixes[entry.Value] = entry.ID
+		case models.AllowlistCIDR:
+			if prefix, err := netip.ParsePrefix(entry.Value); err == nil {
+				m.prefixes = append(m.prefixes, prefixEntry{prefix: prefix, id: entry.ID})
+			}
+		}
+	}
+	for _, domain := range top {
+		m.top[domain] = struct{}{}
+	}
+	return m
+}
+
+// Match - совпадает ли IoC с allowlist; возвращает причину совпадения
+func (m *Matcher) Match(ioc models.IoCDto) (string, bool) {
+	value := strings.ToLower(ioc.Value)
+	if id, ok := m.values[value]; ok {
+		return "value:" + id, true
+	}
+
+	host := value
+	switch strings.ToLower(ioc.Type) {
+	case models.TypeURL:
+		host = domains.URLHost(value)
+	case models.TypeDomain:
+	case models.TypeIP:
+		return m.matchIP(value)
+	default:
+		return "", false
+	}
+	if host == "" {
+		return "", false
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return m.matchIP(host)
+	}
+
+	if id, ok := m.values[host]; ok {
+		return "value:" + id, true
+	}
+	if _, ok := m.top[strings.TrimPrefix(host, "www.")]; ok {
+		return "top:" + host, true
+	}
+	for domain := host; ; {
+		if id, ok := m.suffixes[domain]; ok {
+			return "suffix:" + id, true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return "", false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// matchIP - адрес или подсеть (FireHOL и подобные списки) внутри CIDR записи
+func (m *Matcher) matchIP(value string) (string, bool) {
+	network, err := netip.ParsePrefix(value)
+	if err != nil {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return "", false
+		}
+		network = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	if addr := network.Addr(); addr.Is4In6() {
+		network = netip.PrefixFrom(addr.Unmap(), max(network.Bits()-96, 0))
+	}
+	network = network.Masked()
+	for _, entry := range m.prefixes {
+		if entry.prefix.Bits() <= network.Bits() && entry.prefix.Contains(network.Addr()) {
+			return "cidr:" + entry.id, true
+		}
+	}
+	return "", false
+}
+
+// LoadTopN - первые n доменов из списка популярных сайтов: CSV Majestic Million (колонка Domain)
+// или файл с доменом на строку
+func LoadTopN(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open top-N list: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(64)
+	if !strings.Contains(string(head), ",") {
+		return readDomainLines(reader, n)
+	}
+
+	records := csv.NewReader(reader)
+	records.FieldsPerRecord = -1
+	header, err := records.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read top-N header: %w", err)
+	}
+	column := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), "domain") {
+			column = i
+		}
+	}
+	if column < 0 {
+		return nil, fmt.Errorf("top-N list %s has no Domain column", path)
+	}
+
+	var domains []string
+	for n <= 0 || len(domains) < n {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read top-N list: %w", err)
+		}
+		if column < len(record) && record[column] != "" {
+			domains = append(domains, strings.ToLower(strings.TrimSpace(record[column])))
+		}
+	}
+	return domains, nil
+}
+
+func readDomainLines(r io.Reader, n int) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() && (n <= 0 || len(domains) < n) {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			domains = append(domains, line)
+		}
+	}
+	return domains, scanner.Err()
+}
+
+// Allowlist - текущие записи и политика, применяемая при записи IoC
+type Allowlist struct {
+	policy string
+	top    []string
+
+	mu      sync.RWMutex
+	entries []models.AllowlistEntry
+	matcher *Matcher
+}
+
+// New - allowlist с политикой и списком популярных доменов (может быть пустым)
+func New(policy string, top []string) *Allowlist {
+	return &Allowlist{policy: policy, top: top, matcher: NewMatcher(nil, top)}
+}
+
+// Policy - политика обработки совпавших IoC
+func (a *Allowlist) Policy() string {
+	return a.policy
+}
+
+// Reload - заменяет набор записей
+func (a *Allowlist) Reload(entries []models.AllowlistEntry) {
+	matcher := NewMatcher(entries, a.top)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = entries
+	a.matcher = matcher
+}
+
+// Entries - текущие записи
+func (a *Allowlist) Entries() []models.AllowlistEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]models.AllowlistEntry(nil), a.entries...)
+}
+
+// Entry - запись по id
+func (a *Allowlist) Entry(id string) (models.AllowlistEntry, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, entry := range a.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return models.AllowlistEntry{}, false
+}
+
+// Apply - применяет политику к пачке: при drop совпавшие IoC убираются,
+// при tag и hide помечаются тегом allowlisted (hide дополнительно скрывает их из выдачи).
+// Возвращает пачку для записи и число совпавших IoC.
+func (a *Allowlist) Apply(iocs []models.IoCDto) ([]models.IoCDto, int) {
+	a.mu.RLock()
+	matcher := a.matcher
+	a.mu.RUnlock()
+
+	result := make([]models.IoCDto, 0, len(iocs))
+	matched := 0
+	for _, ioc := range iocs {
+		reason, ok := matcher.Match(ioc)
+		if !ok {
+			result = append(result, ioc)
+			continue
+		}
+		matched++
+		if a.policy == PolicyDrop {
+			continue
+		}
+		result = append(result, mark(ioc, reason, a.policy == PolicyHide))
+	}
+	return result, matched
+}
+
+// mark - тег и причина совпадения; теги и additional_data копируются, чтобы не менять данные вызывающего
+func mark(ioc models.IoCDto, reason string, hide bool) models.IoCDto {
+	tags := make([]string, 0, len(ioc.Tags)+1)
+	tagged := false
+	for _, tag := range ioc.Tags {
+		tagged = tagged || strings.EqualFold(tag, Tag)
+		tags = append(tags, tag)
+	}
+	if !tagged {
+		tags = append(tags, Tag)
+	}
+	ioc.Tags = tags
+
+	additionalData := make(map[string]string, len(ioc.AdditionalData)+1)
+	for key, value := range ioc.AdditionalData {
+		additionalData[key] = value
+	}
+	additionalData[matchKey] = reason
+	ioc.AdditionalData = additionalData
+
+	ioc.Hidden = ioc.Hidden || hide
+	return ioc
+}
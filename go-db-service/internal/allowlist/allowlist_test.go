@@ -0,0 +1,147 @@
+package allowlist
+
+import (
+	"awesomeProject/models"
+	"testing"
+)
+
+func TestNormalizeEntry(t *testing.T) {
+	tests := []struct {
+		kind, value string
+		wantKind    string
+		wantValue   string
+		invalid     bool
+	}{
+		{value: " Example.COM ", wantKind: models.AllowlistValue, wantValue: "example.com"},
+		{value: "*.Google.com", wantKind: models.AllowlistSuffix, wantValue: "google.com"},
+		{value: ".google.com.", wantKind: models.AllowlistSuffix, wantValue: "google.com"},
+		{value: "10.1.2.3/8", wantKind: models.AllowlistCIDR, wantValue: "10.0.0.0/8"},
+		{value: "2001:DB8::/32", wantKind: models.AllowlistCIDR, wantValue: "2001:db8::/32"},
+		{kind: "Value", value: "192.0.2.10", wantKind: models.AllowlistValue, wantValue: "192.0.2.10"},
+		{kind: models.AllowlistSuffix, value: "*.", invalid: true},
+		{kind: models.AllowlistSuffix, value: "10.0.0.0/8", invalid: true},
+		{kind: models.AllowlistCIDR, value: "10.0.0.0/33", invalid: true},
+		{kind: "regexp", value: ".*", invalid: true},
+		{value: "  ", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind+" "+tt.value, func(t *testing.T) {
+			entry, err := NormalizeEntry(models.AllowlistEntry{Kind: tt.kind, Value: tt.value})
+			if tt.invalid {
+				if err == nil {
+					t.Fatalf("accepted as %s %q", entry.Kind, entry.Value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			if entry.Kind != tt.wantKind || entry.Value != tt.wantValue {
+				t.Fatalf("got %s %q, want %s %q", entry.Kind, entry.Value, tt.wantKind, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestMatcherMatch(t *testing.T) {
+	var entries []models.AllowlistEntry
+	for id, value := range map[string]string{
+		"exact":  "update.example.org",
+		"hash":   "d41d8cd98f00b204e9800998ecf8427e",
+		"google": "*.google.com",
+		"lan":    "10.0.0.0/16",
+		"docs":   "2001:db8::/32",
+	} {
+		entry, err := NormalizeEntry(models.AllowlistEntry{ID: id, Value: value})
+		if err != nil {
+			t.Fatalf("normalize %s: %v", value, err)
+		}
+		entries = append(entries, entry)
+	}
+	m := NewMatcher(entries, []string{"wikipedia.org"})
+
+	tests := []struct {
+		iocType, value string
+		want           string // Причина совпадения, пустая - нет совпадения
+	}{
+		// Точные значения
+		{models.TypeDomain, "Update.Example.org", "value:exact"},
+		{models.TypeMD5, "D41D8CD98F00B204E9800998ECF8427E", "value:hash"},
+		{models.TypeURL, "https://update.example.org/file.exe", "value:exact"},
+		{models.TypeDomain, "cdn.update.example.org", ""},
+
+		// Суффиксы доменов: сам домен и поддомены, но не похожие имена
+		{models.TypeDomain, "google.com", "suffix:google"},
+		{models.TypeDomain, "mail.google.com", "suffix:google"},
+		{models.TypeURL, "http://docs.google.com:8080/a", "suffix:google"},
+		{models.TypeDomain, "evilgoogle.com", ""},
+		{models.TypeDomain, "google.com.evil.net", ""},
+
+		// Популярные домены: точное совпадение с www
+		{models.TypeDomain, "www.wikipedia.org", "top:www.wikipedia.org"},
+		{models.TypeDomain, "en.wikipedia.org", ""},
+
+		// CIDR: адреса и подсети внутри записи
+		{models.TypeIP, "10.0.3.4", "cidr:lan"},
+		{models.TypeIP, "::ffff:10.0.3.4", "cidr:lan"},
+		{models.TypeIP, "10.1.0.1", ""},
+		{models.TypeIP, "10.0.0.0/24", "cidr:lan"},
+		{models.TypeIP, "10.0.255.0/24", "cidr:lan"},
+		{models.TypeIP, "10.0.0.0/16", "cidr:lan"},
+		{models.TypeIP, "10.0.0.0/8", ""},
+		{models.TypeIP, "10.0.0.5/30", "cidr:lan"},
+		{models.TypeIP, "::ffff:10.0.0.0/120", "cidr:lan"},
+		{models.TypeIP, "2001:db8:1::/48", "cidr:docs"},
+		{models.TypeIP, "2001:db9::/48", ""},
+		{models.TypeURL, "http://10.0.0.7/payload", "cidr:lan"},
+		{models.TypeIP, "not-an-ip", ""},
+
+		// Хеш не сравнивается с доменными записями
+		{models.TypeSHA256, "google.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.iocType+" "+tt.value, func(t *testing.T) {
+			reason, ok := m.Match(models.IoCDto{Type: tt.iocType, Value: tt.value})
+			if ok != (tt.want != "") || reason != tt.want {
+				t.Fatalf("got %q (%v), want %q", reason, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPolicies(t *testing.T) {
+	entry, _ := NormalizeEntry(models.AllowlistEntry{ID: "lan", Value: "10.0.0.0/8"})
+	batch := []models.IoCDto{
+		{Type: models.TypeIP, Value: "10.1.2.3", Tags: []string{"scan"}},
+		{Type: models.TypeIP, Value: "192.0.2.10"},
+	}
+	tests := []struct {
+		policy string
+		kept   int
+		hidden bool
+	}{
+		{PolicyDrop, 1, false},
+		{PolicyTag, 2, false},
+		{PolicyHide, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			a := New(tt.policy, nil)
+			a.Reload([]models.AllowlistEntry{entry})
+			result, matched := a.Apply(batch)
+			if matched != 1 || len(result) != tt.kept {
+				t.Fatalf("matched %d, kept %d, want 1 and %d", matched, len(result), tt.kept)
+			}
+			if tt.policy == PolicyDrop {
+				return
+			}
+			marked := result[0]
+			if len(marked.Tags) != 2 || marked.Tags[1] != Tag || marked.AdditionalData[matchKey] != "cidr:lan" || marked.Hidden != tt.hidden {
+				t.Fatalf("marked IoC: tags %v, additional_data %v, hidden %v", marked.Tags, marked.AdditionalData, marked.Hidden)
+			}
+			if len(batch[0].Tags) != 1 || batch[0].AdditionalData != nil {
+				t.Fatal("Apply changed the caller's batch")
+			}
+		})
+	}
+}
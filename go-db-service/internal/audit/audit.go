@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"awesomeProject/internal/auth"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	queueSize      = 1000        // Записи, ожидающие записи в хранилище
+	batchSize      = 200         // Максимум записей в одной вставке
+	flushInterval  = time.Second // Как часто сбрасывать неполную пачку
+	enqueueTimeout = time.Second // Наибольшее ожидание места в очереди, прежде чем потерять запись
+	writeTimeout   = 10 * time.Second
+)
+
+// Имена действий, которые не являются RPC
+const (
+	MethodBrokerIngest = "broker.ingest"
+	MethodHTTPExport   = "http.export"
+	MethodCLIImport    = "cli.import"
+)
+
+// Writer - хранилище журнала аудита
+type Writer interface {
+	StoreAuditRecords(ctx context.Context, records []models.AuditRecord) error
+}
+
+type peerKey struct{}
+
+// WithPeer - адрес клиента для вызовов не через gRPC (HTTP)
+func WithPeer(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, peerKey{}, addr)
+}
+
+// Log - журнал аудита: записи копятся в очереди и пачками пишутся в хранилище в фоне,
+// чтобы аудит не добавлял задержку к записи IoC. Nil журнал ничего не пишет.
+type Log struct {
+	writer Writer
+	logger logger.CustomZapLogger
+	queue  chan models.AuditRecord
+	done   chan struct{}
+
+	dropped atomic.Int64 // Потеряно из-за переполненной очереди или ошибок хранилища
+}
+
+// NewLog - журнал с записью через writer; запись начинается после Run
+func NewLog(writer Writer, logger logger.CustomZapLogger) *Log {
+	return &Log{
+		writer: writer,
+		logger: logger,
+		queue:  make(chan models.AuditRecord, queueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Record - добавляет запись о вызове: клиент и адрес берутся из контекста, params сериализуются в JSON.
+// Место в очереди ждется, пока жив ctx вызова, но не дольше enqueueTimeout; потерянная запись уходит
+// только в лог приложения и учитывается в Dropped.
+func (l *Log) Record(ctx context.Context, method string, params interface{}, rows int, err error) {
+	if l == nil {
+		return
+	}
+	record := models.AuditRecord{
+		ID:      uuid.NewString(),
+		Time:    time.Now().UTC(),
+		Actor:   auth.IdentityFromContext(ctx),
+		Peer:    peerFromContext(ctx),
+		Method:  method,
+		Rows:    int64(rows),
+		Outcome: models.AuditOK,
+	}
+	if params != nil {
+		if encoded, marshalErr := json.Marshal(params); marshalErr == nil {
+			record.Params = string(encoded)
+		}
+	}
+	if err != nil {
+		record.Outcome = models.AuditError
+		record.Error = err.Error()
+	}
+
+	select {
+	case l.queue <- record:
+		return
+	default:
+	}
+	timer := time.NewTimer(enqueueTimeout)
+	defer timer.Stop()
+	select {
+	case l.queue <- record:
+	case <-ctx.Done():
+		l.drop(record)
+	case <-timer.C:
+		l.drop(record)
+	}
+}
+
+// drop - учитывает запись, для которой не нашлось места в очереди
+func (l *Log) drop(record models.AuditRecord) {
+	l.dropped.Add(1)
+	l.logger.Error("Audit queue is full, record dropped",
+		zap.String("method", record.Method),
+		zap.String("actor", record.Actor),
+		zap.String("params", record.Params),
+		zap.Int64("rows", record.Rows),
+		zap.String("outcome", record.Outcome))
+}
+
+// Handler - оборачивает обработчик пачек (консьюмер брокера) записью в журнал от имени actor
+func (l *Log) Handler(method, actor string, params interface{}, next func(ctx context.Context, iocs []models.IoCDto) error) func(ctx context.Context, iocs []models.IoCDto) error {
+	return func(ctx context.Context, iocs []models.IoCDto) error {
+		err := next(ctx, iocs)
+		l.Record(auth.WithIdentity(ctx, actor), method, params, len(iocs), err)
+		return err
+	}
+}
+
+// Run - пишет очередь в хранилище, пока не отменен ctx; остаток очереди сбрасывается перед выходом
+func (l *Log) Run(ctx context.Context) {
+	defer close(l.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditRecord, 0, batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case record := <-l.queue:
+					batch = append(batch, record)
+					if len(batch) == batchSize {
+						batch = l.flush(batch)
+					}
+				default:
+					l.flush(batch)
+					return
+				}
+			}
+		case record := <-l.queue:
+			batch = append(batch, record)
+			if len(batch) == batchSize {
+				batch = l.flush(batch)
+			}
+		case <-ticker.C:
+			batch = l.flush(batch)
+		}
+	}
+}
+
+// Wait - ждет, пока Run сбросит очередь после отмены контекста
+func (l *Log) Wait() {
+	if l != nil {
+		<-l.done
+	}
+}
+
+// Dropped - сколько записей не попало в хранилище
+func (l *Log) Dropped() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.dropped.Load()
+}
+
+func (l *Log) flush(batch []models.AuditRecord) []models.AuditRecord {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := l.writer.StoreAuditRecords(ctx, batch); err != nil {
+		// Записи не теряются бесследно: минимум остается в логе приложения
+		l.dropped.Add(int64(len(batch)))
+		for _, record := range batch {
+			l.logger.Error("Failed to write audit record",
+				zap.String("method", record.Method),
+				zap.String("actor", record.Actor),
+				zap.Time("time", record.Time),
+				zap.String("params", record.Params),
+				zap.Int64("rows", record.Rows),
+				zap.String("outcome", record.Outcome),
+				zap.Error(err))
+		}
+	}
+	return batch[:0]
+}
+
+func peerFromContext(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	addr, _ := ctx.Value(peerKey{}).(string)
+	return addr
+}
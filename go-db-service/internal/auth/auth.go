@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type identityKey struct{}
+
+type adminKey struct{}
+
+// Authenticator - проверка статических токенов клиентов HTTP API.
+// Токен передается как Bearer или как пароль HTTP Basic (TAXII клиенты обычно умеют только Basic).
+type Authenticator struct {
+	clients map[string]string // имя клиента -> токен
+	admins  map[string]bool   // клиенты, которым доступны административные вызовы
+}
+
+// NewAuthenticator - разбирает список клиентов вида "partner-a:token1,siem:token2"
+func NewAuthenticator(spec string) (*Authenticator, error) {
+	a := &Authenticator{clients: make(map[string]string)}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, token, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || token == "" {
+			return nil, fmt.Errorf("invalid auth client entry %q, expected name:token", pair)
+		}
+		a.clients[name] = token
+	}
+	return a, nil
+}
+
+// SetAdmins - клиенты из AUTH_CLIENTS, которым доступны административные вызовы (журнал аудита,
+// изменение allowlist) и запись IoC любого арендатора; список вида "ops,siem"
+func (a *Authenticator) SetAdmins(spec string) error {
+	a.admins = make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := a.clients[name]; !ok {
+			return fmt.Errorf("admin client %q is not listed in auth clients", name)
+		}
+		a.admins[name] = true
+	}
+	return nil
+}
+
+// Enabled - заданы ли клиенты; без них закрытые ручки недоступны никому
+func (a *Authenticator) Enabled() bool {
+	return len(a.clients) > 0
+}
+
+// Authenticate - возвращает имя клиента по заголовку Authorization
+func (a *Authenticator) Authenticate(r *http.Request) (string, bool) {
+	if user, password, ok := r.BasicAuth(); ok {
+		return user, a.check(user, password)
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.AuthenticateToken(token)
+	}
+	return "", false
+}
+
+// AuthenticateToken - имя клиента по токену
+func (a *Authenticator) AuthenticateToken(token string) (string, bool) {
+	for name := range a.clients {
+		if a.check(name, token) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (a *Authenticator) check(name, token string) bool {
+	expected, ok := a.clients[name]
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+// Middleware - пропускает только аутентифицированные запросы и кладет имя клиента в контекст
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := a.Authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ioc-db"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(a.withIdentity(r.Context(), name)))
+	})
+}
+
+// withIdentity - кладет в контекст проверенное имя клиента и отметку администратора
+func (a *Authenticator) withIdentity(ctx context.Context, name string) context.Context {
+	ctx = WithIdentity(ctx, name)
+	if a.admins[name] {
+		ctx = WithAdmin(ctx)
+	}
+	return ctx
+}
+
+// WithIdentity - кладет имя клиента в контекст
+func WithIdentity(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, identityKey{}, name)
+}
+
+// IdentityFromContext - имя клиента из контекста или пустая строка
+func IdentityFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(identityKey{}).(string)
+	return name
+}
+
+// WithAdmin - отмечает вызов как административный: клиент из AUTH_ADMINS или внутренний
+// источник сервиса (брокер, импорт из консоли)
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin - административный ли вызов
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
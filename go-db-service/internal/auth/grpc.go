@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnverifiedPrefix - префикс имени клиента, которое передано в x-client-id без токена
+const UnverifiedPrefix = "unverified:"
+
+// IdentityFromMetadata - имя клиента gRPC: по токену из authorization (Bearer), иначе
+// заявленное в x-client-id с префиксом unverified:; true - имя подтверждено токеном.
+// gRPC API пока не закрыт, поэтому запросы без токена не отклоняются, а только помечаются для аудита.
+func (a *Authenticator) IdentityFromMetadata(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			if name, ok := a.AuthenticateToken(token); ok {
+				return name, true
+			}
+		}
+	}
+	for _, value := range md.Get("x-client-id") {
+		if value = strings.TrimSpace(value); value != "" {
+			return UnverifiedPrefix + value, false
+		}
+	}
+	return "", false
+}
+
+// VerifiedIdentity - имя клиента, подтвержденное токеном; заявленное через x-client-id не подходит
+func VerifiedIdentity(ctx context.Context) (string, bool) {
+	name := IdentityFromContext(ctx)
+	if name == "" || strings.HasPrefix(name, UnverifiedPrefix) {
+		return "", false
+	}
+	return name, true
+}
+
+// UnaryInterceptor - кладет имя клиента gRPC в контекст унарных вызовов
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(a.withMetadataIdentity(ctx), req)
+	}
+}
+
+// StreamInterceptor - кладет имя клиента gRPC в контекст стримов
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &identityStream{ServerStream: stream, ctx: a.withMetadataIdentity(stream.Context())})
+	}
+}
+
+func (a *Authenticator) withMetadataIdentity(ctx context.Context) context.Context {
+	name, verified := a.IdentityFromMetadata(ctx)
+	switch {
+	case verified:
+		return a.withIdentity(ctx, name)
+	case name != "":
+		return WithIdentity(ctx, name)
+	}
+	return ctx
+}
+
+// identityStream - стрим с контекстом, в который добавлено имя клиента
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
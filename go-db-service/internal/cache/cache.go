@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"awesomeProject/pkg/logger"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// Any - TTL для методов без собственного правила
+const Any = "*"
+
+// Backend - хранилище кэша. Записи живут в поколениях: Invalidate начинает новое поколение,
+// и записи старых становятся невидимыми. Set сохраняет значение, только если поколение gen
+// еще текущее, поэтому результат, посчитанный до инвалидации, не попадет в кэш после нее.
+type Backend interface {
+	Get(ctx context.Context, key string) (value []byte, gen uint64, found bool, err error)
+	Set(ctx context.Context, key string, gen uint64, value []byte, ttl time.Duration) error
+	Invalidate(ctx context.Context) error
+}
+
+// Stats - попадания и промахи метода
+type Stats struct {
+	Method string `json:"method"`
+	Hits   int64  `json:"hits"`
+	Misses int64  `json:"misses"`
+	Shared int64  `json:"shared"` // Промахи, обслуженные одним запросом к хранилищу вместе с другими вызовами
+	Errors int64  `json:"errors"` // Ошибки бэкенда кэша; запрос в таких случаях идет в хранилище
+}
+
+type counters struct {
+	hits, misses, shared, errors atomic.Int64
+}
+
+// Cache - read-through кэш результатов запросов с TTL по методам.
+// Одновременные промахи по одному ключу выполняют запрос один раз (singleflight).
+type Cache struct {
+	backend Backend
+	ttls    map[string]time.Duration
+	logger  logger.CustomZapLogger
+	group   singleflight.Group
+
+	invalidations atomic.Int64
+	mu            sync.Mutex
+	stats         map[string]*counters
+}
+
+// New - кэш поверх backend; методы без TTL в ttls не кэшируются
+func New(backend Backend, ttls map[string]time.Duration, logger logger.CustomZapLogger) *Cache {
+	return &Cache{backend: backend, ttls: ttls, logger: logger, stats: make(map[string]*counters)}
+}
+
+// ParseTTLs - TTL по методам вида "*=30s,UnaryLoad=10s,SourceReport=5m"; 0 - метод не кэшируется
+func ParseTTLs(spec string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(method) == "" {
+			return nil, fmt.Errorf("invalid cache TTL entry %q, expected method=duration", pair)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid cache TTL %q for %s", value, method)
+		}
+		ttls[strings.TrimSpace(method)] = ttl
+	}
+	return ttls, nil
+}
+
+// Key - ключ кэша по аргументам запроса
+func Key(parts ...interface{}) string {
+	data, _ := json.Marshal(parts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Load - значение из кэша или результат load, сохраненный на TTL метода.
+// Ошибки load не кэшируются; при недоступности бэкенда запрос выполняется напрямую.
+func Load[T any](ctx context.Context, c *Cache, method, key string, load func() (T, error)) (T, error) {
+	ttl := c.ttl(method)
+	if ttl <= 0 {
+		return load()
+	}
+	stats := c.counters(method)
+	key = method + ":" + key
+
+	data, gen, found, err := c.backend.Get(ctx, key)
+	if err != nil {
+		stats.errors.Add(1)
+		c.logger.Warn("Cache get failed", zap.String("method", method), zap.Error(err))
+		return load()
+	}
+	if found {
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			stats.hits.Add(1)
+			return value, nil
+		}
+	}
+	stats.misses.Add(1)
+
+	// Поколение входит в ключ группы: после инвалидации запросы не присоединяются к старым
+	result, err, shared := c.group.Do(key+"@"+strconv.FormatUint(gen, 10), func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.backend.Set(context.WithoutCancel(ctx), key, gen, data, ttl); err != nil {
+			stats.errors.Add(1)
+			c.logger.Warn("Cache set failed", zap.String("method", method), zap.Error(err))
+		}
+		return data, nil
+	})
+	if shared {
+		stats.shared.Add(1)
+		// Общий запрос мог оборваться из-за отмены другого вызова
+		if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) && ctx.Err() == nil {
+			return load()
+		}
+	}
+	var value T
+	if err != nil {
+		return value, err
+	}
+	// Каждый вызов получает свою копию, поэтому изменения результата не попадут к другим
+	if err := json.Unmarshal(result.([]byte), &value); err != nil {
+		return value, err
+	}
+	return value, nil
+}
+
+// Invalidate - сбрасывает все записи кэша; вызывается после записи в хранилище
+func (c *Cache) Invalidate(ctx context.Context) {
+	c.invalidations.Add(1)
+	if err := c.backend.Invalidate(ctx); err != nil {
+		c.logger.Error("Cache invalidation failed, entries expire by TTL", zap.Error(err))
+	}
+}
+
+// Invalidations - сколько раз кэш сбрасывался с запуска
+func (c *Cache) Invalidations() int64 {
+	return c.invalidations.Load()
+}
+
+// Stats - попадания и промахи по методам
+func (c *Cache) Stats() []Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := make([]Stats, 0, len(c.stats))
+	for method, counters := range c.stats {
+		stats = append(stats, Stats{
+			Method: method,
+			Hits:   counters.hits.Load(),
+			Misses: counters.misses.Load(),
+			Shared: counters.shared.Load(),
+			Errors: counters.errors.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Method < stats[j].Method })
+	return stats
+}
+
+func (c *Cache) ttl(method string) time.Duration {
+	if ttl, ok := c.ttls[method]; ok {
+		return ttl
+	}
+	return c.ttls[Any]
+}
+
+func (c *Cache) counters(method string) *counters {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats, ok := c.stats[method]
+	if !ok {
+		stats = &counters{}
+		c.stats[method] = stats
+	}
+	return stats
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"awesomeProject/pkg/logger"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// get - значение записи LRU или "" если ее нет
+func get(t *testing.T, l *LRU, key string) string {
+	t.Helper()
+	value, _, found, err := l.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	if !found {
+		return ""
+	}
+	return string(value)
+}
+
+func set(t *testing.T, l *LRU, key, value string, ttl time.Duration) {
+	t.Helper()
+	if err := l.Set(context.Background(), key, l.gen, []byte(value), ttl); err != nil {
+		t.Fatalf("set %s: %v", key, err)
+	}
+}
+
+func TestLRUExpiresByTTL(t *testing.T) {
+	l := NewLRU(1 << 10)
+	set(t, l, "a", "1", time.Minute)
+	set(t, l, "b", "2", time.Minute)
+
+	// Запись "a" устарела: срок сдвигается в прошлое вместо ожидания
+	l.items["a"].Value.(*lruEntry).expires = time.Now().Add(-time.Second)
+	if got := get(t, l, "a"); got != "" {
+		t.Fatalf("expired entry returned %q", got)
+	}
+	if got := get(t, l, "b"); got != "2" {
+		t.Fatalf("live entry: got %q", got)
+	}
+	if _, ok := l.items["a"]; ok || l.bytes != 2 {
+		t.Fatalf("expired entry kept: %d bytes", l.bytes)
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	// Каждая запись занимает 2 байта: ключ и значение
+	l := NewLRU(6)
+	set(t, l, "a", "1", time.Minute)
+	set(t, l, "b", "2", time.Minute)
+	set(t, l, "c", "3", time.Minute)
+	get(t, l, "a")
+	set(t, l, "d", "4", time.Minute)
+
+	for key, want := range map[string]string{"a": "1", "b": "", "c": "3", "d": "4"} {
+		if got := get(t, l, key); got != want {
+			t.Errorf("%s: got %q, want %q", key, got, want)
+		}
+	}
+	if l.bytes != 6 {
+		t.Fatalf("size: got %d, want 6", l.bytes)
+	}
+
+	// Перезапись не увеличивает размер, значение больше емкости не сохраняется
+	set(t, l, "d", "5", time.Minute)
+	set(t, l, "big", "1234567", time.Minute)
+	if got := get(t, l, "d"); got != "5" || l.bytes != 6 {
+		t.Fatalf("overwrite: got %q, %d bytes", got, l.bytes)
+	}
+	if got := get(t, l, "big"); got != "" {
+		t.Fatal("entry over capacity stored")
+	}
+}
+
+func TestLRUIgnoresSetFromOldGeneration(t *testing.T) {
+	l := NewLRU(1 << 10)
+	_, gen, _, _ := l.Get(context.Background(), "a")
+	if err := l.Invalidate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Set(context.Background(), "a", gen, []byte("stale"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, l, "a"); got != "" {
+		t.Fatalf("value from old generation stored: %q", got)
+	}
+}
+
+// failingBackend - бэкенд, недоступный на каждом вызове
+type failingBackend struct{}
+
+func (failingBackend) Get(context.Context, string) ([]byte, uint64, bool, error) {
+	return nil, 0, false, errors.New("unavailable")
+}
+
+func (failingBackend) Set(context.Context, string, uint64, []byte, time.Duration) error {
+	return errors.New("unavailable")
+}
+
+func (failingBackend) Invalidate(context.Context) error { return errors.New("unavailable") }
+
+// counter - load, считающий вызовы
+func counter(calls *atomic.Int64, value int) func() (int, error) {
+	return func() (int, error) {
+		calls.Add(1)
+		return value, nil
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	c := New(NewLRU(1<<20), map[string]time.Duration{Any: time.Minute, "Live": 0}, *logger.NewNop())
+	var calls atomic.Int64
+
+	for i := 0; i < 3; i++ {
+		if v, err := Load(ctx, c, "Count", Key("x"), counter(&calls, 7)); err != nil || v != 7 {
+			t.Fatalf("load: %d, %v", v, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("cached method loaded %d times", calls.Load())
+	}
+
+	// Метод с TTL 0 не кэшируется
+	calls.Store(0)
+	for i := 0; i < 2; i++ {
+		Load(ctx, c, "Live", Key("x"), counter(&calls, 1))
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("uncached method loaded %d times", calls.Load())
+	}
+
+	// После инвалидации значение считается заново
+	c.Invalidate(ctx)
+	if v, _ := Load(ctx, c, "Count", Key("x"), counter(&calls, 8)); v != 8 {
+		t.Fatalf("value after invalidation: %d", v)
+	}
+
+	// Ошибки не кэшируются
+	if _, err := Load(ctx, c, "Count", Key("y"), func() (int, error) { return 0, errors.New("boom") }); err == nil {
+		t.Fatal("error lost")
+	}
+	if v, _ := Load(ctx, c, "Count", Key("y"), counter(&calls, 9)); v != 9 {
+		t.Fatalf("error was cached: %d", v)
+	}
+
+	stats := c.Stats()
+	if len(stats) != 1 || stats[0].Method != "Count" || stats[0].Hits != 2 || stats[0].Misses != 4 {
+		t.Fatalf("stats: %+v", stats)
+	}
+	if c.Invalidations() != 1 {
+		t.Fatalf("invalidations: %d", c.Invalidations())
+	}
+}
+
+func TestLoadSharesConcurrentMisses(t *testing.T) {
+	c := New(NewLRU(1<<20), map[string]time.Duration{Any: time.Minute}, *logger.NewNop())
+	var calls atomic.Int64
+	release := make(chan struct{})
+	load := func() (int, error) {
+		calls.Add(1)
+		<-release
+		return 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := Load(context.Background(), c, "Count", Key("x"), load); err != nil || v != 1 {
+				t.Errorf("load: %d, %v", v, err)
+			}
+		}()
+	}
+	// Ждем, пока все вызовы промахнутся и присоединятся к одному запросу
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if stats := c.Stats(); len(stats) == 1 && stats[0].Misses == 5 {
+			break
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("concurrent misses loaded %d times", calls.Load())
+	}
+	if stats := c.Stats(); stats[0].Shared != 5 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
+
+func TestLoadBypassesFailingBackend(t *testing.T) {
+	c := New(failingBackend{}, map[string]time.Duration{Any: time.Minute}, *logger.NewNop())
+	var calls atomic.Int64
+	for i := 0; i < 2; i++ {
+		if v, err := Load(context.Background(), c, "Count", Key("x"), counter(&calls, 3)); err != nil || v != 3 {
+			t.Fatalf("load: %d, %v", v, err)
+		}
+	}
+	if calls.Load() != 2 || c.Stats()[0].Errors != 2 {
+		t.Fatalf("calls %d, stats %+v", calls.Load(), c.Stats())
+	}
+}
+
+func TestParseTTLs(t *testing.T) {
+	ttls, err := ParseTTLs(" *=30s, UnaryLoad=10s,SourceReport=0 ")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]time.Duration{Any: 30 * time.Second, "UnaryLoad": 10 * time.Second, "SourceReport": 0}
+	if len(ttls) != len(want) {
+		t.Fatalf("got %v", ttls)
+	}
+	for method, ttl := range want {
+		if ttls[method] != ttl {
+			t.Errorf("%s: got %s, want %s", method, ttls[method], ttl)
+		}
+	}
+	for _, spec := range []string{"UnaryLoad", "=10s", "UnaryLoad=soon", "UnaryLoad=-1s"} {
+		if _, err := ParseTTLs(spec); err == nil {
+			t.Errorf("%q: accepted", spec)
+		}
+	}
+}
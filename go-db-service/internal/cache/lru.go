@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// lruEntry - запись LRU
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// LRU - кэш в памяти процесса, ограниченный суммарным размером значений.
+// Инвалидация видна только этому процессу; на других репликах записи живут до TTL.
+type LRU struct {
+	maxBytes int64
+
+	mu    sync.Mutex
+	gen   uint64
+	bytes int64
+	items map[string]*list.Element
+	order *list.List // Спереди - недавно использованные
+}
+
+func NewLRU(maxBytes int64) *LRU {
+	return &LRU{maxBytes: maxBytes, items: make(map[string]*list.Element), order: list.New()}
+}
+
+func (l *LRU) Get(_ context.Context, key string) ([]byte, uint64, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	element, ok := l.items[key]
+	if !ok {
+		return nil, l.gen, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		l.remove(element)
+		return nil, l.gen, false, nil
+	}
+	l.order.MoveToFront(element)
+	return entry.value, l.gen, true, nil
+}
+
+func (l *LRU) Set(_ context.Context, key string, gen uint64, value []byte, ttl time.Duration) error {
+	size := int64(len(key) + len(value))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if gen != l.gen || size > l.maxBytes {
+		return nil
+	}
+	if element, ok := l.items[key]; ok {
+		l.remove(element)
+	}
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, value: value, expires: time.Now().Add(ttl)})
+	l.bytes += size
+	for l.bytes > l.maxBytes {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+func (l *LRU) Invalidate(_ context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.gen++
+	l.items = make(map[string]*list.Element)
+	l.order.Init()
+	l.bytes = 0
+	return nil
+}
+
+func (l *LRU) remove(element *list.Element) {
+	entry := l.order.Remove(element).(*lruEntry)
+	delete(l.items, entry.key)
+	l.bytes -= int64(len(entry.key) + len(entry.value))
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Поколение и запись читаются одним скриптом, чтобы не тратить на поиск два запроса к Redis
+var (
+	redisGet = redis.NewScript(`
+local gen = redis.call('GET', KEYS[1]) or '0'
+return {gen, redis.call('GET', ARGV[1] .. gen .. ':' .. ARGV[2])}`)
+	redisSet = redis.NewScript(`
+if (redis.call('GET', KEYS[1]) or '0') ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', ARGV[2] .. ARGV[1] .. ':' .. ARGV[3], ARGV[4], 'PX', ARGV[5])
+return 1`)
+)
+
+// Redis - общий кэш реплик в Redis: запись одной реплики сбрасывает кэш всех.
+// Ключи имеют вид <prefix><поколение>:<ключ>; записи старых поколений удаляются по TTL.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis - подключение к Redis; prefix отделяет ключи сервиса от чужих
+func NewRedis(ctx context.Context, options *redis.Options, prefix string) (*Redis, error) {
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return &Redis{client: client, prefix: prefix}, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, uint64, bool, error) {
+	result, err := redisGet.Run(ctx, r.client, []string{r.genKey()}, r.prefix, key).Slice()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if len(result) != 2 {
+		return nil, 0, false, fmt.Errorf("unexpected Redis reply %v", result)
+	}
+	genValue, _ := result[0].(string)
+	gen, err := strconv.ParseUint(genValue, 10, 64)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("invalid cache generation %q", genValue)
+	}
+	value, ok := result[1].(string)
+	if !ok {
+		return nil, gen, false, nil
+	}
+	return []byte(value), gen, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, gen uint64, value []byte, ttl time.Duration) error {
+	err := redisSet.Run(ctx, r.client, []string{r.genKey()},
+		strconv.FormatUint(gen, 10), r.prefix, key, value, ttl.Milliseconds()).Err()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+func (r *Redis) Invalidate(ctx context.Context) error {
+	return r.client.Incr(ctx, r.genKey()).Err()
+}
+
+// Close - закрывает соединения с Redis
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) genKey() string {
+	return r.prefix + "generation"
+}
@@ -0,0 +1,299 @@
+package changes
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBufferSize     = 10000 // Сколько последних событий хранится для возобновления по курсору
+	subscriberChannelSize = 1000  // Очередь подписчика; переполнение отключает подписчика
+)
+
+// ErrSlowSubscriber - подписчик не успевал читать события и был отключен
+var ErrSlowSubscriber = errors.New("subscriber is too slow, resume with the last received cursor")
+
+// ErrClosed - сервис останавливается, подписку нужно возобновить с последним курсором
+var ErrClosed = errors.New("server is shutting down, resume with the last received cursor")
+
+// Event - IoC, закоммиченный в хранилище
+type Event struct {
+	Seq         uint64
+	IoC         models.IoCDto
+	CommittedAt time.Time
+}
+
+// Cursor - позиция в потоке изменений.
+// Epoch отличает запуски сервиса: после рестарта номера событий начинаются заново,
+// и возобновление идет по времени Time из хранилища.
+type Cursor struct {
+	Epoch string
+	Seq   uint64
+	Time  time.Time
+}
+
+// String - курсор в виде "<epoch>-<seq>-<unix>"
+func (c Cursor) String() string {
+	return fmt.Sprintf("%s-%d-%d", c.Epoch, c.Seq, c.Time.Unix())
+}
+
+// ParseCursor - разбирает курсор, выданный Cursor.String
+func ParseCursor(raw string) (Cursor, error) {
+	parts := strings.Split(raw, "-")
+	if len(parts) != 3 {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", raw)
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", raw)
+	}
+	unix, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", raw)
+	}
+	return Cursor{Epoch: parts[0], Seq: seq, Time: time.Unix(unix, 0).UTC()}, nil
+}
+
+// Filter - фильтр подписки; пустые списки пропускают все, теги совпадают по любому из списка
+type Filter struct {
+	Types   []string
+	Sources []string
+	Tags    []string
+}
+
+// Match - подходит ли IoC под фильтр
+func (f Filter) Match(ioc models.IoCDto) bool {
+	if len(f.Types) > 0 && !containsFold(f.Types, ioc.Type) {
+		return false
+	}
+	if len(f.Sources) > 0 && !containsFold(f.Sources, ioc.Source) {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		for _, tag := range ioc.Tags {
+			if containsFold(f.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hub - раздает подписчикам IoC после успешной записи в хранилище.
+// Последние события хранятся в кольцевом буфере, чтобы переподключившийся клиент ничего не пропустил.
+type Hub struct {
+	epoch string
+
+	mu          sync.Mutex
+	buffer      []Event // кольцевой буфер последних событий
+	next        uint64  // номер следующего события, начинается с 1
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub - конструктор хаба с буфером на bufferSize событий
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer:      make([]Event, bufferSize),
+		next:        1,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish - рассылает закоммиченные IoC. Значения приводятся к виду, в котором их пишет хранилище.
+func (h *Hub) Publish(iocs []models.IoCDto) {
+	if len(iocs) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, ioc := range iocs {
+		ioc = normalize(ioc)
+		committedAt := now
+		ioc.AddedAt = &committedAt
+
+		event := Event{Seq: h.next, IoC: ioc, CommittedAt: now}
+		h.buffer[h.next%uint64(len(h.buffer))] = event
+		h.next++
+
+		for sub := range h.subscribers {
+			select {
+			case sub.events <- event:
+			default:
+				// Не блокируем запись из-за медленного клиента: отключаем его, он вернется с курсором
+				h.drop(sub)
+			}
+		}
+	}
+}
+
+// Subscribe - подписка на изменения начиная с курсора (пустой курсор - только новые события).
+// Если курсор старше буфера или выдан до рестарта, Subscription.ReplayFrom указывает,
+// с какого времени нужно дочитать пропущенное из хранилища. visible решает, какие события
+// видны подписчику (арендатор, статусы lifecycle); nil - видны все.
+func (h *Hub) Subscribe(cursor string, visible func(models.IoCDto) bool) (*Subscription, error) {
+	var from Cursor
+	if cursor != "" {
+		parsed, err := ParseCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		from = parsed
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &Subscription{hub: h, visible: visible, Start: h.headLocked()}
+
+	var missed []Event
+	if cursor != "" {
+		oldest := uint64(1)
+		if h.next > uint64(len(h.buffer)) {
+			oldest = h.next - uint64(len(h.buffer))
+		}
+		if from.Epoch == h.epoch && from.Seq+1 >= oldest && from.Seq < h.next {
+			// Пропущенные события еще в буфере
+			for seq := from.Seq + 1; seq < h.next; seq++ {
+				missed = append(missed, h.buffer[seq%uint64(len(h.buffer))])
+			}
+			sub.Start = from
+		} else {
+			replayFrom := from.Time
+			sub.ReplayFrom = &replayFrom
+		}
+	}
+
+	sub.events = make(chan Event, subscriberChannelSize+len(missed))
+	for _, event := range missed {
+		sub.events <- event
+	}
+
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Head - курсор последнего опубликованного события
+func (h *Hub) Head() Cursor {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.headLocked()
+}
+
+func (h *Hub) headLocked() Cursor {
+	head := Cursor{Epoch: h.epoch, Seq: h.next - 1, Time: time.Now().UTC()}
+	if h.next > 1 {
+		head.Time = h.buffer[(h.next-1)%uint64(len(h.buffer))].CommittedAt
+	}
+	return head
+}
+
+// Close - закрывает все подписки и перестает принимать новые
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		sub.closed = true
+		close(sub.events)
+	}
+}
+
+func (h *Hub) drop(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	sub.dropped = true
+	close(sub.events)
+}
+
+// Subscription - подписка на поток изменений
+type Subscription struct {
+	hub     *Hub
+	events  chan Event
+	visible func(models.IoCDto) bool
+	dropped bool
+	closed  bool // Подписка закрыта остановкой сервиса
+
+	Start      Cursor     // Курсор, с которого начинается поток событий подписки
+	ReplayFrom *time.Time // Не nil, если пропущенное нужно дочитать из хранилища
+}
+
+// Events - канал событий; закрывается при отключении медленного подписчика или Close
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Cursor - курсор события, после которого можно возобновить подписку
+func (s *Subscription) Cursor(event Event) Cursor {
+	return Cursor{Epoch: s.hub.epoch, Seq: event.Seq, Time: event.CommittedAt}
+}
+
+// Visible - видно ли событие подписчику. Невидимые события все равно сдвигают курсор,
+// поэтому фильтруются при отправке, а не в Publish.
+func (s *Subscription) Visible(event Event) bool {
+	return s.visible == nil || s.visible(event.IoC)
+}
+
+// Err - причина закрытия канала событий
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if s.dropped {
+		return ErrSlowSubscriber
+	}
+	if s.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Close - отписка
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subscribers[s]; ok {
+		delete(s.hub.subscribers, s)
+		close(s.events)
+	}
+}
+
+// normalize - хранилище пишет источник, тип, значение и теги в нижнем регистре
+func normalize(ioc models.IoCDto) models.IoCDto {
+	ioc.Source = strings.ToLower(ioc.Source)
+	ioc.Type = strings.ToLower(ioc.Type)
+	ioc.Value = strings.ToLower(ioc.Value)
+	tags := make([]string, len(ioc.Tags))
+	for i, tag := range ioc.Tags {
+		tags[i] = strings.ToLower(tag)
+	}
+	ioc.Tags = tags
+	return ioc
+}
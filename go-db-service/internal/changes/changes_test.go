@@ -0,0 +1,181 @@
+package changes
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func publish(h *Hub, values ...string) {
+	iocs := make([]models.IoCDto, 0, len(values))
+	for _, value := range values {
+		iocs = append(iocs, models.IoCDto{Type: models.TypeDomain, Value: value})
+	}
+	h.Publish(iocs)
+}
+
+// pending - значения событий, уже лежащих в канале подписки
+func pending(sub *Subscription) []string {
+	var values []string
+	for {
+		select {
+		case event := <-sub.events:
+			values = append(values, event.IoC.Value)
+		default:
+			return values
+		}
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	cursor := Cursor{Epoch: "lx3k9a", Seq: 42, Time: time.Unix(1700000000, 0).UTC()}
+	parsed, err := ParseCursor(cursor.String())
+	if err != nil || parsed != cursor {
+		t.Fatalf("round trip %q: got %+v, %v", cursor.String(), parsed, err)
+	}
+	// Курсор дочитывания из хранилища идет без эпохи
+	if parsed, err := ParseCursor("-0-1700000000"); err != nil || parsed.Epoch != "" || parsed.Time.Unix() != 1700000000 {
+		t.Fatalf("cursor without epoch: got %+v, %v", parsed, err)
+	}
+
+	for _, raw := range []string{"", "abc", "lx3k9a-42", "lx3k9a-42-1700000000-1", "lx3k9a--1700000000", "lx3k9a-x-1700000000", "lx3k9a-42-soon", "lx3k9a--1-1700000000"} {
+		if _, err := ParseCursor(raw); err == nil {
+			t.Errorf("%q: parsed without error", raw)
+		}
+	}
+}
+
+func TestSubscribeFromCursor(t *testing.T) {
+	h := NewHub(3)
+	publish(h, "e1", "e2", "e3", "e4", "e5") // в буфере остались e3..e5
+	head := h.Head()
+	old := time.Unix(1700000000, 0).UTC()
+
+	tests := []struct {
+		name       string
+		cursor     string
+		missed     string // События из буфера
+		replayFrom *time.Time
+	}{
+		{name: "only new events", cursor: ""},
+		{name: "head", cursor: head.String()},
+		{name: "inside buffer", cursor: Cursor{Epoch: h.epoch, Seq: 3, Time: old}.String(), missed: "[e4 e5]"},
+		{name: "oldest buffered event is next", cursor: Cursor{Epoch: h.epoch, Seq: 2, Time: old}.String(), missed: "[e3 e4 e5]"},
+		{name: "older than buffer", cursor: Cursor{Epoch: h.epoch, Seq: 1, Time: old}.String(), replayFrom: &old},
+		{name: "stale epoch", cursor: Cursor{Epoch: "previous", Seq: 4, Time: old}.String(), replayFrom: &old},
+		{name: "storage replay cursor", cursor: Cursor{Time: old}.String(), replayFrom: &old},
+		{name: "ahead of hub", cursor: Cursor{Epoch: h.epoch, Seq: 9, Time: old}.String(), replayFrom: &old},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := h.Subscribe(tt.cursor, nil)
+			if err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+			defer sub.Close()
+
+			missed := ""
+			if values := pending(sub); len(values) > 0 {
+				missed = fmt.Sprint(values)
+			}
+			if missed != tt.missed {
+				t.Fatalf("missed events %s, want %s", missed, tt.missed)
+			}
+			switch {
+			case tt.replayFrom == nil && sub.ReplayFrom != nil:
+				t.Fatalf("unexpected replay from %v", sub.ReplayFrom)
+			case tt.replayFrom != nil && (sub.ReplayFrom == nil || !sub.ReplayFrom.Equal(*tt.replayFrom)):
+				t.Fatalf("replay from %v, want %v", sub.ReplayFrom, tt.replayFrom)
+			}
+		})
+	}
+
+	if _, err := h.Subscribe("not-a-cursor", nil); err == nil {
+		t.Fatal("malformed cursor accepted")
+	}
+}
+
+func TestSubscriptionCursorResumes(t *testing.T) {
+	h := NewHub(10)
+	sub, err := h.Subscribe("", nil)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	publish(h, "E1", "e2")
+	first := <-sub.Events()
+	if first.IoC.Value != "e1" || first.IoC.AddedAt == nil {
+		t.Fatalf("event not normalized: value %q, added_at %v", first.IoC.Value, first.IoC.AddedAt)
+	}
+	sub.Close()
+
+	resumed, err := h.Subscribe(sub.Cursor(first).String(), nil)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	defer resumed.Close()
+	if got := fmt.Sprint(pending(resumed)); got != "[e2]" {
+		t.Fatalf("resumed with %s, want [e2]", got)
+	}
+	if resumed.Start.String() != sub.Cursor(first).String() {
+		t.Fatalf("start %v, want %v", resumed.Start, sub.Cursor(first))
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	h := NewHub(10)
+	sub, err := h.Subscribe("", nil)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	for i := 0; i <= subscriberChannelSize; i++ {
+		publish(h, fmt.Sprintf("e%d", i))
+	}
+	for range sub.Events() {
+	}
+	if err := sub.Err(); !errors.Is(err, ErrSlowSubscriber) {
+		t.Fatalf("got %v, want %v", err, ErrSlowSubscriber)
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	h := NewHub(10)
+	sub, err := h.Subscribe("", nil)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	h.Close()
+	if _, open := <-sub.Events(); open {
+		t.Fatal("events channel is open after Close")
+	}
+	if err := sub.Err(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got %v, want %v", err, ErrClosed)
+	}
+	sub.Close()
+	if _, err := h.Subscribe("", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("subscribe after Close: got %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	ioc := models.IoCDto{Type: models.TypeIP, Source: "feodotracker", Tags: []string{"botnet", "c2"}}
+	tests := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{Types: []string{"domain", "IP"}}, true},
+		{Filter{Types: []string{"domain"}}, false},
+		{Filter{Sources: []string{"FeodoTracker"}}, true},
+		{Filter{Sources: []string{"urlhaus"}}, false},
+		{Filter{Tags: []string{"apt", "C2"}}, true},
+		{Filter{Tags: []string{"apt"}}, false},
+		{Filter{Types: []string{"ip"}, Tags: []string{"apt"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(ioc); got != tt.want {
+			t.Errorf("%+v: got %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
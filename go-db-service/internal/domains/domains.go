@@ -0,0 +1,71 @@
+package domains
+
+import (
+	"awesomeProject/models"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Parts - части имени хоста для поиска по иерархии доменов
+type Parts struct {
+	Host              string // Полное имя хоста: mail.evil.co.uk
+	RegistrableDomain string // eTLD+1 по public suffix list: evil.co.uk
+	TLD               string // Последняя метка: uk
+}
+
+// FromIoC - части домена для DOMAIN и URL IoC; для URL с IP вместо хоста заполняется только Host
+func FromIoC(iocType, value string) (Parts, bool) {
+	var host string
+	switch strings.ToLower(iocType) {
+	case models.TypeDomain:
+		host = Normalize(value)
+	case models.TypeURL:
+		host = URLHost(value)
+	default:
+		return Parts{}, false
+	}
+	if host == "" {
+		return Parts{}, false
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return Parts{Host: host}, true
+	}
+
+	parts := Parts{Host: host, TLD: host[strings.LastIndexByte(host, '.')+1:]}
+	// Для самого публичного суффикса (co.uk) регистрируемого домена нет
+	if registrable, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		parts.RegistrableDomain = registrable
+	}
+	return parts, true
+}
+
+// RegistrableDomain - eTLD+1 для фильтра: evil.com из "www.evil.com" или "evil.com"
+func RegistrableDomain(value string) string {
+	host := Normalize(value)
+	if registrable, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return registrable
+	}
+	return host
+}
+
+// Normalize - имя хоста в нижнем регистре без точки в конце и "*." в начале
+func Normalize(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "*.")
+	return strings.Trim(value, ".")
+}
+
+// URLHost - хост из URL IoC (схема может отсутствовать)
+func URLHost(raw string) string {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return Normalize(parsed.Hostname())
+}
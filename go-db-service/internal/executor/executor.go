@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"awesomeProject/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Class - класс операций со своим лимитом одновременных задач
+type Class string
+
+const (
+	Interactive Class = "interactive" // Короткие чтения: Load, Count*, allowlist, lifecycle, отчеты
+	Write       Class = "write"       // Запись пачек IoC
+	Bulk        Class = "bulk"        // Долгие задачи: стримы чтения и записи, выгрузки, импорт
+)
+
+var (
+	ErrQueueFull = errors.New("task queue is full")
+	ErrClosed    = errors.New("executor is shutting down")
+)
+
+// Config - размеры пулов по классам и поведение очереди
+type Config struct {
+	Workers      map[Class]int // Одновременных задач на класс
+	QueueSize    int           // Сколько задач класса может ждать свободного места
+	QueueTimeout time.Duration // Сколько задача ждет места, прежде чем получить ErrQueueFull
+}
+
+// pool - семафор одного класса и число задач, ожидающих места
+type pool struct {
+	class   Class
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// Stats - состояние пула класса
+type Stats struct {
+	Class   Class `json:"class"`
+	Workers int   `json:"workers"`
+	Running int   `json:"running"`
+	Waiting int64 `json:"waiting"`
+}
+
+// Executor - выполняет задачи сервиса с отдельным лимитом на каждый класс операций,
+// поэтому долгие выгрузки не занимают места коротких запросов. Задача ждет места не дольше
+// QueueTimeout и дедлайна вызывающего; после Close новые задачи не принимаются.
+type Executor struct {
+	pools        map[Class]*pool
+	queueSize    int64
+	queueTimeout time.Duration
+	logger       logger.CustomZapLogger
+
+	mu      sync.RWMutex
+	closed  bool
+	closing chan struct{}
+	running sync.WaitGroup
+}
+
+func New(cfg Config, logger logger.CustomZapLogger) (*Executor, error) {
+	if cfg.QueueSize < 0 || cfg.QueueTimeout <= 0 {
+		return nil, fmt.Errorf("invalid executor queue: size %d, timeout %s", cfg.QueueSize, cfg.QueueTimeout)
+	}
+	e := &Executor{
+		pools:        make(map[Class]*pool),
+		queueSize:    int64(cfg.QueueSize),
+		queueTimeout: cfg.QueueTimeout,
+		logger:       logger,
+		closing:      make(chan struct{}),
+	}
+	for _, class := range []Class{Interactive, Write, Bulk} {
+		workers := cfg.Workers[class]
+		if workers <= 0 {
+			return nil, fmt.Errorf("invalid executor size for %s: %d", class, workers)
+		}
+		e.pools[class] = &pool{class: class, slots: make(chan struct{}, workers)}
+	}
+	logger.Info("Executor initialized", zap.Any("workers", cfg.Workers), zap.Int("queueSize", cfg.QueueSize), zap.Duration("queueTimeout", cfg.QueueTimeout))
+	return e, nil
+}
+
+// Submit - ждет места в пуле класса и запускает задачу в отдельной горутине.
+// Возвращает ErrQueueFull, если очередь класса заполнена или место не освободилось за QueueTimeout,
+// ошибку ctx, если вызывающий перестал ждать, и ErrClosed после Close.
+func (e *Executor) Submit(ctx context.Context, class Class, task func()) error {
+	p, ok := e.pools[class]
+	if !ok {
+		return fmt.Errorf("unknown executor class %q", class)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := e.acquire(ctx, p); err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	if e.closed {
+		e.mu.RUnlock()
+		<-p.slots
+		return ErrClosed
+	}
+	e.running.Add(1)
+	e.mu.RUnlock()
+
+	go func() {
+		defer e.running.Done()
+		defer func() { <-p.slots }()
+		task()
+	}()
+	return nil
+}
+
+func (e *Executor) acquire(ctx context.Context, p *pool) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-e.closing:
+		return ErrClosed
+	default:
+	}
+
+	if p.waiting.Add(1) > e.queueSize {
+		p.waiting.Add(-1)
+		e.logger.Warn("Task queue is full, rejecting task", zap.String("class", string(p.class)))
+		return fmt.Errorf("%w: %s", ErrQueueFull, p.class)
+	}
+	defer p.waiting.Add(-1)
+
+	timer := time.NewTimer(e.queueTimeout)
+	defer timer.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		// Задача не запускается, если вызывающий уже не дождется результата
+		return ctx.Err()
+	case <-timer.C:
+		e.logger.Warn("No free worker within queue timeout, rejecting task", zap.String("class", string(p.class)), zap.Duration("timeout", e.queueTimeout))
+		return fmt.Errorf("%w: no %s worker within %s", ErrQueueFull, p.class, e.queueTimeout)
+	case <-e.closing:
+		return ErrClosed
+	}
+}
+
+// Stats - занятость пулов по классам
+func (e *Executor) Stats() []Stats {
+	stats := make([]Stats, 0, len(e.pools))
+	for _, class := range []Class{Interactive, Write, Bulk} {
+		p := e.pools[class]
+		stats = append(stats, Stats{Class: class, Workers: cap(p.slots), Running: len(p.slots), Waiting: p.waiting.Load()})
+	}
+	return stats
+}
+
+// Close - перестает принимать задачи и ждет завершения запущенных, пока не истечет ctx
+func (e *Executor) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.closing)
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		e.logger.Info("Executor drained")
+		return nil
+	case <-ctx.Done():
+		for _, stats := range e.Stats() {
+			if stats.Running > 0 {
+				e.logger.Warn("Tasks still running after drain timeout", zap.String("class", string(stats.Class)), zap.Int("running", stats.Running))
+			}
+		}
+		return ctx.Err()
+	}
+}
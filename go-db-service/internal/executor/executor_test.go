@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"awesomeProject/pkg/logger"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestExecutor(t *testing.T, workers int, queueSize int, queueTimeout time.Duration) *Executor {
+	t.Helper()
+	e, err := New(Config{
+		Workers:      map[Class]int{Interactive: workers, Write: workers, Bulk: workers},
+		QueueSize:    queueSize,
+		QueueTimeout: queueTimeout,
+	}, *logger.NewNop())
+	if err != nil {
+		t.Fatalf("executor: %v", err)
+	}
+	return e
+}
+
+// occupy - занимает все места класса задачами, которые ждут release
+func occupy(t *testing.T, e *Executor, class Class, release <-chan struct{}) {
+	t.Helper()
+	for i := 0; i < cap(e.pools[class].slots); i++ {
+		if err := e.Submit(context.Background(), class, func() { <-release }); err != nil {
+			t.Fatalf("submit %s: %v", class, err)
+		}
+	}
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	tests := []Config{
+		{Workers: map[Class]int{Interactive: 1, Write: 1}, QueueSize: 1, QueueTimeout: time.Second},
+		{Workers: map[Class]int{Interactive: 1, Write: 1, Bulk: 0}, QueueSize: 1, QueueTimeout: time.Second},
+		{Workers: map[Class]int{Interactive: 1, Write: 1, Bulk: 1}, QueueSize: -1, QueueTimeout: time.Second},
+		{Workers: map[Class]int{Interactive: 1, Write: 1, Bulk: 1}, QueueSize: 1},
+	}
+	for _, cfg := range tests {
+		if _, err := New(cfg, *logger.NewNop()); err == nil {
+			t.Errorf("%+v: accepted", cfg)
+		}
+	}
+}
+
+func TestQueueTimeoutIsPerClass(t *testing.T) {
+	for _, class := range []Class{Interactive, Write, Bulk} {
+		t.Run(string(class), func(t *testing.T) {
+			e := newTestExecutor(t, 1, 1, 50*time.Millisecond)
+			release := make(chan struct{})
+			defer close(release)
+			occupy(t, e, class, release)
+
+			started := time.Now()
+			err := e.Submit(context.Background(), class, func() {})
+			if !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("busy %s: got error %v, want %v", class, err, ErrQueueFull)
+			}
+			if waited := time.Since(started); waited < 50*time.Millisecond {
+				t.Fatalf("rejected after %s, before the queue timeout", waited)
+			}
+
+			// Занятый класс не мешает остальным
+			for _, other := range []Class{Interactive, Write, Bulk} {
+				if other == class {
+					continue
+				}
+				done := make(chan struct{})
+				if err := e.Submit(context.Background(), other, func() { close(done) }); err != nil {
+					t.Fatalf("%s while %s is busy: %v", other, class, err)
+				}
+				<-done
+			}
+		})
+	}
+}
+
+func TestQueueSizeRejectsImmediately(t *testing.T) {
+	e := newTestExecutor(t, 1, 1, time.Minute)
+	release := make(chan struct{})
+	occupy(t, e, Write, release)
+
+	// Первая задача ждет места в очереди
+	queued := make(chan error, 1)
+	go func() { queued <- e.Submit(context.Background(), Write, func() {}) }()
+	for e.pools[Write].waiting.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	started := time.Now()
+	if err := e.Submit(context.Background(), Write, func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("over queue size: got error %v, want %v", err, ErrQueueFull)
+	}
+	if waited := time.Since(started); waited > time.Second {
+		t.Fatalf("rejected after %s, want immediately", waited)
+	}
+	if stats := e.Stats()[1]; stats.Class != Write || stats.Running != 1 || stats.Waiting != 1 {
+		t.Fatalf("stats: %+v", stats)
+	}
+
+	close(release)
+	if err := <-queued; err != nil {
+		t.Fatalf("queued task: %v", err)
+	}
+}
+
+func TestSubmitHonorsCallerContext(t *testing.T) {
+	e := newTestExecutor(t, 1, 1, time.Minute)
+	release := make(chan struct{})
+	defer close(release)
+	occupy(t, e, Interactive, release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ran := false
+	if err := e.Submit(ctx, Interactive, func() { ran = true }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if ran {
+		t.Fatal("task ran after the caller stopped waiting")
+	}
+
+	if err := e.Submit(context.Background(), Class("batch"), func() {}); err == nil {
+		t.Fatal("unknown class accepted")
+	}
+}
+
+func TestCloseDrainsRunningTasks(t *testing.T) {
+	e := newTestExecutor(t, 2, 1, time.Minute)
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	if err := e.Submit(context.Background(), Bulk, func() { <-release; close(finished) }); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	// Задача, ждущая места, получает ErrClosed
+	occupy(t, e, Interactive, release)
+	waiting := make(chan error, 1)
+	go func() { waiting <- e.Submit(context.Background(), Interactive, func() {}) }()
+	for e.pools[Interactive].waiting.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := e.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("close with running task: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-waiting; !errors.Is(err, ErrClosed) {
+		t.Fatalf("waiting task: got %v, want %v", err, ErrClosed)
+	}
+	if err := e.Submit(context.Background(), Bulk, func() {}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("submit after close: got %v, want %v", err, ErrClosed)
+	}
+
+	close(release)
+	if err := e.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Close returned before the running task finished")
+	}
+}
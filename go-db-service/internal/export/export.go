@@ -0,0 +1,224 @@
+package export
+
+import (
+	"awesomeProject/internal/stix"
+	"awesomeProject/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые форматы выгрузки
+const (
+	FormatSTIX      = "stix"
+	FormatCSV       = "csv"
+	FormatNDJSON    = "ndjson"
+	FormatBlocklist = "blocklist"
+)
+
+// CSVHeader - колонки CSV выгрузки
+var CSVHeader = []string{"id", "source", "type", "value", "first_seen", "last_seen", "tags", "additional_data"}
+
+// Writer - потоковый кодировщик IoC в формат выгрузки
+type Writer interface {
+	// Write - кодирует очередной IoC; false - IoC не выражается форматом и пропущен
+	Write(ioc *models.IoCDto) (bool, error)
+	// Close - дописывает окончание формата и сбрасывает буфер, не закрывает нижележащий io.Writer
+	Close() error
+}
+
+// NewWriter - создает кодировщик для указанного формата
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	buf := bufio.NewWriter(w)
+	switch strings.ToLower(format) {
+	case FormatSTIX:
+		return &stixWriter{w: buf}, nil
+	case FormatCSV:
+		cw := csv.NewWriter(buf)
+		if err := cw.Write(CSVHeader); err != nil {
+			return nil, err
+		}
+		return &csvWriter{buf: buf, w: cw}, nil
+	case FormatNDJSON, "":
+		return &ndjsonWriter{w: buf, enc: json.NewEncoder(buf)}, nil
+	case FormatBlocklist:
+		return &blocklistWriter{w: buf}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// Validate - проверяет формат выгрузки; blocklist собирается по одному типу IoC
+func Validate(format string, request models.LoadRequest) error {
+	switch strings.ToLower(format) {
+	case FormatSTIX, FormatCSV, FormatNDJSON, "":
+		return nil
+	case FormatBlocklist:
+		if request.Type == "" {
+			return fmt.Errorf("blocklist export requires type")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ContentType - MIME тип для формата
+func ContentType(format string) string {
+	switch strings.ToLower(format) {
+	case FormatSTIX:
+		return "application/stix+json;version=2.1"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatBlocklist:
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// FileName - имя файла для скачивания выгрузки
+func FileName(format, iocType string) string {
+	name := "iocs"
+	if iocType != "" {
+		name += "-" + strings.ToLower(iocType)
+	}
+	switch strings.ToLower(format) {
+	case FormatSTIX:
+		return name + ".stix.json"
+	case FormatCSV:
+		return name + ".csv"
+	case FormatBlocklist:
+		return name + ".txt"
+	default:
+		return name + ".ndjson"
+	}
+}
+
+type stixWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+func (s *stixWriter) begin() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	_, err := fmt.Fprintf(s.w, `{"type":"bundle","id":%q,"objects":[`, stix.NewBundleID())
+	return err
+}
+
+func (s *stixWriter) Write(ioc *models.IoCDto) (bool, error) {
+	indicator, ok := stix.FromIoC(*ioc)
+	if !ok {
+		// Тип не выражается STIX паттерном - пропускаем
+		return false, nil
+	}
+	first := !s.started
+	if err := s.begin(); err != nil {
+		return false, err
+	}
+	if !first {
+		if err := s.w.WriteByte(','); err != nil {
+			return false, err
+		}
+	}
+	data, err := json.Marshal(indicator)
+	if err != nil {
+		return false, err
+	}
+	if _, err = s.w.Write(data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *stixWriter) Close() error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	if _, err := s.w.WriteString("]}\n"); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+type csvWriter struct {
+	buf *bufio.Writer
+	w   *csv.Writer
+}
+
+func (c *csvWriter) Write(ioc *models.IoCDto) (bool, error) {
+	additionalData := ""
+	if len(ioc.AdditionalData) > 0 {
+		data, err := json.Marshal(ioc.AdditionalData)
+		if err != nil {
+			return false, err
+		}
+		additionalData = string(data)
+	}
+	err := c.w.Write([]string{
+		ioc.ID,
+		ioc.Source,
+		ioc.Type,
+		ioc.Value,
+		formatTime(ioc.FirstSeen),
+		formatTime(ioc.LastSeen),
+		strings.Join(ioc.Tags, ";"),
+		additionalData,
+	})
+	return err == nil, err
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return c.buf.Flush()
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(ioc *models.IoCDto) (bool, error) {
+	err := n.enc.Encode(ioc)
+	return err == nil, err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+type blocklistWriter struct {
+	w *bufio.Writer
+}
+
+func (b *blocklistWriter) Write(ioc *models.IoCDto) (bool, error) {
+	if ioc.Value == "" {
+		return false, nil
+	}
+	if _, err := b.w.WriteString(ioc.Value); err != nil {
+		return false, err
+	}
+	err := b.w.WriteByte('\n')
+	return err == nil, err
+}
+
+func (b *blocklistWriter) Close() error {
+	return b.w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,169 @@
+package geoip
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+)
+
+// cityRecord - поля GeoLite2/GeoIP2 City или Country, которые нам нужны
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord - поля GeoLite2 ASN
+type asnRecord struct {
+	Number       uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// database - открытый mmdb файл и время его изменения для горячей перезагрузки
+type database struct {
+	path    string
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+// Enricher - обогащение IP IoC страной, городом и ASN из локальных mmdb файлов.
+// Файлы перечитываются при изменении, сетевых обращений нет.
+type Enricher struct {
+	logger logger.CustomZapLogger
+
+	mu   sync.RWMutex
+	city *database
+	asn  *database
+}
+
+// NewEnricher - открывает базы City (или Country) и ASN; пустой путь отключает соответствующую базу
+func NewEnricher(cityPath, asnPath string, logger logger.CustomZapLogger) (*Enricher, error) {
+	e := &Enricher{logger: logger}
+	if cityPath != "" {
+		db, err := open(cityPath)
+		if err != nil {
+			return nil, err
+		}
+		e.city = db
+	}
+	if asnPath != "" {
+		db, err := open(asnPath)
+		if err != nil {
+			return nil, err
+		}
+		e.asn = db
+	}
+	return e, nil
+}
+
+func open(path string) (*database, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat mmdb %s: %w", path, err)
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mmdb %s: %w", path, err)
+	}
+	return &database{path: path, reader: reader, modTime: info.ModTime()}, nil
+}
+
+// Run - раз в interval проверяет время изменения файлов и перечитывает обновленные
+func (e *Enricher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.Close()
+			return
+		case <-ticker.C:
+			e.reloadChanged()
+		}
+	}
+}
+
+func (e *Enricher) reloadChanged() {
+	for _, slot := range []**database{&e.city, &e.asn} {
+		e.mu.RLock()
+		current := *slot
+		e.mu.RUnlock()
+		if current == nil {
+			continue
+		}
+
+		info, err := os.Stat(current.path)
+		if err != nil || info.ModTime().Equal(current.modTime) {
+			continue
+		}
+		// Файл мог быть дописан не до конца: при ошибке оставляем старую базу и пробуем в следующий раз
+		updated, err := open(current.path)
+		if err != nil {
+			e.logger.Warn("Failed to reload mmdb, keeping previous version", zap.String("path", current.path), zap.Error(err))
+			continue
+		}
+
+		e.mu.Lock()
+		*slot = updated
+		e.mu.Unlock()
+		current.reader.Close()
+		e.logger.Info("GeoIP database reloaded", zap.String("path", current.path), zap.Time("modTime", updated.modTime))
+	}
+}
+
+// Close - закрывает базы
+func (e *Enricher) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, db := range []*database{e.city, e.asn} {
+		if db != nil {
+			db.reader.Close()
+		}
+	}
+	e.city, e.asn = nil, nil
+}
+
+// Enrich - заполняет страну, город и ASN у IP IoC с одиночным адресом
+func (e *Enricher) Enrich(iocs []models.IoCDto) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for i := range iocs {
+		if !strings.EqualFold(iocs[i].Type, models.TypeIP) {
+			continue
+		}
+		addr, err := netip.ParseAddr(iocs[i].Value)
+		if err != nil {
+			continue
+		}
+		ip := net.IP(addr.Unmap().AsSlice())
+
+		if e.city != nil {
+			var record cityRecord
+			if err := e.city.reader.Lookup(ip, &record); err == nil {
+				iocs[i].Country = record.Country.ISOCode
+				iocs[i].City = record.City.Names["en"]
+			}
+		}
+		if e.asn != nil {
+			var record asnRecord
+			if err := e.asn.reader.Lookup(ip, &record); err == nil {
+				iocs[i].ASN = record.Number
+				iocs[i].ASOrg = record.Organization
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package graph
+
+import (
+	"awesomeProject/internal/domains"
+	"awesomeProject/models"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultDepth = 1
+	MaxDepth     = 3    // Глубже окрестность популярных узлов (кампаний, хостингов) взрывается
+	DefaultLimit = 500  // Максимум узлов подграфа по умолчанию
+	MaxLimit     = 5000 // Максимум узлов подграфа
+)
+
+var ErrInvalidRelationship = errors.New("invalid relationship")
+
+var kinds = map[string]bool{
+	models.RelationResolvesTo:     true,
+	models.RelationHosts:          true,
+	models.RelationDrops:          true,
+	models.RelationPartOfCampaign: true,
+}
+
+// ValidKind - известен ли вид связи
+func ValidKind(kind string) bool {
+	return kinds[kind]
+}
+
+// InferType - тип индикатора по значению: ip, url, md5, sha256, иначе domain
+func InferType(value string) string {
+	if _, err := netip.ParseAddr(value); err == nil {
+		return models.TypeIP
+	}
+	if strings.Contains(value, "://") {
+		return models.TypeURL
+	}
+	if _, err := hex.DecodeString(value); err == nil {
+		switch len(value) {
+		case 32:
+			return models.TypeMD5
+		case 64:
+			return models.TypeSHA256
+		}
+	}
+	return models.TypeDomain
+}
+
+// NormalizeValue - каноничное значение конца связи: домены и хеши в нижнем регистре
+func NormalizeValue(iocType, value string) string {
+	value = strings.TrimSpace(value)
+	switch iocType {
+	case models.TypeDomain:
+		return domains.Normalize(value)
+	case models.TypeMD5, models.TypeSHA256, models.TypeIP:
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// Normalize - проверяет связь и приводит концы к каноничному виду; концы должны быть заданы значениями
+func Normalize(rel models.Relationship) (models.Relationship, error) {
+	rel.Kind = strings.ToLower(strings.TrimSpace(rel.Kind))
+	if !ValidKind(rel.Kind) {
+		return rel, fmt.Errorf("%w: unknown kind %q", ErrInvalidRelationship, rel.Kind)
+	}
+	var err error
+	if rel.FromType, rel.FromValue, err = normalizeEnd(rel.FromType, rel.FromValue); err != nil {
+		return rel, err
+	}
+	if rel.ToType, rel.ToValue, err = normalizeEnd(rel.ToType, rel.ToValue); err != nil {
+		return rel, err
+	}
+	if rel.Kind == models.RelationPartOfCampaign && rel.ToType != models.TypeCampaign {
+		// Кампания задается именем, тип по значению не определить
+		rel.ToType = models.TypeCampaign
+	}
+	if rel.FromType == rel.ToType && rel.FromValue == rel.ToValue {
+		return rel, fmt.Errorf("%w: self loop on %s", ErrInvalidRelationship, rel.FromValue)
+	}
+	rel.Source = strings.TrimSpace(rel.Source)
+	if rel.UpdatedAt.IsZero() {
+		rel.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	return rel, nil
+}
+
+func normalizeEnd(iocType, value string) (string, string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", "", fmt.Errorf("%w: empty value", ErrInvalidRelationship)
+	}
+	iocType = strings.ToLower(strings.TrimSpace(iocType))
+	if iocType == "" {
+		iocType = InferType(value)
+	}
+	if iocType == models.TypeCampaign {
+		return iocType, value, nil
+	}
+	return iocType, NormalizeValue(iocType, value), nil
+}
+
+// Ключи additional_data, из которых при записи строятся связи
+const (
+	HintResolvesTo = "resolves_to" // IP, в которые резолвится домен
+	HintHosts      = "hosts"       // Хеши файлов, которые раздает URL/домен/IP
+	HintDrops      = "drops"       // Хеши или URL, которые загружает файл
+	HintCampaign   = "campaign"    // Кампания
+	HintMalware    = "malware"     // Семейство ВПО (ThreatFox malware_printable)
+)
+
+var hintKinds = []struct {
+	key  string
+	kind string
+}{
+	{HintResolvesTo, models.RelationResolvesTo},
+	{HintHosts, models.RelationHosts},
+	{HintDrops, models.RelationDrops},
+	{HintCampaign, models.RelationPartOfCampaign},
+	{HintMalware, models.RelationPartOfCampaign},
+}
+
+// FromHints - связи из подсказок в additional_data; значения в подсказке разделяются запятыми
+func FromHints(iocs []models.IoCDto) []models.Relationship {
+	var result []models.Relationship
+	for _, ioc := range iocs {
+		for _, hint := range hintKinds {
+			values, ok := ioc.AdditionalData[hint.key]
+			if !ok {
+				continue
+			}
+			for _, value := range strings.Split(values, ",") {
+				rel := models.Relationship{
+					FromType:  ioc.Type,
+					FromValue: ioc.Value,
+					Kind:      hint.kind,
+					ToValue:   value,
+					Source:    ioc.Source,
+				}
+				if hint.kind == models.RelationPartOfCampaign {
+					rel.ToType = models.TypeCampaign
+				}
+				if rel, err := Normalize(rel); err == nil {
+					result = append(result, rel)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Fetch - связи, у которых любой из концов входит в values
+type Fetch func(ctx context.Context, values []string) ([]models.Relationship, error)
+
+// Traverse - обход в ширину от значения до depth шагов в обе стороны связей
+func Traverse(ctx context.Context, request models.NeighborsRequest, fetch Fetch) (models.Subgraph, error) {
+	var graph models.Subgraph
+	start := strings.TrimSpace(request.Value)
+	if start == "" {
+		return graph, fmt.Errorf("%w: empty value", ErrInvalidRelationship)
+	}
+	depth, limit := request.Depth, request.Limit
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	if depth > MaxDepth {
+		return graph, fmt.Errorf("%w: depth must not exceed %d", ErrInvalidRelationship, MaxDepth)
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	var kindFilter map[string]bool
+	if len(request.Kinds) > 0 {
+		kindFilter = make(map[string]bool, len(request.Kinds))
+		for _, kind := range request.Kinds {
+			kindFilter[strings.ToLower(kind)] = true
+		}
+	}
+
+	startType := InferType(start)
+	type nodeKey struct{ iocType, value string }
+	visited := map[nodeKey]bool{}
+	edges := map[models.Relationship]bool{}
+	// Тип исходного значения заранее неизвестен (имя кампании не отличить от домена), поэтому
+	// ищем и исходное, и нормализованное значение
+	frontier := []string{start}
+	if normalized := NormalizeValue(startType, start); normalized != start {
+		frontier = append(frontier, normalized)
+	}
+	startValues := map[string]bool{}
+	seenValues := map[string]bool{}
+	for _, value := range frontier {
+		startValues[value] = true
+		seenValues[value] = true
+	}
+
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		relationships, err := fetch(ctx, frontier)
+		if err != nil {
+			return graph, err
+		}
+		var next []string
+		for _, rel := range relationships {
+			if kindFilter != nil && !kindFilter[rel.Kind] {
+				continue
+			}
+			key := rel
+			key.UpdatedAt = time.Time{}
+			if edges[key] {
+				continue
+			}
+			for _, end := range []nodeKey{{rel.FromType, rel.FromValue}, {rel.ToType, rel.ToValue}} {
+				if visited[end] {
+					continue
+				}
+				nodeDepth := level
+				if startValues[end.value] {
+					nodeDepth = 0
+				}
+				if len(graph.Nodes) >= limit {
+					graph.Truncated = true
+					return graph, nil
+				}
+				visited[end] = true
+				graph.Nodes = append(graph.Nodes, models.GraphNode{Type: end.iocType, Value: end.value, Depth: nodeDepth})
+				if !seenValues[end.value] {
+					seenValues[end.value] = true
+					next = append(next, end.value)
+				}
+			}
+			edges[key] = true
+			graph.Edges = append(graph.Edges, rel)
+		}
+		frontier = next
+	}
+	if len(graph.Nodes) == 0 {
+		graph.Nodes = append(graph.Nodes, models.GraphNode{Type: startType, Value: NormalizeValue(startType, start)})
+	}
+	return graph, nil
+}
@@ -0,0 +1,409 @@
+package importer
+
+import (
+	"awesomeProject/internal/misp"
+	"awesomeProject/internal/stix"
+	"awesomeProject/models"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Поддерживаемые форматы импорта
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatSTIX   = "stix"
+	FormatMISP   = "misp"
+)
+
+const (
+	// DefaultSource - источник для строк, где он не указан ни в файле, ни в опциях
+	DefaultSource = "import"
+	// BatchTagPrefix - префикс тега, которым помечаются все IoC одного импорта
+	BatchTagPrefix = "import:"
+	// BatchIDKey - ключ additional_data с идентификатором импорта
+	BatchIDKey = "import_batch_id"
+	// maxReportedErrors - сколько ошибок по строкам возвращаем клиенту, остальные только считаем
+	maxReportedErrors = 1000
+	maxLineSize       = 1024 * 1024
+)
+
+// Options - параметры импорта файла
+type Options struct {
+	Format  string
+	Source  string            // Источник по умолчанию
+	Type    string            // Тип по умолчанию (например, для CSV без колонки type)
+	Columns map[string]string // Поле IoC -> имя колонки CSV
+	BatchID string            // Идентификатор импорта, генерируется если пустой
+}
+
+// LineError - ошибка разбора или валидации конкретной строки (объекта) файла
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Result - итог импорта
+type Result struct {
+	BatchID  string      `json:"batch_id"`
+	Accepted int         `json:"accepted"`
+	Rejected int         `json:"rejected"`
+	Errors   []LineError `json:"errors,omitempty"`
+}
+
+func (r *Result) reject(line int, err error) {
+	r.Rejected++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, LineError{Line: line, Error: err.Error()})
+	}
+}
+
+// Parse - потоково разбирает файл, валидирует каждую запись и передает корректные IoC в emit.
+// Ошибка emit прерывает импорт, ошибки отдельных строк попадают в Result.
+func Parse(r io.Reader, opts Options, emit func(models.IoCDto) error) (Result, error) {
+	if opts.BatchID == "" {
+		opts.BatchID = uuid.NewString()
+	}
+	p := &parser{opts: opts, emit: emit, result: Result{BatchID: opts.BatchID}}
+
+	var err error
+	switch strings.ToLower(opts.Format) {
+	case FormatCSV:
+		err = p.parseCSV(r)
+	case FormatNDJSON:
+		err = p.parseNDJSON(r)
+	case FormatSTIX:
+		err = p.parseSTIX(r)
+	case FormatMISP:
+		err = p.parseMISP(r)
+	default:
+		err = fmt.Errorf("unsupported import format: %s", opts.Format)
+	}
+	return p.result, err
+}
+
+// ParseColumns - разбирает маппинг колонок вида "value=indicator,type=kind"
+func ParseColumns(spec string) (map[string]string, error) {
+	columns := make(map[string]string)
+	if strings.TrimSpace(spec) == "" {
+		return columns, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(field) == "" || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid column mapping: %s", pair)
+		}
+		columns[strings.TrimSpace(field)] = strings.TrimSpace(column)
+	}
+	return columns, nil
+}
+
+type parser struct {
+	opts   Options
+	emit   func(models.IoCDto) error
+	result Result
+}
+
+// accept - дополняет IoC значениями по умолчанию, валидирует и передает дальше
+func (p *parser) accept(line int, ioc models.IoCDto) error {
+	if ioc.Source == "" {
+		ioc.Source = p.opts.Source
+	}
+	if ioc.Source == "" {
+		ioc.Source = DefaultSource
+	}
+	if ioc.Type == "" {
+		ioc.Type = p.opts.Type
+	}
+	if err := Validate(&ioc); err != nil {
+		p.result.reject(line, err)
+		return nil
+	}
+
+	if _, err := uuid.Parse(ioc.ID); err != nil {
+		ioc.ID = uuid.NewString()
+	}
+	now := time.Now().UTC()
+	if ioc.FirstSeen == nil {
+		ioc.FirstSeen = &now
+	}
+	if ioc.LastSeen == nil {
+		ioc.LastSeen = ioc.FirstSeen
+	}
+	ioc.Tags = append(ioc.Tags, BatchTagPrefix+p.opts.BatchID)
+	if ioc.AdditionalData == nil {
+		ioc.AdditionalData = make(map[string]string)
+	}
+	ioc.AdditionalData[BatchIDKey] = p.opts.BatchID
+
+	if err := p.emit(ioc); err != nil {
+		return err
+	}
+	p.result.Accepted++
+	return nil
+}
+
+// csvFields - поля IoC, которые можно сопоставить с колонками CSV
+var csvFields = []string{"id", "source", "type", "value", "first_seen", "last_seen", "tags"}
+
+func (p *parser) parseCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	positions := make(map[string]int)
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	index := make(map[string]int)
+	for _, field := range csvFields {
+		column := field
+		if mapped, ok := p.opts.Columns[field]; ok {
+			column = strings.ToLower(mapped)
+		}
+		if pos, ok := positions[column]; ok {
+			index[field] = pos
+		}
+	}
+	if _, ok := index["value"]; !ok {
+		return fmt.Errorf("csv has no value column (header: %s)", strings.Join(header, ","))
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				// Строка, с которой началась запись: незакрытая кавычка ломает запись ниже ее начала
+				p.result.reject(parseErr.StartLine, err)
+				continue
+			}
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		get := func(field string) string {
+			if pos, ok := index[field]; ok && pos < len(record) {
+				return strings.TrimSpace(record[pos])
+			}
+			return ""
+		}
+
+		ioc := models.IoCDto{
+			ID:     get("id"),
+			Source: get("source"),
+			Type:   get("type"),
+			Value:  get("value"),
+		}
+		if tags := get("tags"); tags != "" {
+			ioc.Tags = strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' || r == '|' })
+		}
+		if ioc.FirstSeen, err = parseTimestamp(get("first_seen")); err != nil {
+			p.result.reject(line, err)
+			continue
+		}
+		if ioc.LastSeen, err = parseTimestamp(get("last_seen")); err != nil {
+			p.result.reject(line, err)
+			continue
+		}
+		if err := p.accept(line, ioc); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) parseNDJSON(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var ioc models.IoCDto
+		if err := json.Unmarshal(data, &ioc); err != nil {
+			p.result.reject(line, fmt.Errorf("invalid json: %v", err))
+			continue
+		}
+		if err := p.accept(line, ioc); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseSTIX - читает объекты bundle по одному, не загружая весь файл в память
+func (p *parser) parseSTIX(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return fmt.Errorf("invalid stix bundle: %w", err)
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("invalid stix bundle: %w", err)
+		}
+		if key != "objects" {
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return fmt.Errorf("invalid stix bundle: %w", err)
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '['); err != nil {
+			return fmt.Errorf("invalid stix bundle objects: %w", err)
+		}
+		object := 0
+		for decoder.More() {
+			object++
+			var indicator stix.Indicator
+			if err := decoder.Decode(&indicator); err != nil {
+				return fmt.Errorf("invalid stix object %d: %w", object, err)
+			}
+			if indicator.Type != "indicator" {
+				// identity, malware, relationship и прочие объекты не являются IoC
+				continue
+			}
+			ioc, err := stix.ToIoC(indicator)
+			if err != nil {
+				p.result.reject(object, err)
+				continue
+			}
+			if err := p.accept(object, ioc); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("invalid stix bundle objects: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseMISP - принимает одиночное событие, массив событий или ответ REST API MISP ({"response": [...]})
+func (p *parser) parseMISP(r io.Reader) error {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return fmt.Errorf("invalid misp json: %w", err)
+	}
+
+	var events []misp.EventWrapper
+	trimmed := bytes.TrimSpace(raw)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return fmt.Errorf("invalid misp events: %w", err)
+		}
+	default:
+		var envelope struct {
+			Response []misp.EventWrapper `json:"response"`
+			Event    *misp.Event         `json:"Event"`
+		}
+		if err := json.Unmarshal(trimmed, &envelope); err != nil {
+			return fmt.Errorf("invalid misp event: %w", err)
+		}
+		events = envelope.Response
+		if envelope.Event != nil {
+			events = append(events, misp.EventWrapper{Event: *envelope.Event})
+		}
+	}
+
+	attribute := 0
+	for _, wrapper := range events {
+		event := wrapper.Event
+		attributes := event.Attribute
+		for _, object := range event.Object {
+			attributes = append(attributes, object.Attribute...)
+		}
+
+		for _, attr := range attributes {
+			attribute++
+			iocType, ok := misp.IoCType(attr.Type)
+			if !ok {
+				p.result.reject(attribute, fmt.Errorf("unsupported misp attribute type: %s", attr.Type))
+				continue
+			}
+			ioc := models.IoCDto{
+				ID:             attr.UUID,
+				Type:           iocType,
+				Value:          misp.AttributeValue(attr.Type, attr.Value),
+				AdditionalData: map[string]string{"misp_event": event.Info},
+			}
+			if event.Orgc != nil {
+				ioc.Source = event.Orgc.Name
+			}
+			for _, tag := range event.Tag {
+				ioc.Tags = append(ioc.Tags, tag.Name)
+			}
+			for _, tag := range attr.Tag {
+				ioc.Tags = append(ioc.Tags, tag.Name)
+			}
+			if attr.Timestamp != "" {
+				seen, err := parseTimestamp(attr.Timestamp)
+				if err != nil {
+					p.result.reject(attribute, err)
+					continue
+				}
+				ioc.FirstSeen, ioc.LastSeen = seen, seen
+			}
+			if err := p.accept(attribute, ioc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseTimestamp - понимает RFC 3339, дату и unix-время в секундах
+func parseTimestamp(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t := time.Unix(seconds, 0).UTC()
+		return &t, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid timestamp: %s", value)
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %q, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package importer
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// parsed - IoC, переданные в emit, в виде "тип:значение"
+func parsed(t *testing.T, opts Options, input string) ([]string, Result, error) {
+	t.Helper()
+	var iocs []string
+	result, err := Parse(strings.NewReader(input), opts, func(ioc models.IoCDto) error {
+		iocs = append(iocs, ioc.Type+":"+ioc.Value)
+		return nil
+	})
+	return iocs, result, err
+}
+
+// rejectedLines - номера отклоненных строк
+func rejectedLines(result Result) string {
+	lines := make([]string, 0, len(result.Errors))
+	for _, lineErr := range result.Errors {
+		lines = append(lines, fmt.Sprint(lineErr.Line))
+	}
+	return strings.Join(lines, ",")
+}
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		input    string
+		want     string // Принятые IoC "тип:значение" через запятую
+		rejected string // Номера отклоненных строк (объектов, атрибутов)
+	}{
+		{
+			name: "csv with type column",
+			opts: Options{Format: FormatCSV},
+			input: "type,value,first_seen\n" +
+				"ip,192.0.2.10,2024-01-02\n" +
+				"domain,evil.example.com,1704153600\n" +
+				"sha256," + strings.Repeat("ab", 32) + ",2024-01-02T03:04:05Z\n",
+			want: "ip:192.0.2.10,domain:evil.example.com,sha256:" + strings.Repeat("ab", 32),
+		},
+		{
+			name:  "csv default type and column mapping",
+			opts:  Options{Format: FormatCSV, Type: models.TypeDomain, Columns: map[string]string{"value": "Indicator"}},
+			input: "Indicator,comment\nevil.example.com,c2\nbad.example.com,\"phishing, kit\"\n",
+			want:  "domain:evil.example.com,domain:bad.example.com",
+		},
+		{
+			name: "csv malformed rows",
+			opts: Options{Format: FormatCSV},
+			input: "type,value,last_seen\n" +
+				"ip,192.0.2.10,\n" + // 2: ok
+				"ip,not-an-ip,\n" + // 3: значение не соответствует типу
+				"domain,\"unterminated\n" + // 4: незакрытая кавычка съедает остаток файла
+				"ip,192.0.2.11,\n",
+			want:     "ip:192.0.2.10",
+			rejected: "3,4",
+		},
+		{
+			name: "csv invalid rows",
+			opts: Options{Format: FormatCSV},
+			input: "type,value,first_seen\n" +
+				"ip,192.0.2.10,yesterday\n" + // 2: время
+				"hostname,evil.example.com,\n" + // 3: тип
+				",evil.example.com,\n" + // 4: пустой тип
+				"md5,abc,\n" + // 5: длина хеша
+				"ip,,\n" + // 6: пустое значение
+				"ip\n" + // 7: короткая строка без значения
+				"ip,198.51.100.0/24,\n", // 8: подсеть - допустимый ip
+			want:     "ip:198.51.100.0/24",
+			rejected: "2,3,4,5,6,7",
+		},
+		{
+			name:     "ndjson",
+			opts:     Options{Format: FormatNDJSON, Source: "ir-case"},
+			input:    `{"type":"url","value":"http://evil.example.com/a"}` + "\n\n" + `{"type":"url","value":` + "\n" + `{"type":"url","value":"evil.example.com"}` + "\n" + `{"type":"IP","value":" 192.0.2.10 "}` + "\n",
+			want:     "url:http://evil.example.com/a,ip:192.0.2.10",
+			rejected: "3,4",
+		},
+		{
+			name: "stix type from pattern",
+			opts: Options{Format: FormatSTIX},
+			input: `{"type":"bundle","id":"bundle--1","objects":[
+				{"type":"identity","name":"CERT"},
+				{"type":"indicator","pattern":"[ipv4-addr:value = '192.0.2.10']","pattern_type":"stix"},
+				{"type":"indicator","pattern":"[ipv6-addr:value = '2001:db8::1']"},
+				{"type":"indicator","pattern":"[domain-name:value = 'evil.example.com']"},
+				{"type":"indicator","pattern":"[url:value = 'http://evil.example.com/a']"},
+				{"type":"indicator","pattern":"[file:hashes.'SHA-256' = '` + strings.Repeat("ab", 32) + `']"},
+				{"type":"indicator","pattern":"[file:hashes.MD5 = '` + strings.Repeat("cd", 16) + `']"},
+				{"type":"indicator","pattern":"[email-addr:value = 'a@example.com']"},
+				{"type":"indicator","pattern":"rule x {}","pattern_type":"yara"},
+				{"type":"indicator","pattern":"[ipv4-addr:value = '999.0.0.1']"}
+			]}`,
+			want:     "ip:192.0.2.10,ip:2001:db8::1,domain:evil.example.com,url:http://evil.example.com/a,sha256:" + strings.Repeat("ab", 32) + ",md5:" + strings.Repeat("cd", 16),
+			rejected: "8,9,10",
+		},
+		{
+			name: "misp type from attribute",
+			opts: Options{Format: FormatMISP},
+			input: `{"Event":{"info":"campaign","Orgc":{"name":"CIRCL"},"Attribute":[
+				{"type":"ip-dst|port","value":"192.0.2.10|443"},
+				{"type":"hostname","value":"evil.example.com"},
+				{"type":"filename|md5","value":"dropper.exe|` + strings.Repeat("cd", 16) + `"},
+				{"type":"email-src","value":"a@example.com"},
+				{"type":"url","value":"http://evil.example.com/a","timestamp":"soon"}
+			],"Object":[{"name":"file","Attribute":[{"type":"sha256","value":"` + strings.Repeat("ab", 32) + `"}]}]}}`,
+			want:     "ip:192.0.2.10,domain:evil.example.com,md5:" + strings.Repeat("cd", 16) + ",sha256:" + strings.Repeat("ab", 32),
+			rejected: "4,5",
+		},
+		{
+			name:  "misp events array and rest response",
+			opts:  Options{Format: FormatMISP},
+			input: `[{"Event":{"info":"a","Attribute":[{"type":"domain","value":"a.example.com"}]}},{"Event":{"info":"b","Attribute":[{"type":"ip-src","value":"192.0.2.1"}]}}]`,
+			want:  "domain:a.example.com,ip:192.0.2.1",
+		},
+		{
+			name:  "misp rest response",
+			opts:  Options{Format: FormatMISP},
+			input: `{"response":[{"Event":{"info":"a","Attribute":[{"type":"link","value":"https://a.example.com/"}]}}]}`,
+			want:  "url:https://a.example.com/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iocs, result, err := parsed(t, tt.opts, tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := strings.Join(iocs, ","); got != tt.want {
+				t.Fatalf("accepted:\n got %s\nwant %s", got, tt.want)
+			}
+			if got := rejectedLines(result); got != tt.rejected {
+				t.Fatalf("rejected lines %q, want %q (errors: %+v)", got, tt.rejected, result.Errors)
+			}
+			if result.Accepted != len(iocs) || result.Rejected != len(result.Errors) {
+				t.Fatalf("result counts: %+v", result)
+			}
+		})
+	}
+}
+
+func TestParseInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		input string
+		err   string
+	}{
+		{"unsupported format", Options{Format: "xml"}, "<iocs/>", "unsupported import format"},
+		{"csv without value column", Options{Format: FormatCSV}, "type,indicator\nip,192.0.2.10\n", "csv has no value column"},
+		{"csv mapped column missing", Options{Format: FormatCSV, Columns: map[string]string{"value": "ioc"}}, "type,value\nip,192.0.2.10\n", "csv has no value column"},
+		{"stix not an object", Options{Format: FormatSTIX}, `[]`, "invalid stix bundle"},
+		{"stix broken object", Options{Format: FormatSTIX}, `{"objects":[{"type":"indicator","pattern":1}]}`, "invalid stix object 1"},
+		{"stix truncated", Options{Format: FormatSTIX}, `{"objects":[{"type":"indicator"}`, "invalid stix object 2"},
+		{"misp broken json", Options{Format: FormatMISP}, `{"Event":`, "invalid misp json"},
+		{"misp wrong shape", Options{Format: FormatMISP}, `[{"Event":[]}]`, "invalid misp events"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parsed(t, tt.opts, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseTagsBatch(t *testing.T) {
+	var iocs []models.IoCDto
+	result, err := Parse(strings.NewReader("value,tags\nevil.example.com,apt;c2\n"), Options{Format: FormatCSV, Type: models.TypeDomain, BatchID: "b1"}, func(ioc models.IoCDto) error {
+		iocs = append(iocs, ioc)
+		return nil
+	})
+	if err != nil || result.BatchID != "b1" || len(iocs) != 1 {
+		t.Fatalf("parse: %v, %+v", err, result)
+	}
+	ioc := iocs[0]
+	if strings.Join(ioc.Tags, ",") != "apt,c2,"+BatchTagPrefix+"b1" || ioc.AdditionalData[BatchIDKey] != "b1" {
+		t.Fatalf("batch tags: %v, additional_data %v", ioc.Tags, ioc.AdditionalData)
+	}
+	if ioc.Source != DefaultSource || ioc.ID == "" || ioc.FirstSeen == nil || ioc.LastSeen != ioc.FirstSeen {
+		t.Fatalf("defaults: source %q, id %q, first_seen %v, last_seen %v", ioc.Source, ioc.ID, ioc.FirstSeen, ioc.LastSeen)
+	}
+}
+
+func TestParseStopsOnEmitError(t *testing.T) {
+	errStop := errors.New("storage is down")
+	calls := 0
+	result, err := Parse(strings.NewReader("a.example.com\nb.example.com\n"), Options{Format: FormatCSV, Type: models.TypeDomain, Columns: map[string]string{"value": "a.example.com"}}, func(models.IoCDto) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 || result.Accepted != 0 {
+		t.Fatalf("got %v after %d calls, %+v", err, calls, result)
+	}
+}
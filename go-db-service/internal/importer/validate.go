@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"awesomeProject/models"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// Validate - проверяет и нормализует IoC перед записью: тип из поддерживаемых, значение соответствует типу
+func Validate(ioc *models.IoCDto) error {
+	ioc.Type = strings.ToLower(strings.TrimSpace(ioc.Type))
+	ioc.Value = strings.TrimSpace(ioc.Value)
+	ioc.Source = strings.TrimSpace(ioc.Source)
+
+	if ioc.Value == "" {
+		return fmt.Errorf("empty value")
+	}
+
+	switch ioc.Type {
+	case models.TypeIP:
+		if _, err := netip.ParseAddr(ioc.Value); err == nil {
+			return nil
+		}
+		// FireHOL и подобные списки содержат подсети
+		if _, err := netip.ParsePrefix(ioc.Value); err == nil {
+			return nil
+		}
+		return fmt.Errorf("invalid ip: %s", ioc.Value)
+	case models.TypeDomain:
+		return validateDomain(ioc.Value)
+	case models.TypeURL:
+		u, err := url.Parse(ioc.Value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid url: %s", ioc.Value)
+		}
+		return nil
+	case models.TypeSHA256:
+		return validateHash(ioc.Value, 64)
+	case models.TypeMD5:
+		return validateHash(ioc.Value, 32)
+	case "":
+		return fmt.Errorf("empty type")
+	default:
+		return fmt.Errorf("unsupported type: %s", ioc.Type)
+	}
+}
+
+func validateDomain(value string) error {
+	domain := strings.TrimSuffix(value, ".")
+	if len(domain) > 253 || !strings.Contains(domain, ".") {
+		return fmt.Errorf("invalid domain: %s", value)
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("invalid domain: %s", value)
+		}
+		for _, r := range label {
+			if !(r == '-' || r == '_' || r == '*' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127) {
+				return fmt.Errorf("invalid domain: %s", value)
+			}
+		}
+	}
+	return nil
+}
+
+func validateHash(value string, length int) error {
+	if len(value) != length {
+		return fmt.Errorf("invalid hash length %d, expected %d", len(value), length)
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return fmt.Errorf("invalid hash: %s", value)
+	}
+	return nil
+}
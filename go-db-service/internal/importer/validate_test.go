@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"awesomeProject/models"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		iocType string
+		value   string
+		valid   bool
+	}{
+		{"ip", "192.0.2.10", true},
+		{"IP", " 2001:db8::1 ", true},
+		{"ip", "198.51.100.0/24", true},
+		{"ip", "192.0.2.256", false},
+		{"domain", "evil.example.com", true},
+		{"domain", "evil.example.com.", true},
+		{"domain", "*.example.com", true},
+		{"domain", "xn--e1afmkfd.xn--p1ai", true},
+		{"domain", "localhost", false},
+		{"domain", "evil..example.com", false},
+		{"domain", "evil example.com", false},
+		{"domain", strings.Repeat("a", 64) + ".example.com", false},
+		{"url", "http://evil.example.com/a?b=c", true},
+		{"url", "evil.example.com/a", false},
+		{"url", "http:///a", false},
+		{"sha256", strings.Repeat("AB", 32), true},
+		{"sha256", strings.Repeat("ab", 31), false},
+		{"md5", strings.Repeat("zz", 16), false},
+		{"md5", strings.Repeat("0f", 16), true},
+		{"hostname", "evil.example.com", false},
+		{"", "evil.example.com", false},
+		{"ip", "  ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.iocType+" "+tt.value, func(t *testing.T) {
+			ioc := models.IoCDto{Type: tt.iocType, Value: tt.value}
+			err := Validate(&ioc)
+			if (err == nil) != tt.valid {
+				t.Fatalf("valid %v, got error %v", tt.valid, err)
+			}
+			if err == nil && (ioc.Type != strings.ToLower(strings.TrimSpace(tt.iocType)) || ioc.Value != strings.TrimSpace(tt.value)) {
+				t.Fatalf("not normalized: type %q, value %q", ioc.Type, ioc.Value)
+			}
+		})
+	}
+}
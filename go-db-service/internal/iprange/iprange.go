@@ -0,0 +1,92 @@
+package iprange
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ErrInvalidRange - значение не является IP, CIDR или диапазоном start-end
+var ErrInvalidRange = errors.New("invalid ip range")
+
+// Range - непрерывный диапазон адресов. IPv4 хранится как IPv4-mapped IPv6,
+// чтобы IPv4 и IPv6 лежали в одной колонке ClickHouse IPv6 и сравнивались напрямую.
+type Range struct {
+	Start netip.Addr
+	End   netip.Addr
+}
+
+// Parse - разбирает IP ("1.2.3.4"), CIDR ("185.220.0.0/16") или диапазон ("1.2.3.0-1.2.5.255")
+func Parse(value string) (Range, error) {
+	value = strings.TrimSpace(value)
+	if start, end, ok := strings.Cut(value, "-"); ok {
+		startAddr, err1 := netip.ParseAddr(strings.TrimSpace(start))
+		endAddr, err2 := netip.ParseAddr(strings.TrimSpace(end))
+		if err1 != nil || err2 != nil {
+			return Range{}, fmt.Errorf("%w: %q", ErrInvalidRange, value)
+		}
+		r := Range{Start: to16(startAddr), End: to16(endAddr)}
+		if r.End.Less(r.Start) {
+			return Range{}, fmt.Errorf("%w: %q ends before it starts", ErrInvalidRange, value)
+		}
+		return r, nil
+	}
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return Range{}, fmt.Errorf("%w: %q", ErrInvalidRange, value)
+		}
+		return FromPrefix(prefix), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return Range{}, fmt.Errorf("%w: %q", ErrInvalidRange, value)
+	}
+	addr = to16(addr)
+	return Range{Start: addr, End: addr}, nil
+}
+
+// FromPrefix - первый и последний адрес сети
+func FromPrefix(prefix netip.Prefix) Range {
+	prefix = prefix.Masked()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		bits += 96
+	}
+	start := to16(prefix.Addr())
+	end := start.As16()
+	for i := bits; i < 128; i++ {
+		end[i/8] |= 1 << (7 - i%8)
+	}
+	return Range{Start: start, End: netip.AddrFrom16(end)}
+}
+
+// Zero - значение колонок диапазона для IoC, которые не являются IP
+var Zero = Range{Start: netip.IPv6Unspecified(), End: netip.IPv6Unspecified()}
+
+// FromIoC - диапазон IP IoC (адрес, CIDR или start-end); false для остальных типов и мусора
+func FromIoC(iocType, value string) (Range, bool) {
+	if !strings.EqualFold(iocType, "ip") {
+		return Zero, false
+	}
+	r, err := Parse(value)
+	if err != nil {
+		return Zero, false
+	}
+	return r, true
+}
+
+// IP - адрес в виде net.IP для записи в колонку IPv6
+func IP(addr netip.Addr) net.IP {
+	bytes := addr.As16()
+	return net.IP(bytes[:])
+}
+
+func to16(addr netip.Addr) netip.Addr {
+	if addr.Is4() {
+		return netip.AddrFrom16(addr.As16())
+	}
+	return addr
+}
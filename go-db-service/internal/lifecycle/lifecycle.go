@@ -0,0 +1,116 @@
+package lifecycle
+
+import (
+	"awesomeProject/internal/graph"
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalid - неизвестный статус или запрос без обязательных полей
+var ErrInvalid = errors.New("invalid lifecycle request")
+
+var statuses = map[string]bool{
+	models.StatusActive:        true,
+	models.StatusFalsePositive: true,
+	models.StatusRevoked:       true,
+	models.StatusExpired:       true,
+}
+
+// ParseStatus - проверка статуса
+func ParseStatus(status string) (string, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if !statuses[status] {
+		return "", fmt.Errorf("%w: unknown status %q, expected active, false_positive, revoked or expired", ErrInvalid, status)
+	}
+	return status, nil
+}
+
+// Ref - тип и значение IoC, к которым привязан статус; пустой тип определяется по значению.
+// Значение приводится к нижнему регистру, как при записи IoC.
+func Ref(iocType, value string) (string, string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", "", fmt.Errorf("%w: empty value", ErrInvalid)
+	}
+	iocType = strings.ToLower(strings.TrimSpace(iocType))
+	if iocType == "" {
+		iocType = graph.InferType(value)
+	}
+	return iocType, value, nil
+}
+
+// Index - IoC с неактивным статусом; по нему из потока подписчиков и оповещений убираются
+// повторно записанные false_positive и revoked. Общая запись и запись владельца скрывают IoC
+// от всех, запись другого арендатора - только от него самого (см. Suppressed).
+type Index struct {
+	mu         sync.RWMutex
+	suppressed map[string]string // арендатор|тип|значение -> статус
+}
+
+func NewIndex() *Index {
+	return &Index{suppressed: make(map[string]string)}
+}
+
+func key(owner, iocType, value string) string {
+	return owner + "|" + strings.ToLower(iocType) + "|" + strings.ToLower(value)
+}
+
+// Reload - заменяет набор неактивных IoC
+func (i *Index) Reload(records []models.Lifecycle) {
+	suppressed := make(map[string]string, len(records))
+	for _, record := range records {
+		if record.Status != models.StatusActive {
+			suppressed[key(record.Tenant, record.Type, record.Value)] = record.Status
+		}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.suppressed = suppressed
+}
+
+// Set - обновляет статус одного IoC для арендатора записи
+func (i *Index) Set(record models.Lifecycle) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if record.Status == models.StatusActive {
+		delete(i.suppressed, key(record.Tenant, record.Type, record.Value))
+		return
+	}
+	i.suppressed[key(record.Tenant, record.Type, record.Value)] = record.Status
+}
+
+// Active - IoC из пачки без неактивного статуса
+func (i *Index) Active(iocs []models.IoCDto) []models.IoCDto {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if len(i.suppressed) == 0 {
+		return iocs
+	}
+	result := make([]models.IoCDto, 0, len(iocs))
+	for _, ioc := range iocs {
+		_, shared := i.suppressed[key("", ioc.Type, ioc.Value)]
+		_, own := i.suppressed[key(ioc.Tenant, ioc.Type, ioc.Value)]
+		if !shared && !own {
+			result = append(result, ioc)
+		}
+	}
+	return result
+}
+
+// Suppressed - скрыт ли IoC от арендатора viewer: общей записью, записью владельца или его собственной
+func (i *Index) Suppressed(viewer string, ioc models.IoCDto) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if len(i.suppressed) == 0 {
+		return false
+	}
+	for _, owner := range []string{"", ioc.Tenant, viewer} {
+		if _, ok := i.suppressed[key(owner, ioc.Type, ioc.Value)]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,385 @@
+package misp
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	manifestFile = "manifest.json"
+	hashesFile   = "hashes.csv"
+	stateFile    = ".feed-state.json"
+
+	// regenerateOverlap - перекрытие окон между запусками, чтобы не пропустить записи,
+	// закоммиченные с added_at чуть раньше начала предыдущего прогона
+	regenerateOverlap = time.Minute
+	orgName           = "ThreatIntelligencePlatform"
+)
+
+var (
+	// feedNamespace - namespace для детерминированных uuid событий и атрибутов фида
+	feedNamespace = uuid.MustParse("a3d9f0b4-5c2e-4e71-8b6a-1f0c9d7e2a58")
+	orgUUID       = uuid.NewSHA1(feedNamespace, []byte("org:"+orgName)).String()
+)
+
+// Loader - источник IoC для фида (сервисный слой); ошибка чтения приходит в канал ошибок
+// после закрытия канала IoC
+type Loader interface {
+	Load(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, chan error, error)
+}
+
+// ManifestEntry - запись manifest.json о событии фида
+type ManifestEntry struct {
+	Info          string `json:"info"`
+	Date          string `json:"date"`
+	Timestamp     string `json:"timestamp"`
+	Analysis      string `json:"analysis"`
+	ThreatLevelID string `json:"threat_level_id"`
+	Published     bool   `json:"published"`
+	Orgc          Orgc   `json:"Orgc"`
+	Tag           []Tag  `json:"Tag"`
+}
+
+// feedState - состояние генератора между перезапусками
+type feedState struct {
+	GeneratedUntil time.Time `json:"generated_until"`
+}
+
+// group - событие фида: все IoC одного источника, записанные за один день (UTC)
+type group struct {
+	source string
+	day    time.Time
+}
+
+// FeedGenerator - генерирует MISP feed (manifest.json, <uuid>.json, hashes.csv) в каталог на диске.
+// Каждый прогон пересобирает только события тех (источник, день), куда с прошлого раза добавились IoC.
+type FeedGenerator struct {
+	dir    string
+	loader Loader
+	logger logger.CustomZapLogger
+	mu     sync.Mutex
+}
+
+// NewFeedGenerator - конструктор генератора фида
+func NewFeedGenerator(dir string, loader Loader, logger logger.CustomZapLogger) *FeedGenerator {
+	return &FeedGenerator{dir: dir, loader: loader, logger: logger}
+}
+
+// Dir - каталог фида
+func (g *FeedGenerator) Dir() string {
+	return g.dir
+}
+
+// Run - перегенерирует фид сразу и затем с заданным интервалом до отмены контекста
+func (g *FeedGenerator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := g.Generate(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			g.logger.Error("MISP feed generation failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Generate - инкрементально обновляет фид
+func (g *FeedGenerator) Generate(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := os.MkdirAll(g.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create feed dir: %w", err)
+	}
+	state, err := g.readState()
+	if err != nil {
+		return err
+	}
+	runStart := time.Now().UTC()
+
+	groups, err := g.changedGroups(ctx, state.GeneratedUntil)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		g.logger.Debug("MISP feed is up to date")
+		return g.writeState(feedState{GeneratedUntil: runStart.Add(-regenerateOverlap)})
+	}
+
+	manifest, err := g.readManifest()
+	if err != nil {
+		return err
+	}
+	hashes := make(map[string][]string, len(groups)) // uuid события -> строки hashes.csv
+	for _, grp := range groups {
+		eventUUID, entry, eventHashes, err := g.writeEvent(ctx, grp)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			// Все IoC группы ушли (например, переписаны в другой день) - удаляем событие
+			delete(manifest, eventUUID)
+			_ = os.Remove(filepath.Join(g.dir, eventUUID+".json"))
+		} else {
+			manifest[eventUUID] = *entry
+		}
+		hashes[eventUUID] = eventHashes
+	}
+
+	if err := g.writeJSON(manifestFile, manifest); err != nil {
+		return err
+	}
+	if err := g.rewriteHashes(hashes); err != nil {
+		return err
+	}
+	if err := g.writeState(feedState{GeneratedUntil: runStart.Add(-regenerateOverlap)}); err != nil {
+		return err
+	}
+
+	g.logger.Info("MISP feed regenerated", zap.Int("events", len(groups)), zap.Int("totalEvents", len(manifest)))
+	return nil
+}
+
+// changedGroups - пары (источник, день), в которые записывались IoC после since
+func (g *FeedGenerator) changedGroups(ctx context.Context, since time.Time) ([]group, error) {
+	request := models.LoadRequest{}
+	if !since.IsZero() {
+		request.AddedAfter = &since
+	}
+	stream, errs, err := g.loader.Load(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load changed IoCs: %w", err)
+	}
+
+	seen := make(map[group]struct{})
+	for ioc := range stream {
+		if ioc.AddedAt == nil {
+			continue
+		}
+		seen[group{source: ioc.Source, day: truncateDay(*ioc.AddedAt)}] = struct{}{}
+	}
+	if err := <-errs; err != nil {
+		return nil, fmt.Errorf("failed to load changed IoCs: %w", err)
+	}
+
+	groups := make([]group, 0, len(seen))
+	for grp := range seen {
+		groups = append(groups, grp)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].day.Equal(groups[j].day) {
+			return groups[i].day.Before(groups[j].day)
+		}
+		return groups[i].source < groups[j].source
+	})
+	return groups, nil
+}
+
+// writeEvent - пересобирает файл события группы; entry = nil, если в группе не осталось IoC
+func (g *FeedGenerator) writeEvent(ctx context.Context, grp group) (string, *ManifestEntry, []string, error) {
+	date := grp.day.Format("2006-01-02")
+	eventUUID := uuid.NewSHA1(feedNamespace, []byte("event:"+grp.source+":"+date)).String()
+
+	// DateTime хранится с точностью до секунды, added_at > (начало дня - 1с) включает полночь
+	from := grp.day.Add(-time.Second)
+	to := grp.day.AddDate(0, 0, 1)
+	stream, errs, err := g.loader.Load(ctx, models.LoadRequest{Source: grp.source, AddedAfter: &from, AddedBefore: &to})
+	if err != nil {
+		return eventUUID, nil, nil, fmt.Errorf("failed to load IoCs for %s %s: %w", grp.source, date, err)
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	event := Event{
+		UUID:             eventUUID,
+		Info:             fmt.Sprintf("%s indicators %s", grp.source, date),
+		Date:             date,
+		Timestamp:        now,
+		PublishTimestamp: now,
+		Published:        true,
+		Analysis:         "2",
+		ThreatLevelID:    "3",
+		Orgc:             &Orgc{Name: orgName, UUID: orgUUID},
+		Tag:              []Tag{{Name: "source:" + grp.source}},
+	}
+	var hashes []string
+	for ioc := range stream {
+		attr := toAttribute(ioc)
+		event.Attribute = append(event.Attribute, attr)
+		sum := md5.Sum([]byte(attr.Value))
+		hashes = append(hashes, hex.EncodeToString(sum[:])+","+eventUUID)
+	}
+	if err := <-errs; err != nil {
+		return eventUUID, nil, nil, fmt.Errorf("failed to load IoCs for %s %s: %w", grp.source, date, err)
+	}
+	if len(event.Attribute) == 0 {
+		return eventUUID, nil, nil, nil
+	}
+
+	if err := g.writeJSON(eventUUID+".json", EventWrapper{Event: event}); err != nil {
+		return eventUUID, nil, nil, err
+	}
+	entry := &ManifestEntry{
+		Info:          event.Info,
+		Date:          event.Date,
+		Timestamp:     event.Timestamp,
+		Analysis:      event.Analysis,
+		ThreatLevelID: event.ThreatLevelID,
+		Published:     event.Published,
+		Orgc:          *event.Orgc,
+		Tag:           event.Tag,
+	}
+	return eventUUID, entry, hashes, nil
+}
+
+// toAttribute - атрибут MISP из IoC, тип атрибута берется из типа IoC
+func toAttribute(ioc *models.IoCDto) Attribute {
+	attrUUID := ioc.ID
+	if _, err := uuid.Parse(attrUUID); err != nil {
+		attrUUID = uuid.NewSHA1(feedNamespace, []byte("attribute:"+ioc.Type+":"+ioc.Value)).String()
+	}
+	seen := ioc.LastSeen
+	if seen == nil || seen.IsZero() {
+		seen = ioc.AddedAt
+	}
+
+	attr := Attribute{
+		UUID:     attrUUID,
+		Type:     AttributeType(ioc.Type),
+		Category: Category(ioc.Type),
+		Value:    ioc.Value,
+		ToIDS:    true,
+	}
+	if seen != nil {
+		attr.Timestamp = strconv.FormatInt(seen.Unix(), 10)
+	}
+	for _, tag := range ioc.Tags {
+		if tag != "" {
+			attr.Tag = append(attr.Tag, Tag{Name: tag})
+		}
+	}
+	return attr
+}
+
+func (g *FeedGenerator) readManifest() (map[string]ManifestEntry, error) {
+	manifest := make(map[string]ManifestEntry)
+	data, err := os.ReadFile(filepath.Join(g.dir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func (g *FeedGenerator) readState() (feedState, error) {
+	var state feedState
+	data, err := os.ReadFile(filepath.Join(g.dir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read feed state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		// Поврежденное состояние - просто пересобираем фид целиком
+		g.logger.Warn("Failed to parse MISP feed state, regenerating from scratch", zap.Error(err))
+		return feedState{}, nil
+	}
+	return state, nil
+}
+
+func (g *FeedGenerator) writeState(state feedState) error {
+	return g.writeJSON(stateFile, state)
+}
+
+// rewriteHashes - заменяет в hashes.csv строки пересобранных событий
+func (g *FeedGenerator) rewriteHashes(updated map[string][]string) error {
+	path := filepath.Join(g.dir, hashesFile)
+	return g.writeAtomically(hashesFile, func(w io.Writer) error {
+		buf := bufio.NewWriter(w)
+		if existing, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(existing)
+			for scanner.Scan() {
+				line := scanner.Text()
+				_, eventUUID, _ := strings.Cut(line, ",")
+				if _, replaced := updated[eventUUID]; replaced {
+					continue
+				}
+				buf.WriteString(line)
+				buf.WriteByte('\n')
+			}
+			existing.Close()
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("failed to read hashes: %w", err)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to open hashes: %w", err)
+		}
+
+		for _, lines := range updated {
+			for _, line := range lines {
+				buf.WriteString(line)
+				buf.WriteByte('\n')
+			}
+		}
+		return buf.Flush()
+	})
+}
+
+func (g *FeedGenerator) writeJSON(name string, value interface{}) error {
+	return g.writeAtomically(name, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(value)
+	})
+}
+
+// writeAtomically - пишет во временный файл и переименовывает, чтобы клиенты не увидели недописанный файл
+func (g *FeedGenerator) writeAtomically(name string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(g.dir, "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(g.dir, name)); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
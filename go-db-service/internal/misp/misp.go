@@ -0,0 +1,114 @@
+package misp
+
+import (
+	"awesomeProject/models"
+	"strings"
+)
+
+// Tag - тег MISP
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Attribute - атрибут события MISP
+type Attribute struct {
+	UUID      string `json:"uuid,omitempty"`
+	Type      string `json:"type"`
+	Category  string `json:"category,omitempty"`
+	Value     string `json:"value"`
+	ToIDS     bool   `json:"to_ids"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+	Tag       []Tag  `json:"Tag,omitempty"`
+}
+
+// Object - объект MISP, группирующий атрибуты
+type Object struct {
+	Name      string      `json:"name"`
+	Attribute []Attribute `json:"Attribute"`
+}
+
+// Orgc - организация-автор события
+type Orgc struct {
+	Name string `json:"name"`
+	UUID string `json:"uuid,omitempty"`
+}
+
+// Event - событие MISP
+type Event struct {
+	UUID             string      `json:"uuid,omitempty"`
+	Info             string      `json:"info"`
+	Date             string      `json:"date,omitempty"`
+	Timestamp        string      `json:"timestamp,omitempty"`
+	PublishTimestamp string      `json:"publish_timestamp,omitempty"`
+	Published        bool        `json:"published"`
+	Analysis         string      `json:"analysis,omitempty"`
+	ThreatLevelID    string      `json:"threat_level_id,omitempty"`
+	Orgc             *Orgc       `json:"Orgc,omitempty"`
+	Tag              []Tag       `json:"Tag,omitempty"`
+	Attribute        []Attribute `json:"Attribute"`
+	Object           []Object    `json:"Object,omitempty"`
+}
+
+// EventWrapper - событие в том виде, в котором MISP отдает и принимает его в JSON
+type EventWrapper struct {
+	Event Event `json:"Event"`
+}
+
+// IoCType - тип IoC платформы для типа атрибута MISP; ok = false, если тип не поддерживается
+func IoCType(attributeType string) (string, bool) {
+	switch strings.ToLower(attributeType) {
+	case "ip-dst", "ip-src", "ip-dst|port", "ip-src|port":
+		return models.TypeIP, true
+	case "domain", "hostname", "domain|ip":
+		return models.TypeDomain, true
+	case "url", "uri", "link":
+		return models.TypeURL, true
+	case "sha256", "filename|sha256":
+		return models.TypeSHA256, true
+	case "md5", "filename|md5":
+		return models.TypeMD5, true
+	default:
+		return "", false
+	}
+}
+
+// AttributeValue - значение индикатора из составного атрибута MISP (например ip-dst|port или filename|sha256)
+func AttributeValue(attributeType, value string) string {
+	parts := strings.SplitN(value, "|", 2)
+	if len(parts) != 2 {
+		return value
+	}
+	if strings.HasPrefix(strings.ToLower(attributeType), "filename|") {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+// AttributeType - тип атрибута MISP для типа IoC платформы
+func AttributeType(iocType string) string {
+	switch strings.ToLower(iocType) {
+	case models.TypeIP:
+		return "ip-dst"
+	case models.TypeDomain:
+		return "domain"
+	case models.TypeURL:
+		return "url"
+	case models.TypeSHA256:
+		return "sha256"
+	case models.TypeMD5:
+		return "md5"
+	default:
+		return "text"
+	}
+}
+
+// Category - категория атрибута MISP для типа IoC платформы
+func Category(iocType string) string {
+	switch strings.ToLower(iocType) {
+	case models.TypeSHA256, models.TypeMD5:
+		return "Payload delivery"
+	default:
+		return "Network activity"
+	}
+}
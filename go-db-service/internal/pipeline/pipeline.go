@@ -0,0 +1,285 @@
+package pipeline
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultStageTimeout = 5 * time.Second
+
+// Политики при ошибке или таймауте шага
+const (
+	FailOpen   = "open"   // Пропустить пачку дальше без изменений шага
+	FailClosed = "closed" // Не записывать пачку
+)
+
+// ErrStageFailed - шаг с политикой closed завершился ошибкой, пачка не записывается
+var ErrStageFailed = errors.New("enrichment stage failed")
+
+// Stage - шаг обогащения: получает пачку и возвращает ее обогащенной или отфильтрованной.
+// Пачка принадлежит шагу, ее можно менять на месте.
+type Stage interface {
+	Name() string
+	Process(ctx context.Context, iocs []models.IoCDto) ([]models.IoCDto, error)
+}
+
+// Factory - создает шаг по параметрам из конфигурации
+type Factory func(options json.RawMessage) (Stage, error)
+
+var factories = map[string]Factory{
+	"extract":  newExtractStage,
+	"hashtype": newHashTypeStage,
+	"tags":     newTagStage,
+}
+
+// Register - добавляет шаг, доступный в конфигурации по имени
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// StageConfig - шаг в файле конфигурации
+type StageConfig struct {
+	Stage   string          `json:"stage"`
+	Types   []string        `json:"types,omitempty"`    // Типы IoC, которые получает шаг; пусто - все
+	Timeout Duration        `json:"timeout,omitempty"`  // По умолчанию 5s
+	OnError string          `json:"on_error,omitempty"` // open (по умолчанию) или closed
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// Duration - длительность в JSON строкой ("2s", "500ms")
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"2s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig - читает упорядоченный список шагов из JSON файла
+func LoadConfig(path string) ([]StageConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline config: %w", err)
+	}
+	var configs []StageConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline config: %w", err)
+	}
+	return configs, nil
+}
+
+// stage - шаг с настройками и метриками
+type stage struct {
+	Stage
+	types   map[string]bool
+	timeout time.Duration
+	onError string
+
+	batches  atomic.Int64
+	in       atomic.Int64
+	out      atomic.Int64
+	errors   atomic.Int64
+	timeouts atomic.Int64
+	nanos    atomic.Int64
+}
+
+// StageStats - метрики шага с момента запуска
+type StageStats struct {
+	Stage     string  `json:"stage"`
+	Batches   int64   `json:"batches"`
+	In        int64   `json:"in"`
+	Out       int64   `json:"out"`
+	Errors    int64   `json:"errors"`
+	Timeouts  int64   `json:"timeouts"`
+	AvgMillis float64 `json:"avg_ms"`
+}
+
+// Pipeline - упорядоченные шаги обогащения между брокером и хранилищем
+type Pipeline struct {
+	stages []*stage
+	logger logger.CustomZapLogger
+}
+
+// New - собирает пайплайн; неизвестный шаг или политика не дают запустить сервис
+func New(configs []StageConfig, logger logger.CustomZapLogger) (*Pipeline, error) {
+	p := &Pipeline{logger: logger}
+	for i, config := range configs {
+		factory, ok := factories[config.Stage]
+		if !ok {
+			return nil, fmt.Errorf("pipeline stage %d: unknown stage %q", i, config.Stage)
+		}
+		impl, err := factory(config.Options)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline stage %d (%s): %w", i, config.Stage, err)
+		}
+
+		s := &stage{Stage: impl, timeout: time.Duration(config.Timeout), onError: strings.ToLower(config.OnError)}
+		if s.timeout <= 0 {
+			s.timeout = defaultStageTimeout
+		}
+		switch s.onError {
+		case "":
+			s.onError = FailOpen
+		case FailOpen, FailClosed:
+		default:
+			return nil, fmt.Errorf("pipeline stage %d (%s): on_error must be open or closed, got %q", i, config.Stage, config.OnError)
+		}
+		if len(config.Types) > 0 {
+			s.types = make(map[string]bool, len(config.Types))
+			for _, iocType := range config.Types {
+				s.types[strings.ToLower(iocType)] = true
+			}
+		}
+		p.stages = append(p.stages, s)
+	}
+	return p, nil
+}
+
+// Stages - число шагов
+func (p *Pipeline) Stages() int {
+	return len(p.stages)
+}
+
+// Handler - оборачивает обработчик пачек брокера: пачка проходит шаги и передается дальше
+func (p *Pipeline) Handler(next func(ctx context.Context, iocs []models.IoCDto) error) func(ctx context.Context, iocs []models.IoCDto) error {
+	return func(ctx context.Context, iocs []models.IoCDto) error {
+		iocs, err := p.Process(ctx, iocs)
+		if err != nil {
+			return err
+		}
+		if len(iocs) == 0 {
+			return nil
+		}
+		return next(ctx, iocs)
+	}
+}
+
+// Process - прогоняет пачку через шаги по порядку. Шаг получает только IoC своих типов,
+// остальные проходят мимо него без изменений и остаются на своих местах в пачке.
+func (p *Pipeline) Process(ctx context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	for _, s := range p.stages {
+		selected, own := s.split(iocs)
+		if len(selected) == 0 {
+			continue
+		}
+		processed, err := p.run(ctx, s, selected)
+		if err != nil {
+			if s.onError == FailClosed {
+				return nil, fmt.Errorf("%w: %s: %v", ErrStageFailed, s.Name(), err)
+			}
+			p.logger.Warn("Enrichment stage failed, passing batch through", zap.String("stage", s.Name()), zap.Error(err))
+			continue
+		}
+		iocs = merge(iocs, own, processed)
+	}
+	return iocs, nil
+}
+
+// split - копия IoC для шага и отметки, какие позиции пачки ему достались (nil - все)
+func (s *stage) split(iocs []models.IoCDto) (selected []models.IoCDto, own []bool) {
+	if s.types == nil {
+		return append([]models.IoCDto(nil), iocs...), nil
+	}
+	own = make([]bool, len(iocs))
+	for i, ioc := range iocs {
+		if s.types[strings.ToLower(ioc.Type)] {
+			selected = append(selected, ioc)
+			own[i] = true
+		}
+	}
+	return selected, own
+}
+
+// merge - возвращает результат шага на позиции его IoC. Шаг сохраняет порядок своих IoC:
+// если он что-то отфильтровал, освобождаются его последние позиции, добавленные IoC идут в конец пачки.
+func merge(iocs []models.IoCDto, own []bool, processed []models.IoCDto) []models.IoCDto {
+	if own == nil {
+		return processed
+	}
+	result := make([]models.IoCDto, 0, len(iocs)+len(processed))
+	next := 0
+	for i, ioc := range iocs {
+		switch {
+		case !own[i]:
+			result = append(result, ioc)
+		case next < len(processed):
+			result = append(result, processed[next])
+			next++
+		}
+	}
+	return append(result, processed[next:]...)
+}
+
+// run - вызов шага с таймаутом; после таймаута результат брошенного вызова игнорируется
+func (p *Pipeline) run(ctx context.Context, s *stage, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	type result struct {
+		iocs []models.IoCDto
+		err  error
+	}
+	resultChan := make(chan result, 1)
+	started := time.Now()
+	go func() {
+		processed, err := s.Process(ctx, iocs)
+		resultChan <- result{iocs: processed, err: err}
+	}()
+
+	s.batches.Add(1)
+	s.in.Add(int64(len(iocs)))
+	defer func() { s.nanos.Add(int64(time.Since(started))) }()
+
+	select {
+	case res := <-resultChan:
+		if res.err != nil {
+			s.errors.Add(1)
+			return nil, res.err
+		}
+		s.out.Add(int64(len(res.iocs)))
+		return res.iocs, nil
+	case <-ctx.Done():
+		s.timeouts.Add(1)
+		return nil, fmt.Errorf("timed out after %s", s.timeout)
+	}
+}
+
+// Stats - метрики шагов в порядке выполнения
+func (p *Pipeline) Stats() []StageStats {
+	stats := make([]StageStats, len(p.stages))
+	for i, s := range p.stages {
+		stats[i] = StageStats{
+			Stage:    s.Name(),
+			Batches:  s.batches.Load(),
+			In:       s.in.Load(),
+			Out:      s.out.Load(),
+			Errors:   s.errors.Load(),
+			Timeouts: s.timeouts.Load(),
+		}
+		if stats[i].Batches > 0 {
+			stats[i].AvgMillis = float64(s.nanos.Load()) / float64(stats[i].Batches) / float64(time.Millisecond)
+		}
+	}
+	return stats
+}
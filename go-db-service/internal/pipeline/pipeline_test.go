@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// funcStage - шаг из функции для тестов
+type funcStage struct {
+	name    string
+	process func(ctx context.Context, iocs []models.IoCDto) ([]models.IoCDto, error)
+}
+
+func (s funcStage) Name() string { return s.name }
+
+func (s funcStage) Process(ctx context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	return s.process(ctx, iocs)
+}
+
+func newTestPipeline(t *testing.T, stages ...*stage) *Pipeline {
+	t.Helper()
+	for _, s := range stages {
+		if s.timeout == 0 {
+			s.timeout = time.Second
+		}
+		if s.onError == "" {
+			s.onError = FailOpen
+		}
+	}
+	return &Pipeline{stages: stages, logger: *logger.NewNop()}
+}
+
+func batch(values ...string) []models.IoCDto {
+	iocs := make([]models.IoCDto, 0, len(values))
+	for _, value := range values {
+		iocType, value, _ := strings.Cut(value, ":")
+		iocs = append(iocs, models.IoCDto{Type: iocType, Value: value})
+	}
+	return iocs
+}
+
+func values(iocs []models.IoCDto) string {
+	result := make([]string, 0, len(iocs))
+	for _, ioc := range iocs {
+		result = append(result, ioc.Type+":"+ioc.Value)
+	}
+	return strings.Join(result, ",")
+}
+
+func TestProcessKeepsOrderWithTypeFilter(t *testing.T) {
+	upper := funcStage{name: "upper", process: func(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+		for i := range iocs {
+			iocs[i].Value = strings.ToUpper(iocs[i].Value)
+		}
+		return iocs, nil
+	}}
+	dropFirst := funcStage{name: "drop", process: func(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+		return iocs[1:], nil
+	}}
+	addOne := funcStage{name: "add", process: func(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+		return append(iocs, models.IoCDto{Type: models.TypeDomain, Value: "added"}), nil
+	}}
+	domainsOnly := map[string]bool{models.TypeDomain: true}
+
+	tests := []struct {
+		name  string
+		stage *stage
+		want  string
+	}{
+		{"in place", &stage{Stage: upper, types: domainsOnly}, "domain:A,ip:b,domain:C,ip:d"},
+		{"all types", &stage{Stage: upper}, "domain:A,ip:B,domain:C,ip:D"},
+		{"filtered", &stage{Stage: dropFirst, types: domainsOnly}, "domain:c,ip:b,ip:d"},
+		{"added", &stage{Stage: addOne, types: domainsOnly}, "domain:a,ip:b,domain:c,ip:d,domain:added"},
+		{"no IoCs of stage types", &stage{Stage: upper, types: map[string]bool{models.TypeURL: true}}, "domain:a,ip:b,domain:c,ip:d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPipeline(t, tt.stage)
+			got, err := p.Process(context.Background(), batch("domain:a", "ip:b", "domain:c", "ip:d"))
+			if err != nil {
+				t.Fatalf("process: %v", err)
+			}
+			if values(got) != tt.want {
+				t.Fatalf("got %s, want %s", values(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessStageFailures(t *testing.T) {
+	errBroken := errors.New("lookup service is down")
+	failing := funcStage{name: "failing", process: func(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+		iocs[0].Value = "changed"
+		return nil, errBroken
+	}}
+	slow := funcStage{name: "slow", process: func(ctx context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		iocs[0].Value = "late"
+		return iocs, nil
+	}}
+	tag := funcStage{name: "tag", process: func(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+		for i := range iocs {
+			iocs[i].Tags = append(iocs[i].Tags, "seen")
+		}
+		return iocs, nil
+	}}
+
+	tests := []struct {
+		name     string
+		stage    *stage
+		err      bool
+		errors   int64
+		timeouts int64
+	}{
+		{"error fail open", &stage{Stage: failing}, false, 1, 0},
+		{"error fail closed", &stage{Stage: failing, onError: FailClosed}, true, 1, 0},
+		{"timeout fail open", &stage{Stage: slow, timeout: 20 * time.Millisecond}, false, 0, 1},
+		{"timeout fail closed", &stage{Stage: slow, timeout: 20 * time.Millisecond, onError: FailClosed}, true, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPipeline(t, tt.stage, &stage{Stage: tag})
+			input := batch("domain:a", "domain:b")
+			got, err := p.Process(context.Background(), input)
+			if tt.err {
+				if !errors.Is(err, ErrStageFailed) {
+					t.Fatalf("got error %v, want %v", err, ErrStageFailed)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("process: %v", err)
+				}
+				// Шаг пропущен: следующий получил пачку без его изменений
+				if values(got) != "domain:a,domain:b" || len(got[0].Tags) != 1 {
+					t.Fatalf("got %s with tags %v", values(got), got[0].Tags)
+				}
+			}
+			if input[0].Value != "a" {
+				t.Fatal("failed stage changed the caller's batch")
+			}
+			stats := p.Stats()[0]
+			if stats.Errors != tt.errors || stats.Timeouts != tt.timeouts || stats.Batches != 1 || stats.In != 2 || stats.Out != 0 {
+				t.Fatalf("stats: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"valid", `[{"stage":"extract","types":["URL"]},{"stage":"tags","timeout":"2s","on_error":"closed","options":{"aliases":{"Cobalt Strike":"cobaltstrike"}}}]`, ""},
+		{"unknown stage", `[{"stage":"whois"}]`, `unknown stage "whois"`},
+		{"unknown policy", `[{"stage":"tags","on_error":"retry"}]`, "on_error must be open or closed"},
+		{"invalid options", `[{"stage":"tags","options":{"aliases":["a"]}}]`, "invalid tags options"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var configs []StageConfig
+			if err := json.Unmarshal([]byte(tt.config), &configs); err != nil {
+				t.Fatalf("config: %v", err)
+			}
+			p, err := New(configs, *logger.NewNop())
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("new: %v", err)
+				}
+				if p.Stages() != 2 || p.stages[0].timeout != defaultStageTimeout || p.stages[0].onError != FailOpen || !p.stages[0].types[models.TypeURL] || p.stages[1].timeout != 2*time.Second {
+					t.Fatalf("stages not configured: %+v, %+v", p.stages[0], p.stages[1])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	var config []StageConfig
+	if err := json.Unmarshal([]byte(`[{"stage":"tags","timeout":5}]`), &config); err == nil {
+		t.Fatal("numeric timeout accepted")
+	}
+}
@@ -0,0 +1,155 @@
+package pipeline
+
+import (
+	"awesomeProject/internal/domains"
+	"awesomeProject/models"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// extractedFromKey - ключ additional_data со ссылкой на исходный URL
+const extractedFromKey = "extracted_from"
+
+// extractStage - из URL IoC добавляет отдельные IoC хоста: domain или ip
+type extractStage struct{}
+
+func newExtractStage(json.RawMessage) (Stage, error) {
+	return extractStage{}, nil
+}
+
+func (extractStage) Name() string { return "extract" }
+
+func (extractStage) Process(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	seen := make(map[string]bool, len(iocs))
+	for _, ioc := range iocs {
+		seen[strings.ToLower(ioc.Type)+"|"+strings.ToLower(ioc.Value)] = true
+	}
+
+	result := iocs
+	for _, ioc := range iocs {
+		if !strings.EqualFold(ioc.Type, models.TypeURL) {
+			continue
+		}
+		host := domains.URLHost(ioc.Value)
+		if host == "" {
+			continue
+		}
+		iocType := models.TypeDomain
+		if addr, err := netip.ParseAddr(host); err == nil {
+			iocType, host = models.TypeIP, addr.Unmap().String()
+		}
+		key := iocType + "|" + host
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		result = append(result, models.IoCDto{
+			ID:             uuid.NewString(),
+			Source:         ioc.Source,
+			FirstSeen:      ioc.FirstSeen,
+			LastSeen:       ioc.LastSeen,
+			Type:           iocType,
+			Value:          host,
+			Tags:           append([]string(nil), ioc.Tags...),
+			AdditionalData: map[string]string{extractedFromKey: ioc.Value},
+		})
+	}
+	return result, nil
+}
+
+// hashTypeStage - определяет тип хеша по длине hex значения: исправляет общий тип ("hash",
+// "filehash") и ошибочно указанный md5/sha256; значение приводится к нижнему регистру
+type hashTypeStage struct{}
+
+var hashTypes = map[string]bool{"hash": true, "filehash": true, "file_hash": true, models.TypeMD5: true, models.TypeSHA256: true}
+
+func newHashTypeStage(json.RawMessage) (Stage, error) {
+	return hashTypeStage{}, nil
+}
+
+func (hashTypeStage) Name() string { return "hashtype" }
+
+func (hashTypeStage) Process(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	for i := range iocs {
+		if !hashTypes[strings.ToLower(iocs[i].Type)] {
+			continue
+		}
+		value := strings.ToLower(strings.TrimSpace(iocs[i].Value))
+		if _, err := hex.DecodeString(value); err != nil {
+			continue
+		}
+		switch len(value) {
+		case 32:
+			iocs[i].Type = models.TypeMD5
+		case 64:
+			iocs[i].Type = models.TypeSHA256
+		default:
+			continue
+		}
+		iocs[i].Value = value
+	}
+	return iocs, nil
+}
+
+// tagStage - нормализация тегов: нижний регистр, пробелы и "_" заменяются на "-",
+// синонимы из aliases приводятся к одному тегу, дубликаты удаляются
+type tagStage struct {
+	aliases map[string]string
+}
+
+type tagOptions struct {
+	Aliases map[string]string `json:"aliases"`
+}
+
+func newTagStage(options json.RawMessage) (Stage, error) {
+	var opts tagOptions
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("invalid tags options: %w", err)
+		}
+	}
+	stage := tagStage{aliases: make(map[string]string, len(opts.Aliases))}
+	for alias, tag := range opts.Aliases {
+		stage.aliases[normalizeTag(alias)] = normalizeTag(tag)
+	}
+	return stage, nil
+}
+
+func (tagStage) Name() string { return "tags" }
+
+func (s tagStage) Process(_ context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	for i := range iocs {
+		if len(iocs[i].Tags) == 0 {
+			continue
+		}
+		tags := make([]string, 0, len(iocs[i].Tags))
+		seen := make(map[string]bool, len(iocs[i].Tags))
+		for _, tag := range iocs[i].Tags {
+			tag = normalizeTag(tag)
+			if alias, ok := s.aliases[tag]; ok {
+				tag = alias
+			}
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		iocs[i].Tags = tags
+	}
+	return iocs, nil
+}
+
+func normalizeTag(tag string) string {
+	fields := strings.FieldsFunc(strings.ToLower(tag), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '\t'
+	})
+	return strings.Join(fields, "-")
+}
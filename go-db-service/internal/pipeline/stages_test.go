@@ -0,0 +1,82 @@
+package pipeline
+
+import (
+	"awesomeProject/models"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExtractStage(t *testing.T) {
+	input := []models.IoCDto{
+		{Type: models.TypeURL, Value: "http://Evil.Example.com/a", Source: "urlhaus", Tags: []string{"phishing"}},
+		{Type: models.TypeURL, Value: "https://evil.example.com/b"},
+		{Type: models.TypeURL, Value: "http://[::ffff:192.0.2.10]:8080/x"},
+		{Type: models.TypeURL, Value: "http://known.example.com/"},
+		{Type: models.TypeDomain, Value: "known.example.com"},
+		{Type: models.TypeURL, Value: "not a url"},
+	}
+	got, err := extractStage{}.Process(context.Background(), input)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if want := "url:http://Evil.Example.com/a,url:https://evil.example.com/b,url:http://[::ffff:192.0.2.10]:8080/x,url:http://known.example.com/,domain:known.example.com,url:not a url,domain:evil.example.com,ip:192.0.2.10"; values(got) != want {
+		t.Fatalf("got  %s\nwant %s", values(got), want)
+	}
+	extracted := got[len(input)]
+	if extracted.Source != "urlhaus" || extracted.ID == "" || strings.Join(extracted.Tags, ",") != "phishing" || extracted.AdditionalData[extractedFromKey] != input[0].Value {
+		t.Fatalf("extracted IoC: %+v", extracted)
+	}
+}
+
+func TestHashTypeStage(t *testing.T) {
+	md5 := strings.Repeat("AB", 16)
+	sha256 := strings.Repeat("cd", 32)
+	tests := []struct {
+		iocType, value string
+		want           string
+	}{
+		{"hash", md5, "md5:" + strings.ToLower(md5)},
+		{"FileHash", " " + sha256 + " ", "sha256:" + sha256},
+		{models.TypeMD5, sha256, "sha256:" + sha256},
+		{models.TypeSHA256, md5, "md5:" + strings.ToLower(md5)},
+		{"hash", strings.Repeat("ab", 20), "hash:" + strings.Repeat("ab", 20)}, // sha1 не поддерживается
+		{"hash", strings.Repeat("zz", 16), "hash:" + strings.Repeat("zz", 16)},
+		{models.TypeDomain, md5, "domain:" + md5},
+	}
+	for _, tt := range tests {
+		got, err := hashTypeStage{}.Process(context.Background(), []models.IoCDto{{Type: tt.iocType, Value: tt.value}})
+		if err != nil {
+			t.Fatalf("process: %v", err)
+		}
+		if values(got) != tt.want {
+			t.Errorf("%s %q: got %s, want %s", tt.iocType, tt.value, values(got), tt.want)
+		}
+	}
+}
+
+func TestTagStage(t *testing.T) {
+	stage, err := newTagStage(json.RawMessage(`{"aliases":{"Cobalt Strike":"cobaltstrike","cs":"CobaltStrike"}}`))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{[]string{"APT_28", "apt 28", "  Botnet  "}, "apt-28,botnet"},
+		{[]string{"Cobalt Strike", "cobalt_strike", "CS", "strike"}, "cobaltstrike,strike"},
+		{[]string{"", "  ", "c2"}, "c2"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		got, err := stage.Process(context.Background(), []models.IoCDto{{Tags: tt.tags}})
+		if err != nil {
+			t.Fatalf("process: %v", err)
+		}
+		if strings.Join(got[0].Tags, ",") != tt.want {
+			t.Errorf("%q: got %v, want %s", tt.tags, got[0].Tags, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Any - правило для всех клиентов или всех методов
+const Any = "*"
+
+// idleAfter - через сколько простоя корзина клиента удаляется; к этому времени она снова полная
+const idleAfter = 10 * time.Minute
+
+// Rules - значения ограничений по ключам "Method", "client@Method" и "*".
+// Для вызова выбирается самое точное правило: клиент и метод, затем метод, затем "*".
+type Rules[V any] map[string]V
+
+// Lookup - правило для клиента и метода; ok = false - вызов не ограничен
+func (r Rules[V]) Lookup(client, method string) (V, bool) {
+	if value, ok := r[client+"@"+method]; ok {
+		return value, true
+	}
+	if value, ok := r[method]; ok {
+		return value, true
+	}
+	value, ok := r[Any]
+	return value, ok
+}
+
+// Rate - скорость пополнения корзины в вызовах в секунду и ее емкость
+type Rate struct {
+	PerSecond float64
+	Burst     float64
+}
+
+// ParseRates - правила вида "*=50:100,StreamLoad=2:4,siem-a@Load=200:400"; 0 - без ограничения
+func ParseRates(spec string) (Rules[Rate], error) {
+	rules := make(Rules[Rate])
+	err := parseRules(spec, func(key, value string) error {
+		perSecond, burst, ok := strings.Cut(value, ":")
+		if !ok {
+			burst = perSecond
+		}
+		rate, err := strconv.ParseFloat(perSecond, 64)
+		if err != nil || rate < 0 || math.IsInf(rate, 0) {
+			return fmt.Errorf("invalid rate %q", value)
+		}
+		size, err := strconv.ParseFloat(burst, 64)
+		if err != nil || size < 1 && rate > 0 || math.IsInf(size, 0) {
+			return fmt.Errorf("invalid burst %q", value)
+		}
+		rules[key] = Rate{PerSecond: rate, Burst: size}
+		return nil
+	})
+	return rules, err
+}
+
+// ParseCounts - правила вида "*=4,Export=1"; 0 - без ограничения
+func ParseCounts(spec string) (Rules[int64], error) {
+	rules := make(Rules[int64])
+	err := parseRules(spec, func(key, value string) error {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || count < 0 {
+			return fmt.Errorf("invalid limit %q", value)
+		}
+		rules[key] = count
+		return nil
+	})
+	return rules, err
+}
+
+func parseRules(spec string, parse func(key, value string) error) error {
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid limit entry %q, expected key=value", pair)
+		}
+		if err := parse(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// bucket - корзина токенов одного клиента и метода
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter - token bucket на каждую пару клиент и метод
+type Limiter struct {
+	rules Rules[Rate]
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(rules Rules[Rate]) *Limiter {
+	return &Limiter{rules: rules, buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+// Allow - забирает токен для вызова; если корзина пуста, возвращает, через сколько появится токен
+func (l *Limiter) Allow(client, method string) (time.Duration, bool) {
+	rate, ok := l.rules.Lookup(client, method)
+	if !ok || rate.PerSecond == 0 {
+		return 0, true
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	key := client + "@" + method
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rate.Burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(rate.Burst, b.tokens+now.Sub(b.last).Seconds()*rate.PerSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / rate.PerSecond * float64(time.Second)), false
+}
+
+// sweep - удаляет корзины простаивающих клиентов, чтобы карта не росла от разовых клиентов
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleAfter {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleAfter {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Concurrency - число одновременных вызовов (стримов) на каждую пару клиент и метод
+type Concurrency struct {
+	rules Rules[int64]
+
+	mu     sync.Mutex
+	active map[string]int64
+}
+
+func NewConcurrency(rules Rules[int64]) *Concurrency {
+	return &Concurrency{rules: rules, active: make(map[string]int64)}
+}
+
+// Acquire - занимает место для вызова; release нужно вызвать по его завершении
+func (c *Concurrency) Acquire(client, method string) (release func(), ok bool) {
+	limit, ok := c.rules.Lookup(client, method)
+	if !ok || limit == 0 {
+		return func() {}, true
+	}
+
+	key := client + "@" + method
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active[key] >= limit {
+		return nil, false
+	}
+	c.active[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.active[key]--; c.active[key] == 0 {
+				delete(c.active, key)
+			}
+		})
+	}, true
+}
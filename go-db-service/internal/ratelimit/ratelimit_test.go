@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// rewind - сдвигает время последнего вызова корзины в прошлое, как будто прошло d
+func rewind(l *Limiter, client, method string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets[client+"@"+method].last = l.buckets[client+"@"+method].last.Add(-d)
+}
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	rules, err := ParseRates("*=2:3")
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	l := NewLimiter(rules)
+
+	for i := 0; i < 3; i++ {
+		if _, ok := l.Allow("siem", "Load"); !ok {
+			t.Fatalf("call %d within burst rejected", i+1)
+		}
+	}
+	wait, ok := l.Allow("siem", "Load")
+	if ok {
+		t.Fatal("call over burst allowed")
+	}
+	if wait <= 0 || wait > 500*time.Millisecond {
+		t.Fatalf("retry after %s, want up to 500ms at 2 calls per second", wait)
+	}
+
+	// За полсекунды при 2 вызовах в секунду появляется один токен
+	rewind(l, "siem", "Load", 500*time.Millisecond)
+	if _, ok := l.Allow("siem", "Load"); !ok {
+		t.Fatal("refilled token rejected")
+	}
+	if _, ok := l.Allow("siem", "Load"); ok {
+		t.Fatal("second call after a single refilled token allowed")
+	}
+
+	// Корзина не наполняется больше емкости
+	rewind(l, "siem", "Load", time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, ok := l.Allow("siem", "Load"); !ok {
+			t.Fatalf("call %d after idle rejected", i+1)
+		}
+	}
+	if _, ok := l.Allow("siem", "Load"); ok {
+		t.Fatal("idle bucket refilled over burst")
+	}
+}
+
+func TestLimiterBucketsPerClientAndMethod(t *testing.T) {
+	rules, err := ParseRates("*=1:1,Export=0,vip@Load=100:100")
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	l := NewLimiter(rules)
+
+	if _, ok := l.Allow("siem", "Load"); !ok {
+		t.Fatal("first call rejected")
+	}
+	if _, ok := l.Allow("siem", "Load"); ok {
+		t.Fatal("second call allowed")
+	}
+	if _, ok := l.Allow("siem", "Count"); !ok {
+		t.Fatal("other method shares the bucket")
+	}
+	if _, ok := l.Allow("soc", "Load"); !ok {
+		t.Fatal("other client shares the bucket")
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := l.Allow("siem", "Export"); !ok {
+			t.Fatal("method with rate 0 is limited")
+		}
+		if _, ok := l.Allow("vip", "Load"); !ok {
+			t.Fatal("client rule is not applied")
+		}
+	}
+}
+
+func TestLimiterSweepsIdleBuckets(t *testing.T) {
+	rules, _ := ParseRates("*=1:1")
+	l := NewLimiter(rules)
+	l.Allow("once", "Load")
+	rewind(l, "once", "Load", 2*idleAfter)
+	l.lastSweep = l.lastSweep.Add(-2 * idleAfter)
+
+	l.Allow("siem", "Load")
+	if _, ok := l.buckets["once@Load"]; ok {
+		t.Fatal("idle bucket was not removed")
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	rules, err := ParseRates(" *=50:100 , StreamLoad=2, siem-a@Load=0.5:1 ")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]Rate{Any: {50, 100}, "StreamLoad": {2, 2}, "siem-a@Load": {0.5, 1}}
+	for key, rate := range want {
+		if rules[key] != rate {
+			t.Errorf("%s: got %+v, want %+v", key, rules[key], rate)
+		}
+	}
+	if rate, _ := rules.Lookup("siem-a", "Load"); rate != want["siem-a@Load"] {
+		t.Errorf("client rule: got %+v", rate)
+	}
+	if rate, _ := rules.Lookup("siem-b", "StreamLoad"); rate != want["StreamLoad"] {
+		t.Errorf("method rule: got %+v", rate)
+	}
+	if rate, _ := rules.Lookup("siem-b", "Load"); rate != want[Any] {
+		t.Errorf("default rule: got %+v", rate)
+	}
+
+	for _, spec := range []string{"Load", "=1", "Load=fast", "Load=-1", "Load=1:0.5", "Load=1:x", "Load=Inf"} {
+		if _, err := ParseRates(spec); err == nil {
+			t.Errorf("%q: accepted", spec)
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	rules, err := ParseCounts("*=2,Export=1")
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	c := NewConcurrency(rules)
+
+	release, ok := c.Acquire("siem", "Export")
+	if !ok {
+		t.Fatal("first export rejected")
+	}
+	if _, ok := c.Acquire("siem", "Export"); ok {
+		t.Fatal("second concurrent export allowed")
+	}
+	if _, ok := c.Acquire("soc", "Export"); !ok {
+		t.Fatal("other client shares the limit")
+	}
+	release()
+	release()
+	if _, ok := c.Acquire("siem", "Export"); !ok {
+		t.Fatal("export rejected after release")
+	}
+	if _, ok := c.Acquire("siem", "Export"); ok {
+		t.Fatal("double release freed two places")
+	}
+}
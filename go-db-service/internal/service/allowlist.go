@@ -0,0 +1,162 @@
+package service
+
+import (
+	"awesomeProject/internal/allowlist"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SetAllowlist - подключает allowlist; вызывается до начала приема данных
+func (s *Service) SetAllowlist(list *allowlist.Allowlist) {
+	s.allowlist = list
+}
+
+// applyAllowlist - применяет политику allowlist к пачке перед записью в хранилище
+func (s *Service) applyAllowlist(iocs []models.IoCDto) []models.IoCDto {
+	if s.allowlist == nil {
+		return iocs
+	}
+	result, matched := s.allowlist.Apply(iocs)
+	if matched > 0 {
+		s.logger.Info("Allowlisted IoCs in batch", zap.Int("matched", matched), zap.String("policy", s.allowlist.Policy()))
+	}
+	return result
+}
+
+// ReloadAllowlist перечитывает записи allowlist из хранилища
+func (s *Service) ReloadAllowlist(ctx context.Context) error {
+	if s.allowlist == nil {
+		return nil
+	}
+	entries, err := s.ListAllowlist(ctx)
+	if err != nil {
+		return err
+	}
+	s.allowlist.Reload(entries)
+	return nil
+}
+
+// RunAllowlistRefresh периодически перечитывает allowlist, чтобы подхватить изменения других реплик
+func (s *Service) RunAllowlistRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ReloadAllowlist(ctx); err != nil {
+				s.logger.Error("Failed to refresh allowlist", zap.Error(err))
+			}
+		}
+	}
+}
+
+// ListAllowlist возвращает записи allowlist из хранилища
+func (s *Service) ListAllowlist(ctx context.Context) ([]models.AllowlistEntry, error) {
+	resultChan := make(chan []models.AllowlistEntry, 1)
+	errChan := make(chan error, 1)
+
+	task := func() {
+		defer close(resultChan)
+		defer close(errChan)
+
+		entries, err := s.storage.ListAllowlist(ctx)
+		if err != nil {
+			s.logger.Error("Error listing allowlist", zap.Error(err))
+			errChan <- err
+			return
+		}
+		resultChan <- entries
+	}
+
+	err := s.enqueueTask(ctx, task)
+	if err != nil {
+		close(resultChan)
+		close(errChan)
+		return nil, err
+	}
+
+	select {
+	case result := <-resultChan:
+		return result, nil
+	case err := <-errChan:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// PutAllowlistEntries создает записи без id и изменяет существующие, возвращает сохраненные записи
+func (s *Service) PutAllowlistEntries(ctx context.Context, entries []models.AllowlistEntry) ([]models.AllowlistEntry, error) {
+	if s.allowlist == nil {
+		return nil, fmt.Errorf("allowlist is not configured")
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	normalized := make([]models.AllowlistEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry, err := allowlist.NormalizeEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if entry.ID == "" {
+			entry.ID = uuid.NewString()
+			entry.CreatedAt = now
+		} else if existing, ok := s.allowlist.Entry(entry.ID); ok {
+			entry.CreatedAt = existing.CreatedAt
+		} else {
+			return nil, fmt.Errorf("%w: %s", allowlist.ErrNotFound, entry.ID)
+		}
+		entry.UpdatedAt = now
+		normalized = append(normalized, entry)
+	}
+
+	if err := s.runStorageTask(ctx, func() error { return s.storage.StoreAllowlistEntries(ctx, normalized) }); err != nil {
+		s.logger.Error("Error storing allowlist entries", zap.Error(err))
+		return nil, err
+	}
+	if err := s.ReloadAllowlist(ctx); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Allowlist entries saved", zap.Int("count", len(normalized)))
+	return normalized, nil
+}
+
+// DeleteAllowlistEntry удаляет запись allowlist
+func (s *Service) DeleteAllowlistEntry(ctx context.Context, id string) error {
+	if s.allowlist == nil {
+		return fmt.Errorf("allowlist is not configured")
+	}
+	if _, ok := s.allowlist.Entry(id); !ok {
+		return fmt.Errorf("%w: %s", allowlist.ErrNotFound, id)
+	}
+	if err := s.runStorageTask(ctx, func() error { return s.storage.DeleteAllowlistEntry(ctx, id) }); err != nil {
+		s.logger.Error("Error deleting allowlist entry", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	s.logger.Info("Allowlist entry deleted", zap.String("id", id))
+	return s.ReloadAllowlist(ctx)
+}
+
+// runStorageTask - выполняет операцию хранилища в воркер пуле и ждет ее завершения
+func (s *Service) runStorageTask(ctx context.Context, operation func() error) error {
+	errChan := make(chan error, 1)
+	err := s.enqueueTask(ctx, func() {
+		errChan <- operation()
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 10000
+)
+
+// ErrInvalidAuditQuery - некорректный фильтр журнала аудита
+var ErrInvalidAuditQuery = errors.New("invalid audit query")
+
+// QueryAudit возвращает записи журнала аудита по фильтру, новые первыми
+func (s *Service) QueryAudit(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit and offset must not be negative", ErrInvalidAuditQuery)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAuditQuery)
+	}
+	switch query.Outcome = strings.ToLower(strings.TrimSpace(query.Outcome)); query.Outcome {
+	case "", models.AuditOK, models.AuditError:
+	default:
+		return nil, fmt.Errorf("%w: unknown outcome %q", ErrInvalidAuditQuery, query.Outcome)
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultAuditLimit
+	}
+	if query.Limit > MaxAuditLimit {
+		query.Limit = MaxAuditLimit
+	}
+
+	var records []models.AuditRecord
+	err := s.runStorageTask(ctx, func() error {
+		var err error
+		records, err = s.storage.QueryAudit(ctx, query)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Error querying audit log", zap.Error(err))
+		return nil, err
+	}
+	return records, nil
+}
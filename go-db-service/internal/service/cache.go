@@ -0,0 +1,132 @@
+package service
+
+import (
+	"awesomeProject/internal/cache"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+)
+
+// SetCache - кэширует тяжелые запросы дашборда (Count*, SourceReport и первые страницы Load).
+// TTL задаются по именам методов Storage; Load кэшируется, пока offset+limit не больше maxLoadRows.
+// Вызывается до начала приема данных.
+func (s *Service) SetCache(c *cache.Cache, maxLoadRows int64) {
+	s.cache = c
+	s.storage = &cachedStorage{Storage: s.storage, cache: c, maxLoadRows: maxLoadRows}
+}
+
+// CacheStats - попадания и промахи кэша по методам; false, если кэш выключен
+func (s *Service) CacheStats() ([]cache.Stats, int64, bool) {
+	if s.cache == nil {
+		return nil, 0, false
+	}
+	return s.cache.Stats(), s.cache.Invalidations(), true
+}
+
+// cachedStorage - read-through кэш перед хранилищем. Результаты зависят от арендатора вызова,
+// поэтому он входит в ключ. Запись IoC и смена их статуса сбрасывают кэш.
+type cachedStorage struct {
+	Storage
+	cache       *cache.Cache
+	maxLoadRows int64
+}
+
+func (c *cachedStorage) UnaryStore(ctx context.Context, iocs []models.IoCDto) error {
+	err := c.Storage.UnaryStore(ctx, iocs)
+	if err == nil {
+		c.cache.Invalidate(context.WithoutCancel(ctx))
+	}
+	return err
+}
+
+// StreamStore - кэш сбрасывается и при ошибке: часть стрима могла записаться
+func (c *cachedStorage) StreamStore(ctx context.Context, stream <-chan models.IoCDto) error {
+	err := c.Storage.StreamStore(ctx, stream)
+	c.cache.Invalidate(context.WithoutCancel(ctx))
+	return err
+}
+
+// PutLifecycle - статус меняет выдачу Load
+func (c *cachedStorage) PutLifecycle(ctx context.Context, record models.Lifecycle) error {
+	err := c.Storage.PutLifecycle(ctx, record)
+	if err == nil {
+		c.cache.Invalidate(context.WithoutCancel(ctx))
+	}
+	return err
+}
+
+// UnaryLoad - кэшируются только первые страницы; агрегаты sightings меняются без записи IoC
+func (c *cachedStorage) UnaryLoad(ctx context.Context, request models.LoadRequest) ([]models.IoCDto, error) {
+	if request.IncludeSightings || request.Limit <= 0 || request.Offset+request.Limit > c.maxLoadRows {
+		return c.Storage.UnaryLoad(ctx, request)
+	}
+	return cache.Load(ctx, c.cache, "UnaryLoad", cache.Key(tenant.FromContext(ctx), request), func() ([]models.IoCDto, error) {
+		return c.Storage.UnaryLoad(ctx, request)
+	})
+}
+
+func (c *cachedStorage) AllIocsCount(ctx context.Context) (int64, error) {
+	return cache.Load(ctx, c.cache, "AllIocsCount", cache.Key(tenant.FromContext(ctx)), func() (int64, error) {
+		return c.Storage.AllIocsCount(ctx)
+	})
+}
+
+func (c *cachedStorage) CountByType(ctx context.Context) (map[string]int64, error) {
+	return cache.Load(ctx, c.cache, "CountByType", cache.Key(tenant.FromContext(ctx)), func() (map[string]int64, error) {
+		return c.Storage.CountByType(ctx)
+	})
+}
+
+func (c *cachedStorage) CountSpecificType(ctx context.Context, typeName string) (int64, error) {
+	return cache.Load(ctx, c.cache, "CountSpecificType", cache.Key(tenant.FromContext(ctx), typeName), func() (int64, error) {
+		return c.Storage.CountSpecificType(ctx, typeName)
+	})
+}
+
+func (c *cachedStorage) CountBySource(ctx context.Context) (map[string]int64, error) {
+	return cache.Load(ctx, c.cache, "CountBySource", cache.Key(tenant.FromContext(ctx)), func() (map[string]int64, error) {
+		return c.Storage.CountBySource(ctx)
+	})
+}
+
+func (c *cachedStorage) CountSpecificSource(ctx context.Context, sourceName string) (int64, error) {
+	return cache.Load(ctx, c.cache, "CountSpecificSource", cache.Key(tenant.FromContext(ctx), sourceName), func() (int64, error) {
+		return c.Storage.CountSpecificSource(ctx, sourceName)
+	})
+}
+
+func (c *cachedStorage) CountTypesBySource(ctx context.Context) (map[string]map[string]int64, error) {
+	return cache.Load(ctx, c.cache, "CountTypesBySource", cache.Key(tenant.FromContext(ctx)), func() (map[string]map[string]int64, error) {
+		return c.Storage.CountTypesBySource(ctx)
+	})
+}
+
+func (c *cachedStorage) CountBySourceAndType(ctx context.Context, sourceName string) (map[string]int64, error) {
+	return cache.Load(ctx, c.cache, "CountBySourceAndType", cache.Key(tenant.FromContext(ctx), sourceName), func() (map[string]int64, error) {
+		return c.Storage.CountBySourceAndType(ctx, sourceName)
+	})
+}
+
+func (c *cachedStorage) CountByTypeAndSource(ctx context.Context, typeName string) (map[string]int64, error) {
+	return cache.Load(ctx, c.cache, "CountByTypeAndSource", cache.Key(tenant.FromContext(ctx), typeName), func() (map[string]int64, error) {
+		return c.Storage.CountByTypeAndSource(ctx, typeName)
+	})
+}
+
+func (c *cachedStorage) CountByCountry(ctx context.Context) (map[string]int64, error) {
+	return cache.Load(ctx, c.cache, "CountByCountry", cache.Key(tenant.FromContext(ctx)), func() (map[string]int64, error) {
+		return c.Storage.CountByCountry(ctx)
+	})
+}
+
+func (c *cachedStorage) CountByASN(ctx context.Context, limit int64) ([]models.ASNCount, error) {
+	return cache.Load(ctx, c.cache, "CountByASN", cache.Key(tenant.FromContext(ctx), limit), func() ([]models.ASNCount, error) {
+		return c.Storage.CountByASN(ctx, limit)
+	})
+}
+
+func (c *cachedStorage) SourceReport(ctx context.Context, churnDays int) (models.SourceReport, error) {
+	return cache.Load(ctx, c.cache, "SourceReport", cache.Key(tenant.FromContext(ctx), churnDays), func() (models.SourceReport, error) {
+		return c.Storage.SourceReport(ctx, churnDays)
+	})
+}
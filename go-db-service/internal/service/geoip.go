@@ -0,0 +1,56 @@
+package service
+
+import (
+	"awesomeProject/internal/geoip"
+	"awesomeProject/models"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// SetGeoIP - подключает GeoIP/ASN обогащение; вызывается до начала приема данных
+func (s *Service) SetGeoIP(enricher *geoip.Enricher) {
+	s.geoip = enricher
+}
+
+// prepareBatch - allowlist и обогащение пачки перед записью в хранилище.
+// Пачка копируется, чтобы не менять данные вызывающего.
+func (s *Service) prepareBatch(iocs []models.IoCDto) []models.IoCDto {
+	iocs = s.applyAllowlist(iocs)
+	if s.geoip == nil || len(iocs) == 0 {
+		return iocs
+	}
+	enriched := append([]models.IoCDto(nil), iocs...)
+	s.geoip.Enrich(enriched)
+	return enriched
+}
+
+// CountByCountry возвращает количество IP IoC по странам
+func (s *Service) CountByCountry(ctx context.Context) (map[string]int64, error) {
+	var counts map[string]int64
+	err := s.runStorageTask(ctx, func() error {
+		var err error
+		counts, err = s.storage.CountByCountry(ctx)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Error counting IoCs by country", zap.Error(err))
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountByASN возвращает количество IP IoC по автономным системам, limit = 0 - без ограничения
+func (s *Service) CountByASN(ctx context.Context, limit int64) ([]models.ASNCount, error) {
+	var counts []models.ASNCount
+	err := s.runStorageTask(ctx, func() error {
+		var err error
+		counts, err = s.storage.CountByASN(ctx, limit)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Error counting IoCs by ASN", zap.Error(err))
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"awesomeProject/internal/graph"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// StoreRelationships записывает связи; концы, заданные id, заменяются типом и значением IoC
+func (s *Service) StoreRelationships(ctx context.Context, relationships []models.Relationship) error {
+	if err := s.resolveRelationshipIDs(ctx, relationships); err != nil {
+		return err
+	}
+	normalized := make([]models.Relationship, 0, len(relationships))
+	for _, rel := range relationships {
+		rel, err := graph.Normalize(rel)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, rel)
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+
+	if err := s.runStorageTask(ctx, func() error { return s.storage.StoreRelationships(ctx, normalized) }); err != nil {
+		s.logger.Error("Error storing relationships", zap.Error(err))
+		return err
+	}
+	s.logger.Info("Relationships stored", zap.Int("count", len(normalized)))
+	return nil
+}
+
+// resolveRelationshipIDs - подставляет тип и значение для концов, заданных только id
+func (s *Service) resolveRelationshipIDs(ctx context.Context, relationships []models.Relationship) error {
+	var ids []string
+	for _, rel := range relationships {
+		if rel.FromValue == "" && rel.FromID != "" {
+			ids = append(ids, rel.FromID)
+		}
+		if rel.ToValue == "" && rel.ToID != "" {
+			ids = append(ids, rel.ToID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var found map[string]models.IoCDto
+	err := s.runStorageTask(ctx, func() error {
+		var err error
+		found, err = s.storage.IoCsByID(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i := range relationships {
+		rel := &relationships[i]
+		if rel.FromValue == "" && rel.FromID != "" {
+			ioc, ok := found[rel.FromID]
+			if !ok {
+				return fmt.Errorf("%w: IoC %s not found", graph.ErrInvalidRelationship, rel.FromID)
+			}
+			rel.FromType, rel.FromValue = ioc.Type, ioc.Value
+		}
+		if rel.ToValue == "" && rel.ToID != "" {
+			ioc, ok := found[rel.ToID]
+			if !ok {
+				return fmt.Errorf("%w: IoC %s not found", graph.ErrInvalidRelationship, rel.ToID)
+			}
+			rel.ToType, rel.ToValue = ioc.Type, ioc.Value
+		}
+	}
+	return nil
+}
+
+// storeHintedRelationships - связи из additional_data записанной пачки; ошибка не отменяет запись IoC
+func (s *Service) storeHintedRelationships(ctx context.Context, iocs []models.IoCDto) {
+	// Граф общий для всех арендаторов, поэтому связи строятся только по общим IoC
+	shared := make([]models.IoCDto, 0, len(iocs))
+	for _, ioc := range iocs {
+		if ioc.Tenant == tenant.Shared {
+			shared = append(shared, ioc)
+		}
+	}
+	relationships := graph.FromHints(shared)
+	if len(relationships) == 0 {
+		return
+	}
+	if err := s.storage.StoreRelationships(ctx, relationships); err != nil {
+		s.logger.Error("Failed to store relationships from additional_data", zap.Int("count", len(relationships)), zap.Error(err))
+	}
+}
+
+// Neighbors возвращает окрестность индикатора глубиной до depth связей
+func (s *Service) Neighbors(ctx context.Context, request models.NeighborsRequest) (models.Subgraph, error) {
+	fetch := func(ctx context.Context, values []string) ([]models.Relationship, error) {
+		var relationships []models.Relationship
+		err := s.runStorageTask(ctx, func() error {
+			var err error
+			relationships, err = s.storage.RelationshipsOf(ctx, values, graph.MaxLimit)
+			return err
+		})
+		return relationships, err
+	}
+	subgraph, err := graph.Traverse(ctx, request, fetch)
+	if err != nil {
+		s.logger.Error("Error loading neighbors", zap.String("value", request.Value), zap.Error(err))
+		return models.Subgraph{}, err
+	}
+	return subgraph, nil
+}
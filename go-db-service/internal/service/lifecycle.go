@@ -0,0 +1,167 @@
+package service
+
+import (
+	"awesomeProject/internal/lifecycle"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// nonActiveStatuses - статусы, которые убирают IoC из выдачи, подписок и оповещений
+var nonActiveStatuses = []string{models.StatusFalsePositive, models.StatusRevoked, models.StatusExpired}
+
+// GetLifecycle возвращает статус IoC с заметками; IoC без записи считается active
+func (s *Service) GetLifecycle(ctx context.Context, iocType, value string) (models.Lifecycle, error) {
+	iocType, value, err := lifecycle.Ref(iocType, value)
+	if err != nil {
+		return models.Lifecycle{}, err
+	}
+	var record models.Lifecycle
+	err = s.runStorageTask(ctx, func() error {
+		var err error
+		record, _, err = s.storage.GetLifecycle(ctx, iocType, value)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Error loading lifecycle", zap.String("value", value), zap.Error(err))
+		return models.Lifecycle{}, err
+	}
+	return record, nil
+}
+
+// ListLifecycle возвращает IoC, которых касались аналитики, с указанными статусами (пусто - все)
+func (s *Service) ListLifecycle(ctx context.Context, statuses []string, limit, offset int64) ([]models.Lifecycle, error) {
+	parsed := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		status, err := lifecycle.ParseStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, status)
+	}
+	var records []models.Lifecycle
+	err := s.runStorageTask(ctx, func() error {
+		var err error
+		records, err = s.storage.ListLifecycle(ctx, parsed, limit, offset)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Error listing lifecycle", zap.Error(err))
+		return nil, err
+	}
+	return records, nil
+}
+
+// SetStatus меняет статус IoC; для неактивных статусов нужна причина
+func (s *Service) SetStatus(ctx context.Context, iocType, value, status, reason, changedBy string) (models.Lifecycle, error) {
+	status, err := lifecycle.ParseStatus(status)
+	if err != nil {
+		return models.Lifecycle{}, err
+	}
+	reason = strings.TrimSpace(reason)
+	if status != models.StatusActive && reason == "" {
+		return models.Lifecycle{}, fmt.Errorf("%w: reason is required for status %s", lifecycle.ErrInvalid, status)
+	}
+	return s.updateLifecycle(ctx, iocType, value, changedBy, func(record *models.Lifecycle) {
+		record.Status = status
+		record.Reason = reason
+	})
+}
+
+// SetManualTags заменяет ручные теги IoC; они добавляются к тегам фида при чтении
+func (s *Service) SetManualTags(ctx context.Context, iocType, value string, tags []string, changedBy string) (models.Lifecycle, error) {
+	manualTags := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			manualTags = append(manualTags, tag)
+		}
+	}
+	return s.updateLifecycle(ctx, iocType, value, changedBy, func(record *models.Lifecycle) {
+		record.ManualTags = manualTags
+	})
+}
+
+// updateLifecycle - читает текущую запись, применяет изменение и пишет новую версию
+func (s *Service) updateLifecycle(ctx context.Context, iocType, value, changedBy string, change func(record *models.Lifecycle)) (models.Lifecycle, error) {
+	iocType, value, err := lifecycle.Ref(iocType, value)
+	if err != nil {
+		return models.Lifecycle{}, err
+	}
+	if changedBy = strings.TrimSpace(changedBy); changedBy == "" {
+		return models.Lifecycle{}, fmt.Errorf("%w: changed_by is required", lifecycle.ErrInvalid)
+	}
+
+	var record models.Lifecycle
+	err = s.runStorageTask(ctx, func() error {
+		current, _, err := s.storage.GetLifecycle(ctx, iocType, value)
+		if err != nil {
+			return err
+		}
+		record = current
+		change(&record)
+		record.Tenant = tenant.FromContext(ctx)
+		record.ChangedBy = changedBy
+		record.ChangedAt = time.Now().UTC().Truncate(time.Second)
+		return s.storage.PutLifecycle(ctx, record)
+	})
+	if err != nil {
+		s.logger.Error("Error updating lifecycle", zap.String("value", value), zap.Error(err))
+		return models.Lifecycle{}, err
+	}
+	s.lifecycle.Set(record)
+	s.logger.Info("Lifecycle updated", zap.String("type", iocType), zap.String("value", value),
+		zap.String("status", record.Status), zap.String("changedBy", changedBy))
+	return record, nil
+}
+
+// AddNote добавляет заметку аналитика к IoC
+func (s *Service) AddNote(ctx context.Context, iocType, value, author, text string) (models.Note, error) {
+	iocType, value, err := lifecycle.Ref(iocType, value)
+	if err != nil {
+		return models.Note{}, err
+	}
+	author, text = strings.TrimSpace(author), strings.TrimSpace(text)
+	if author == "" || text == "" {
+		return models.Note{}, fmt.Errorf("%w: note author and text are required", lifecycle.ErrInvalid)
+	}
+	note := models.Note{ID: uuid.NewString(), Author: author, Text: text, CreatedAt: time.Now().UTC().Truncate(time.Second)}
+
+	if err := s.runStorageTask(ctx, func() error { return s.storage.AddNote(ctx, iocType, value, note) }); err != nil {
+		s.logger.Error("Error adding note", zap.String("value", value), zap.Error(err))
+		return models.Note{}, err
+	}
+	return note, nil
+}
+
+// ReloadLifecycle перечитывает неактивные IoC всех арендаторов, которые не должны уходить
+// подписчикам и в оповещения
+func (s *Service) ReloadLifecycle(ctx context.Context) error {
+	records, err := s.ListLifecycle(tenant.WithAllTenants(ctx), nonActiveStatuses, 0, 0)
+	if err != nil {
+		return err
+	}
+	s.lifecycle.Reload(records)
+	return nil
+}
+
+// RunLifecycleRefresh периодически перечитывает статусы, чтобы подхватить изменения других реплик
+func (s *Service) RunLifecycleRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ReloadLifecycle(ctx); err != nil {
+				s.logger.Error("Failed to refresh lifecycle", zap.Error(err))
+			}
+		}
+	}
+}
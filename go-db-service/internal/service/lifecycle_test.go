@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"awesomeProject/internal/changes"
+	"awesomeProject/internal/storage"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"testing"
+	"time"
+)
+
+// receiveVisible - значения событий подписки, видимых подписчику, пока не придет stop
+func receiveVisible(t *testing.T, subscription *changes.Subscription, stop string) []string {
+	t.Helper()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				t.Fatalf("subscription closed: %v", subscription.Err())
+			}
+			if !subscription.Visible(event) {
+				continue
+			}
+			if event.IoC.Value == stop {
+				return got
+			}
+			got = append(got, event.IoC.Value)
+		case <-timeout:
+			t.Fatalf("received %v before timeout", got)
+		}
+	}
+}
+
+func TestTenantFalsePositiveHiddenOnlyFromItsSubscribers(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	shared := context.Background()
+	if err := s.UnaryStoreSync(shared, []models.IoCDto{newIoC("feed", models.TypeDomain, "fp.example.com")}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	unitA := tenant.WithTenant(shared, "unit-a")
+	if _, err := s.SetStatus(unitA, models.TypeDomain, "fp.example.com", models.StatusFalsePositive, "internal host", "analyst"); err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+
+	subscriptions := make(map[string]*changes.Subscription)
+	for name, ctx := range map[string]context.Context{"unit-a": unitA, "unit-b": tenant.WithTenant(shared, "unit-b"), "shared": shared} {
+		subscription, err := s.Subscribe(ctx, "")
+		if err != nil {
+			t.Fatalf("subscribe %s: %v", name, err)
+		}
+		defer subscription.Close()
+		subscriptions[name] = subscription
+	}
+
+	batch := []models.IoCDto{newIoC("feed", models.TypeDomain, "fp.example.com"), newIoC("feed", models.TypeDomain, "stop.example.com")}
+	if err := s.UnaryStoreSync(shared, batch); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	if got := receiveVisible(t, subscriptions["unit-a"], "stop.example.com"); len(got) != 0 {
+		t.Fatalf("unit-a received its false positive: %v", got)
+	}
+	for _, name := range []string{"unit-b", "shared"} {
+		if got := receiveVisible(t, subscriptions[name], "stop.example.com"); !equalValues(got, []string{"fp.example.com"}) {
+			t.Fatalf("%s: got %v, want [fp.example.com]", name, got)
+		}
+	}
+}
+
+// recordingAlerter - запоминает IoC, которые дошли бы до правила арендатора viewer
+type recordingAlerter struct {
+	viewer string
+	got    []string
+}
+
+func (a *recordingAlerter) Evaluate(iocs []models.IoCDto, suppressed func(viewer string, ioc models.IoCDto) bool) {
+	for _, ioc := range iocs {
+		if !suppressed(a.viewer, ioc) {
+			a.got = append(a.got, ioc.Value)
+		}
+	}
+}
+
+func TestTenantFalsePositiveSuppressesItsAlerts(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	unitA, unitB := &recordingAlerter{viewer: "unit-a"}, &recordingAlerter{viewer: "unit-b"}
+	s.SetAlerter(alerters{unitA, unitB})
+
+	unitACtx := tenant.WithTenant(context.Background(), "unit-a")
+	if _, err := s.SetStatus(unitACtx, models.TypeDomain, "fp.example.com", models.StatusFalsePositive, "internal host", "analyst"); err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+	if err := s.UnaryStoreSync(context.Background(), []models.IoCDto{newIoC("feed", models.TypeDomain, "fp.example.com")}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	if len(unitA.got) != 0 {
+		t.Fatalf("unit-a rules received its false positive: %v", unitA.got)
+	}
+	if !equalValues(unitB.got, []string{"fp.example.com"}) {
+		t.Fatalf("unit-b rules: got %v", unitB.got)
+	}
+}
+
+type alerters []*recordingAlerter
+
+func (a alerters) Evaluate(iocs []models.IoCDto, suppressed func(viewer string, ioc models.IoCDto) bool) {
+	for _, alerter := range a {
+		alerter.Evaluate(iocs, suppressed)
+	}
+}
+
+func TestLifecycleStatusIsPerTenant(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	shared := context.Background()
+	if err := s.UnaryStoreSync(shared, []models.IoCDto{newIoC("feed", models.TypeDomain, "fp.example.com")}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	unitA := tenant.WithTenant(shared, "unit-a")
+	record, err := s.SetStatus(unitA, models.TypeDomain, "FP.example.com", models.StatusFalsePositive, "internal host", "analyst")
+	if err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+	if record.Value != "fp.example.com" || record.ChangedBy != "analyst" || record.Tenant != "unit-a" {
+		t.Fatalf("set status: got value %q, changed_by %q, tenant %q", record.Value, record.ChangedBy, record.Tenant)
+	}
+
+	if got := loadValues(t, s, unitA, models.LoadRequest{Source: "feed"}); len(got) != 0 {
+		t.Fatalf("unit-a still sees its false positive: %v", got)
+	}
+	unitB := tenant.WithTenant(shared, "unit-b")
+	if got := loadValues(t, s, unitB, models.LoadRequest{Source: "feed"}); !equalValues(got, []string{"fp.example.com"}) {
+		t.Fatalf("unit-b load: got %v", got)
+	}
+	if got := loadValues(t, s, shared, models.LoadRequest{Source: "feed"}); !equalValues(got, []string{"fp.example.com"}) {
+		t.Fatalf("shared load: got %v", got)
+	}
+}
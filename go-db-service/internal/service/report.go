@@ -0,0 +1,36 @@
+package service
+
+import (
+	"awesomeProject/models"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultChurnDays = 30
+	MaxChurnDays     = 365
+)
+
+// SourceReport возвращает показатели качества и пересечения источников; churnDays <= 0 - за 30 дней
+func (s *Service) SourceReport(ctx context.Context, churnDays int) (models.SourceReport, error) {
+	if churnDays <= 0 {
+		churnDays = DefaultChurnDays
+	}
+	if churnDays > MaxChurnDays {
+		churnDays = MaxChurnDays
+	}
+
+	var report models.SourceReport
+	err := s.runStorageTask(ctx, func() error {
+		var err error
+		report, err = s.storage.SourceReport(ctx, churnDays)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Error building source report", zap.Error(err))
+		return models.SourceReport{}, err
+	}
+	s.logger.Info("Source report built", zap.Int("sources", len(report.Sources)), zap.Int("overlaps", len(report.Overlaps)))
+	return report, nil
+}
@@ -1,25 +1,51 @@
 package service
 
 import (
+	"awesomeProject/internal/allowlist"
+	"awesomeProject/internal/cache"
+	"awesomeProject/internal/changes"
+	"awesomeProject/internal/executor"
+	"awesomeProject/internal/geoip"
+	"awesomeProject/internal/importer"
+	"awesomeProject/internal/lifecycle"
+	"awesomeProject/internal/spool"
+	"awesomeProject/internal/tenant"
 	"awesomeProject/models"
 	"awesomeProject/pkg/logger"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"go.uber.org/zap"
 )
 
 const (
-	WorkerPoolSize = 10  // Количество воркеров в пуле
-	TaskQueueSize  = 100 // Буферизация очереди задач
-	loadBuffSize   = 100
+	loadBuffSize = 100
+
+	importBatchSize = 500  // Размер пачки записи при импорте файлов
+	streamBatchSize = 5000 // Размер пачки записи StreamStore; подписчики получают IoC после коммита каждой
 )
 
 // Service основная структура сервисного слоя
 type Service struct {
 	logger    logger.CustomZapLogger
 	storage   Storage
-	taskQueue chan func() // Канал задач, где функция представляет работу, выполняемую для клиента
+	executor  *executor.Executor   // Пулы задач по классам операций
+	changes   *changes.Hub         // Поток закоммиченных IoC для подписчиков
+	alerter   Alerter              // Правила оповещений, может быть nil
+	allowlist *allowlist.Allowlist // Allowlist, применяемый при записи, может быть nil
+	geoip     *geoip.Enricher      // GeoIP/ASN обогащение IP IoC, может быть nil
+	lifecycle *lifecycle.Index     // IoC, которые аналитики пометили неактивными
+	quotas    *tenantQuotas        // Квоты арендаторов, может быть nil
+	spool     *spool.Spool         // Пачки, не записанные из-за недоступности хранилища, может быть nil
+	cache     *cache.Cache         // Кэш запросов перед хранилищем, может быть nil
+}
+
+// Alerter - проверка записанных пачек по правилам оповещений.
+// suppressed - скрыт ли IoC статусом lifecycle от арендатора правила.
+type Alerter interface {
+	Evaluate(iocs []models.IoCDto, suppressed func(viewer string, ioc models.IoCDto) bool)
 }
 
 type Storage interface {
@@ -29,7 +55,8 @@ type Storage interface {
 
 	// Стримовые операции
 	StreamStore(ctx context.Context, stream <-chan models.IoCDto) error
-	StreamLoad(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, error)
+	// Ошибка чтения приходит в канал ошибок после закрытия канала IoC; закрытый канал без значения - успех
+	StreamLoad(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, chan error, error)
 
 	// Методы для подсчета IoC
 	AllIocsCount(ctx context.Context) (int64, error)
@@ -40,65 +67,120 @@ type Storage interface {
 	CountTypesBySource(ctx context.Context) (map[string]map[string]int64, error)
 	CountBySourceAndType(ctx context.Context, sourceName string) (map[string]int64, error)
 	CountByTypeAndSource(ctx context.Context, typeName string) (map[string]int64, error)
+	CountByCountry(ctx context.Context) (map[string]int64, error)
+	CountByASN(ctx context.Context, limit int64) ([]models.ASNCount, error)
+
+	// Allowlist
+	ListAllowlist(ctx context.Context) ([]models.AllowlistEntry, error)
+	StoreAllowlistEntries(ctx context.Context, entries []models.AllowlistEntry) error
+	DeleteAllowlistEntry(ctx context.Context, id string) error
+
+	// Граф связей
+	StoreRelationships(ctx context.Context, relationships []models.Relationship) error
+	RelationshipsOf(ctx context.Context, values []string, limit int) ([]models.Relationship, error)
+	IoCsByID(ctx context.Context, ids []string) (map[string]models.IoCDto, error)
+
+	// Sightings
+	RecordSightings(ctx context.Context, sightings []models.Sighting) error
+	SightingsBySource(ctx context.Context) (map[string]models.SourceSightings, error)
+
+	// Отчет по источникам
+	SourceReport(ctx context.Context, churnDays int) (models.SourceReport, error)
+
+	// Lifecycle IoC
+	GetLifecycle(ctx context.Context, iocType, value string) (models.Lifecycle, bool, error)
+	ListLifecycle(ctx context.Context, statuses []string, limit, offset int64) ([]models.Lifecycle, error)
+	PutLifecycle(ctx context.Context, record models.Lifecycle) error
+	AddNote(ctx context.Context, iocType, value string, note models.Note) error
+
+	// Арендаторы
+	CountTenant(ctx context.Context, id string) (int64, error)
+
+	// Журнал аудита
+	QueryAudit(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, error)
 }
 
-// Конструктор для создания сервиса с воркер пулом
-func NewService(logger logger.CustomZapLogger, storage Storage) *Service {
-	service := &Service{
+// Конструктор для создания сервиса; задачи хранилища выполняются в пулах executor
+func NewService(logger logger.CustomZapLogger, storage Storage, executor *executor.Executor) *Service {
+	return &Service{
 		logger:    logger,
 		storage:   storage,
-		taskQueue: make(chan func(), TaskQueueSize), // Задаем очередь задач
+		executor:  executor,
+		changes:   changes.NewHub(changes.DefaultBufferSize),
+		lifecycle: lifecycle.NewIndex(),
 	}
+}
 
-	// Инициализируем воркер пул
-	for i := 0; i < WorkerPoolSize; i++ {
-		go service.worker(i)
-	}
+// Drain - перестает принимать вызовы и ждет завершения начатых задач, пока не истечет ctx
+func (s *Service) Drain(ctx context.Context) error {
+	return s.executor.Close(ctx)
+}
 
-	logger.Info("Worker Pool initialized", zap.Int("poolSize", WorkerPoolSize), zap.Int("taskQueueSize", TaskQueueSize))
-	return service
+// ExecutorStats - занятость пулов задач
+func (s *Service) ExecutorStats() []executor.Stats {
+	return s.executor.Stats()
 }
 
-func (s *Service) worker(workerID int) {
-	for {
-		select {
-		case task, ok := <-s.taskQueue:
-			if !ok {
-				s.logger.Debug("Worker shutting down", zap.Int("workerID", workerID))
-				return
+// SetAlerter - подключает правила оповещений; вызывается до начала приема данных
+func (s *Service) SetAlerter(alerter Alerter) {
+	s.alerter = alerter
+}
+
+// committed - вызывается после успешной записи пачки в хранилище: связи из additional_data,
+// подписчики и оповещения; скрытые и неактивные IoC наружу не уходят
+func (s *Service) committed(ctx context.Context, iocs []models.IoCDto) {
+	s.storeHintedRelationships(ctx, iocs)
+
+	// Повторно записанные false_positive и revoked не должны снова вызывать оповещения.
+	// Здесь убираются IoC, скрытые для всех; пометки отдельных арендаторов применяются
+	// к их подпискам и правилам оповещений.
+	visible := s.lifecycle.Active(iocs)
+	if s.allowlist != nil && s.allowlist.Policy() == allowlist.PolicyHide {
+		batch := visible
+		visible = make([]models.IoCDto, 0, len(batch))
+		for _, ioc := range batch {
+			if !ioc.Hidden {
+				visible = append(visible, ioc)
 			}
-			s.logger.Debug("Worker started processing task", zap.Int("workerID", workerID))
-			task()
-			s.logger.Debug("Worker finished task", zap.Int("workerID", workerID))
 		}
 	}
+	s.changes.Publish(visible)
+	if s.alerter != nil {
+		s.alerter.Evaluate(visible, s.lifecycle.Suppressed)
+	}
 }
 
-func (s *Service) enqueueTask(task func()) error {
-	select {
-	case s.taskQueue <- task:
-		s.logger.Debug("Task enqueued successfully")
-		return nil
-	default:
-		s.logger.Warn("Task queue is full, rejecting task.")
-		return fmt.Errorf("task queue is full") // ЕСЛИ НЕТ МЕСТО ДЛЯ ЗАДАЧИ
-	}
+// enqueueTask - короткая задача: ждет места в пуле интерактивных вызовов
+func (s *Service) enqueueTask(ctx context.Context, task func()) error {
+	return s.executor.Submit(ctx, executor.Interactive, task)
+}
+
+// enqueueWriteTask - запись пачки IoC
+func (s *Service) enqueueWriteTask(ctx context.Context, task func()) error {
+	return s.executor.Submit(ctx, executor.Write, task)
+}
+
+// enqueueBulkTask - долгая задача: стримы чтения и записи, импорт
+func (s *Service) enqueueBulkTask(ctx context.Context, task func()) error {
+	return s.executor.Submit(ctx, executor.Bulk, task)
 }
 
 // UnaryStore выполняет унарный запрос на запись данных
 func (s *Service) UnaryStore(ctx context.Context, iocs []models.IoCDto) error {
+	// Арендатор и квота проверяются до постановки в очередь, чтобы клиент получил ошибку
+	iocs, err := s.scopeBatch(ctx, iocs)
+	if err != nil {
+		s.logger.Warn("Rejected UnaryStore batch", zap.Error(err))
+		return err
+	}
+	// Ответ уходит клиенту до записи, поэтому задача не должна отменяться вместе с его вызовом;
+	// значения контекста (клиент, арендатор) при этом сохраняются
+	taskCtx := context.WithoutCancel(ctx)
 	task := func() {
 		s.logger.Info("UnaryStore task started")
-		err := s.storage.UnaryStore(ctx, iocs)
-		// s.logger.Debug(fmt.Sprintf("stored %v", iocs))
-		if err != nil {
-			s.logger.Error("Error storing IoCs in UnaryStore", zap.Error(err))
-			// s.logger.Debug(fmt.Sprintf("failed store %v", iocs))
-			return
-		}
-		s.logger.Info("Successfully stored IoCs in UnaryStore", zap.Int("count", len(iocs)))
+		_ = s.storeBatch(taskCtx, iocs)
 	}
-	err := s.enqueueTask(task)
+	err = s.enqueueWriteTask(ctx, task)
 	if err != nil {
 		s.logger.Error("Error enqueuing task in UnaryStore", zap.Error(err))
 		return err
@@ -106,6 +188,56 @@ func (s *Service) UnaryStore(ctx context.Context, iocs []models.IoCDto) error {
 	return nil
 }
 
+// UnaryStoreSync - как UnaryStore, но возвращается после коммита пачки. Консьюмер брокера
+// подтверждает сообщения только после записи, поэтому пачка не теряется при остановке сервиса.
+func (s *Service) UnaryStoreSync(ctx context.Context, iocs []models.IoCDto) error {
+	iocs, err := s.scopeBatch(ctx, iocs)
+	if err != nil {
+		s.logger.Warn("Rejected UnaryStore batch", zap.Error(err))
+		return err
+	}
+	errChan := make(chan error, 1)
+	if err := s.enqueueWriteTask(ctx, func() { errChan <- s.storeBatch(ctx, iocs) }); err != nil {
+		s.logger.Error("Error enqueuing task in UnaryStoreSync", zap.Error(err))
+		return err
+	}
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// storeBatch - allowlist и обогащение, запись пачки и рассылка закоммиченных IoC.
+// Если хранилище недоступно, пачка откладывается в spool и дописывается позже; отмена или дедлайн
+// вызова в spool не попадают - пачку повторит сам вызывающий (брокер не подтвердит сообщения).
+func (s *Service) storeBatch(ctx context.Context, iocs []models.IoCDto) error {
+	iocs = s.prepareBatch(iocs)
+	if len(iocs) == 0 {
+		return nil
+	}
+	// Пока в spool есть пачки, новые встают за ними, чтобы не записаться раньше более старых
+	if s.spool != nil && s.spool.Pending() {
+		return s.spoolBatch(iocs, nil)
+	}
+	if err := s.storage.UnaryStore(ctx, iocs); err != nil {
+		s.logger.Error("Error storing IoCs in UnaryStore", zap.Error(err))
+		if s.spool != nil && !callerGone(ctx, err) {
+			return s.spoolBatch(iocs, err)
+		}
+		return err
+	}
+	s.committed(ctx, iocs)
+	s.logger.Info("Successfully stored IoCs in UnaryStore", zap.Int("count", len(iocs)))
+	return nil
+}
+
+// callerGone - ошибка из-за отмены или дедлайна вызова, а не из-за хранилища
+func callerGone(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 // UnaryLoad выполняет унарный запрос на загрузку данных
 func (s *Service) UnaryLoad(ctx context.Context, request models.LoadRequest) ([]models.IoCDto, error) {
 	outputChan := make(chan []models.IoCDto)
@@ -128,7 +260,7 @@ func (s *Service) UnaryLoad(ctx context.Context, request models.LoadRequest) ([]
 		outputChan <- iocs
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(outputChan)
 		close(errChan)
@@ -145,19 +277,53 @@ func (s *Service) UnaryLoad(ctx context.Context, request models.LoadRequest) ([]
 	}
 }
 
+// Store пишет IoC из стрима пачками по streamBatchSize; каждая пачка рассылается подписчикам
+// сразу после своего коммита, поэтому память не растет с длиной стрима
 func (s *Service) Store(ctx context.Context, stream chan models.IoCDto) error {
 	task := func() {
 		s.logger.Info("Processing StreamStore task")
-		err := s.storage.StreamStore(ctx, stream) // Передаём поток канала напрямую в хранилище
-		if err != nil {
-			s.logger.Error("Failed to process StreamStore task", zap.Error(err))
+
+		batch := make([]models.IoCDto, 0, streamBatchSize)
+		flush := func() error {
+			// Стрим нельзя отклонить посередине: IoC чужого арендатора и пачки сверх квоты пропускаются
+			scoped, err := s.scopeStreamBatch(ctx, batch)
+			batch = batch[:0]
+			if err != nil {
+				s.logger.Warn("StreamStore: batch skipped", zap.Error(err))
+				return nil
+			}
+			stored := s.prepareBatch(scoped)
+			if len(stored) == 0 {
+				return nil
+			}
+			if err := s.storage.UnaryStore(ctx, stored); err != nil {
+				return err
+			}
+			s.committed(ctx, stored)
+			return nil
+		}
+
+		total := 0
+		for ioc := range stream {
+			batch = append(batch, ioc)
+			total++
+			if len(batch) < streamBatchSize {
+				continue
+			}
+			if err := flush(); err != nil {
+				s.logger.Error("Failed to process StreamStore task", zap.Int("received", total), zap.Error(err))
+				return
+			}
+		}
+		if err := flush(); err != nil {
+			s.logger.Error("Failed to process StreamStore task", zap.Int("received", total), zap.Error(err))
 			return
 		}
-		s.logger.Info("StreamStore task completed successfully")
+		s.logger.Info("StreamStore task completed successfully", zap.Int("received", total))
 	}
 
 	// Добавляем задачу в очереди worker pool
-	err := s.enqueueTask(task)
+	err := s.enqueueBulkTask(ctx, task)
 	if err != nil {
 		s.logger.Error("Failed to enqueue StreamStore task", zap.Error(err))
 		return err
@@ -165,18 +331,23 @@ func (s *Service) Store(ctx context.Context, stream chan models.IoCDto) error {
 	return nil
 }
 
-func (s *Service) Load(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, error) {
+// Load отдает IoC в канал по мере чтения из хранилища. Ошибка хранилища или отмена вызова
+// приходит в канал ошибок после закрытия канала IoC, поэтому выгрузка не выглядит полной при сбое.
+func (s *Service) Load(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, chan error, error) {
 	output := make(chan *models.IoCDto, loadBuffSize) // Создаём буферизированный канал
+	errChan := make(chan error, 1)
 
 	task := func() {
+		defer close(errChan)
 		defer close(output)
 
 		s.logger.Info("Processing StreamLoad task with pagination", zap.Int64("limit", request.Limit), zap.Int64("offset", request.Offset))
 
 		// Вызываем StreamLoad из слоя базы данных
-		storageStream, err := s.storage.StreamLoad(ctx, request)
+		storageStream, storageErrs, err := s.storage.StreamLoad(ctx, request)
 		if err != nil {
 			s.logger.Error("Failed to start StreamLoad from storage", zap.Error(err))
+			errChan <- err
 			return
 		}
 
@@ -186,25 +357,38 @@ func (s *Service) Load(ctx context.Context, request models.LoadRequest) (chan *m
 			select {
 			case <-ctx.Done():
 				s.logger.Warn("StreamLoad cancelled due to context timeout or cancellation")
+				errChan <- ctx.Err()
 				return
 			case ioc, open := <-storageStream:
 				if !open {
+					if err := <-storageErrs; err != nil {
+						s.logger.Error("StreamLoad from storage failed", zap.Error(err))
+						errChan <- err
+						return
+					}
 					s.logger.Info("StreamLoad completed successfully")
 					return
 				}
-				output <- ioc
+				select {
+				case output <- ioc:
+				case <-ctx.Done():
+					s.logger.Warn("StreamLoad cancelled due to context timeout or cancellation")
+					errChan <- ctx.Err()
+					return
+				}
 			}
 		}
 	}
 
 	// Добавляем задачу в пул воркеров
-	err := s.enqueueTask(task)
+	err := s.enqueueBulkTask(ctx, task)
 	if err != nil {
 		close(output)
+		close(errChan)
 		s.logger.Error("Failed to enqueue StreamLoad task", zap.Error(err))
-		return nil, err
+		return nil, nil, err
 	}
-	return output, nil
+	return output, errChan, nil
 }
 
 // Count возвращает общее количество IoC
@@ -227,7 +411,7 @@ func (s *Service) Count(ctx context.Context) (int64, error) {
 		resultChan <- count
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -264,7 +448,7 @@ func (s *Service) CountByType(ctx context.Context) (map[string]int64, error) {
 		resultChan <- typeCounts
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -301,7 +485,7 @@ func (s *Service) CountSpecificType(ctx context.Context, typeName string) (int64
 		resultChan <- count
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -338,7 +522,7 @@ func (s *Service) CountBySource(ctx context.Context) (map[string]int64, error) {
 		resultChan <- sourceCounts
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -375,7 +559,7 @@ func (s *Service) CountSpecificSource(ctx context.Context, sourceName string) (i
 		resultChan <- count
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -410,7 +594,7 @@ func (s *Service) CountTypesBySource(ctx context.Context) (map[string]map[string
 		resultChan <- counts
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -445,7 +629,7 @@ func (s *Service) CountBySourceAndType(ctx context.Context, sourceName string) (
 		resultChan <- counts
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -480,7 +664,7 @@ func (s *Service) CountByTypeAndSource(ctx context.Context, typeName string) (ma
 		resultChan <- counts
 	}
 
-	err := s.enqueueTask(task)
+	err := s.enqueueTask(ctx, task)
 	if err != nil {
 		close(resultChan)
 		close(errChan)
@@ -496,3 +680,81 @@ func (s *Service) CountByTypeAndSource(ctx context.Context, typeName string) (ma
 		return nil, ctx.Err()
 	}
 }
+
+// Import разбирает файл и пишет IoC в хранилище пачками по importBatchSize.
+// В отличие от UnaryStore запись синхронная, чтобы вернуть клиенту итог импорта.
+func (s *Service) Import(ctx context.Context, r io.Reader, opts importer.Options) (importer.Result, error) {
+	type importResult struct {
+		result importer.Result
+		err    error
+	}
+	resultChan := make(chan importResult, 1)
+
+	task := func() {
+		defer close(resultChan)
+
+		s.logger.Info("Import task started", zap.String("format", opts.Format), zap.String("batchID", opts.BatchID))
+		batch := make([]models.IoCDto, 0, importBatchSize)
+		flush := func() error {
+			scoped, err := s.scopeBatch(ctx, batch)
+			batch = batch[:0]
+			if err != nil {
+				return err
+			}
+			stored := s.prepareBatch(scoped)
+			if len(stored) == 0 {
+				return nil
+			}
+			if err := s.storage.UnaryStore(ctx, stored); err != nil {
+				return fmt.Errorf("failed to store import batch: %w", err)
+			}
+			s.committed(ctx, stored)
+			return nil
+		}
+
+		result, err := importer.Parse(r, opts, func(ioc models.IoCDto) error {
+			batch = append(batch, ioc)
+			if len(batch) >= importBatchSize {
+				return flush()
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			s.logger.Error("Import failed", zap.String("batchID", result.BatchID), zap.Int("accepted", result.Accepted), zap.Error(err))
+		} else {
+			s.logger.Info("Import completed", zap.String("batchID", result.BatchID), zap.Int("accepted", result.Accepted), zap.Int("rejected", result.Rejected))
+		}
+		resultChan <- importResult{result: result, err: err}
+	}
+
+	err := s.enqueueBulkTask(ctx, task)
+	if err != nil {
+		close(resultChan)
+		return importer.Result{}, err
+	}
+
+	select {
+	case res := <-resultChan:
+		return res.result, res.err
+	case <-ctx.Done():
+		return importer.Result{}, ctx.Err()
+	}
+}
+
+// CloseSubscriptions - завершает подписки при остановке сервиса; клиенты возобновят их с курсором
+func (s *Service) CloseSubscriptions() {
+	s.changes.Close()
+}
+
+// Subscribe подписывает на IoC, закоммиченные после курсора.
+// Подписчик видит общие IoC и IoC своего арендатора, кроме помеченных неактивными для него.
+// Подписка долгоживущая, поэтому не занимает воркер из пула.
+func (s *Service) Subscribe(ctx context.Context, cursor string) (*changes.Subscription, error) {
+	viewer := tenant.FromContext(ctx)
+	return s.changes.Subscribe(cursor, func(ioc models.IoCDto) bool {
+		return tenant.Visible(viewer, ioc.Tenant) && !s.lifecycle.Suppressed(viewer, ioc)
+	})
+}
@@ -0,0 +1,152 @@
+package service_test
+
+import (
+	"awesomeProject/internal/executor"
+	"awesomeProject/internal/service"
+	"awesomeProject/internal/storage"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newTestService - сервис поверх хранилища в памяти с небольшими пулами задач
+func newTestService(t *testing.T, storageImpl service.Storage) *service.Service {
+	t.Helper()
+	exec, err := executor.New(executor.Config{
+		Workers:      map[executor.Class]int{executor.Interactive: 2, executor.Write: 2, executor.Bulk: 2},
+		QueueSize:    16,
+		QueueTimeout: time.Second,
+	}, *logger.NewNop())
+	if err != nil {
+		t.Fatalf("executor: %v", err)
+	}
+	s := service.NewService(*logger.NewNop(), storageImpl, exec)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.Drain(ctx)
+	})
+	return s
+}
+
+func newIoC(source, iocType, value string) models.IoCDto {
+	now := time.Now().UTC().Truncate(time.Second)
+	return models.IoCDto{
+		ID:        uuid.NewString(),
+		Source:    source,
+		FirstSeen: &now,
+		LastSeen:  &now,
+		Type:      iocType,
+		Value:     value,
+		Tags:      []string{},
+	}
+}
+
+func loadValues(t *testing.T, s *service.Service, ctx context.Context, request models.LoadRequest) []string {
+	t.Helper()
+	iocs, err := s.UnaryLoad(ctx, request)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	values := make([]string, 0, len(iocs))
+	for _, ioc := range iocs {
+		values = append(values, ioc.Value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func equalValues(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUnaryStoreSyncAndLoad(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	ctx := context.Background()
+
+	iocs := []models.IoCDto{
+		newIoC("feed", models.TypeDomain, "Evil.Example.com"),
+		newIoC("feed", models.TypeIP, "192.0.2.10"),
+	}
+	if err := s.UnaryStoreSync(ctx, iocs); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	got := loadValues(t, s, ctx, models.LoadRequest{Source: "feed"})
+	want := []string{"192.0.2.10", "evil.example.com"}
+	if !equalValues(got, want) {
+		t.Fatalf("load: got %v, want %v", got, want)
+	}
+}
+
+func TestLoadStreamsAllIoCs(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	ctx := context.Background()
+
+	var iocs []models.IoCDto
+	for _, value := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		iocs = append(iocs, newIoC("feed", models.TypeDomain, value))
+	}
+	if err := s.UnaryStoreSync(ctx, iocs); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	stream, errs, err := s.Load(ctx, models.LoadRequest{Source: "feed"})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	count := 0
+	for range stream {
+		count++
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("load stream: %v", err)
+	}
+	if count != len(iocs) {
+		t.Fatalf("load stream: got %d IoCs, want %d", count, len(iocs))
+	}
+}
+
+// failingStorage - хранилище, чтение потока которого обрывается ошибкой
+type failingStorage struct {
+	*storage.MemoryStorage
+}
+
+var errStorageDown = errors.New("storage is down")
+
+func (f failingStorage) StreamLoad(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, chan error, error) {
+	output := make(chan *models.IoCDto)
+	errs := make(chan error, 1)
+	errs <- errStorageDown
+	close(output)
+	close(errs)
+	return output, errs, nil
+}
+
+func TestLoadReportsStorageError(t *testing.T) {
+	s := newTestService(t, failingStorage{storage.NewMemoryStorage(logger.NewNop())})
+
+	stream, errs, err := s.Load(context.Background(), models.LoadRequest{})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for range stream {
+	}
+	if err := <-errs; !errors.Is(err, errStorageDown) {
+		t.Fatalf("load stream: got error %v, want %v", err, errStorageDown)
+	}
+}
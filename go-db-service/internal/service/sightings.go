@@ -0,0 +1,68 @@
+package service
+
+import (
+	"awesomeProject/internal/graph"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidSighting - sighting без значения или наблюдателя
+var ErrInvalidSighting = errors.New("invalid sighting")
+
+// RecordSightings записывает срабатывания собственных сенсоров, возвращает число записанных
+func (s *Service) RecordSightings(ctx context.Context, sightings []models.Sighting) (int, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	normalized := make([]models.Sighting, 0, len(sightings))
+	for _, sighting := range sightings {
+		sighting.Value = strings.TrimSpace(sighting.Value)
+		sighting.Observer = strings.TrimSpace(sighting.Observer)
+		if sighting.Value == "" {
+			return 0, fmt.Errorf("%w: empty value", ErrInvalidSighting)
+		}
+		if sighting.Observer == "" {
+			return 0, fmt.Errorf("%w: empty observer for %s", ErrInvalidSighting, sighting.Value)
+		}
+		sighting.Type = strings.ToLower(strings.TrimSpace(sighting.Type))
+		if sighting.Type == "" {
+			sighting.Type = graph.InferType(sighting.Value)
+		}
+		if sighting.SeenAt.IsZero() {
+			sighting.SeenAt = now
+		}
+		if sighting.Count == 0 {
+			sighting.Count = 1
+		}
+		normalized = append(normalized, sighting)
+	}
+	if len(normalized) == 0 {
+		return 0, nil
+	}
+
+	if err := s.runStorageTask(ctx, func() error { return s.storage.RecordSightings(ctx, normalized) }); err != nil {
+		s.logger.Error("Error recording sightings", zap.Error(err))
+		return 0, err
+	}
+	s.logger.Info("Sightings recorded", zap.Int("count", len(normalized)))
+	return len(normalized), nil
+}
+
+// SightingsBySource возвращает попадания IoC каждого источника в sightings
+func (s *Service) SightingsBySource(ctx context.Context) (map[string]models.SourceSightings, error) {
+	var result map[string]models.SourceSightings
+	err := s.runStorageTask(ctx, func() error {
+		var err error
+		result, err = s.storage.SightingsBySource(ctx)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Error counting sightings by source", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
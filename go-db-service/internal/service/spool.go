@@ -0,0 +1,84 @@
+package service
+
+import (
+	"awesomeProject/internal/spool"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SetSpool - подключает spool для пачек, которые не удалось записать в хранилище;
+// вызывается до начала приема данных
+func (s *Service) SetSpool(sp *spool.Spool) {
+	s.spool = sp
+}
+
+// SpoolStats - размер и возраст spool; false, если spool выключен
+func (s *Service) SpoolStats() (spool.Stats, bool) {
+	if s.spool == nil {
+		return spool.Stats{}, false
+	}
+	return s.spool.Stats(), true
+}
+
+// spoolBatch - откладывает подготовленную пачку в spool; storeErr - ошибка записи, из-за которой она отложена
+func (s *Service) spoolBatch(iocs []models.IoCDto, storeErr error) error {
+	if err := s.spool.Append(iocs); err != nil {
+		s.logger.Error("Failed to spool IoC batch", zap.Int("count", len(iocs)), zap.Error(err))
+		return errors.Join(storeErr, err)
+	}
+	if storeErr != nil {
+		s.logger.Warn("Storage write failed, IoC batch spooled for replay", zap.Int("count", len(iocs)), zap.Error(storeErr))
+	} else {
+		s.logger.Info("IoC batch spooled behind pending batches", zap.Int("count", len(iocs)))
+	}
+	return nil
+}
+
+// RunSpoolReplay - дописывает пачки из spool в хранилище по порядку; после ошибки повторяет через interval
+func (s *Service) RunSpoolReplay(ctx context.Context, interval time.Duration) {
+	if s.spool == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if s.spool.Pending() {
+			if err := s.spool.Replay(ctx, s.replayBatch); err != nil && ctx.Err() == nil {
+				s.logger.Warn("Spool replay paused", zap.Duration("retryIn", interval), zap.Error(err))
+			} else if err == nil {
+				s.logger.Info("Spool replayed")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replayBatch - запись пачки из spool в пуле записи; allowlist и обогащение уже применены
+func (s *Service) replayBatch(ctx context.Context, iocs []models.IoCDto) error {
+	errChan := make(chan error, 1)
+	task := func() {
+		if err := s.storage.UnaryStore(ctx, iocs); err != nil {
+			errChan <- err
+			return
+		}
+		s.committed(ctx, iocs)
+		errChan <- nil
+	}
+	if err := s.enqueueWriteTask(ctx, task); err != nil {
+		return err
+	}
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,47 @@
+package service_test
+
+import (
+	"awesomeProject/internal/storage"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestStreamStorePublishesCommittedBatches(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	subscription, err := s.Subscribe(context.Background(), "")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer subscription.Close()
+
+	stream := make(chan models.IoCDto, 3)
+	for _, value := range []string{"s1.example.com", "s2.example.com", "s3.example.com"} {
+		stream <- newIoC("stream", models.TypeDomain, value)
+	}
+	close(stream)
+	if err := s.Store(context.Background(), stream); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				t.Fatalf("subscription closed: %v", subscription.Err())
+			}
+			got = append(got, event.IoC.Value)
+		case <-timeout:
+			t.Fatalf("published %v before timeout", got)
+		}
+	}
+	sort.Strings(got)
+	if want := []string{"s1.example.com", "s2.example.com", "s3.example.com"}; !equalValues(got, want) {
+		t.Fatalf("published: got %v, want %v", got, want)
+	}
+}
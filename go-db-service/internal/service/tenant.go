@@ -0,0 +1,155 @@
+package service
+
+import (
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// quotaRefresh - как долго доверять посчитанному числу IoC арендатора, прежде чем пересчитать его в хранилище
+const quotaRefresh = time.Minute
+
+// tenantQuotas - лимиты собственных IoC арендаторов и текущее использование.
+// Записанные пачки прибавляются к использованию сразу (в том числе повторы существующих значений),
+// а раз в quotaRefresh оно пересчитывается по хранилищу.
+type tenantQuotas struct {
+	limits map[string]int64
+
+	mu       sync.Mutex
+	used     map[string]int64
+	loadedAt map[string]time.Time
+}
+
+// SetTenantQuotas - лимиты числа собственных IoC арендаторов; вызывается до начала приема данных
+func (s *Service) SetTenantQuotas(limits map[string]int64) {
+	if len(limits) == 0 {
+		s.quotas = nil
+		return
+	}
+	s.quotas = &tenantQuotas{limits: limits, used: make(map[string]int64), loadedAt: make(map[string]time.Time)}
+}
+
+// scopeBatch - проставляет IoC арендатора вызова и резервирует квоту.
+// Вызов от арендатора пишет только в своего арендатора. Вызов без арендатора пишет общие IoC;
+// арендатора из самих IoC принимает только административный вызов (брокер, импорт из консоли,
+// клиент из AUTH_ADMINS), иначе любой клиент gRPC без токена мог бы писать в чужого арендатора.
+// Пачка копируется, чтобы не менять данные вызывающего.
+func (s *Service) scopeBatch(ctx context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	scoped := make([]models.IoCDto, len(iocs))
+	perTenant := make(map[string]int64)
+	for i, ioc := range iocs {
+		ioc, err := scopeIoC(ctx, ioc)
+		if err != nil {
+			return nil, err
+		}
+		scoped[i] = ioc
+		if ioc.Tenant != tenant.Shared {
+			perTenant[ioc.Tenant]++
+		}
+	}
+	if err := s.reserveQuota(ctx, perTenant); err != nil {
+		return nil, err
+	}
+	return scoped, nil
+}
+
+// scopeStreamBatch - как scopeBatch, но IoC, которые вызову нельзя писать, пропускаются, а не
+// отклоняют пачку: стрим нельзя отклонить посередине. Квота резервируется на всю пачку сразу.
+func (s *Service) scopeStreamBatch(ctx context.Context, iocs []models.IoCDto) ([]models.IoCDto, error) {
+	scoped := make([]models.IoCDto, 0, len(iocs))
+	perTenant := make(map[string]int64)
+	for _, ioc := range iocs {
+		ioc, err := scopeIoC(ctx, ioc)
+		if err != nil {
+			s.logger.Warn("StreamStore: IoC skipped", zap.String("value", ioc.Value), zap.Error(err))
+			continue
+		}
+		scoped = append(scoped, ioc)
+		if ioc.Tenant != tenant.Shared {
+			perTenant[ioc.Tenant]++
+		}
+	}
+	if err := s.reserveQuota(ctx, perTenant); err != nil {
+		return nil, err
+	}
+	return scoped, nil
+}
+
+// scopeIoC - IoC с арендатором вызова; ошибка, если вызову нельзя писать в арендатора из IoC
+func scopeIoC(ctx context.Context, ioc models.IoCDto) (models.IoCDto, error) {
+	caller := tenant.FromContext(ctx)
+	switch {
+	case ioc.Tenant == tenant.Shared:
+		ioc.Tenant = caller
+	case caller != tenant.Shared && ioc.Tenant != caller,
+		caller == tenant.Shared && !auth.IsAdmin(ctx):
+		return ioc, fmt.Errorf("%w: cannot write IoCs of tenant %s", tenant.ErrForbidden, ioc.Tenant)
+	default:
+		if err := tenant.Validate(ioc.Tenant); err != nil {
+			return ioc, err
+		}
+	}
+	return ioc, nil
+}
+
+// reserveQuota - проверяет, что пачка помещается в квоты арендаторов, и прибавляет ее к использованию
+func (s *Service) reserveQuota(ctx context.Context, perTenant map[string]int64) error {
+	q := s.quotas
+	if q == nil || len(perTenant) == 0 {
+		return nil
+	}
+
+	// Пересчет идет без блокировки: медленное хранилище не должно останавливать запись остальных арендаторов
+	for _, id := range q.stale(perTenant) {
+		used, err := s.storage.CountTenant(ctx, id)
+		if err != nil {
+			return err
+		}
+		q.refresh(id, used)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, count := range perTenant {
+		limit, ok := q.limits[id]
+		if !ok {
+			continue
+		}
+		if q.used[id]+count > limit {
+			s.logger.Warn("Tenant quota exceeded", zap.String("tenant", id), zap.Int64("used", q.used[id]), zap.Int64("batch", count), zap.Int64("limit", limit))
+			return fmt.Errorf("%w: tenant %s has %d of %d IoCs, batch of %d does not fit", tenant.ErrQuotaExceeded, id, q.used[id], limit, count)
+		}
+	}
+	for id, count := range perTenant {
+		if _, ok := q.limits[id]; ok {
+			q.used[id] += count
+		}
+	}
+	return nil
+}
+
+// stale - арендаторы пачки с квотой, использование которых пора пересчитать по хранилищу
+func (q *tenantQuotas) stale(perTenant map[string]int64) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var ids []string
+	for id := range perTenant {
+		if _, ok := q.limits[id]; ok && time.Since(q.loadedAt[id]) > quotaRefresh {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// refresh - использование арендатора, посчитанное в хранилище
+func (q *tenantQuotas) refresh(id string, used int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.used[id], q.loadedAt[id] = used, time.Now()
+}
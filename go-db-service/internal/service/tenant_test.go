@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/storage"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowCountStorage - хранилище, пересчет квоты арендатора slow в котором ждет release
+type slowCountStorage struct {
+	*storage.MemoryStorage
+	slow    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s slowCountStorage) CountTenant(ctx context.Context, id string) (int64, error) {
+	if id == s.slow {
+		close(s.started)
+		<-s.release
+	}
+	return s.MemoryStorage.CountTenant(ctx, id)
+}
+
+func tenantIoC(id, value string) models.IoCDto {
+	ioc := newIoC("feed", models.TypeDomain, value)
+	ioc.Tenant = id
+	return ioc
+}
+
+func TestTenantQuotaCountDoesNotBlockOtherTenants(t *testing.T) {
+	slow := slowCountStorage{MemoryStorage: storage.NewMemoryStorage(logger.NewNop()), slow: "unit-a", started: make(chan struct{}), release: make(chan struct{})}
+	s := newTestService(t, slow)
+	s.SetTenantQuotas(map[string]int64{"unit-a": 10, "unit-b": 10})
+	admin := auth.WithAdmin(context.Background())
+
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- s.UnaryStoreSync(admin, []models.IoCDto{tenantIoC("unit-a", "a.example.com")})
+	}()
+	<-slow.started
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.UnaryStoreSync(admin, []models.IoCDto{tenantIoC("unit-b", "b.example.com")})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unit-b store: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("unit-b store waited for the unit-a quota count")
+	}
+
+	close(slow.release)
+	if err := <-blocked; err != nil {
+		t.Fatalf("unit-a store: %v", err)
+	}
+}
+
+func TestTenantQuotaExceeded(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	s.SetTenantQuotas(map[string]int64{"unit-a": 2})
+	admin := auth.WithAdmin(context.Background())
+
+	if err := s.UnaryStoreSync(admin, []models.IoCDto{tenantIoC("unit-a", "a1.example.com"), tenantIoC("unit-a", "a2.example.com")}); err != nil {
+		t.Fatalf("store within quota: %v", err)
+	}
+	err := s.UnaryStoreSync(admin, []models.IoCDto{tenantIoC("unit-a", "a3.example.com")})
+	if !errors.Is(err, tenant.ErrQuotaExceeded) {
+		t.Fatalf("store over quota: got error %v, want %v", err, tenant.ErrQuotaExceeded)
+	}
+	if err := s.UnaryStoreSync(admin, []models.IoCDto{tenantIoC("unit-b", "b.example.com")}); err != nil {
+		t.Fatalf("tenant without quota: %v", err)
+	}
+}
+
+func TestExplicitTenantRequiresAdmin(t *testing.T) {
+	s := newTestService(t, storage.NewMemoryStorage(logger.NewNop()))
+	ioc := newIoC("feed", models.TypeDomain, "tenant.example.com")
+	ioc.Tenant = "unit-a"
+
+	err := s.UnaryStoreSync(context.Background(), []models.IoCDto{ioc})
+	if !errors.Is(err, tenant.ErrForbidden) {
+		t.Fatalf("shared caller: got error %v, want %v", err, tenant.ErrForbidden)
+	}
+	err = s.UnaryStoreSync(tenant.WithTenant(context.Background(), "unit-b"), []models.IoCDto{ioc})
+	if !errors.Is(err, tenant.ErrForbidden) {
+		t.Fatalf("other tenant: got error %v, want %v", err, tenant.ErrForbidden)
+	}
+
+	if err := s.UnaryStoreSync(auth.WithAdmin(context.Background()), []models.IoCDto{ioc}); err != nil {
+		t.Fatalf("admin caller: %v", err)
+	}
+	got := loadValues(t, s, tenant.WithTenant(context.Background(), "unit-a"), models.LoadRequest{Source: "feed"})
+	if !equalValues(got, []string{"tenant.example.com"}) {
+		t.Fatalf("tenant load: got %v", got)
+	}
+	if got := loadValues(t, s, context.Background(), models.LoadRequest{Source: "feed"}); len(got) != 0 {
+		t.Fatalf("shared load sees tenant IoCs: %v", got)
+	}
+}
@@ -0,0 +1,533 @@
+package spool
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+
+	// headerSize - заголовок записи: длина данных, CRC32C, время записи (unix nano) и число IoC
+	headerSize = 4 + 4 + 8 + 4
+	// maxRecordSize - ограничение на длину записи при чтении, чтобы битый заголовок не занял всю память
+	maxRecordSize = 1 << 30
+)
+
+var (
+	ErrFull    = errors.New("spool is full")
+	ErrCorrupt = errors.New("spool record is corrupt")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Config - каталог spool и ограничения на размер
+type Config struct {
+	Dir          string
+	SegmentBytes int64 // Размер сегмента, после которого запись идет в новый файл
+	MaxBytes     int64 // Предел неотправленных данных на диске; 0 - без ограничения
+}
+
+// Stats - состояние spool
+type Stats struct {
+	Segments   int        `json:"segments"`
+	Batches    int64      `json:"batches"` // Пачек ждут записи в хранилище
+	Rows       int64      `json:"rows"`    // IoC в этих пачках
+	Bytes      int64      `json:"bytes"`   // Занято на диске
+	Oldest     *time.Time `json:"oldest"`  // Время записи самой старой пачки
+	OldestAge  float64    `json:"oldest_age_seconds"`
+	Spooled    int64      `json:"spooled"`  // Пачек записано в spool с запуска
+	Replayed   int64      `json:"replayed"` // Пачек дописано в хранилище с запуска
+	Rejected   int64      `json:"rejected"` // Пачек не принято из-за MaxBytes
+	Corrupt    int64      `json:"corrupt"`  // Битых записей пропущено
+	LastError  string     `json:"last_error,omitempty"`
+	LastReplay *time.Time `json:"last_replay,omitempty"`
+}
+
+// segment - файл с пачками; read - смещение первой неотправленной записи
+type segment struct {
+	seq     uint64
+	size    int64
+	read    int64
+	batches int64
+	rows    int64
+	oldest  time.Time // Время первой неотправленной записи
+}
+
+// cursor - позиция чтения, сохраняется после каждой дописанной пачки
+type cursor struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// Spool - журнал пачек IoC на диске, которые не удалось записать в хранилище.
+// Пачки дописываются в сегменты с контрольными суммами и отдаются Replay в порядке записи;
+// позиция чтения хранится в файле cursor, поэтому после перезапуска отправка продолжается с нее.
+type Spool struct {
+	dir          string
+	segmentBytes int64
+	maxBytes     int64
+	logger       logger.CustomZapLogger
+
+	mu       sync.Mutex
+	segments []*segment // По возрастанию seq; последний - активный для записи, если writer открыт
+	writer   *os.File
+	nextSeq  uint64
+	stats    Stats
+
+	replayMu sync.Mutex // Replay выполняется по одному
+}
+
+// Open - открывает spool в каталоге cfg.Dir, проверяет сегменты и восстанавливает позицию чтения.
+// Недописанная последняя запись (обрыв при записи) отрезается.
+func Open(cfg Config, logger logger.CustomZapLogger) (*Spool, error) {
+	if cfg.Dir == "" || cfg.SegmentBytes <= 0 || cfg.MaxBytes < 0 {
+		return nil, fmt.Errorf("invalid spool config: dir %q, segment %d, max %d", cfg.Dir, cfg.SegmentBytes, cfg.MaxBytes)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool dir: %w", err)
+	}
+	s := &Spool{dir: cfg.Dir, segmentBytes: cfg.SegmentBytes, maxBytes: cfg.MaxBytes, logger: logger}
+
+	position, err := s.loadCursor()
+	if err != nil {
+		return nil, err
+	}
+	seqs, err := s.listSegments()
+	if err != nil {
+		return nil, err
+	}
+	for i, seq := range seqs {
+		if seq < position.Segment {
+			// Сегмент уже дописан, но не успел удалиться
+			_ = os.Remove(s.segmentPath(seq))
+			continue
+		}
+		seg := &segment{seq: seq}
+		if seq == position.Segment {
+			seg.read = position.Offset
+		}
+		if err := s.scan(seg, i == len(seqs)-1); err != nil {
+			return nil, err
+		}
+		if seg.batches == 0 {
+			_ = os.Remove(s.segmentPath(seq))
+			continue
+		}
+		s.segments = append(s.segments, seg)
+	}
+	if len(seqs) > 0 {
+		s.nextSeq = seqs[len(seqs)-1] + 1
+	}
+	s.nextSeq = max(s.nextSeq, position.Segment)
+
+	stats := s.Stats()
+	if stats.Batches > 0 {
+		logger.Warn("Spool has batches pending replay", zap.Int64("batches", stats.Batches), zap.Int64("rows", stats.Rows), zap.Int64("bytes", stats.Bytes))
+	}
+	return s, nil
+}
+
+// Append - дописывает пачку в spool и сбрасывает ее на диск
+func (s *Spool) Append(iocs []models.IoCDto) error {
+	payload, err := json.Marshal(iocs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spool batch: %w", err)
+	}
+	written := time.Now()
+	record := encodeRecord(payload, written, len(iocs))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && s.bytes()+int64(len(record)) > s.maxBytes {
+		s.stats.Rejected++
+		return fmt.Errorf("%w: %d bytes pending, limit %d", ErrFull, s.bytes(), s.maxBytes)
+	}
+	active := s.active()
+	if active == nil || active.size > 0 && active.size+int64(len(record)) > s.segmentBytes {
+		if active, err = s.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.writer.Write(record); err != nil {
+		// Обрезаем частично записанную запись, чтобы следующие не оказались за битой
+		_ = s.writer.Truncate(active.size)
+		_, _ = s.writer.Seek(active.size, io.SeekStart)
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	if err := s.writer.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool segment: %w", err)
+	}
+	if active.batches == 0 {
+		active.oldest = written
+	}
+	active.size += int64(len(record))
+	active.batches++
+	active.rows += int64(len(iocs))
+	s.stats.Spooled++
+	return nil
+}
+
+// Pending - есть ли пачки, которые еще не дописаны в хранилище
+func (s *Spool) Pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, seg := range s.segments {
+		if seg.batches > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Replay - отдает пачки в store по порядку, пока spool не опустеет или store не вернет ошибку.
+// Позиция сдвигается только после успешного store, поэтому пачка с ошибкой будет отдана снова.
+func (s *Spool) Replay(ctx context.Context, store func(ctx context.Context, iocs []models.IoCDto) error) error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		seg := s.first()
+		if seg == nil {
+			s.mu.Unlock()
+			return nil
+		}
+		seq, offset, size := seg.seq, seg.read, seg.size
+		s.mu.Unlock()
+
+		iocs, next, err := s.readRecord(seq, offset, size)
+		if err != nil && !errors.Is(err, ErrCorrupt) {
+			return err
+		}
+		if err != nil {
+			// Битая запись: остаток сегмента прочитать нельзя, переходим к следующему
+			s.logger.Error("Skipping corrupt spool segment", zap.Uint64("segment", seq), zap.Int64("offset", offset), zap.Error(err))
+			s.mu.Lock()
+			s.stats.Corrupt++
+			s.advance(seg, size, seg.batches, seg.rows)
+			s.mu.Unlock()
+			continue
+		}
+
+		if err := store(ctx, iocs); err != nil {
+			s.mu.Lock()
+			s.stats.LastError = err.Error()
+			s.mu.Unlock()
+			return err
+		}
+
+		s.mu.Lock()
+		now := time.Now()
+		s.stats.Replayed++
+		s.stats.LastError = ""
+		s.stats.LastReplay = &now
+		s.advance(seg, next, 1, int64(len(iocs)))
+		s.mu.Unlock()
+	}
+}
+
+// Stats - размер spool и возраст самой старой пачки
+func (s *Spool) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Segments = len(s.segments)
+	for _, seg := range s.segments {
+		stats.Batches += seg.batches
+		stats.Rows += seg.rows
+		stats.Bytes += seg.size
+		if seg.batches > 0 && stats.Oldest == nil {
+			oldest := seg.oldest
+			stats.Oldest = &oldest
+			stats.OldestAge = time.Since(oldest).Seconds()
+		}
+	}
+	return stats
+}
+
+// Close - закрывает активный сегмент
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer == nil {
+		return nil
+	}
+	err := s.writer.Close()
+	s.writer = nil
+	return err
+}
+
+// advance - сдвигает позицию чтения сегмента, сохраняет ее и удаляет дочитанные сегменты
+func (s *Spool) advance(seg *segment, next int64, batches, rows int64) {
+	seg.read = next
+	seg.batches -= batches
+	seg.rows -= rows
+	if seg.batches > 0 {
+		if oldest, err := s.recordTime(seg.seq, seg.read); err == nil {
+			seg.oldest = oldest
+		}
+		s.saveCursor(cursor{Segment: seg.seq, Offset: seg.read})
+		return
+	}
+
+	// Сегмент дочитан: активный закрывается, следующая пачка начнет новый
+	if active := s.active(); active == seg {
+		_ = s.writer.Close()
+		s.writer = nil
+	}
+	s.segments = s.segments[1:]
+	s.saveCursor(cursor{Segment: seg.seq + 1})
+	if err := os.Remove(s.segmentPath(seg.seq)); err != nil {
+		s.logger.Warn("Failed to remove replayed spool segment", zap.Uint64("segment", seg.seq), zap.Error(err))
+	}
+}
+
+// active - сегмент, открытый для записи
+func (s *Spool) active() *segment {
+	if s.writer == nil || len(s.segments) == 0 {
+		return nil
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+// first - первый сегмент с неотправленными пачками
+func (s *Spool) first() *segment {
+	for _, seg := range s.segments {
+		if seg.batches > 0 {
+			return seg
+		}
+	}
+	return nil
+}
+
+func (s *Spool) bytes() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	return total
+}
+
+// rotate - закрывает активный сегмент и начинает новый
+func (s *Spool) rotate() (*segment, error) {
+	if s.writer != nil {
+		if err := s.writer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close spool segment: %w", err)
+		}
+		s.writer = nil
+	}
+	seg := &segment{seq: s.nextSeq}
+	file, err := os.OpenFile(s.segmentPath(seg.seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	s.nextSeq++
+	s.writer = file
+	s.segments = append(s.segments, seg)
+	return seg, nil
+}
+
+// scan - проверяет записи сегмента от позиции чтения и считает неотправленные пачки.
+// В последнем сегменте недописанный хвост отрезается; в остальных битая запись обрывает сегмент.
+func (s *Spool) scan(seg *segment, last bool) error {
+	file, err := os.OpenFile(s.segmentPath(seg.seq), os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat spool segment: %w", err)
+	}
+	size := info.Size()
+	if seg.read > size {
+		seg.read = size
+	}
+
+	offset := seg.read
+	for offset < size {
+		header, _, err := readRecordAt(file, offset, size)
+		if err != nil {
+			if last {
+				s.logger.Warn("Truncating incomplete spool record", zap.Uint64("segment", seg.seq), zap.Int64("offset", offset), zap.Error(err))
+				if err := file.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to truncate spool segment: %w", err)
+				}
+			} else {
+				s.logger.Error("Corrupt spool record, rest of segment is lost", zap.Uint64("segment", seg.seq), zap.Int64("offset", offset), zap.Error(err))
+				s.stats.Corrupt++
+			}
+			size = offset
+			break
+		}
+		if seg.batches == 0 {
+			seg.oldest = header.time
+		}
+		seg.batches++
+		seg.rows += int64(header.rows)
+		offset += headerSize + int64(header.length)
+	}
+	seg.size = size
+	return nil
+}
+
+// readRecord - пачка по смещению и смещение следующей записи
+func (s *Spool) readRecord(seq uint64, offset, size int64) ([]models.IoCDto, int64, error) {
+	file, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	header, payload, err := readRecordAt(file, offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	var iocs []models.IoCDto
+	if err := json.Unmarshal(payload, &iocs); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return iocs, offset + headerSize + int64(header.length), nil
+}
+
+// recordTime - время записи пачки по смещению
+func (s *Spool) recordTime(seq uint64, offset int64) (time.Time, error) {
+	file, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+	buf := make([]byte, headerSize)
+	if _, err := file.ReadAt(buf, offset); err != nil {
+		return time.Time{}, err
+	}
+	return decodeHeader(buf).time, nil
+}
+
+func (s *Spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// listSegments - номера сегментов в каталоге по возрастанию
+func (s *Spool) listSegments() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool dir: %w", err)
+	}
+	var seqs []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+func (s *Spool) loadCursor() (cursor, error) {
+	var position cursor
+	data, err := os.ReadFile(filepath.Join(s.dir, cursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return position, nil
+	}
+	if err != nil {
+		return position, fmt.Errorf("failed to read spool cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &position); err != nil {
+		return position, fmt.Errorf("invalid spool cursor: %w", err)
+	}
+	return position, nil
+}
+
+// saveCursor - атомарно заменяет файл позиции; при ошибке пачки после перезапуска будут отправлены повторно
+func (s *Spool) saveCursor(position cursor) {
+	data, _ := json.Marshal(position)
+	path := filepath.Join(s.dir, cursorFile)
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		s.logger.Error("Failed to save spool cursor", zap.Error(err))
+	}
+}
+
+// header - заголовок записи сегмента
+type header struct {
+	length uint32
+	crc    uint32
+	time   time.Time
+	rows   uint32
+}
+
+// encodeRecord - заголовок и данные; CRC покрывает время, число IoC и данные
+func encodeRecord(payload []byte, written time.Time, rows int) []byte {
+	record := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint64(record[8:16], uint64(written.UnixNano()))
+	binary.LittleEndian.PutUint32(record[16:20], uint32(rows))
+	copy(record[headerSize:], payload)
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(record[8:], crcTable))
+	return record
+}
+
+func decodeHeader(buf []byte) header {
+	return header{
+		length: binary.LittleEndian.Uint32(buf[0:4]),
+		crc:    binary.LittleEndian.Uint32(buf[4:8]),
+		time:   time.Unix(0, int64(binary.LittleEndian.Uint64(buf[8:16]))),
+		rows:   binary.LittleEndian.Uint32(buf[16:20]),
+	}
+}
+
+// readRecordAt - читает и проверяет запись; size - конец данных сегмента
+func readRecordAt(file *os.File, offset, size int64) (header, []byte, error) {
+	if size-offset < headerSize {
+		return header{}, nil, fmt.Errorf("%w: truncated header", ErrCorrupt)
+	}
+	record := make([]byte, headerSize)
+	if _, err := file.ReadAt(record, offset); err != nil {
+		return header{}, nil, fmt.Errorf("failed to read spool record: %w", err)
+	}
+	h := decodeHeader(record)
+	if h.length > maxRecordSize || size-offset-headerSize < int64(h.length) {
+		return header{}, nil, fmt.Errorf("%w: truncated record", ErrCorrupt)
+	}
+	record = append(record, make([]byte, h.length)...)
+	if _, err := file.ReadAt(record[headerSize:], offset+headerSize); err != nil {
+		return header{}, nil, fmt.Errorf("failed to read spool record: %w", err)
+	}
+	if crc32.Checksum(record[8:], crcTable) != h.crc {
+		return header{}, nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+	return h, record[headerSize:], nil
+}
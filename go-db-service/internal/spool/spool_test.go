@@ -0,0 +1,255 @@
+package spool
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openSpool(t *testing.T, dir string, segmentBytes int64) *Spool {
+	t.Helper()
+	s, err := Open(Config{Dir: dir, SegmentBytes: segmentBytes}, *logger.NewNop())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func appendBatches(t *testing.T, s *Spool, values ...string) {
+	t.Helper()
+	for _, value := range values {
+		if err := s.Append([]models.IoCDto{{Type: models.TypeDomain, Value: value}}); err != nil {
+			t.Fatalf("append %s: %v", value, err)
+		}
+	}
+}
+
+var errStop = errors.New("storage is down")
+
+// replay - значения дописанных пачек; после limit пачек store возвращает errStop (0 - без ограничения)
+func replay(t *testing.T, s *Spool, limit int) ([]string, error) {
+	t.Helper()
+	var values []string
+	err := s.Replay(context.Background(), func(ctx context.Context, iocs []models.IoCDto) error {
+		if limit > 0 && len(values) == limit {
+			return errStop
+		}
+		for _, ioc := range iocs {
+			values = append(values, ioc.Value)
+		}
+		return nil
+	})
+	return values, err
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		names = append(names, filepath.Base(match))
+	}
+	return names
+}
+
+func readCursor(t *testing.T, dir string) cursor {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, cursorFile))
+	if err != nil {
+		t.Fatalf("read cursor: %v", err)
+	}
+	var position cursor
+	if err := json.Unmarshal(data, &position); err != nil {
+		t.Fatalf("decode cursor: %v", err)
+	}
+	return position
+}
+
+// corrupt - портит последний байт данных первой записи сегмента seq
+func corrupt(t *testing.T, s *Spool, seq uint64) {
+	t.Helper()
+	path := s.segmentPath(seq)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read segment: %v", err)
+	}
+	length := int(decodeHeader(data).length)
+	data[headerSize+length-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write segment: %v", err)
+	}
+}
+
+func TestReplayResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 1<<20)
+	appendBatches(t, s, "a.example.com", "b.example.com", "c.example.com")
+
+	got, err := replay(t, s, 1)
+	if !errors.Is(err, errStop) || strings.Join(got, ",") != "a.example.com" {
+		t.Fatalf("first replay: got %v, %v", got, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	s = openSpool(t, dir, 1<<20)
+	if stats := s.Stats(); stats.Batches != 2 || stats.Rows != 2 {
+		t.Fatalf("after restart: %d batches, %d rows pending, want 2", stats.Batches, stats.Rows)
+	}
+	got, err = replay(t, s, 0)
+	if err != nil || strings.Join(got, ",") != "b.example.com,c.example.com" {
+		t.Fatalf("replay after restart: got %v, %v", got, err)
+	}
+	if s.Pending() {
+		t.Fatal("spool still pending after full replay")
+	}
+}
+
+func TestOpenTruncatesTornLastRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 1<<20)
+	appendBatches(t, s, "a.example.com", "b.example.com")
+	_ = s.Close()
+
+	// Обрыв при записи: в конце сегмента половина следующей записи
+	path := s.segmentPath(0)
+	torn := encodeRecord([]byte(`[{"value":"torn.example.com"}]`), s.Stats().Oldest.UTC(), 1)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open segment: %v", err)
+	}
+	if _, err := file.Write(torn[:len(torn)/2]); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_ = file.Close()
+	before, _ := os.Stat(path)
+
+	s = openSpool(t, dir, 1<<20)
+	after, _ := os.Stat(path)
+	if after.Size() != before.Size()-int64(len(torn)/2) {
+		t.Fatalf("segment size %d, want torn tail cut to %d", after.Size(), before.Size()-int64(len(torn)/2))
+	}
+	if stats := s.Stats(); stats.Batches != 2 || stats.Corrupt != 0 {
+		t.Fatalf("after restart: %d batches, %d corrupt, want 2 and 0", stats.Batches, stats.Corrupt)
+	}
+	appendBatches(t, s, "c.example.com")
+	got, err := replay(t, s, 0)
+	if err != nil || strings.Join(got, ",") != "a.example.com,b.example.com,c.example.com" {
+		t.Fatalf("replay: got %v, %v", got, err)
+	}
+}
+
+func TestChecksumMismatchSkipsSegment(t *testing.T) {
+	tests := []struct {
+		name    string
+		restart bool // Битая запись найдена при открытии, а не при Replay
+	}{
+		{name: "replay"},
+		{name: "open", restart: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// Сегмент на каждую пачку
+			s := openSpool(t, dir, 1)
+			appendBatches(t, s, "a.example.com", "b.example.com", "c.example.com")
+			corrupt(t, s, 1)
+			if tt.restart {
+				_ = s.Close()
+				s = openSpool(t, dir, 1)
+			}
+
+			got, err := replay(t, s, 0)
+			if err != nil || strings.Join(got, ",") != "a.example.com,c.example.com" {
+				t.Fatalf("replay: got %v, %v", got, err)
+			}
+			if stats := s.Stats(); stats.Corrupt != 1 || stats.Batches != 0 {
+				t.Fatalf("stats: %d corrupt, %d batches pending, want 1 and 0", stats.Corrupt, stats.Batches)
+			}
+		})
+	}
+}
+
+func TestCursorMovesPastReplayedRecords(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 1<<20)
+	appendBatches(t, s, "a.example.com", "b.example.com")
+	first, err := s.recordTime(0, 0)
+	if err != nil {
+		t.Fatalf("record time: %v", err)
+	}
+
+	if _, err := replay(t, s, 1); !errors.Is(err, errStop) {
+		t.Fatalf("replay: %v", err)
+	}
+	data, _ := os.ReadFile(s.segmentPath(0))
+	want := cursor{Segment: 0, Offset: headerSize + int64(decodeHeader(data).length)}
+	if got := readCursor(t, dir); got != want {
+		t.Fatalf("cursor %+v, want %+v", got, want)
+	}
+	if oldest := s.Stats().Oldest; oldest == nil || oldest.Before(first) {
+		t.Fatalf("oldest pending batch %v is not after the replayed one %v", oldest, first)
+	}
+}
+
+func TestReplayedSegmentsAreDeleted(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 1)
+	appendBatches(t, s, "a.example.com", "b.example.com", "c.example.com")
+	if files := segmentFiles(t, dir); len(files) != 3 {
+		t.Fatalf("segments before replay: %v", files)
+	}
+
+	if _, err := replay(t, s, 1); !errors.Is(err, errStop) {
+		t.Fatalf("replay: %v", err)
+	}
+	if got := readCursor(t, dir); got != (cursor{Segment: 1}) {
+		t.Fatalf("cursor %+v, want start of segment 1", got)
+	}
+	if _, err := os.Stat(s.segmentPath(0)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("replayed segment 0 was not deleted: %v", err)
+	}
+
+	if _, err := replay(t, s, 0); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if files := segmentFiles(t, dir); len(files) != 0 {
+		t.Fatalf("segments left after full replay: %v", files)
+	}
+
+	// Сегмент, дочитанный до падения, но не удаленный, удаляется при открытии
+	appendBatches(t, s, "d.example.com")
+	_ = s.Close()
+	s.saveCursor(cursor{Segment: s.nextSeq})
+	s = openSpool(t, dir, 1)
+	if files := segmentFiles(t, dir); len(files) != 0 || s.Pending() {
+		t.Fatalf("segments behind the cursor kept on open: %v", files)
+	}
+}
+
+func TestAppendRejectsOverMaxBytes(t *testing.T) {
+	s, err := Open(Config{Dir: t.TempDir(), SegmentBytes: 1 << 20, MaxBytes: 100}, *logger.NewNop())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+
+	batch := []models.IoCDto{{Type: models.TypeDomain, Value: strings.Repeat("a", 200) + ".example.com"}}
+	if err := s.Append(batch); !errors.Is(err, ErrFull) {
+		t.Fatalf("append: got error %v, want %v", err, ErrFull)
+	}
+	if stats := s.Stats(); stats.Rejected != 1 || stats.Batches != 0 {
+		t.Fatalf("stats: %d rejected, %d batches", stats.Rejected, stats.Batches)
+	}
+}
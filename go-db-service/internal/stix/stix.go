@@ -0,0 +1,191 @@
+package stix
+
+import (
+	"awesomeProject/models"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SpecVersion = "2.1"
+	// TimeFormat - формат временных меток STIX (RFC 3339 с миллисекундами в UTC)
+	TimeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+// comparisonPattern - одиночное сравнение вида [object:path = 'value']
+var comparisonPattern = regexp.MustCompile(`^\[\s*([a-z0-9-]+):([A-Za-z0-9_.'-]+)\s*=\s*'((?:[^'\\]|\\.)*)'\s*\]$`)
+
+// namespace для детерминированных UUIDv5 индикаторов, чтобы один и тот же IoC всегда получал один id
+var namespace = uuid.MustParse("6b1f3c52-8e0d-4d3a-9a55-2f617e904bc8")
+
+// ExternalReference - ссылка на внешний источник индикатора
+type ExternalReference struct {
+	SourceName string `json:"source_name"`
+}
+
+// Indicator - STIX 2.1 объект indicator
+type Indicator struct {
+	Type               string              `json:"type"`
+	SpecVersion        string              `json:"spec_version"`
+	ID                 string              `json:"id"`
+	Created            string              `json:"created"`
+	Modified           string              `json:"modified"`
+	Name               string              `json:"name,omitempty"`
+	IndicatorTypes     []string            `json:"indicator_types,omitempty"`
+	Pattern            string              `json:"pattern"`
+	PatternType        string              `json:"pattern_type"`
+	ValidFrom          string              `json:"valid_from"`
+	Labels             []string            `json:"labels,omitempty"`
+	ExternalReferences []ExternalReference `json:"external_references,omitempty"`
+}
+
+// Bundle - STIX 2.1 bundle
+type Bundle struct {
+	Type    string      `json:"type"`
+	ID      string      `json:"id"`
+	Objects []Indicator `json:"objects"`
+}
+
+// NewBundleID - генерирует случайный идентификатор bundle
+func NewBundleID() string {
+	return "bundle--" + uuid.NewString()
+}
+
+// IndicatorID - детерминированный id индикатора по типу и значению IoC
+func IndicatorID(iocType, value string) string {
+	name := strings.ToLower(iocType) + ":" + strings.ToLower(value)
+	return "indicator--" + uuid.NewSHA1(namespace, []byte(name)).String()
+}
+
+// Pattern - строит STIX паттерн для IoC; ok = false, если тип не поддерживается
+func Pattern(iocType, value string) (string, bool) {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	switch strings.ToLower(iocType) {
+	case models.TypeIP:
+		if strings.Contains(value, ":") {
+			return fmt.Sprintf("[ipv6-addr:value = '%s']", escaped), true
+		}
+		return fmt.Sprintf("[ipv4-addr:value = '%s']", escaped), true
+	case models.TypeDomain:
+		return fmt.Sprintf("[domain-name:value = '%s']", escaped), true
+	case models.TypeURL:
+		return fmt.Sprintf("[url:value = '%s']", escaped), true
+	case models.TypeSHA256:
+		return fmt.Sprintf("[file:hashes.'SHA-256' = '%s']", escaped), true
+	case models.TypeMD5:
+		return fmt.Sprintf("[file:hashes.MD5 = '%s']", escaped), true
+	default:
+		return "", false
+	}
+}
+
+// FromIoC - преобразует IoCDto в STIX индикатор
+func FromIoC(ioc models.IoCDto) (Indicator, bool) {
+	pattern, ok := Pattern(ioc.Type, ioc.Value)
+	if !ok {
+		return Indicator{}, false
+	}
+
+	now := time.Now().UTC()
+	created, modified := now, now
+	if ioc.FirstSeen != nil && !ioc.FirstSeen.IsZero() {
+		created = ioc.FirstSeen.UTC()
+	}
+	if ioc.LastSeen != nil && !ioc.LastSeen.IsZero() {
+		modified = ioc.LastSeen.UTC()
+	}
+	if modified.Before(created) {
+		modified = created
+	}
+
+	indicator := Indicator{
+		Type:           "indicator",
+		SpecVersion:    SpecVersion,
+		ID:             IndicatorID(ioc.Type, ioc.Value),
+		Created:        created.Format(TimeFormat),
+		Modified:       modified.Format(TimeFormat),
+		Name:           ioc.Value,
+		IndicatorTypes: []string{"malicious-activity"},
+		Pattern:        pattern,
+		PatternType:    "stix",
+		ValidFrom:      created.Format(TimeFormat),
+	}
+	for _, tag := range ioc.Tags {
+		if tag != "" {
+			indicator.Labels = append(indicator.Labels, tag)
+		}
+	}
+	if ioc.Source != "" {
+		indicator.ExternalReferences = []ExternalReference{{SourceName: ioc.Source}}
+	}
+	return indicator, true
+}
+
+// ParsePattern - извлекает тип и значение IoC из STIX паттерна с одиночным сравнением
+func ParsePattern(pattern string) (string, string, error) {
+	match := comparisonPattern.FindStringSubmatch(strings.TrimSpace(pattern))
+	if match == nil {
+		return "", "", fmt.Errorf("unsupported stix pattern: %s", pattern)
+	}
+	object, path := match[1], strings.ToLower(strings.ReplaceAll(match[2], "'", ""))
+	value := strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(match[3])
+
+	switch {
+	case (object == "ipv4-addr" || object == "ipv6-addr") && path == "value":
+		return models.TypeIP, value, nil
+	case object == "domain-name" && path == "value":
+		return models.TypeDomain, value, nil
+	case object == "url" && path == "value":
+		return models.TypeURL, value, nil
+	case object == "file" && (path == "hashes.sha-256" || path == "hashes.sha256"):
+		return models.TypeSHA256, value, nil
+	case object == "file" && path == "hashes.md5":
+		return models.TypeMD5, value, nil
+	default:
+		return "", "", fmt.Errorf("unsupported stix object path: %s:%s", object, match[2])
+	}
+}
+
+// ToIoC - преобразует STIX индикатор в IoCDto
+func ToIoC(indicator Indicator) (models.IoCDto, error) {
+	if indicator.Type != "indicator" {
+		return models.IoCDto{}, fmt.Errorf("unsupported stix object type: %s", indicator.Type)
+	}
+	if indicator.PatternType != "" && indicator.PatternType != "stix" {
+		return models.IoCDto{}, fmt.Errorf("unsupported pattern_type: %s", indicator.PatternType)
+	}
+	iocType, value, err := ParsePattern(indicator.Pattern)
+	if err != nil {
+		return models.IoCDto{}, err
+	}
+
+	ioc := models.IoCDto{
+		Type:  iocType,
+		Value: value,
+		Tags:  indicator.Labels,
+	}
+	if len(indicator.ExternalReferences) > 0 {
+		ioc.Source = indicator.ExternalReferences[0].SourceName
+	}
+	if t, err := parseTime(indicator.ValidFrom, indicator.Created); err == nil {
+		ioc.FirstSeen = &t
+	}
+	if t, err := parseTime(indicator.Modified); err == nil {
+		ioc.LastSeen = &t
+	}
+	return ioc, nil
+}
+
+// parseTime - разбирает первую непустую временную метку
+func parseTime(values ...string) (time.Time, error) {
+	for _, value := range values {
+		if value != "" {
+			return time.Parse(time.RFC3339Nano, value)
+		}
+	}
+	return time.Time{}, fmt.Errorf("empty timestamp")
+}
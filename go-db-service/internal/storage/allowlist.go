@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ListAllowlist - актуальные записи allowlist
+func (s *ClickHouseStorage) ListAllowlist(ctx context.Context) ([]models.AllowlistEntry, error) {
+	query := `SELECT id, kind, value, comment, created_at, updated_at FROM allowlist FINAL WHERE deleted = 0 ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		s.logger.Error("Failed to list allowlist", zap.Error(err))
+		return nil, fmt.Errorf("failed to list allowlist: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AllowlistEntry
+	for rows.Next() {
+		var entry models.AllowlistEntry
+		if err := rows.Scan(&entry.ID, &entry.Kind, &entry.Value, &entry.Comment, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			s.logger.Error("Failed to scan allowlist row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan allowlist row: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// StoreAllowlistEntries - создание или изменение записей allowlist (новая версия строки по id)
+func (s *ClickHouseStorage) StoreAllowlistEntries(ctx context.Context, entries []models.AllowlistEntry) error {
+	return s.writeAllowlist(ctx, entries, false)
+}
+
+// DeleteAllowlistEntry - удаление записи allowlist
+func (s *ClickHouseStorage) DeleteAllowlistEntry(ctx context.Context, id string) error {
+	return s.writeAllowlist(ctx, []models.AllowlistEntry{{ID: id, UpdatedAt: time.Now().UTC()}}, true)
+}
+
+func (s *ClickHouseStorage) writeAllowlist(ctx context.Context, entries []models.AllowlistEntry, deleted bool) error {
+	query := `INSERT INTO allowlist (id, kind, value, comment, created_at, updated_at, deleted, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		_, err := stmt.ExecContext(ctx,
+			entry.ID,
+			entry.Kind,
+			entry.Value,
+			entry.Comment,
+			entry.CreatedAt,
+			entry.UpdatedAt,
+			boolToUInt8(deleted),
+			uint64(time.Now().UnixNano()),
+		)
+		if err != nil {
+			s.logger.Error("Failed to write allowlist entry", zap.String("id", entry.ID), zap.Error(err))
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
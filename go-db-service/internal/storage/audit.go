@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// StoreAuditRecords - добавление записей в журнал аудита; изменять и удалять записи хранилище не умеет
+func (s *ClickHouseStorage) StoreAuditRecords(ctx context.Context, records []models.AuditRecord) error {
+	query := `INSERT INTO audit_log (id, time, actor, peer, method, params, rows, outcome, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		_, err := stmt.ExecContext(ctx,
+			record.ID,
+			record.Time,
+			record.Actor,
+			record.Peer,
+			record.Method,
+			record.Params,
+			record.Rows,
+			record.Outcome,
+			record.Error,
+		)
+		if err != nil {
+			s.logger.Error("Failed to write audit record", zap.String("method", record.Method), zap.Error(err))
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// QueryAudit - записи журнала аудита по фильтру, новые первыми
+func (s *ClickHouseStorage) QueryAudit(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, query.Actor)
+	}
+	if query.Method != "" {
+		conditions = append(conditions, "method = ?")
+		args = append(args, query.Method)
+	}
+	if query.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, query.Outcome)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, query.From.UTC())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "time < ?")
+		args = append(args, query.To.UTC())
+	}
+
+	sqlQuery := `SELECT id, time, actor, peer, method, params, rows, outcome, error FROM audit_log`
+	if len(conditions) > 0 {
+		sqlQuery += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	sqlQuery += ` ORDER BY time DESC, id LIMIT ? OFFSET ?`
+	args = append(args, query.Limit, query.Offset)
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		s.logger.Error("Failed to query audit log", zap.Error(err))
+		return nil, fmt.Errorf("failed to query audit log: %v", err)
+	}
+	defer rows.Close()
+
+	var records []models.AuditRecord
+	for rows.Next() {
+		var record models.AuditRecord
+		if err := rows.Scan(&record.ID, &record.Time, &record.Actor, &record.Peer, &record.Method,
+			&record.Params, &record.Rows, &record.Outcome, &record.Error); err != nil {
+			s.logger.Error("Failed to scan audit row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan audit row: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// SetAuditRetention - срок хранения журнала аудита в днях через TTL таблицы; 0 - хранить бессрочно
+func (s *ClickHouseStorage) SetAuditRetention(ctx context.Context, days int) error {
+	query := `ALTER TABLE audit_log REMOVE TTL`
+	if days > 0 {
+		query = fmt.Sprintf(`ALTER TABLE audit_log MODIFY TTL time + INTERVAL %d DAY`, days)
+	}
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		// REMOVE TTL падает, если TTL не был задан - для бессрочного хранения это не ошибка
+		if days <= 0 {
+			s.logger.Debug("Audit log has no TTL to remove", zap.Error(err))
+			return nil
+		}
+		return fmt.Errorf("failed to set audit retention: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,673 @@
+package conformance
+
+import (
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// allStatuses - все статусы lifecycle, чтобы выборка не зависела от статуса
+var allStatuses = []string{models.StatusActive, models.StatusFalsePositive, models.StatusRevoked, models.StatusExpired}
+
+// setup - запись набора. Между унарной и стримовой записью проходит больше секунды, чтобы
+// added_at пачек различались: на этом проверяются AddedAfter/AddedBefore и пагинация.
+func (s *suite) setup() error {
+	s.f = s.newFixture()
+	if err := s.storage.UnaryStore(s.ctx, s.f.unary); err != nil {
+		return fmt.Errorf("unary store: %v", err)
+	}
+	if err := s.storage.UnaryStore(s.shared, s.f.shared); err != nil {
+		return fmt.Errorf("unary store (shared): %v", err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	stream := make(chan models.IoCDto, len(s.f.stream))
+	for _, ioc := range s.f.stream {
+		stream <- ioc
+	}
+	close(stream)
+	if err := s.storage.StreamStore(s.ctx, stream); err != nil {
+		return fmt.Errorf("stream store: %v", err)
+	}
+
+	err := s.storage.PutLifecycle(s.ctx, models.Lifecycle{
+		Type:       models.TypeDomain,
+		Value:      s.f.values[keyOrg],
+		Status:     models.StatusRevoked,
+		Reason:     "conformance",
+		ChangedBy:  s.tenant,
+		ChangedAt:  s.now,
+		ManualTags: []string{"analyst-" + s.run},
+	})
+	if err != nil {
+		return fmt.Errorf("put lifecycle: %v", err)
+	}
+
+	iocs, err := s.load(models.LoadRequest{IncludeHidden: true, Statuses: allStatuses})
+	if err != nil {
+		return fmt.Errorf("load: %v", err)
+	}
+	for _, ioc := range iocs {
+		s.byValue[ioc.Value] = ioc
+	}
+	if len(s.byValue) != len(s.f.values) {
+		return fmt.Errorf("load after store: got %d IoCs, want %d", len(s.byValue), len(s.f.values))
+	}
+	for key, value := range s.f.values {
+		ioc, ok := s.byValue[value]
+		if !ok {
+			return fmt.Errorf("load after store: %s (%s) is missing", key, value)
+		}
+		if ioc.AddedAt == nil {
+			return fmt.Errorf("load after store: %s has no added_at", key)
+		}
+	}
+
+	// Границы пачек: последняя унарная и первая стримовая запись
+	for _, ioc := range s.f.unary {
+		if addedAt := *s.byValue[strings.ToLower(ioc.Value)].AddedAt; addedAt.After(s.unaryAddedAt) {
+			s.unaryAddedAt = addedAt
+		}
+	}
+	for _, ioc := range s.f.stream {
+		if addedAt := *s.byValue[ioc.Value].AddedAt; s.streamAddedAt.IsZero() || addedAt.Before(s.streamAddedAt) {
+			s.streamAddedAt = addedAt
+		}
+	}
+	if !s.streamAddedAt.After(s.unaryAddedAt) {
+		return fmt.Errorf("added_at: stream batch %v is not after unary batch %v", s.streamAddedAt, s.unaryAddedAt)
+	}
+	return nil
+}
+
+// storeLoadRoundtrip - все поля IoC читаются так, как записаны
+func (s *suite) storeLoadRoundtrip() error {
+	want := s.f.unary[0]
+	got := s.byValue[want.Value]
+	if got.FirstSeen == nil || got.LastSeen == nil {
+		return fmt.Errorf("first_seen/last_seen: got %v/%v", got.FirstSeen, got.LastSeen)
+	}
+	return firstError(
+		expectEqual("id", got.ID, want.ID),
+		expectEqual("source", got.Source, want.Source),
+		expectEqual("type", got.Type, want.Type),
+		expectEqual("tags", got.Tags, want.Tags),
+		expectEqual("additional_data", got.AdditionalData, want.AdditionalData),
+		expectEqual("first_seen", got.FirstSeen.UTC(), want.FirstSeen.UTC()),
+		expectEqual("last_seen", got.LastSeen.UTC(), want.LastSeen.UTC()),
+		expectEqual("hidden", got.Hidden, false),
+		expectEqual("geo", []interface{}{got.Country, got.City, got.ASN, got.ASOrg}, []interface{}{want.Country, want.City, want.ASN, want.ASOrg}),
+		expectEqual("tenant", got.Tenant, s.tenant),
+		expectEqual("status", got.Status, models.StatusActive),
+		expectEqual("sighting_count", got.SightingCount, int64(0)),
+	)
+}
+
+// writeNormalization - источник, тип, значение и теги приводятся к нижнему регистру; фильтры тоже
+func (s *suite) writeNormalization() error {
+	got := s.byValue[s.f.values[keyDomain]]
+	err := firstError(
+		expectEqual("source", got.Source, s.source),
+		expectEqual("type", got.Type, models.TypeDomain),
+		expectEqual("tags", got.Tags, []string{"phishing"}),
+	)
+	if err != nil {
+		return err
+	}
+	return s.expectKeys("upper case source and type", models.LoadRequest{Source: strings.ToUpper(s.source), Type: "DOMAIN", Filter: "phishing"}, keyDomain)
+}
+
+// streamStoreLoad - теги стримовой записи и полнота StreamLoad
+func (s *suite) streamStoreLoad() error {
+	if err := expectEqual("stream tags", s.byValue[s.f.values[keyStream1]].Tags, []string{"streamed", "second"}); err != nil {
+		return err
+	}
+
+	stream, errs, err := s.storage.StreamLoad(s.ctx, models.LoadRequest{Source: s.source, SortByAdded: true})
+	if err != nil {
+		return fmt.Errorf("stream load: %v", err)
+	}
+	var streamed []string
+	for ioc := range stream {
+		streamed = append(streamed, ioc.Value)
+	}
+	if err := <-errs; err != nil {
+		return fmt.Errorf("stream load: %v", err)
+	}
+	unary, err := s.load(models.LoadRequest{SortByAdded: true})
+	if err != nil {
+		return fmt.Errorf("unary load: %v", err)
+	}
+	return expectEqual("stream load", streamed, values(unary))
+}
+
+// filterTypeAndText - фильтры по типу и подстроке id, значения и тегов
+func (s *suite) filterTypeAndText() error {
+	return firstError(
+		s.expectKeys("default load", models.LoadRequest{},
+			keyIP1, keyIP2, keyNet, keyIPv4, keyDomain, keyWWW, keyURL, keyNeedle, keyOverlap, keyStream1, keyStream2),
+		s.expectKeys("type", models.LoadRequest{Type: "IP"}, keyIP1, keyIP2, keyNet, keyIPv4),
+		s.expectKeys("filter by value", models.LoadRequest{Filter: "www."}, keyWWW),
+		s.expectKeys("filter by tag", models.LoadRequest{Filter: "needle-"}, keyNeedle),
+		s.expectKeys("filter by id", models.LoadRequest{Filter: s.byValue[s.f.values[keyIP1]].ID}, keyIP1),
+	)
+}
+
+// filterIPRanges - IPWithin и IPContains по адресам и сетям, IPv4 и IPv6
+func (s *suite) filterIPRanges() error {
+	ip1, network := s.f.values[keyIP1], s.f.values[keyNet]
+	prefix := strings.TrimSuffix(network, "/64")
+	ipv4 := s.f.values[keyIPv4]
+	ipv4Net := ipv4[:strings.LastIndex(ipv4, ".")] + ".0/24"
+	return firstError(
+		s.expectKeys("ip within /126", models.LoadRequest{IPWithin: []string{prefix + "/126"}}, keyIP1, keyIP2),
+		s.expectKeys("ip within /64", models.LoadRequest{IPWithin: []string{network}}, keyIP1, keyIP2, keyNet),
+		s.expectKeys("ip within several ranges", models.LoadRequest{IPWithin: []string{ipv4Net, s.f.values[keyIP2]}}, keyIPv4, keyIP2),
+		s.expectKeys("ip contains address", models.LoadRequest{IPContains: ip1}, keyIP1, keyNet),
+		s.expectKeys("ip contains ipv4", models.LoadRequest{IPContains: ipv4}, keyIPv4),
+	)
+}
+
+// filterDomains - иерархия доменов: eTLD+1, поддомены и TLD
+func (s *suite) filterDomains() error {
+	domain := s.f.values[keyDomain]
+	return firstError(
+		s.expectKeys("registrable domain", models.LoadRequest{RegistrableDomain: "example.com"}, keyDomain, keyWWW, keyURL, keyStream1, keyStream2),
+		s.expectKeys("subdomain of", models.LoadRequest{SubdomainOf: strings.ToUpper(domain)}, keyDomain, keyWWW, keyURL, keyStream1, keyStream2),
+		s.expectKeys("subdomain of www", models.LoadRequest{SubdomainOf: s.f.values[keyWWW]}, keyWWW),
+		s.expectKeys("tld", models.LoadRequest{TLD: "net"}, keyOverlap),
+		s.expectKeys("tld with status", models.LoadRequest{TLD: "org", Statuses: []string{models.StatusRevoked}}, keyOrg),
+	)
+}
+
+// filterGeo - страна и автономная система IP IoC
+func (s *suite) filterGeo() error {
+	return firstError(
+		s.expectKeys("country", models.LoadRequest{Country: "nl"}, keyIP1, keyIP2),
+		s.expectKeys("asn", models.LoadRequest{ASN: s.f.asn + 1}, keyNet),
+	)
+}
+
+// filterAddedAt - AddedAfter и AddedBefore строгие
+func (s *suite) filterAddedAt() error {
+	return firstError(
+		s.expectKeys("added after", models.LoadRequest{AddedAfter: &s.unaryAddedAt}, keyStream1, keyStream2),
+		s.expectKeys("added before", models.LoadRequest{AddedBefore: &s.streamAddedAt},
+			keyIP1, keyIP2, keyNet, keyIPv4, keyDomain, keyWWW, keyURL, keyNeedle, keyOverlap),
+	)
+}
+
+// hidden - скрытые IoC отдаются только с IncludeHidden
+func (s *suite) hidden() error {
+	err := firstError(
+		s.expectKeys("hidden by default", models.LoadRequest{Type: models.TypeSHA256}, keyNeedle),
+		s.expectKeys("include hidden", models.LoadRequest{Type: models.TypeSHA256, IncludeHidden: true}, keyNeedle, keyHidden),
+	)
+	if err != nil {
+		return err
+	}
+	return expectEqual("hidden flag", s.byValue[s.f.values[keyHidden]].Hidden, true)
+}
+
+// lifecycle - статус и ручные теги в выдаче, заметки и список записей
+func (s *suite) lifecycle() error {
+	org := s.f.values[keyOrg]
+	got := s.byValue[org]
+	err := firstError(
+		expectEqual("loaded status", got.Status, models.StatusRevoked),
+		expectEqual("loaded tags", got.Tags, []string{"analyst-" + s.run}),
+		s.expectKeys("revoked only", models.LoadRequest{Statuses: []string{"REVOKED"}}, keyOrg),
+		s.expectKeys("active and revoked", models.LoadRequest{Type: models.TypeDomain, TLD: "org", Statuses: []string{models.StatusActive, models.StatusRevoked}}, keyOrg),
+	)
+	if err != nil {
+		return err
+	}
+
+	note := models.Note{ID: uuid.NewString(), Author: s.tenant, Text: "conformance note", CreatedAt: s.now}
+	if err := s.storage.AddNote(s.ctx, models.TypeDomain, org, note); err != nil {
+		return fmt.Errorf("add note: %v", err)
+	}
+	record, found, err := s.storage.GetLifecycle(s.ctx, models.TypeDomain, org)
+	if err != nil {
+		return fmt.Errorf("get lifecycle: %v", err)
+	}
+	err = firstError(
+		expectEqual("found", found, true),
+		expectEqual("status", record.Status, models.StatusRevoked),
+		expectEqual("changed_by", record.ChangedBy, s.tenant),
+		expectEqual("changed_at", record.ChangedAt.UTC(), s.now),
+		expectEqual("manual_tags", record.ManualTags, []string{"analyst-" + s.run}),
+		expectEqual("notes", len(record.Notes), 1),
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectEqual("note", []string{record.Notes[0].ID, record.Notes[0].Text}, []string{note.ID, note.Text}); err != nil {
+		return err
+	}
+
+	record, found, err = s.storage.GetLifecycle(s.ctx, models.TypeDomain, s.f.values[keyWWW])
+	if err != nil {
+		return fmt.Errorf("get lifecycle: %v", err)
+	}
+	if err := firstError(expectEqual("untouched found", found, false), expectEqual("untouched status", record.Status, models.StatusActive)); err != nil {
+		return err
+	}
+
+	records, err := s.storage.ListLifecycle(s.ctx, []string{models.StatusRevoked}, 0, 0)
+	if err != nil {
+		return fmt.Errorf("list lifecycle: %v", err)
+	}
+	for _, record := range records {
+		if record.Value == org {
+			return nil
+		}
+	}
+	return fmt.Errorf("list lifecycle: %s is missing", org)
+}
+
+// pagination - SortByAdded упорядочивает по added_at и значению побайтно, страницы складываются в полную выборку
+func (s *suite) pagination() error {
+	request := models.LoadRequest{SortByAdded: true, IncludeHidden: true, Statuses: allStatuses}
+	full, err := s.load(request)
+	if err != nil {
+		return fmt.Errorf("load: %v", err)
+	}
+	if err := expectEqual("rows", len(full), len(s.f.values)); err != nil {
+		return err
+	}
+	sorted := sort.SliceIsSorted(full, func(i, j int) bool {
+		if !full[i].AddedAt.Equal(*full[j].AddedAt) {
+			return full[i].AddedAt.Before(*full[j].AddedAt)
+		}
+		return full[i].Value < full[j].Value
+	})
+	if !sorted {
+		return fmt.Errorf("order: %v is not sorted by added_at, value", values(full))
+	}
+
+	var paged []models.IoCDto
+	for offset := int64(0); offset < int64(len(full))+4; offset += 4 {
+		request.Limit, request.Offset = 4, offset
+		page, err := s.load(request)
+		if err != nil {
+			return fmt.Errorf("load page at %d: %v", offset, err)
+		}
+		paged = append(paged, page...)
+	}
+	return expectEqual("pages", values(paged), values(full))
+}
+
+// counts - счетчики учитывают скрытые и отозванные IoC; глобальные счетчики сравниваются с общими
+// данными, которые видны без арендатора, поэтому чужие IoC в базе проверке не мешают
+func (s *suite) counts() error {
+	byType := map[string]int64{models.TypeIP: 4, models.TypeDomain: 6, models.TypeURL: 1, models.TypeSHA256: 2}
+	total := int64(len(s.f.unary) + len(s.f.stream))
+
+	bySourceAndType, err := s.storage.CountBySourceAndType(s.ctx, s.source)
+	if err != nil {
+		return fmt.Errorf("count by source and type: %v", err)
+	}
+	typesBySource, err := s.storage.CountTypesBySource(s.ctx)
+	if err != nil {
+		return fmt.Errorf("count types by source: %v", err)
+	}
+	bySource, err := s.storage.CountBySource(s.ctx)
+	if err != nil {
+		return fmt.Errorf("count by source: %v", err)
+	}
+	specificSource, err := s.storage.CountSpecificSource(s.ctx, s.source)
+	if err != nil {
+		return fmt.Errorf("count specific source: %v", err)
+	}
+	byTypeAndSource, err := s.storage.CountByTypeAndSource(s.ctx, models.TypeSHA256)
+	if err != nil {
+		return fmt.Errorf("count by type and source: %v", err)
+	}
+	tenantCount, err := s.storage.CountTenant(s.ctx, s.tenant)
+	if err != nil {
+		return fmt.Errorf("count tenant: %v", err)
+	}
+	err = firstError(
+		expectEqual("count by source and type", bySourceAndType, byType),
+		expectEqual("count types by source", typesBySource[s.source], byType),
+		expectEqual("count types by other source", typesBySource[s.otherSource], map[string]int64{models.TypeDomain: 1}),
+		expectEqual("count by source", []int64{bySource[s.source], bySource[s.otherSource]}, []int64{total, 1}),
+		expectEqual("count specific source", specificSource, total),
+		expectEqual("count by type and source", byTypeAndSource[s.source], int64(2)),
+		expectEqual("count tenant", tenantCount, total),
+	)
+	if err != nil {
+		return err
+	}
+
+	count := func(ctx context.Context) (int64, map[string]int64, int64, map[string]int64, error) {
+		all, err := s.storage.AllIocsCount(ctx)
+		if err != nil {
+			return 0, nil, 0, nil, fmt.Errorf("all iocs count: %v", err)
+		}
+		types, err := s.storage.CountByType(ctx)
+		if err != nil {
+			return 0, nil, 0, nil, fmt.Errorf("count by type: %v", err)
+		}
+		urls, err := s.storage.CountSpecificType(ctx, models.TypeURL)
+		if err != nil {
+			return 0, nil, 0, nil, fmt.Errorf("count specific type: %v", err)
+		}
+		countries, err := s.storage.CountByCountry(ctx)
+		if err != nil {
+			return 0, nil, 0, nil, fmt.Errorf("count by country: %v", err)
+		}
+		return all, types, urls, countries, nil
+	}
+	all, types, urls, countries, err := count(s.ctx)
+	if err != nil {
+		return err
+	}
+	sharedAll, sharedTypes, sharedURLs, sharedCountries, err := count(s.shared)
+	if err != nil {
+		return err
+	}
+	err = firstError(
+		expectEqual("all iocs count", all-sharedAll, total),
+		expectEqual("count by type", delta(types, sharedTypes), byType),
+		expectEqual("count specific type", urls-sharedURLs, int64(1)),
+		expectEqual("count by country", delta(countries, sharedCountries), map[string]int64{"NL": 2, "DE": 1}),
+	)
+	if err != nil {
+		return err
+	}
+
+	asns, err := s.storage.CountByASN(s.ctx, 0)
+	if err != nil {
+		return fmt.Errorf("count by asn: %v", err)
+	}
+	var own []models.ASNCount
+	for _, asn := range asns {
+		if asn.ASN == s.f.asn || asn.ASN == s.f.asn+1 {
+			own = append(own, asn)
+		}
+	}
+	return expectEqual("count by asn", own, []models.ASNCount{{ASN: s.f.asn, Org: asOrg, Count: 2}, {ASN: s.f.asn + 1, Org: asOrg, Count: 1}})
+}
+
+// tenantIsolation - IoC арендатора не видны без арендатора и другим арендаторам, общие видны всем
+func (s *suite) tenantIsolation() error {
+	other := tenant.WithTenant(s.ctx, s.tenant+"-other")
+	for name, ctx := range map[string]context.Context{"shared": s.shared, "other tenant": other} {
+		iocs, err := s.storage.UnaryLoad(ctx, models.LoadRequest{Source: s.source, IncludeHidden: true, Statuses: allStatuses})
+		if err != nil {
+			return fmt.Errorf("%s load: %v", name, err)
+		}
+		if err := expectEqual(name+" load", len(iocs), 0); err != nil {
+			return err
+		}
+		count, err := s.storage.CountSpecificSource(ctx, s.source)
+		if err != nil {
+			return fmt.Errorf("%s count: %v", name, err)
+		}
+		if err := expectEqual(name+" count", count, int64(0)); err != nil {
+			return err
+		}
+	}
+
+	iocs, err := s.storage.UnaryLoad(other, models.LoadRequest{Source: s.otherSource})
+	if err != nil {
+		return fmt.Errorf("other tenant load: %v", err)
+	}
+	if err := expectEqual("shared feed for other tenant", values(iocs), []string{s.f.values[keyOverlap]}); err != nil {
+		return err
+	}
+
+	byID, err := s.storage.IoCsByID(s.shared, []string{s.byValue[s.f.values[keyIP1]].ID})
+	if err != nil {
+		return fmt.Errorf("shared iocs by id: %v", err)
+	}
+	return expectEqual("shared iocs by id", len(byID), 0)
+}
+
+// sightings - агрегаты обнаружений в выдаче и по источникам
+func (s *suite) sightings() error {
+	ip1 := s.f.values[keyIP1]
+	err := s.storage.RecordSightings(s.ctx, []models.Sighting{
+		{Value: ip1, Type: models.TypeIP, Observer: s.tenant, SeenAt: s.now.Add(-time.Minute), Count: 3},
+		{Value: ip1, Type: models.TypeIP, Observer: s.tenant, SeenAt: s.now, Count: 2},
+	})
+	if err != nil {
+		return fmt.Errorf("record sightings: %v", err)
+	}
+
+	iocs, err := s.load(models.LoadRequest{IPContains: ip1, IncludeSightings: true})
+	if err != nil {
+		return fmt.Errorf("load with sightings: %v", err)
+	}
+	var found bool
+	for _, ioc := range iocs {
+		switch ioc.Value {
+		case ip1:
+			found = true
+			if ioc.FirstSighted == nil || ioc.LastSighted == nil {
+				return fmt.Errorf("sighting times: got %v/%v", ioc.FirstSighted, ioc.LastSighted)
+			}
+			err = firstError(
+				expectEqual("sighting count", ioc.SightingCount, int64(5)),
+				expectEqual("first sighted", ioc.FirstSighted.UTC(), s.now.Add(-time.Minute)),
+				expectEqual("last sighted", ioc.LastSighted.UTC(), s.now),
+			)
+		default:
+			err = expectEqual("sighting count of "+ioc.Value, ioc.SightingCount, int64(0))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("load with sightings: %s is missing", ip1)
+	}
+
+	bySource, err := s.storage.SightingsBySource(s.ctx)
+	if err != nil {
+		return fmt.Errorf("sightings by source: %v", err)
+	}
+	return expectEqual("sightings by source", bySource[s.source], models.SourceSightings{IoCs: 1, Sightings: 5})
+}
+
+// relationships - повторная запись связи заменяет ее, поиск по любому концу, IoC по id
+func (s *suite) relationships() error {
+	ip1, www := s.f.values[keyIP1], s.f.values[keyWWW]
+	rel := models.Relationship{FromType: models.TypeDomain, FromValue: www, Kind: models.RelationResolvesTo,
+		ToType: models.TypeIP, ToValue: ip1, Source: s.source, UpdatedAt: s.now.Add(-time.Minute)}
+	if err := s.storage.StoreRelationships(s.ctx, []models.Relationship{rel}); err != nil {
+		return fmt.Errorf("store relationships: %v", err)
+	}
+	rel.UpdatedAt = s.now
+	if err := s.storage.StoreRelationships(s.ctx, []models.Relationship{rel}); err != nil {
+		return fmt.Errorf("store relationships: %v", err)
+	}
+
+	for _, value := range []string{ip1, www} {
+		rels, err := s.storage.RelationshipsOf(s.ctx, []string{value}, 10)
+		if err != nil {
+			return fmt.Errorf("relationships of %s: %v", value, err)
+		}
+		if err := expectEqual("relationships of "+value, len(rels), 1); err != nil {
+			return err
+		}
+		got := rels[0]
+		got.UpdatedAt = got.UpdatedAt.UTC()
+		if err := expectEqual("relationship", got, rel); err != nil {
+			return err
+		}
+	}
+
+	id := s.byValue[ip1].ID
+	byID, err := s.storage.IoCsByID(s.ctx, []string{id, uuid.NewString()})
+	if err != nil {
+		return fmt.Errorf("iocs by id: %v", err)
+	}
+	return expectEqual("iocs by id", byID, map[string]models.IoCDto{id: {ID: id, Type: models.TypeIP, Value: ip1}})
+}
+
+// allowlist - запись, изменение и удаление записи allowlist
+func (s *suite) allowlist() error {
+	entry := models.AllowlistEntry{ID: s.tenant, Kind: models.AllowlistValue, Value: s.f.values[keyNeedle],
+		Comment: "conformance", CreatedAt: s.now, UpdatedAt: s.now}
+	find := func() (*models.AllowlistEntry, error) {
+		entries, err := s.storage.ListAllowlist(s.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list allowlist: %v", err)
+		}
+		for _, e := range entries {
+			if e.ID == entry.ID {
+				return &e, nil
+			}
+		}
+		return nil, nil
+	}
+
+	if err := s.storage.StoreAllowlistEntries(s.ctx, []models.AllowlistEntry{entry}); err != nil {
+		return fmt.Errorf("store allowlist: %v", err)
+	}
+	entry.Comment, entry.UpdatedAt = "conformance updated", s.now.Add(time.Second)
+	if err := s.storage.StoreAllowlistEntries(s.ctx, []models.AllowlistEntry{entry}); err != nil {
+		return fmt.Errorf("update allowlist: %v", err)
+	}
+	got, err := find()
+	if err != nil {
+		return err
+	}
+	if got == nil {
+		return fmt.Errorf("list allowlist: %s is missing", entry.ID)
+	}
+	err = firstError(
+		expectEqual("entry", []string{got.Kind, got.Value, got.Comment}, []string{entry.Kind, entry.Value, entry.Comment}),
+		expectEqual("updated_at", got.UpdatedAt.UTC(), entry.UpdatedAt),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteAllowlistEntry(s.ctx, entry.ID); err != nil {
+		return fmt.Errorf("delete allowlist: %v", err)
+	}
+	if got, err = find(); err != nil {
+		return err
+	}
+	if got != nil {
+		return fmt.Errorf("list allowlist: deleted %s is still listed", entry.ID)
+	}
+	return nil
+}
+
+// audit - записи журнала аудита по актору и результату; limit задается явно, хранилище получает его от сервиса
+func (s *suite) audit() error {
+	writer := s.storage.(audit.Writer)
+	records := []models.AuditRecord{
+		{ID: uuid.NewString(), Time: s.now, Actor: s.tenant, Method: "conformance.store", Params: "{}", Rows: 1, Outcome: models.AuditOK},
+		{ID: uuid.NewString(), Time: s.now, Actor: s.tenant, Method: "conformance.load", Params: "{}", Outcome: models.AuditError, Error: "failed"},
+	}
+	if err := writer.StoreAuditRecords(s.ctx, records); err != nil {
+		return fmt.Errorf("store audit records: %v", err)
+	}
+	got, err := s.storage.QueryAudit(s.ctx, models.AuditQuery{Actor: s.tenant, Limit: 10})
+	if err != nil {
+		return fmt.Errorf("query audit: %v", err)
+	}
+	if err := expectEqual("audit records", len(got), len(records)); err != nil {
+		return err
+	}
+	failed, err := s.storage.QueryAudit(s.ctx, models.AuditQuery{Actor: s.tenant, Outcome: models.AuditError, Limit: 10})
+	if err != nil {
+		return fmt.Errorf("query audit: %v", err)
+	}
+	if err := expectEqual("failed audit records", len(failed), 1); err != nil {
+		return err
+	}
+	return expectEqual("failed audit record", []string{failed[0].ID, failed[0].Method}, []string{records[1].ID, records[1].Method})
+}
+
+// sourceReport - отчет по источникам: пересечение с общим фидом и с другим источником того же арендатора,
+// отставание, совпадения с текущим allowlist и churn
+func (s *suite) sourceReport() error {
+	// Другой источник арендатора сообщает о www с более старым last_seen: строка ioc_data не заменяется,
+	// но источник все равно учитывается в пересечении
+	peerSource := s.source + "-peer"
+	firstSeen, lastSeen := s.now.Add(-2*time.Hour+time.Minute), s.now.Add(-time.Hour)
+	peer := models.IoCDto{ID: uuid.NewString(), Source: peerSource, FirstSeen: &firstSeen, LastSeen: &lastSeen,
+		Type: models.TypeDomain, Value: s.f.values[keyWWW], Tags: []string{}, Tenant: s.tenant}
+	if err := s.storage.UnaryStore(s.ctx, []models.IoCDto{peer}); err != nil {
+		return fmt.Errorf("unary store (peer): %v", err)
+	}
+
+	// Allowlist считается по текущим записям, а не по тегу: needle по значению, адреса и сеть /64 по CIDR
+	entries := []models.AllowlistEntry{
+		{ID: s.tenant + "-report-value", Kind: models.AllowlistValue, Value: s.f.values[keyNeedle], CreatedAt: s.now, UpdatedAt: s.now},
+		{ID: s.tenant + "-report-cidr", Kind: models.AllowlistCIDR, Value: s.f.values[keyNet], CreatedAt: s.now, UpdatedAt: s.now},
+	}
+	if err := s.storage.StoreAllowlistEntries(s.ctx, entries); err != nil {
+		return fmt.Errorf("store allowlist: %v", err)
+	}
+	defer func() {
+		for _, entry := range entries {
+			_ = s.storage.DeleteAllowlistEntry(s.ctx, entry.ID)
+		}
+	}()
+
+	report, err := s.storage.SourceReport(s.ctx, 7)
+	if err != nil {
+		return fmt.Errorf("source report: %v", err)
+	}
+	own, other, peerStats := report.Sources[s.source], report.Sources[s.otherSource], report.Sources[peerSource]
+	if own == nil || other == nil || peerStats == nil {
+		return fmt.Errorf("source report: sources %s, %s and %s are missing", s.source, s.otherSource, peerSource)
+	}
+	total := int64(len(s.f.unary) + len(s.f.stream))
+	err = firstError(
+		expectEqual("own totals", []int64{own.Total, own.Unique, own.Shared, own.FirstReported, own.Allowlisted}, []int64{total, total - 2, 2, 1, 4}),
+		expectEqual("own median lag", own.MedianLag, float64(60)),
+		expectEqual("other totals", []int64{other.Total, other.Unique, other.Shared, other.FirstReported}, []int64{1, 0, 1, 1}),
+		expectEqual("other median lag", other.MedianLag, float64(0)),
+		expectEqual("peer totals", []int64{peerStats.Total, peerStats.Unique, peerStats.Shared, peerStats.FirstReported}, []int64{1, 0, 1, 1}),
+	)
+	if err != nil {
+		return err
+	}
+
+	overlaps := make(map[[2]string]int64)
+	for _, overlap := range report.Overlaps {
+		overlaps[[2]string{overlap.SourceA, overlap.SourceB}] = overlap.Shared
+	}
+	err = firstError(
+		expectEqual("overlap with shared feed", overlaps[[2]string{s.source, s.otherSource}], int64(1)),
+		expectEqual("overlap with peer", overlaps[[2]string{s.source, peerSource}], int64(1)),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Давно не встречавшиеся ipv4 и needle считаются пропавшими
+	var added, removed int64
+	for _, churn := range own.Churn {
+		added += churn.Added
+		removed += churn.Removed
+	}
+	return expectEqual("churn", []int64{added, removed}, []int64{total, 2})
+}
+
+// delta - разница счетчиков без нулевых значений
+func delta(counts, base map[string]int64) map[string]int64 {
+	result := make(map[string]int64)
+	for key, count := range counts {
+		if d := count - base[key]; d != 0 {
+			result[key] = d
+		}
+	}
+	return result
+}
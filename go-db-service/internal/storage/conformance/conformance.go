@@ -0,0 +1,171 @@
+// Package conformance - общий набор проверок хранилищ IoC. Любая реализация service.Storage должна
+// одинаково применять фильтры LoadRequest, пагинацию, счетчики, арендаторов и вспомогательные таблицы.
+package conformance
+
+import (
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/service"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Result - итог одной проверки
+type Result struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Failed - число непройденных проверок
+func Failed(results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Run - записывает набор IoC от имени отдельного арендатора и проверяет чтение. Значения, источники и
+// арендатор уникальны для запуска, поэтому запуски не мешают друг другу, а чужие данные не влияют на
+// проверки. Удаления в Storage нет, и записанное остается в хранилище: запускать набор стоит на отдельной базе.
+func Run(ctx context.Context, storage service.Storage) []Result {
+	s := newSuite(ctx, storage)
+	if !s.check("setup", s.setup) {
+		return s.results
+	}
+
+	s.check("store_load_roundtrip", s.storeLoadRoundtrip)
+	s.check("write_normalization", s.writeNormalization)
+	s.check("stream_store_load", s.streamStoreLoad)
+	s.check("filter_type_and_text", s.filterTypeAndText)
+	s.check("filter_ip_ranges", s.filterIPRanges)
+	s.check("filter_domains", s.filterDomains)
+	s.check("filter_geo", s.filterGeo)
+	s.check("filter_added_at", s.filterAddedAt)
+	s.check("hidden", s.hidden)
+	s.check("lifecycle", s.lifecycle)
+	s.check("pagination", s.pagination)
+	s.check("counts", s.counts)
+	s.check("tenant_isolation", s.tenantIsolation)
+	s.check("sightings", s.sightings)
+	s.check("relationships", s.relationships)
+	s.check("allowlist", s.allowlist)
+	if _, ok := storage.(audit.Writer); ok {
+		s.check("audit", s.audit)
+	}
+	s.check("source_report", s.sourceReport)
+	return s.results
+}
+
+// suite - состояние одного запуска
+type suite struct {
+	storage service.Storage
+	ctx     context.Context // От имени арендатора запуска
+	shared  context.Context // Без арендатора
+	results []Result
+
+	run         string // Уникальная часть значений запуска
+	tenant      string
+	source      string
+	otherSource string
+	now         time.Time
+
+	f                           fixture
+	unaryAddedAt, streamAddedAt time.Time                // Время записи пачек, как его вернуло хранилище
+	byValue                     map[string]models.IoCDto // Все IoC запуска после записи
+}
+
+func newSuite(ctx context.Context, storage service.Storage) *suite {
+	run := strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	id := "conformance-" + run
+	return &suite{
+		storage:     storage,
+		ctx:         tenant.WithTenant(ctx, id),
+		shared:      tenant.WithTenant(ctx, tenant.Shared),
+		run:         run,
+		tenant:      id,
+		source:      id,
+		otherSource: id + "-feed",
+		now:         time.Now().UTC().Truncate(time.Second),
+		byValue:     make(map[string]models.IoCDto),
+	}
+}
+
+// check - выполняет проверку и записывает результат; паника реализации считается провалом
+func (s *suite) check(name string, fn func() error) (passed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.results = append(s.results, Result{Name: name, Error: fmt.Sprintf("panic: %v", r)})
+			passed = false
+		}
+	}()
+	if err := fn(); err != nil {
+		s.results = append(s.results, Result{Name: name, Error: err.Error()})
+		return false
+	}
+	s.results = append(s.results, Result{Name: name, Passed: true})
+	return true
+}
+
+// load - UnaryLoad арендатора запуска; без Source выборка ограничивается источником запуска
+func (s *suite) load(request models.LoadRequest) ([]models.IoCDto, error) {
+	if request.Source == "" {
+		request.Source = s.source
+	}
+	return s.storage.UnaryLoad(s.ctx, request)
+}
+
+// expectKeys - выборка содержит ровно IoC набора с этими ключами, порядок не важен
+func (s *suite) expectKeys(what string, request models.LoadRequest, keys ...string) error {
+	iocs, err := s.load(request)
+	if err != nil {
+		return fmt.Errorf("%s: %v", what, err)
+	}
+	got := values(iocs)
+	want := make([]string, 0, len(keys))
+	for _, key := range keys {
+		want = append(want, s.f.values[key])
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+// expectEqual - сравнение значения с ожидаемым
+func expectEqual(what string, got, want interface{}) error {
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+// firstError - первая ошибка из проверок
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func values(iocs []models.IoCDto) []string {
+	result := make([]string, 0, len(iocs))
+	for _, ioc := range iocs {
+		result = append(result, ioc.Value)
+	}
+	return result
+}
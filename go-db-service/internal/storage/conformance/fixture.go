@@ -0,0 +1,115 @@
+package conformance
+
+import (
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Значения набора; <run> подставляется в value, чтобы запуски не пересекались
+const (
+	asOrg = "Conformance AS"
+
+	keyIP1     = "ip1"     // IPv6 адрес с GeoIP
+	keyIP2     = "ip2"     // Соседний адрес
+	keyNet     = "net"     // IPv6 сеть /64, содержащая оба адреса
+	keyIPv4    = "ipv4"    // IPv4 адрес, давно не встречался в фиде
+	keyDomain  = "domain"  // Домен, записанный в верхнем регистре
+	keyWWW     = "www"     // Поддомен
+	keyURL     = "url"     // URL на поддомене
+	keyOrg     = "org"     // Домен в другом TLD, аналитик отзывает его
+	keyNeedle  = "needle"  // Хэш с тегами для поиска и allowlisted
+	keyHidden  = "hidden"  // Хэш, скрытый allowlist
+	keyOverlap = "overlap" // Значение, которое есть и в общем фиде
+	keyStream1 = "stream1" // Записаны StreamStore
+	keyStream2 = "stream2"
+)
+
+// fixture - IoC набора по ключам и ожидаемые после записи значения
+type fixture struct {
+	unary  []models.IoCDto // Арендатор запуска, источник запуска
+	shared []models.IoCDto // Общий фид otherSource
+	stream []models.IoCDto // StreamStore, арендатор запуска
+	values map[string]string
+	asn    uint32
+}
+
+// newFixture - IoC запуска. Адреса берутся из документационных сетей 2001:db8::/32 и 10.0.0.0/8.
+func (s *suite) newFixture() fixture {
+	id := uuid.New()
+	a, b := s.run[:4], s.run[4:8]
+	asn := 4200000000 + uint32(id[0])<<16 + uint32(id[1])<<8 + uint32(id[2]) // Частные ASN
+
+	f := fixture{
+		values: map[string]string{
+			keyIP1:     fmt.Sprintf("2001:db8:%s:%s::1", a, b),
+			keyIP2:     fmt.Sprintf("2001:db8:%s:%s::2", a, b),
+			keyNet:     fmt.Sprintf("2001:db8:%s:%s::/64", a, b),
+			keyIPv4:    fmt.Sprintf("10.%d.%d.7", id[3], id[4]),
+			keyDomain:  s.run + ".example.com",
+			keyWWW:     "www." + s.run + ".example.com",
+			keyURL:     "https://mail." + s.run + ".example.com/login",
+			keyOrg:     s.run + ".example.org",
+			keyNeedle:  fmt.Sprintf("%x", id[:]) + s.run,
+			keyHidden:  s.run + fmt.Sprintf("%x", id[:]),
+			keyOverlap: "shared-" + s.run + ".example.net",
+			keyStream1: "stream-1." + s.run + ".example.com",
+			keyStream2: "stream-2." + s.run + ".example.com",
+		},
+		asn: asn,
+	}
+
+	reported := s.now.Add(-2 * time.Hour)
+	reportedLater := reported.Add(time.Minute)
+	stale := s.now.Add(-48 * time.Hour)
+	ioc := func(key, iocType string, lastSeen time.Time) models.IoCDto {
+		firstSeen := reportedLater
+		return models.IoCDto{
+			ID:             uuid.NewString(),
+			Source:         s.source,
+			FirstSeen:      &firstSeen,
+			LastSeen:       &lastSeen,
+			Type:           iocType,
+			Value:          f.values[key],
+			Tags:           []string{},
+			AdditionalData: map[string]string{"key": key},
+			Tenant:         s.tenant,
+		}
+	}
+
+	ip1 := ioc(keyIP1, models.TypeIP, s.now)
+	ip1.Tags = []string{"scanner"}
+	ip1.Country, ip1.City, ip1.ASN, ip1.ASOrg = "NL", "Amsterdam", asn, asOrg
+	ip2 := ioc(keyIP2, models.TypeIP, s.now)
+	ip2.Country, ip2.ASN, ip2.ASOrg = "NL", asn, asOrg
+	network := ioc(keyNet, models.TypeIP, s.now)
+	network.Country, network.ASN, network.ASOrg = "DE", asn+1, asOrg
+	ipv4 := ioc(keyIPv4, models.TypeIP, stale)
+
+	// Запись приводит источник, тип, значение и теги к нижнему регистру
+	domain := ioc(keyDomain, "DOMAIN", s.now)
+	domain.Source, domain.Value, domain.Tags = strings.ToUpper(s.source), strings.ToUpper(f.values[keyDomain]), []string{"Phishing"}
+
+	needle := ioc(keyNeedle, models.TypeSHA256, stale)
+	needle.Tags = []string{"needle-" + s.run, "allowlisted"}
+	hidden := ioc(keyHidden, models.TypeSHA256, s.now)
+	hidden.Hidden = true
+
+	f.unary = []models.IoCDto{ip1, ip2, network, ipv4, domain,
+		ioc(keyWWW, models.TypeDomain, s.now), ioc(keyURL, models.TypeURL, s.now), ioc(keyOrg, models.TypeDomain, s.now),
+		needle, hidden, ioc(keyOverlap, models.TypeDomain, s.now)}
+
+	// Общий фид сообщил о значении раньше источника запуска
+	overlap := ioc(keyOverlap, models.TypeDomain, s.now)
+	overlap.Source, overlap.Tenant, overlap.FirstSeen = s.otherSource, tenant.Shared, &reported
+	f.shared = []models.IoCDto{overlap}
+
+	stream1 := ioc(keyStream1, models.TypeDomain, s.now)
+	stream1.Tags = []string{"Streamed", "second"}
+	f.stream = []models.IoCDto{stream1, ioc(keyStream2, models.TypeDomain, s.now)}
+	return f
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"awesomeProject/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+const (
+	iocTable = "ioc_data"
+
+	streamInsertRows = 50000 // Строк в одной вставке StreamStore
+)
+
+// iocInsertColumns - колонки ioc_data, которые заполняются при записи IoC
+const iocInsertColumns = `id, source, first_seen, last_seen, type, value, tags, additional_data, hidden, ip_start, ip_end,
+	host, registrable_domain, tld, country, city, asn, as_org, tenant`
+
+// SetAsyncInsert - пачки не больше maxRows строк пишутся через async_insert; 0 - выключено.
+// Вставка ждет сброса буфера сервером (wait_for_async_insert), поэтому успех по-прежнему значит запись.
+func (s *ClickHouseStorage) SetAsyncInsert(maxRows int) {
+	s.asyncInsertMaxRows = maxRows
+}
+
+// insertIoCs - пишет IoC одной нативной пачкой по колонкам; encodeTags задает формат колонки tags
+func (s *ClickHouseStorage) insertIoCs(ctx context.Context, table string, iocs []models.IoCDto, encodeTags func([]string) (string, error)) error {
+	if len(iocs) == 0 {
+		return nil
+	}
+	if len(iocs) <= s.asyncInsertMaxRows {
+		ctx = asyncInsert(ctx)
+	}
+
+	n := len(iocs)
+	var (
+		ids, sources, types, values     = make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+		firstSeen, lastSeen             = make([]*time.Time, n), make([]*time.Time, n)
+		tags, additionalData            = make([]string, n), make([]string, n)
+		hidden                          = make([]uint8, n)
+		ipStart, ipEnd                  = make([]netip.Addr, n), make([]netip.Addr, n)
+		hosts, registrableDomains, tlds = make([]string, n), make([]string, n), make([]string, n)
+		countries, cities, asOrgs       = make([]string, n), make([]string, n), make([]string, n)
+		asns                            = make([]uint32, n)
+		tenants                         = make([]string, n)
+	)
+	for i, ioc := range iocs {
+		lowerTags := make([]string, len(ioc.Tags))
+		for j, tag := range ioc.Tags {
+			lowerTags[j] = strings.ToLower(tag)
+		}
+		encodedTags, err := encodeTags(lowerTags)
+		if err != nil {
+			return fmt.Errorf("failed to encode tags: %w", err)
+		}
+		data, err := json.Marshal(ioc.AdditionalData)
+		if err != nil {
+			return fmt.Errorf("failed to marshal additional_data: %w", err)
+		}
+
+		ids[i], sources[i], types[i], values[i] = ioc.ID, strings.ToLower(ioc.Source), strings.ToLower(ioc.Type), strings.ToLower(ioc.Value)
+		firstSeen[i], lastSeen[i] = ioc.FirstSeen, ioc.LastSeen
+		tags[i], additionalData[i] = encodedTags, string(data)
+		hidden[i] = boolToUInt8(ioc.Hidden)
+		ipStart[i], ipEnd[i] = ipColumns(ioc)
+		hosts[i], registrableDomains[i], tlds[i] = domainColumns(ioc)
+		countries[i], cities[i], asns[i], asOrgs[i] = ioc.Country, ioc.City, ioc.ASN, ioc.ASOrg
+		tenants[i] = ioc.Tenant
+	}
+
+	batch, err := s.conn.PrepareBatch(ctx, fmt.Sprintf("INSERT INTO %s (%s)", table, iocInsertColumns))
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+	defer batch.Abort()
+
+	columns := []interface{}{ids, sources, firstSeen, lastSeen, types, values, tags, additionalData, hidden, ipStart, ipEnd,
+		hosts, registrableDomains, tlds, countries, cities, asns, asOrgs, tenants}
+	for i, column := range columns {
+		if err := batch.Column(i).Append(column); err != nil {
+			return fmt.Errorf("failed to append column %d: %w", i, err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+	return nil
+}
+
+// asyncInsert - вставка через буфер async_insert сервера; ответ приходит после сброса буфера в таблицу,
+// поэтому несколько мелких пачек становятся одним part, а ошибка записи не теряется
+func asyncInsert(ctx context.Context) context.Context {
+	return clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"async_insert":          1,
+		"wait_for_async_insert": 1,
+	}))
+}
+
+// joinTags - теги через запятую, формат UnaryStore
+func joinTags(tags []string) (string, error) {
+	return strings.Join(tags, ","), nil
+}
+
+// jsonTags - теги JSON массивом, формат StreamStore
+func jsonTags(tags []string) (string, error) {
+	encoded, err := json.Marshal(tags)
+	return string(encoded), err
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const selectLifecycleColumns = `SELECT type, value, status, reason, changed_by, changed_at, manual_tags, tenant FROM ioc_lifecycle FINAL`
+
+// GetLifecycle - статус IoC с заметками; false, если аналитики его не трогали.
+// Собственная запись арендатора вызова важнее общей.
+func (s *ClickHouseStorage) GetLifecycle(ctx context.Context, iocType, value string) (models.Lifecycle, bool, error) {
+	where, args := tenantCondition(ctx)
+	records, err := s.queryLifecycle(ctx, selectLifecycleColumns+` WHERE `+where+` AND type = ? AND value = ? ORDER BY tenant DESC LIMIT 1`,
+		append(args, iocType, value)...)
+	if err != nil {
+		return models.Lifecycle{}, false, err
+	}
+	notes, err := s.notes(ctx, iocType, value)
+	if err != nil {
+		return models.Lifecycle{}, false, err
+	}
+	if len(records) == 0 {
+		return models.Lifecycle{Type: iocType, Value: value, Status: models.StatusActive, Notes: notes}, false, nil
+	}
+	records[0].Notes = notes
+	return records[0], true, nil
+}
+
+// ListLifecycle - видимые вызову записи lifecycle с указанными статусами (пусто - все); limit = 0 - без ограничения
+func (s *ClickHouseStorage) ListLifecycle(ctx context.Context, statuses []string, limit, offset int64) ([]models.Lifecycle, error) {
+	where, args := tenantCondition(ctx)
+	query := selectLifecycleColumns + ` WHERE ` + where
+	if len(statuses) > 0 {
+		query += ` AND status IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ") + `)`
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+	query += ` ORDER BY changed_at DESC, value`
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+	return s.queryLifecycle(ctx, query, args...)
+}
+
+// PutLifecycle - новая версия статуса IoC от имени арендатора вызова
+func (s *ClickHouseStorage) PutLifecycle(ctx context.Context, record models.Lifecycle) error {
+	query := `INSERT INTO ioc_lifecycle (type, value, status, reason, changed_by, changed_at, manual_tags, version, tenant) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	manualTags := record.ManualTags
+	if manualTags == nil {
+		manualTags = []string{}
+	}
+	_, err = stmt.ExecContext(ctx, record.Type, record.Value, record.Status, record.Reason, record.ChangedBy,
+		record.ChangedAt, manualTags, uint64(time.Now().UnixNano()), tenant.FromContext(ctx))
+	if err != nil {
+		s.logger.Error("Failed to write lifecycle", zap.String("value", record.Value), zap.Error(err))
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddNote - заметка аналитика к IoC от имени арендатора вызова
+func (s *ClickHouseStorage) AddNote(ctx context.Context, iocType, value string, note models.Note) error {
+	query := `INSERT INTO ioc_notes (id, type, value, author, text, created_at, tenant) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, note.ID, iocType, value, note.Author, note.Text, note.CreatedAt, tenant.FromContext(ctx)); err != nil {
+		s.logger.Error("Failed to write note", zap.String("value", value), zap.Error(err))
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *ClickHouseStorage) notes(ctx context.Context, iocType, value string) ([]models.Note, error) {
+	where, args := tenantCondition(ctx)
+	query := `SELECT id, author, text, created_at FROM ioc_notes WHERE ` + where + ` AND type = ? AND value = ? ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query, append(args, iocType, value)...)
+	if err != nil {
+		s.logger.Error("Failed to load notes", zap.Error(err))
+		return nil, fmt.Errorf("failed to load notes: %v", err)
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.ID, &note.Author, &note.Text, &note.CreatedAt); err != nil {
+			s.logger.Error("Failed to scan note row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan note row: %v", err)
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func (s *ClickHouseStorage) queryLifecycle(ctx context.Context, query string, args ...interface{}) ([]models.Lifecycle, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Failed to load lifecycle", zap.Error(err))
+		return nil, fmt.Errorf("failed to load lifecycle: %v", err)
+	}
+	defer rows.Close()
+
+	var records []models.Lifecycle
+	for rows.Next() {
+		var record models.Lifecycle
+		if err := rows.Scan(&record.Type, &record.Value, &record.Status, &record.Reason, &record.ChangedBy, &record.ChangedAt, &record.ManualTags, &record.Tenant); err != nil {
+			s.logger.Error("Failed to scan lifecycle row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan lifecycle row: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,617 @@
+package storage
+
+import (
+	"awesomeProject/internal/domains"
+	"awesomeProject/internal/iprange"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// MemoryStorage - хранилище в памяти процесса для локального запуска и тестов сервиса без внешних баз.
+// Фильтры, пагинация и счетчики совпадают с ClickHouseStorage и PostgresStorage; повторы значения
+// схлопываются сразу при записи, как в PostgreSQL. Данные не переживают перезапуск.
+type MemoryStorage struct {
+	logger *logger.CustomZapLogger
+
+	mu            sync.RWMutex
+	iocs          map[memoryKey]*memoryIoC
+	sources       map[memorySourceKey]*memorySource // ioc_sources: сведения источников для SourceReport
+	allowlist     map[string]models.AllowlistEntry
+	relationships map[memoryRelationKey]models.Relationship
+	sightings     map[memoryValueKey]*memorySightings // По значению IoC и арендатору
+	lifecycle     map[memoryLifecycleKey]models.Lifecycle
+	notes         map[memoryLifecycleKey][]models.Note
+
+	audit              []models.AuditRecord
+	auditRetentionDays int
+}
+
+// memoryKey - ключ ioc_data: собственный IoC арендатора и общий IoC с тем же значением - разные строки
+type memoryKey struct{ value, tenant string }
+
+// Записи аналитиков и sightings, как и ioc_data, различаются по арендатору, который их записал
+type memoryLifecycleKey struct{ iocType, value, tenant string }
+
+type memoryRelationKey struct{ fromValue, kind, toValue, fromType, toType, source, tenant string }
+
+type memoryValueKey struct{ value, tenant string }
+
+type memorySightings struct {
+	count       int64
+	first, last time.Time
+}
+
+// memoryIoC - строка ioc_data в том виде, в котором ее хранят базы: значения в нижнем регистре,
+// теги и additional_data закодированы, колонки диапазонов и доменов вычислены при записи
+type memoryIoC struct {
+	id, source, iocType, value   string
+	tags, additionalData         string
+	firstSeen, lastSeen, addedAt time.Time
+	hidden                       bool
+	ipStart, ipEnd               netip.Addr
+	host, registrableDomain, tld string
+	country, city, asOrg         string
+	asn                          uint32
+	tenant                       string
+}
+
+// NewMemoryStorage - пустое хранилище в памяти
+func NewMemoryStorage(logger *logger.CustomZapLogger) *MemoryStorage {
+	return &MemoryStorage{
+		logger:        logger,
+		iocs:          make(map[memoryKey]*memoryIoC),
+		sources:       make(map[memorySourceKey]*memorySource),
+		allowlist:     make(map[string]models.AllowlistEntry),
+		relationships: make(map[memoryRelationKey]models.Relationship),
+		sightings:     make(map[memoryValueKey]*memorySightings),
+		lifecycle:     make(map[memoryLifecycleKey]models.Lifecycle),
+		notes:         make(map[memoryLifecycleKey][]models.Note),
+	}
+}
+
+// Close - закрывать нечего, данные остаются доступны до конца процесса
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// UnaryStore - пишет пачку целиком или не пишет ничего
+func (s *MemoryStorage) UnaryStore(ctx context.Context, iocs []models.IoCDto) error {
+	if err := s.insertIoCs(ctx, iocs, joinTags); err != nil {
+		s.logger.Error(fmt.Sprintf("failed to insert IoCs %v", err))
+		return err
+	}
+	return nil
+}
+
+// StreamStore - пишет IoC из канала пачками по streamInsertRows; как и в базах,
+// при ошибке или отмене уже записанные пачки остаются
+func (s *MemoryStorage) StreamStore(ctx context.Context, stream <-chan models.IoCDto) error {
+	batch := make([]models.IoCDto, 0, streamInsertRows)
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Warn("StreamStore: Context canceled")
+			return ctx.Err()
+
+		case ioc, open := <-stream:
+			if !open {
+				return s.insertIoCs(ctx, batch, jsonTags)
+			}
+			batch = append(batch, ioc)
+			if len(batch) == streamInsertRows {
+				if err := s.insertIoCs(ctx, batch, jsonTags); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// insertIoCs - строка заменяется, если last_seen новой записи не меньше, - как ReplacingMergeTree(last_seen) после слияния
+func (s *MemoryStorage) insertIoCs(ctx context.Context, iocs []models.IoCDto, encodeTags func([]string) (string, error)) error {
+	if len(iocs) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	addedAt := time.Now().UTC().Truncate(time.Second)
+	rows := make([]*memoryIoC, 0, len(iocs))
+	for _, ioc := range iocs {
+		row, err := newMemoryIoC(ioc, addedAt, encodeTags)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range rows {
+		s.recordSource(row)
+		key := memoryKey{row.value, row.tenant}
+		if existing, ok := s.iocs[key]; ok && row.lastSeen.Before(existing.lastSeen) {
+			continue
+		}
+		s.iocs[key] = row
+	}
+	return nil
+}
+
+// newMemoryIoC - строка хранилища из IoC: те же преобразования, что при записи в базы
+func newMemoryIoC(ioc models.IoCDto, addedAt time.Time, encodeTags func([]string) (string, error)) (*memoryIoC, error) {
+	lowerTags := make([]string, len(ioc.Tags))
+	for i, tag := range ioc.Tags {
+		lowerTags[i] = strings.ToLower(tag)
+	}
+	tags, err := encodeTags(lowerTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tags: %w", err)
+	}
+	data, err := json.Marshal(ioc.AdditionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal additional_data: %w", err)
+	}
+	// id хранится как UUID, поэтому читается в каноническом виде
+	id, err := uuid.Parse(ioc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IoC id %q: %w", ioc.ID, err)
+	}
+
+	row := &memoryIoC{
+		id:             id.String(),
+		source:         strings.ToLower(ioc.Source),
+		iocType:        strings.ToLower(ioc.Type),
+		value:          strings.ToLower(ioc.Value),
+		tags:           tags,
+		additionalData: string(data),
+		firstSeen:      storedTime(ioc.FirstSeen),
+		lastSeen:       storedTime(ioc.LastSeen),
+		addedAt:        addedAt,
+		hidden:         ioc.Hidden,
+		country:        ioc.Country,
+		city:           ioc.City,
+		asOrg:          ioc.ASOrg,
+		asn:            ioc.ASN,
+		tenant:         ioc.Tenant,
+	}
+	row.ipStart, row.ipEnd = ipColumns(ioc)
+	row.host, row.registrableDomain, row.tld = domainColumns(ioc)
+	return row, nil
+}
+
+// UnaryLoad - выборка IoC с пагинацией
+func (s *MemoryStorage) UnaryLoad(ctx context.Context, request models.LoadRequest) ([]models.IoCDto, error) {
+	return s.load(ctx, request)
+}
+
+// StreamLoad - выборка целиком до отдачи в канал, поэтому запись во время чтения ее не меняет
+func (s *MemoryStorage) StreamLoad(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, chan error, error) {
+	iocs, err := s.load(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output := make(chan *models.IoCDto, 100)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(output)
+		for i := range iocs {
+			select {
+			case output <- &iocs[i]:
+			case <-ctx.Done():
+				s.logger.Warn("StreamLoad canceled due to context timeout or cancellation")
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return output, errs, nil
+}
+
+// load - строки, прошедшие фильтр, в порядке ключа (value, tenant) или SortByAdded, с пагинацией
+func (s *MemoryStorage) load(ctx context.Context, request models.LoadRequest) ([]models.IoCDto, error) {
+	match, err := memoryWhere(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	caller := tenant.FromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var rows []*memoryIoC
+	for _, row := range s.iocs {
+		if lifecycle, _ := s.lifecycleOf(caller, row.iocType, row.value); match(row, lifecycle.Status) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if request.SortByAdded && !a.addedAt.Equal(b.addedAt) {
+			return a.addedAt.Before(b.addedAt)
+		}
+		if a.value != b.value {
+			return a.value < b.value
+		}
+		return a.tenant < b.tenant
+	})
+	if request.Limit > 0 {
+		start, end := memoryPage(len(rows), request.Offset, request.Limit)
+		rows = rows[start:end]
+	}
+
+	result := make([]models.IoCDto, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, s.toIoC(row, caller, request.IncludeSightings))
+	}
+	return result, nil
+}
+
+// toIoC - IoC для выдачи вызову арендатора caller: статус и ручные теги lifecycle, агрегаты sightings по запросу
+func (s *MemoryStorage) toIoC(row *memoryIoC, caller string, includeSightings bool) models.IoCDto {
+	firstSeen, lastSeen, addedAt := row.firstSeen, row.lastSeen, row.addedAt
+	ioc := models.IoCDto{
+		ID:        row.id,
+		Source:    row.source,
+		FirstSeen: &firstSeen,
+		LastSeen:  &lastSeen,
+		Type:      row.iocType,
+		Value:     row.value,
+		AddedAt:   &addedAt,
+		Hidden:    row.hidden,
+		Country:   row.country,
+		City:      row.city,
+		ASN:       row.asn,
+		ASOrg:     row.asOrg,
+		Tenant:    row.tenant,
+	}
+	lifecycle, _ := s.lifecycleOf(caller, row.iocType, row.value)
+	ioc.Status = lifecycle.Status
+	if ioc.Status == "" {
+		ioc.Status = models.StatusActive
+	}
+	if hits := s.sightingsOf(caller, row.value); includeSightings && hits.count > 0 {
+		first, last := hits.first, hits.last
+		ioc.SightingCount, ioc.FirstSighted, ioc.LastSighted = hits.count, &first, &last
+	}
+
+	ioc.Tags = mergeTags(decodeTags(row.tags), lifecycle.ManualTags)
+	if err := json.Unmarshal([]byte(row.additionalData), &ioc.AdditionalData); err != nil {
+		s.logger.Warn("Failed to unmarshal additional_data JSON", zap.Error(err))
+	}
+	return ioc
+}
+
+// memoryWhere - условия buildWhere для строк в памяти; status - статус lifecycle строки (пусто - записи нет)
+func memoryWhere(ctx context.Context, request models.LoadRequest) (func(row *memoryIoC, status string) bool, error) {
+	visible := memoryTenant(ctx)
+	conditions := []func(row *memoryIoC, status string) bool{func(row *memoryIoC, _ string) bool { return visible(row) }}
+	where := func(condition func(row *memoryIoC) bool) {
+		conditions = append(conditions, func(row *memoryIoC, _ string) bool { return condition(row) })
+	}
+
+	if !request.IncludeHidden {
+		where(func(row *memoryIoC) bool { return !row.hidden })
+	}
+	// По умолчанию отдаются только активные IoC; пустой статус - записи lifecycle нет
+	statuses := make(map[string]bool)
+	for _, status := range request.Statuses {
+		statuses[strings.ToLower(strings.TrimSpace(status))] = true
+	}
+	if len(request.Statuses) == 0 {
+		statuses[models.StatusActive] = true
+	}
+	if statuses[models.StatusActive] {
+		statuses[""] = true
+	}
+	conditions = append(conditions, func(_ *memoryIoC, status string) bool { return statuses[status] })
+
+	if request.Filter != "" {
+		filter := likePattern("%" + request.Filter + "%")
+		where(func(row *memoryIoC) bool {
+			return filter.MatchString(row.id) || filter.MatchString(row.source) || filter.MatchString(row.iocType) ||
+				filter.MatchString(row.value) || filter.MatchString(row.tags)
+		})
+	}
+	if request.Type != "" {
+		iocType := strings.ToLower(request.Type)
+		where(func(row *memoryIoC) bool { return row.iocType == iocType })
+	}
+	if request.Source != "" {
+		source := strings.ToLower(request.Source)
+		where(func(row *memoryIoC) bool { return row.source == source })
+	}
+	if request.AddedAfter != nil {
+		after := *request.AddedAfter
+		where(func(row *memoryIoC) bool { return row.addedAt.After(after) })
+	}
+	if request.AddedBefore != nil {
+		before := *request.AddedBefore
+		where(func(row *memoryIoC) bool { return row.addedAt.Before(before) })
+	}
+
+	// Диапазонные фильтры работают по ip_start/ip_end, заполненным только у IP IoC
+	if len(request.IPWithin) > 0 {
+		var ranges []iprange.Range
+		for _, value := range request.IPWithin {
+			r, err := iprange.Parse(value)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, r)
+		}
+		where(func(row *memoryIoC) bool {
+			if row.iocType != models.TypeIP {
+				return false
+			}
+			for _, r := range ranges {
+				if row.ipStart.Compare(r.Start) >= 0 && row.ipEnd.Compare(r.End) <= 0 {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if request.IPContains != "" {
+		r, err := iprange.Parse(request.IPContains)
+		if err != nil {
+			return nil, err
+		}
+		where(func(row *memoryIoC) bool {
+			return row.iocType == models.TypeIP && row.ipStart.Compare(r.Start) <= 0 && row.ipEnd.Compare(r.End) >= 0
+		})
+	}
+
+	// Иерархия доменов по host/registrable_domain/tld, заполненным у DOMAIN и URL IoC
+	if request.RegistrableDomain != "" {
+		registrable := domains.RegistrableDomain(request.RegistrableDomain)
+		where(func(row *memoryIoC) bool { return row.registrableDomain == registrable })
+	}
+	if request.SubdomainOf != "" {
+		parent := domains.Normalize(request.SubdomainOf)
+		where(func(row *memoryIoC) bool { return row.host == parent || strings.HasSuffix(row.host, "."+parent) })
+	}
+	if request.TLD != "" {
+		tld := domains.Normalize(request.TLD)
+		where(func(row *memoryIoC) bool { return row.tld == tld })
+	}
+	if request.Country != "" {
+		country := strings.ToUpper(request.Country)
+		where(func(row *memoryIoC) bool { return row.country == country })
+	}
+	if request.ASN != 0 {
+		asn := request.ASN
+		where(func(row *memoryIoC) bool { return row.asn == asn })
+	}
+
+	return func(row *memoryIoC, status string) bool {
+		for _, condition := range conditions {
+			if !condition(row, status) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// memoryTenant - условие tenantCondition для строк ioc_data
+func memoryTenant(ctx context.Context) func(row *memoryIoC) bool {
+	owners := memoryOwners(ctx)
+	return func(row *memoryIoC) bool { return owners(row.tenant) }
+}
+
+// memoryOwners - условие tenantCondition: арендатор видит общие и свои записи, без арендатора - только общие
+func memoryOwners(ctx context.Context) func(owner string) bool {
+	if tenant.AllTenants(ctx) {
+		return func(string) bool { return true }
+	}
+	if id := tenant.FromContext(ctx); id != tenant.Shared {
+		return func(owner string) bool { return owner == tenant.Shared || owner == id }
+	}
+	return func(owner string) bool { return owner == tenant.Shared }
+}
+
+// lifecycleOf - запись lifecycle, действующая для арендатора caller: собственная важнее общей; вызывается под s.mu
+func (s *MemoryStorage) lifecycleOf(caller, iocType, value string) (models.Lifecycle, bool) {
+	if record, ok := s.lifecycle[memoryLifecycleKey{iocType, value, caller}]; ok {
+		return record, true
+	}
+	record, ok := s.lifecycle[memoryLifecycleKey{iocType, value, tenant.Shared}]
+	return record, ok
+}
+
+// sightingsOf - агрегаты sightings значения, видимые арендатору caller: общие и собственные; вызывается под s.mu
+func (s *MemoryStorage) sightingsOf(caller, value string) memorySightings {
+	owners := []string{tenant.Shared}
+	if caller != tenant.Shared {
+		owners = append(owners, caller)
+	}
+	var total memorySightings
+	for _, owner := range owners {
+		hits := s.sightings[memoryValueKey{value, owner}]
+		if hits == nil {
+			continue
+		}
+		if total.count == 0 || hits.first.Before(total.first) {
+			total.first = hits.first
+		}
+		if total.count == 0 || hits.last.After(total.last) {
+			total.last = hits.last
+		}
+		total.count += hits.count
+	}
+	return total
+}
+
+// likePattern - шаблон SQL LIKE: % - любая строка, _ - один символ, \ экранирует следующий символ
+func likePattern(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString(`(?s)^`)
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(`.*`)
+		case r == '_':
+			expr.WriteString(`.`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString(`$`)
+	return regexp.MustCompile(expr.String())
+}
+
+// memoryPage - границы страницы LIMIT limit OFFSET offset в выборке из n строк
+func memoryPage(n int, offset, limit int64) (int, int) {
+	start := min(max(offset, 0), int64(n))
+	end := min(start+max(limit, 0), int64(n))
+	return int(start), int(end)
+}
+
+// visible - строки, которые видит арендатор из контекста
+func (s *MemoryStorage) visible(ctx context.Context) []*memoryIoC {
+	match := memoryTenant(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var rows []*memoryIoC
+	for _, row := range s.iocs {
+		if match(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// countBy - число видимых строк по ключу; пустой ключ строку не учитывает
+func (s *MemoryStorage) countBy(ctx context.Context, key func(row *memoryIoC) string) map[string]int64 {
+	result := make(map[string]int64)
+	for _, row := range s.visible(ctx) {
+		if k := key(row); k != "" {
+			result[k]++
+		}
+	}
+	return result
+}
+
+func (s *MemoryStorage) AllIocsCount(ctx context.Context) (int64, error) {
+	return int64(len(s.visible(ctx))), nil
+}
+
+func (s *MemoryStorage) CountByType(ctx context.Context) (map[string]int64, error) {
+	return s.countBy(ctx, func(row *memoryIoC) string { return row.iocType }), nil
+}
+
+func (s *MemoryStorage) CountSpecificType(ctx context.Context, typeName string) (int64, error) {
+	return s.countBy(ctx, func(row *memoryIoC) string { return row.iocType })[typeName], nil
+}
+
+func (s *MemoryStorage) CountBySource(ctx context.Context) (map[string]int64, error) {
+	return s.countBy(ctx, func(row *memoryIoC) string { return row.source }), nil
+}
+
+func (s *MemoryStorage) CountSpecificSource(ctx context.Context, sourceName string) (int64, error) {
+	return s.countBy(ctx, func(row *memoryIoC) string { return row.source })[sourceName], nil
+}
+
+func (s *MemoryStorage) CountTypesBySource(ctx context.Context) (map[string]map[string]int64, error) {
+	result := make(map[string]map[string]int64)
+	for _, row := range s.visible(ctx) {
+		if _, exists := result[row.source]; !exists {
+			result[row.source] = make(map[string]int64)
+		}
+		result[row.source][row.iocType]++
+	}
+	return result, nil
+}
+
+func (s *MemoryStorage) CountBySourceAndType(ctx context.Context, sourceName string) (map[string]int64, error) {
+	return s.countBy(ctx, func(row *memoryIoC) string {
+		if row.source != sourceName {
+			return ""
+		}
+		return row.iocType
+	}), nil
+}
+
+func (s *MemoryStorage) CountByTypeAndSource(ctx context.Context, typeName string) (map[string]int64, error) {
+	return s.countBy(ctx, func(row *memoryIoC) string {
+		if row.iocType != typeName {
+			return ""
+		}
+		return row.source
+	}), nil
+}
+
+// CountByCountry - количество IP IoC по странам (без необогащенных)
+func (s *MemoryStorage) CountByCountry(ctx context.Context) (map[string]int64, error) {
+	return s.countBy(ctx, func(row *memoryIoC) string { return row.country }), nil
+}
+
+// CountByASN - количество IP IoC по автономным системам по убыванию; limit = 0 - все.
+// Организация - наименьшая из записанных, как min(as_org) в PostgreSQL.
+func (s *MemoryStorage) CountByASN(ctx context.Context, limit int64) ([]models.ASNCount, error) {
+	counts := make(map[uint32]*models.ASNCount)
+	for _, row := range s.visible(ctx) {
+		if row.asn == 0 {
+			continue
+		}
+		count, ok := counts[row.asn]
+		if !ok {
+			count = &models.ASNCount{ASN: row.asn, Org: row.asOrg}
+			counts[row.asn] = count
+		}
+		if row.asOrg < count.Org {
+			count.Org = row.asOrg
+		}
+		count.Count++
+	}
+
+	result := make([]models.ASNCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].ASN < result[j].ASN
+	})
+	if limit > 0 && int64(len(result)) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// CountTenant - число собственных значений арендатора для проверки квоты
+func (s *MemoryStorage) CountTenant(ctx context.Context, id string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var count int64
+	for key := range s.iocs {
+		if key.tenant == id {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"awesomeProject/internal/iprange"
+	"awesomeProject/models"
+	"context"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memorySourceKey - ключ ioc_sources: значение от каждого источника хранится отдельно
+type memorySourceKey struct{ value, source, tenant string }
+
+// memorySource - строка ioc_sources: самые ранние reported_at и added_at, самый поздний last_seen
+type memorySource struct {
+	iocType, host                 string
+	ipStart, ipEnd                netip.Addr
+	reportedAt, addedAt, lastSeen time.Time
+}
+
+// recordSource - сведения источника о строке, в том числе не заменившей более новую; вызывается под s.mu
+func (s *MemoryStorage) recordSource(row *memoryIoC) {
+	// Когда источник сообщил об IoC: first_seen фида, если он есть, иначе время записи
+	reportedAt := row.addedAt
+	if row.firstSeen.After(time.Unix(0, 0)) {
+		reportedAt = row.firstSeen
+	}
+	key := memorySourceKey{row.value, row.source, row.tenant}
+	source, ok := s.sources[key]
+	if !ok {
+		s.sources[key] = &memorySource{iocType: row.iocType, host: row.host, ipStart: row.ipStart, ipEnd: row.ipEnd,
+			reportedAt: reportedAt, addedAt: row.addedAt, lastSeen: row.lastSeen}
+		return
+	}
+	if reportedAt.Before(source.reportedAt) {
+		source.reportedAt = reportedAt
+	}
+	if row.addedAt.Before(source.addedAt) {
+		source.addedAt = row.addedAt
+	}
+	if row.lastSeen.After(source.lastSeen) {
+		source.lastSeen = row.lastSeen
+	}
+}
+
+// memoryAllowlisted - совпадает ли значение с записью allowlist; правила те же, что у allowlistCondition
+func memoryAllowlisted(entries []models.AllowlistEntry, value string, source *memorySource) bool {
+	for _, entry := range entries {
+		switch entry.Kind {
+		case models.AllowlistValue:
+			if value == entry.Value || source.host == entry.Value {
+				return true
+			}
+		case models.AllowlistSuffix:
+			if source.host == entry.Value || strings.HasSuffix(source.host, "."+entry.Value) {
+				return true
+			}
+		case models.AllowlistCIDR:
+			r, err := iprange.Parse(entry.Value)
+			if err == nil && source.iocType == models.TypeIP && source.ipStart.Compare(r.Start) >= 0 && source.ipEnd.Compare(r.End) <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SourceReport - показатели качества источников; churnDays - глубина истории churn в днях.
+// Считается по ioc_sources по тем же правилам, что отчет ClickHouseStorage: значения уникальны по value,
+// пары источников упорядочены побайтно, медиана отставания - элемент n/2 по возрастанию.
+func (s *MemoryStorage) SourceReport(ctx context.Context, churnDays int) (models.SourceReport, error) {
+	report := models.SourceReport{Sources: make(map[string]*models.SourceStats), GeneratedAt: time.Now().UTC()}
+	stats := func(source string) *models.SourceStats {
+		if report.Sources[source] == nil {
+			report.Sources[source] = &models.SourceStats{}
+		}
+		return report.Sources[source]
+	}
+
+	// Строки одного значения от одного источника (общая и собственная арендатора) сводятся вместе
+	type sourceValue struct{ source, value string }
+	bySourceValue := make(map[sourceValue]*memorySource)
+	sourcesOf := make(map[string][]string)
+	allowlisted := make(map[string]bool)
+	visible := memoryOwners(ctx)
+	s.mu.RLock()
+	entries := make([]models.AllowlistEntry, 0, len(s.allowlist))
+	for _, entry := range s.allowlist {
+		entries = append(entries, entry)
+	}
+	for key, row := range s.sources {
+		if !visible(key.tenant) {
+			continue
+		}
+		k := sourceValue{key.source, key.value}
+		r, ok := bySourceValue[k]
+		if !ok {
+			copied := *row
+			bySourceValue[k] = &copied
+			sourcesOf[key.value] = append(sourcesOf[key.value], key.source)
+			r = &copied
+		}
+		if row.reportedAt.Before(r.reportedAt) {
+			r.reportedAt = row.reportedAt
+		}
+		if row.addedAt.Before(r.addedAt) {
+			r.addedAt = row.addedAt
+		}
+		if row.lastSeen.After(r.lastSeen) {
+			r.lastSeen = row.lastSeen
+		}
+		if memoryAllowlisted(entries, key.value, row) {
+			allowlisted[key.value] = true
+		}
+	}
+	s.mu.RUnlock()
+
+	// Уникальные и общие значения, allowlisted; попарное пересечение и отставание по общим значениям
+	type pair struct{ a, b string }
+	overlaps := make(map[pair]int64)
+	lags := make(map[string][]int64)
+	for value, sources := range sourcesOf {
+		for _, source := range sources {
+			st := stats(source)
+			st.Total++
+			if len(sources) == 1 {
+				st.Unique++
+			} else {
+				st.Shared++
+			}
+			if allowlisted[value] {
+				st.Allowlisted++
+			}
+		}
+		if len(sources) == 1 {
+			continue
+		}
+
+		sort.Strings(sources)
+		first := bySourceValue[sourceValue{sources[0], value}].reportedAt
+		for i, source := range sources {
+			for _, other := range sources[i+1:] {
+				overlaps[pair{source, other}]++
+			}
+			if reportedAt := bySourceValue[sourceValue{source, value}].reportedAt; reportedAt.Before(first) {
+				first = reportedAt
+			}
+		}
+		for _, source := range sources {
+			lags[source] = append(lags[source], bySourceValue[sourceValue{source, value}].reportedAt.Unix()-first.Unix())
+		}
+	}
+	for _, st := range report.Sources {
+		if st.Total > 0 {
+			st.AllowlistedShare = float64(st.Allowlisted) / float64(st.Total)
+		}
+	}
+
+	for p, shared := range overlaps {
+		report.Overlaps = append(report.Overlaps, models.SourceOverlap{SourceA: p.a, SourceB: p.b, Shared: shared})
+	}
+	sort.Slice(report.Overlaps, func(i, j int) bool {
+		a, b := report.Overlaps[i], report.Overlaps[j]
+		if a.Shared != b.Shared {
+			return a.Shared > b.Shared
+		}
+		if a.SourceA != b.SourceA {
+			return a.SourceA < b.SourceA
+		}
+		return a.SourceB < b.SourceB
+	})
+
+	for source, sourceLags := range lags {
+		sort.Slice(sourceLags, func(i, j int) bool { return sourceLags[i] < sourceLags[j] })
+		st := stats(source)
+		for _, lag := range sourceLags {
+			if lag == 0 {
+				st.FirstReported++
+			}
+		}
+		st.MedianLag = float64(sourceLags[len(sourceLags)/2])
+	}
+
+	// Churn: новые значения по дню первой записи, пропавшие - по дню, когда источник сообщал о них последний раз
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, -churnDays)
+	type sourceDay struct {
+		source string
+		day    time.Time
+	}
+	churn := make(map[sourceDay]*models.DailyChurn)
+	add := func(source string, day time.Time, added, removed int64) {
+		if day.Before(since) {
+			return
+		}
+		key := sourceDay{source, day}
+		if churn[key] == nil {
+			churn[key] = &models.DailyChurn{Date: day.Format(time.DateOnly)}
+		}
+		churn[key].Added += added
+		churn[key].Removed += removed
+	}
+	for key, r := range bySourceValue {
+		add(key.source, r.addedAt.Truncate(24*time.Hour), 1, 0)
+		if lastDay := r.lastSeen.Truncate(24 * time.Hour); r.lastSeen.After(time.Unix(0, 0)) && lastDay.Before(today) {
+			add(key.source, lastDay, 0, 1)
+		}
+	}
+	days := make([]sourceDay, 0, len(churn))
+	for key := range churn {
+		days = append(days, key)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].source != days[j].source {
+			return days[i].source < days[j].source
+		}
+		return days[i].day.Before(days[j].day)
+	})
+	for _, key := range days {
+		st := stats(key.source)
+		st.Churn = append(st.Churn, *churn[key])
+	}
+	return report, nil
+}
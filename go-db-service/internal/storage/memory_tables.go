@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"sort"
+	"time"
+)
+
+// ListAllowlist - актуальные записи allowlist по возрастанию id
+func (s *MemoryStorage) ListAllowlist(ctx context.Context) ([]models.AllowlistEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var entries []models.AllowlistEntry
+	for _, entry := range s.allowlist {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// StoreAllowlistEntries - создание или изменение записей allowlist по id
+func (s *MemoryStorage) StoreAllowlistEntries(ctx context.Context, entries []models.AllowlistEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range entries {
+		entry.CreatedAt, entry.UpdatedAt = entry.CreatedAt.UTC(), entry.UpdatedAt.UTC()
+		s.allowlist[entry.ID] = entry
+	}
+	return nil
+}
+
+// DeleteAllowlistEntry - удаление записи allowlist
+func (s *MemoryStorage) DeleteAllowlistEntry(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.allowlist, id)
+	return nil
+}
+
+// StoreRelationships - запись связей между индикаторами от имени арендатора вызова;
+// повторная связь обновляет updated_at, если он новее
+func (s *MemoryStorage) StoreRelationships(ctx context.Context, relationships []models.Relationship) error {
+	owner := tenant.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rel := range relationships {
+		key := memoryRelationKey{rel.FromValue, rel.Kind, rel.ToValue, rel.FromType, rel.ToType, rel.Source, owner}
+		if existing, ok := s.relationships[key]; ok && rel.UpdatedAt.Before(existing.UpdatedAt) {
+			continue
+		}
+		s.relationships[key] = models.Relationship{FromType: rel.FromType, FromValue: rel.FromValue, Kind: rel.Kind,
+			ToType: rel.ToType, ToValue: rel.ToValue, Source: rel.Source, UpdatedAt: rel.UpdatedAt.UTC()}
+	}
+	return nil
+}
+
+// RelationshipsOf - видимые вызову связи, у которых любой из концов входит в values, новые первыми
+func (s *MemoryStorage) RelationshipsOf(ctx context.Context, values []string, limit int) ([]models.Relationship, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[string]bool, len(values))
+	for _, value := range values {
+		wanted[value] = true
+	}
+
+	owners := memoryOwners(ctx)
+	s.mu.RLock()
+	var result []models.Relationship
+	for key, rel := range s.relationships {
+		if owners(key.tenant) && (wanted[rel.FromValue] || wanted[rel.ToValue]) {
+			result = append(result, rel)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].UpdatedAt.After(result[j].UpdatedAt) })
+	if limit >= 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// IoCsByID - тип и значение IoC по id; отсутствующие id в результат не попадают
+func (s *MemoryStorage) IoCsByID(ctx context.Context, ids []string) (map[string]models.IoCDto, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	result := make(map[string]models.IoCDto, len(ids))
+	for _, row := range s.visible(ctx) {
+		if wanted[row.id] {
+			result[row.id] = models.IoCDto{ID: row.id, Type: row.iocType, Value: row.value}
+		}
+	}
+	return result, nil
+}
+
+// RecordSightings - запись обнаружений индикаторов от имени арендатора вызова;
+// хранятся сразу агрегаты по значению
+func (s *MemoryStorage) RecordSightings(ctx context.Context, sightings []models.Sighting) error {
+	owner := tenant.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sighting := range sightings {
+		seenAt := sighting.SeenAt.UTC()
+		key := memoryValueKey{sighting.Value, owner}
+		hits, ok := s.sightings[key]
+		if !ok {
+			hits = &memorySightings{first: seenAt, last: seenAt}
+			s.sightings[key] = hits
+		}
+		hits.count += int64(sighting.Count)
+		if seenAt.Before(hits.first) {
+			hits.first = seenAt
+		}
+		if seenAt.After(hits.last) {
+			hits.last = seenAt
+		}
+	}
+	return nil
+}
+
+// SightingsBySource - сколько IoC каждого источника встретилось в sightings и сколько было срабатываний
+func (s *MemoryStorage) SightingsBySource(ctx context.Context) (map[string]models.SourceSightings, error) {
+	type sourceValue struct{ source, value string }
+	seen := make(map[sourceValue]bool)
+	result := make(map[string]models.SourceSightings)
+	rows := s.visible(ctx)
+	caller := tenant.FromContext(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, row := range rows {
+		hits := s.sightingsOf(caller, row.value)
+		key := sourceValue{row.source, row.value}
+		if row.hidden || hits.count == 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		counts := result[row.source]
+		counts.IoCs++
+		counts.Sightings += hits.count
+		result[row.source] = counts
+	}
+	return result, nil
+}
+
+// GetLifecycle - статус IoC с заметками; false, если аналитики его не трогали.
+// Собственная запись арендатора вызова важнее общей.
+func (s *MemoryStorage) GetLifecycle(ctx context.Context, iocType, value string) (models.Lifecycle, bool, error) {
+	caller, owners := tenant.FromContext(ctx), memoryOwners(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var notes []models.Note
+	for key, keyNotes := range s.notes {
+		if key.iocType == iocType && key.value == value && owners(key.tenant) {
+			notes = append(notes, keyNotes...)
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].CreatedAt.Before(notes[j].CreatedAt) })
+
+	record, ok := s.lifecycleOf(caller, iocType, value)
+	if !ok {
+		return models.Lifecycle{Type: iocType, Value: value, Status: models.StatusActive, Notes: notes}, false, nil
+	}
+	record.ManualTags = append([]string{}, record.ManualTags...)
+	record.Notes = notes
+	return record, true, nil
+}
+
+// ListLifecycle - видимые вызову записи lifecycle с указанными статусами (пусто - все); limit = 0 - без ограничения
+func (s *MemoryStorage) ListLifecycle(ctx context.Context, statuses []string, limit, offset int64) ([]models.Lifecycle, error) {
+	wanted := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	owners := memoryOwners(ctx)
+	s.mu.RLock()
+	var records []models.Lifecycle
+	for _, record := range s.lifecycle {
+		if owners(record.Tenant) && (len(statuses) == 0 || wanted[record.Status]) {
+			record.ManualTags = append([]string{}, record.ManualTags...)
+			records = append(records, record)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].ChangedAt.Equal(records[j].ChangedAt) {
+			return records[i].ChangedAt.After(records[j].ChangedAt)
+		}
+		if records[i].Value != records[j].Value {
+			return records[i].Value < records[j].Value
+		}
+		return records[i].Tenant < records[j].Tenant
+	})
+	if limit > 0 {
+		start, end := memoryPage(len(records), offset, limit)
+		records = records[start:end]
+	}
+	return records, nil
+}
+
+// PutLifecycle - статус IoC от имени арендатора вызова; заменяет предыдущий
+func (s *MemoryStorage) PutLifecycle(ctx context.Context, record models.Lifecycle) error {
+	record.ChangedAt = record.ChangedAt.UTC()
+	record.ManualTags = append([]string{}, record.ManualTags...)
+	record.Notes = nil
+	record.Tenant = tenant.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lifecycle[memoryLifecycleKey{record.Type, record.Value, record.Tenant}] = record
+	return nil
+}
+
+// AddNote - заметка аналитика к IoC от имени арендатора вызова
+func (s *MemoryStorage) AddNote(ctx context.Context, iocType, value string, note models.Note) error {
+	note.CreatedAt = note.CreatedAt.UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := memoryLifecycleKey{iocType, value, tenant.FromContext(ctx)}
+	s.notes[key] = append(s.notes[key], note)
+	return nil
+}
+
+// StoreAuditRecords - добавление записей в журнал аудита; записи старше срока хранения удаляются здесь же
+func (s *MemoryStorage) StoreAuditRecords(ctx context.Context, records []models.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		record.Time = record.Time.UTC()
+		s.audit = append(s.audit, record)
+	}
+	s.purgeAudit()
+	return nil
+}
+
+// QueryAudit - записи журнала аудита по фильтру, новые первыми
+func (s *MemoryStorage) QueryAudit(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, error) {
+	s.mu.RLock()
+	var records []models.AuditRecord
+	for _, record := range s.audit {
+		switch {
+		case query.Actor != "" && record.Actor != query.Actor,
+			query.Method != "" && record.Method != query.Method,
+			query.Outcome != "" && record.Outcome != query.Outcome,
+			!query.From.IsZero() && record.Time.Before(query.From),
+			!query.To.IsZero() && !record.Time.Before(query.To):
+			continue
+		}
+		records = append(records, record)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Time.Equal(records[j].Time) {
+			return records[i].Time.After(records[j].Time)
+		}
+		return records[i].ID < records[j].ID
+	})
+	start, end := memoryPage(len(records), query.Offset, query.Limit)
+	return records[start:end], nil
+}
+
+// SetAuditRetention - срок хранения журнала аудита в днях; 0 - хранить бессрочно
+func (s *MemoryStorage) SetAuditRetention(ctx context.Context, days int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auditRetentionDays = days
+	s.purgeAudit()
+	return nil
+}
+
+// purgeAudit - удаляет записи старше срока хранения; вызывается под s.mu
+func (s *MemoryStorage) purgeAudit() {
+	if s.auditRetentionDays <= 0 {
+		return
+	}
+	expired := time.Now().AddDate(0, 0, -s.auditRetentionDays)
+	kept := s.audit[:0]
+	for _, record := range s.audit {
+		if !record.Time.Before(expired) {
+			kept = append(kept, record)
+		}
+	}
+	s.audit = kept
+}
@@ -0,0 +1,33 @@
+-- IoC. Ключ (value, tenant) повторяет ORDER BY ReplacingMergeTree в ClickHouse: повторная запись
+-- значения заменяет строку, если ее last_seen не меньше. Время хранится с точностью до секунды, как DateTime.
+CREATE TABLE IF NOT EXISTS ioc_data (
+    id                 uuid           NOT NULL,
+    source             text           NOT NULL,
+    first_seen         timestamptz(0) NOT NULL DEFAULT to_timestamp(0),
+    last_seen          timestamptz(0) NOT NULL DEFAULT to_timestamp(0),
+    type               text           NOT NULL,
+    value              text           NOT NULL,
+    tags               text           NOT NULL DEFAULT '',
+    additional_data    text           NOT NULL DEFAULT '',
+    added_at           timestamptz(0) NOT NULL DEFAULT date_trunc('second', now()),
+    hidden             smallint       NOT NULL DEFAULT 0,
+    ip_start           inet           NOT NULL DEFAULT '::',
+    ip_end             inet           NOT NULL DEFAULT '::',
+    host               text           NOT NULL DEFAULT '',
+    registrable_domain text           NOT NULL DEFAULT '',
+    tld                text           NOT NULL DEFAULT '',
+    country            text           NOT NULL DEFAULT '',
+    city               text           NOT NULL DEFAULT '',
+    asn                bigint         NOT NULL DEFAULT 0,
+    as_org             text           NOT NULL DEFAULT '',
+    tenant             text           NOT NULL DEFAULT '',
+    PRIMARY KEY (value, tenant)
+);
+
+CREATE INDEX IF NOT EXISTS ioc_data_id_idx ON ioc_data (id);
+CREATE INDEX IF NOT EXISTS ioc_data_source_type_idx ON ioc_data (source, type);
+CREATE INDEX IF NOT EXISTS ioc_data_type_idx ON ioc_data (type);
+CREATE INDEX IF NOT EXISTS ioc_data_added_at_idx ON ioc_data (added_at, value COLLATE "C");
+CREATE INDEX IF NOT EXISTS ioc_data_ip_idx ON ioc_data (ip_start, ip_end) WHERE type = 'ip';
+CREATE INDEX IF NOT EXISTS ioc_data_host_idx ON ioc_data (registrable_domain, host) WHERE host <> '';
+CREATE INDEX IF NOT EXISTS ioc_data_tenant_idx ON ioc_data (tenant) WHERE tenant <> '';
@@ -0,0 +1,71 @@
+-- Allowlist; удаленные записи удаляются из таблицы
+CREATE TABLE IF NOT EXISTS allowlist (
+    id         text        PRIMARY KEY,
+    kind       text        NOT NULL,
+    value      text        NOT NULL,
+    comment    text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+-- Граф связей между индикаторами по значениям; повторная запись связи обновляет updated_at
+CREATE TABLE IF NOT EXISTS ioc_relationships (
+    from_type  text        NOT NULL,
+    from_value text        NOT NULL,
+    kind       text        NOT NULL,
+    to_type    text        NOT NULL,
+    to_value   text        NOT NULL,
+    source     text        NOT NULL,
+    updated_at timestamptz NOT NULL,
+    PRIMARY KEY (from_value, kind, to_value, from_type, to_type, source)
+);
+CREATE INDEX IF NOT EXISTS ioc_relationships_to_value_idx ON ioc_relationships (to_value);
+
+-- Sightings - обнаружения индикаторов собственными сенсорами, только добавление
+CREATE TABLE IF NOT EXISTS ioc_sightings (
+    value       text        NOT NULL,
+    type        text        NOT NULL,
+    observer    text        NOT NULL,
+    seen_at     timestamptz NOT NULL,
+    count       bigint      NOT NULL,
+    recorded_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS ioc_sightings_value_idx ON ioc_sightings (value, seen_at);
+
+-- Статус IoC, который ведут аналитики; ключ - тип и значение, чтобы пометка переживала повторную запись
+CREATE TABLE IF NOT EXISTS ioc_lifecycle (
+    type        text        NOT NULL,
+    value       text        NOT NULL,
+    status      text        NOT NULL,
+    reason      text        NOT NULL DEFAULT '',
+    changed_by  text        NOT NULL DEFAULT '',
+    changed_at  timestamptz NOT NULL,
+    manual_tags text[]      NOT NULL DEFAULT '{}',
+    PRIMARY KEY (type, value)
+);
+CREATE INDEX IF NOT EXISTS ioc_lifecycle_status_idx ON ioc_lifecycle (status, changed_at);
+
+-- Заметки аналитиков к IoC, только добавление
+CREATE TABLE IF NOT EXISTS ioc_notes (
+    id         text        NOT NULL,
+    type       text        NOT NULL,
+    value      text        NOT NULL,
+    author     text        NOT NULL,
+    text       text        NOT NULL,
+    created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ioc_notes_value_idx ON ioc_notes (value, type, created_at);
+
+-- Журнал аудита, только добавление; записи старше срока хранения удаляет хранилище (SetAuditRetention)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id      text        NOT NULL,
+    time    timestamptz NOT NULL,
+    actor   text        NOT NULL,
+    peer    text        NOT NULL,
+    method  text        NOT NULL,
+    params  text        NOT NULL,
+    rows    bigint      NOT NULL,
+    outcome text        NOT NULL,
+    error   text        NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_time_idx ON audit_log (time, method);
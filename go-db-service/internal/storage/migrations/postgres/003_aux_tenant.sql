@@ -0,0 +1,13 @@
+-- Записи аналитиков (lifecycle, заметки, связи) и sightings принадлежат арендатору, который их записал;
+-- пустой арендатор - общие записи, видимые всем. Арендатор входит в ключи, чтобы собственная запись
+-- не заменяла общую с тем же значением.
+ALTER TABLE ioc_lifecycle ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+ALTER TABLE ioc_lifecycle DROP CONSTRAINT IF EXISTS ioc_lifecycle_pkey;
+ALTER TABLE ioc_lifecycle ADD PRIMARY KEY (type, value, tenant);
+
+ALTER TABLE ioc_relationships ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+ALTER TABLE ioc_relationships DROP CONSTRAINT IF EXISTS ioc_relationships_pkey;
+ALTER TABLE ioc_relationships ADD PRIMARY KEY (from_value, kind, to_value, from_type, to_type, source, tenant);
+
+ALTER TABLE ioc_sightings ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+ALTER TABLE ioc_notes ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
@@ -0,0 +1,24 @@
+-- Сведения источников о значениях для отчета по источникам. В ioc_data значение от разных источников
+-- хранится одной строкой (value, tenant), поэтому пересечение источников по ней не посчитать.
+-- reported_at и added_at - самые ранние, last_seen - самый поздний из записанных.
+CREATE TABLE IF NOT EXISTS ioc_sources (
+    value       text           NOT NULL,
+    source      text           NOT NULL,
+    tenant      text           NOT NULL DEFAULT '',
+    type        text           NOT NULL,
+    host        text           NOT NULL DEFAULT '',
+    ip_start    inet           NOT NULL DEFAULT '::',
+    ip_end      inet           NOT NULL DEFAULT '::',
+    reported_at timestamptz(0) NOT NULL,
+    added_at    timestamptz(0) NOT NULL,
+    last_seen   timestamptz(0) NOT NULL,
+    PRIMARY KEY (value, source, tenant)
+);
+CREATE INDEX IF NOT EXISTS ioc_sources_tenant_idx ON ioc_sources (tenant) WHERE tenant <> '';
+
+-- Уже записанные строки; источники, чьи строки были заменены до миграции, восстановить нельзя
+INSERT INTO ioc_sources (value, source, tenant, type, host, ip_start, ip_end, reported_at, added_at, last_seen)
+SELECT value, source, tenant, type, host, ip_start, ip_end,
+    CASE WHEN first_seen > to_timestamp(0) THEN first_seen ELSE added_at END, added_at, last_seen
+FROM ioc_data
+ON CONFLICT DO NOTHING;
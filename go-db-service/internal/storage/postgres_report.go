@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"awesomeProject/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// postgresSourceFacts - сведения источника о значении по ioc_sources, как sourceFacts в ClickHouse
+const postgresSourceFacts = `(SELECT value, source, (array_agg(type))[1] AS type, (array_agg(host))[1] AS host,
+		(array_agg(ip_start))[1] AS ip_start, (array_agg(ip_end))[1] AS ip_end,
+		min(reported_at) AS reported_at, min(added_at) AS added_at, max(last_seen) AS last_seen
+	FROM ioc_sources WHERE %s GROUP BY value, source) AS facts`
+
+// SourceReport - показатели качества источников; churnDays - глубина истории churn в днях.
+// Запросы повторяют отчет ClickHouseStorage по ioc_sources; пары источников и медиана считаются так же.
+func (s *PostgresStorage) SourceReport(ctx context.Context, churnDays int) (models.SourceReport, error) {
+	report := models.SourceReport{Sources: make(map[string]*models.SourceStats), GeneratedAt: time.Now().UTC()}
+	stats := func(source string) *models.SourceStats {
+		if report.Sources[source] == nil {
+			report.Sources[source] = &models.SourceStats{}
+		}
+		return report.Sources[source]
+	}
+	entries, err := s.ListAllowlist(ctx)
+	if err != nil {
+		return report, err
+	}
+	allowlisted, allowlistArgs := allowlistCondition(postgresDialect, entries)
+
+	where, tenantArgs := tenantCondition(ctx)
+	facts := fmt.Sprintf(postgresSourceFacts, where)
+	twice := append(append([]interface{}{}, tenantArgs...), tenantArgs...)
+
+	// Уникальные и общие значения, совпадающие с текущим allowlist
+	err = s.queryReport(ctx, "totals", `SELECT source, count(*), count(*) FILTER (WHERE n = 1), count(*) FILTER (WHERE n > 1),
+			count(*) FILTER (WHERE allowlisted)
+		FROM (SELECT source, count(*) OVER (PARTITION BY value) AS n, bool_or(`+allowlisted+`) OVER (PARTITION BY value) AS allowlisted
+			FROM `+facts+`) AS iocs
+		GROUP BY source`, append(append([]interface{}{}, allowlistArgs...), tenantArgs...), func(rows *sql.Rows) error {
+		var source string
+		var total, unique, shared, allowlisted int64
+		if err := rows.Scan(&source, &total, &unique, &shared, &allowlisted); err != nil {
+			return err
+		}
+		st := stats(source)
+		st.Total, st.Unique, st.Shared, st.Allowlisted = total, unique, shared, allowlisted
+		if total > 0 {
+			st.AllowlistedShare = float64(allowlisted) / float64(total)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Попарное пересечение: пары источников (a < b побайтно, как arraySort) каждого общего значения
+	err = s.queryReport(ctx, "overlap", `SELECT a.source, b.source, count(*)
+		FROM (SELECT DISTINCT value, source FROM ioc_sources WHERE `+where+`) AS a
+		INNER JOIN (SELECT DISTINCT value, source FROM ioc_sources WHERE `+where+`) AS b
+		ON a.value = b.value AND a.source COLLATE "C" < b.source COLLATE "C"
+		GROUP BY a.source, b.source
+		ORDER BY count(*) DESC`, twice, func(rows *sql.Rows) error {
+		var overlap models.SourceOverlap
+		if err := rows.Scan(&overlap.SourceA, &overlap.SourceB, &overlap.Shared); err != nil {
+			return err
+		}
+		report.Overlaps = append(report.Overlaps, overlap)
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Отставание от первого сообщившего по общим значениям; медиана - элемент n/2 по возрастанию, как quantileExact(0.5)
+	err = s.queryReport(ctx, "lag", `SELECT source, count(*) FILTER (WHERE lag = 0), ((array_agg(lag ORDER BY lag))[count(*) / 2 + 1])::float8
+		FROM (
+			SELECT facts.source, extract(epoch FROM facts.reported_at - f.first)::bigint AS lag
+			FROM `+facts+`
+			INNER JOIN (SELECT value, min(reported_at) AS first FROM `+facts+` GROUP BY value HAVING count(*) > 1) AS f
+			USING (value)
+		) AS lags
+		GROUP BY source`, twice, func(rows *sql.Rows) error {
+		var source string
+		var firstReported int64
+		var medianLag float64
+		if err := rows.Scan(&source, &firstReported, &medianLag); err != nil {
+			return err
+		}
+		st := stats(source)
+		st.FirstReported, st.MedianLag = firstReported, medianLag
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Churn: новые значения по дню первой записи, пропавшие - по дню, когда источник сообщал о них последний раз
+	err = s.queryReport(ctx, "churn", `SELECT source, day::text, sum(added)::bigint, sum(removed)::bigint
+		FROM (
+			SELECT source, added_at::date AS day, 1 AS added, 0 AS removed FROM `+facts+`
+			UNION ALL
+			SELECT source, last_seen::date AS day, 0 AS added, 1 AS removed FROM `+facts+`
+			WHERE last_seen > to_timestamp(0) AND last_seen::date < current_date
+		) AS churn
+		WHERE day >= current_date - ?::integer
+		GROUP BY source, day
+		ORDER BY source COLLATE "C", day`, append(twice, churnDays), func(rows *sql.Rows) error {
+		var source string
+		var churn models.DailyChurn
+		if err := rows.Scan(&source, &churn.Date, &churn.Added, &churn.Removed); err != nil {
+			return err
+		}
+		st := stats(source)
+		st.Churn = append(st.Churn, churn)
+		return nil
+	})
+	return report, err
+}
+
+// queryReport - выполняет запрос отчета и передает строки в scan
+func (s *PostgresStorage) queryReport(ctx context.Context, name, query string, args []interface{}, scan func(rows *sql.Rows) error) error {
+	rows, err := s.db.QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		s.logger.Error("Failed to build source report", zap.String("part", name), zap.Error(err))
+		return fmt.Errorf("failed to build source report (%s): %v", name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			s.logger.Error("Failed to scan source report row", zap.String("part", name), zap.Error(err))
+			return fmt.Errorf("failed to scan source report row (%s): %v", name, err)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,402 @@
+package storage
+
+import (
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// auditPurgeInterval - как часто PostgresStorage удаляет записи аудита старше срока хранения
+const auditPurgeInterval = time.Hour
+
+// ListAllowlist - актуальные записи allowlist
+func (s *PostgresStorage) ListAllowlist(ctx context.Context) ([]models.AllowlistEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, kind, value, comment, created_at, updated_at FROM allowlist ORDER BY id COLLATE "C"`)
+	if err != nil {
+		s.logger.Error("Failed to list allowlist", zap.Error(err))
+		return nil, fmt.Errorf("failed to list allowlist: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AllowlistEntry
+	for rows.Next() {
+		var entry models.AllowlistEntry
+		if err := rows.Scan(&entry.ID, &entry.Kind, &entry.Value, &entry.Comment, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			s.logger.Error("Failed to scan allowlist row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan allowlist row: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// StoreAllowlistEntries - создание или изменение записей allowlist по id
+func (s *PostgresStorage) StoreAllowlistEntries(ctx context.Context, entries []models.AllowlistEntry) error {
+	return s.execEach(ctx, "allowlist entry", `INSERT INTO allowlist (id, kind, value, comment, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET kind = excluded.kind, value = excluded.value, comment = excluded.comment,
+		created_at = excluded.created_at, updated_at = excluded.updated_at`, len(entries), func(i int) []interface{} {
+		entry := entries[i]
+		return []interface{}{entry.ID, entry.Kind, entry.Value, entry.Comment, entry.CreatedAt, entry.UpdatedAt}
+	})
+}
+
+// DeleteAllowlistEntry - удаление записи allowlist
+func (s *PostgresStorage) DeleteAllowlistEntry(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM allowlist WHERE id = $1`, id); err != nil {
+		s.logger.Error("Failed to delete allowlist entry", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to delete allowlist entry: %v", err)
+	}
+	return nil
+}
+
+// StoreRelationships - запись связей между индикаторами от имени арендатора вызова;
+// повторная связь обновляет updated_at, если он новее
+func (s *PostgresStorage) StoreRelationships(ctx context.Context, relationships []models.Relationship) error {
+	owner := tenant.FromContext(ctx)
+	return s.execEach(ctx, "relationship", `INSERT INTO ioc_relationships (from_type, from_value, kind, to_type, to_value, source, updated_at, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (from_value, kind, to_value, from_type, to_type, source, tenant) DO UPDATE SET updated_at = excluded.updated_at
+		WHERE excluded.updated_at >= ioc_relationships.updated_at`, len(relationships), func(i int) []interface{} {
+		rel := relationships[i]
+		return []interface{}{rel.FromType, rel.FromValue, rel.Kind, rel.ToType, rel.ToValue, rel.Source, rel.UpdatedAt, owner}
+	})
+}
+
+// RelationshipsOf - видимые вызову связи, у которых любой из концов входит в values
+func (s *PostgresStorage) RelationshipsOf(ctx context.Context, values []string, limit int) ([]models.Relationship, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	where, args := tenantCondition(ctx)
+	query := rebind(`SELECT from_type, from_value, kind, to_type, to_value, source, updated_at FROM ioc_relationships
+		WHERE ` + where + ` AND (from_value = ANY(?) OR to_value = ANY(?))
+		ORDER BY updated_at DESC LIMIT ?`)
+
+	rows, err := s.db.QueryContext(ctx, query, append(args, values, values, limit)...)
+	if err != nil {
+		s.logger.Error("Failed to load relationships", zap.Error(err))
+		return nil, fmt.Errorf("failed to load relationships: %v", err)
+	}
+	defer rows.Close()
+
+	var result []models.Relationship
+	for rows.Next() {
+		var rel models.Relationship
+		if err := rows.Scan(&rel.FromType, &rel.FromValue, &rel.Kind, &rel.ToType, &rel.ToValue, &rel.Source, &rel.UpdatedAt); err != nil {
+			s.logger.Error("Failed to scan relationship row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan relationship row: %v", err)
+		}
+		result = append(result, rel)
+	}
+	return result, rows.Err()
+}
+
+// IoCsByID - тип и значение IoC по id; отсутствующие id в результат не попадают
+func (s *PostgresStorage) IoCsByID(ctx context.Context, ids []string) (map[string]models.IoCDto, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	where, args := tenantCondition(ctx)
+	query := rebind(`SELECT id::text, type, value FROM ioc_data WHERE ` + where + ` AND id::text = ANY(?)`)
+
+	rows, err := s.db.QueryContext(ctx, query, append(args, ids)...)
+	if err != nil {
+		s.logger.Error("Failed to load IoCs by id", zap.Error(err))
+		return nil, fmt.Errorf("failed to load IoCs by id: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]models.IoCDto, len(ids))
+	for rows.Next() {
+		var ioc models.IoCDto
+		if err := rows.Scan(&ioc.ID, &ioc.Type, &ioc.Value); err != nil {
+			s.logger.Error("Failed to scan IoC row in IoCsByID", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan IoC row in IoCsByID: %v", err)
+		}
+		result[ioc.ID] = ioc
+	}
+	return result, rows.Err()
+}
+
+// RecordSightings - запись обнаружений индикаторов от имени арендатора вызова
+func (s *PostgresStorage) RecordSightings(ctx context.Context, sightings []models.Sighting) error {
+	owner := tenant.FromContext(ctx)
+	return s.execEach(ctx, "sighting", `INSERT INTO ioc_sightings (value, type, observer, seen_at, count, tenant) VALUES ($1, $2, $3, $4, $5, $6)`,
+		len(sightings), func(i int) []interface{} {
+			sighting := sightings[i]
+			return []interface{}{sighting.Value, sighting.Type, sighting.Observer, sighting.SeenAt, int64(sighting.Count), owner}
+		})
+}
+
+// SightingsBySource - сколько IoC каждого источника встретилось в sightings и сколько было срабатываний
+func (s *PostgresStorage) SightingsBySource(ctx context.Context) (map[string]models.SourceSightings, error) {
+	where, args := tenantCondition(ctx)
+	query := rebind(`SELECT source, count(*), sum(sightings)::bigint FROM
+		(SELECT DISTINCT source, value FROM ioc_data WHERE ` + where + ` AND hidden = 0) AS iocs
+		INNER JOIN (SELECT value, sum(count) AS sightings FROM ioc_sightings WHERE ` + where + ` GROUP BY value) AS hits USING (value)
+		GROUP BY source`)
+
+	rows, err := s.db.QueryContext(ctx, query, append(args, args...)...)
+	if err != nil {
+		s.logger.Error("Failed to count sightings by source", zap.Error(err))
+		return nil, fmt.Errorf("failed to count sightings by source: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]models.SourceSightings)
+	for rows.Next() {
+		var source string
+		var counts models.SourceSightings
+		if err := rows.Scan(&source, &counts.IoCs, &counts.Sightings); err != nil {
+			s.logger.Error("Failed to scan row in SightingsBySource", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row in SightingsBySource: %v", err)
+		}
+		result[source] = counts
+	}
+	return result, rows.Err()
+}
+
+const selectPostgresLifecycle = `SELECT type, value, status, reason, changed_by, changed_at, manual_tags, tenant FROM ioc_lifecycle`
+
+// GetLifecycle - статус IoC с заметками; false, если аналитики его не трогали.
+// Собственная запись арендатора вызова важнее общей.
+func (s *PostgresStorage) GetLifecycle(ctx context.Context, iocType, value string) (models.Lifecycle, bool, error) {
+	where, args := tenantCondition(ctx)
+	records, err := s.queryLifecycle(ctx, rebind(selectPostgresLifecycle+` WHERE `+where+` AND type = ? AND value = ? ORDER BY tenant DESC LIMIT 1`),
+		append(args, iocType, value)...)
+	if err != nil {
+		return models.Lifecycle{}, false, err
+	}
+	notes, err := s.notes(ctx, iocType, value)
+	if err != nil {
+		return models.Lifecycle{}, false, err
+	}
+	if len(records) == 0 {
+		return models.Lifecycle{Type: iocType, Value: value, Status: models.StatusActive, Notes: notes}, false, nil
+	}
+	records[0].Notes = notes
+	return records[0], true, nil
+}
+
+// ListLifecycle - видимые вызову записи lifecycle с указанными статусами (пусто - все); limit = 0 - без ограничения
+func (s *PostgresStorage) ListLifecycle(ctx context.Context, statuses []string, limit, offset int64) ([]models.Lifecycle, error) {
+	where, args := tenantCondition(ctx)
+	query := selectPostgresLifecycle + ` WHERE ` + where
+	if len(statuses) > 0 {
+		query += ` AND status = ANY(?)`
+		args = append(args, statuses)
+	}
+	query += ` ORDER BY changed_at DESC, value COLLATE "C"`
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+	return s.queryLifecycle(ctx, rebind(query), args...)
+}
+
+// PutLifecycle - статус IoC от имени арендатора вызова; заменяет предыдущий
+func (s *PostgresStorage) PutLifecycle(ctx context.Context, record models.Lifecycle) error {
+	manualTags := record.ManualTags
+	if manualTags == nil {
+		manualTags = []string{}
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO ioc_lifecycle (type, value, status, reason, changed_by, changed_at, manual_tags, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (type, value, tenant) DO UPDATE SET status = excluded.status, reason = excluded.reason,
+		changed_by = excluded.changed_by, changed_at = excluded.changed_at, manual_tags = excluded.manual_tags`,
+		record.Type, record.Value, record.Status, record.Reason, record.ChangedBy, record.ChangedAt, manualTags, tenant.FromContext(ctx))
+	if err != nil {
+		s.logger.Error("Failed to write lifecycle", zap.String("value", record.Value), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// AddNote - заметка аналитика к IoC от имени арендатора вызова
+func (s *PostgresStorage) AddNote(ctx context.Context, iocType, value string, note models.Note) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO ioc_notes (id, type, value, author, text, created_at, tenant) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		note.ID, iocType, value, note.Author, note.Text, note.CreatedAt, tenant.FromContext(ctx))
+	if err != nil {
+		s.logger.Error("Failed to write note", zap.String("value", value), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *PostgresStorage) notes(ctx context.Context, iocType, value string) ([]models.Note, error) {
+	where, args := tenantCondition(ctx)
+	rows, err := s.db.QueryContext(ctx, rebind(`SELECT id, author, text, created_at FROM ioc_notes WHERE `+where+` AND type = ? AND value = ? ORDER BY created_at`),
+		append(args, iocType, value)...)
+	if err != nil {
+		s.logger.Error("Failed to load notes", zap.Error(err))
+		return nil, fmt.Errorf("failed to load notes: %v", err)
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.ID, &note.Author, &note.Text, &note.CreatedAt); err != nil {
+			s.logger.Error("Failed to scan note row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan note row: %v", err)
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func (s *PostgresStorage) queryLifecycle(ctx context.Context, query string, args ...interface{}) ([]models.Lifecycle, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Failed to load lifecycle", zap.Error(err))
+		return nil, fmt.Errorf("failed to load lifecycle: %v", err)
+	}
+	defer rows.Close()
+
+	var records []models.Lifecycle
+	for rows.Next() {
+		var record models.Lifecycle
+		if err := rows.Scan(&record.Type, &record.Value, &record.Status, &record.Reason, &record.ChangedBy, &record.ChangedAt,
+			s.types.SQLScanner(&record.ManualTags), &record.Tenant); err != nil {
+			s.logger.Error("Failed to scan lifecycle row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan lifecycle row: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// StoreAuditRecords - добавление записей в журнал аудита; записи старше срока хранения удаляются
+// здесь же, не чаще auditPurgeInterval
+func (s *PostgresStorage) StoreAuditRecords(ctx context.Context, records []models.AuditRecord) error {
+	err := s.execEach(ctx, "audit record", `INSERT INTO audit_log (id, time, actor, peer, method, params, rows, outcome, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, len(records), func(i int) []interface{} {
+		record := records[i]
+		return []interface{}{record.ID, record.Time, record.Actor, record.Peer, record.Method, record.Params, record.Rows, record.Outcome, record.Error}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditMu.Lock()
+	days, due := s.auditRetentionDays, time.Since(s.auditPurgedAt) >= auditPurgeInterval
+	s.auditMu.Unlock()
+	if days > 0 && due {
+		if err := s.purgeAudit(ctx, days); err != nil {
+			s.logger.Warn("Failed to purge audit log", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// QueryAudit - записи журнала аудита по фильтру, новые первыми
+func (s *PostgresStorage) QueryAudit(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, query.Actor)
+	}
+	if query.Method != "" {
+		conditions = append(conditions, "method = ?")
+		args = append(args, query.Method)
+	}
+	if query.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, query.Outcome)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, query.From.UTC())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "time < ?")
+		args = append(args, query.To.UTC())
+	}
+
+	sqlQuery := `SELECT id, time, actor, peer, method, params, rows, outcome, error FROM audit_log`
+	if len(conditions) > 0 {
+		sqlQuery += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	sqlQuery += ` ORDER BY time DESC, id COLLATE "C" LIMIT ? OFFSET ?`
+	args = append(args, query.Limit, query.Offset)
+
+	rows, err := s.db.QueryContext(ctx, rebind(sqlQuery), args...)
+	if err != nil {
+		s.logger.Error("Failed to query audit log", zap.Error(err))
+		return nil, fmt.Errorf("failed to query audit log: %v", err)
+	}
+	defer rows.Close()
+
+	var records []models.AuditRecord
+	for rows.Next() {
+		var record models.AuditRecord
+		if err := rows.Scan(&record.ID, &record.Time, &record.Actor, &record.Peer, &record.Method,
+			&record.Params, &record.Rows, &record.Outcome, &record.Error); err != nil {
+			s.logger.Error("Failed to scan audit row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan audit row: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// SetAuditRetention - срок хранения журнала аудита в днях; 0 - хранить бессрочно.
+// TTL в PostgreSQL нет, поэтому старые записи удаляются сразу и затем при записи журнала.
+func (s *PostgresStorage) SetAuditRetention(ctx context.Context, days int) error {
+	s.auditMu.Lock()
+	s.auditRetentionDays = days
+	s.auditMu.Unlock()
+	if days <= 0 {
+		return nil
+	}
+	if err := s.purgeAudit(ctx, days); err != nil {
+		return fmt.Errorf("failed to set audit retention: %v", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) purgeAudit(ctx context.Context, days int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM audit_log WHERE time < now() - make_interval(days => $1)`, days)
+	if err != nil {
+		return err
+	}
+	s.auditMu.Lock()
+	s.auditPurgedAt = time.Now()
+	s.auditMu.Unlock()
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		s.logger.Info("Expired audit records deleted", zap.Int64("rows", deleted), zap.Int("retentionDays", days))
+	}
+	return nil
+}
+
+// execEach - выполняет запрос для каждой из n строк одной транзакцией
+func (s *PostgresStorage) execEach(ctx context.Context, what, query string, n int, args func(i int) []interface{}) error {
+	if n == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		if _, err := stmt.ExecContext(ctx, args(i)...); err != nil {
+			s.logger.Error("Failed to write "+what, zap.Error(err))
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"awesomeProject/internal/domains"
+	"awesomeProject/internal/iprange"
+	"awesomeProject/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const selectIoCColumns = `SELECT id, source, first_seen, last_seen, type, value, tags, additional_data, added_at, hidden, country, city, asn, as_org,
+	tenant, lifecycle_status, manual_tags, `
+
+// Агрегаты sightings присоединяются только по запросу, иначе отдаются нули; %s - условие арендатора
+const (
+	noSightingColumns   = `toUInt64(0), toDateTime(0), toDateTime(0) FROM ioc_data`
+	withSightingColumns = `sighting_count, first_sighted, last_sighted FROM ioc_data`
+	sightingsJoin       = ` LEFT JOIN (SELECT value, sum(count) AS sighting_count, min(seen_at) AS first_sighted, max(seen_at) AS last_sighted
+		FROM ioc_sightings WHERE %s GROUP BY value) AS sightings USING (value)`
+)
+
+// lifecycleJoin - статус и ручные теги аналитиков; у IoC без записи статус пустой (active).
+// Собственная запись арендатора важнее общей: пустой арендатор меньше любого другого.
+const lifecycleJoin = ` LEFT JOIN (SELECT type, value, argMax(status, tenant) AS lifecycle_status, argMax(manual_tags, tenant) AS manual_tags
+	FROM ioc_lifecycle FINAL WHERE %s GROUP BY type, value) AS lifecycle USING (type, value)`
+
+// dialect - выражения SQL, которыми различаются хранилища. Фильтры LoadRequest собираются одним
+// buildWhere, поэтому их семантика у ClickHouse и PostgreSQL одинакова.
+type dialect struct {
+	idText    string // id как строка для поиска по Filter
+	ipParam   string // параметр-адрес для сравнения с ip_start/ip_end
+	hasSuffix string // host оканчивается на параметр
+	orderBy   string // порядок SortByAdded: added_at, затем value побайтно
+
+	// Выборка IoC: колонки lifecycle_status и manual_tags, sightings и их соединение;
+	// в lifecycleJoin и sightingsJoin подставляется условие арендатора
+	lifecycleJoin, noSightingColumns, withSightingColumns, sightingsJoin string
+}
+
+var clickhouseDialect = dialect{
+	idText:              `toString(id)`,
+	ipParam:             `toIPv6(?)`,
+	hasSuffix:           `endsWith(host, ?)`,
+	orderBy:             ` ORDER BY added_at, value`,
+	lifecycleJoin:       lifecycleJoin,
+	noSightingColumns:   noSightingColumns,
+	withSightingColumns: withSightingColumns,
+	sightingsJoin:       sightingsJoin,
+}
+
+// buildWhere - собирает WHERE по фильтрам LoadRequest (без пагинации); первым всегда идет условие арендатора
+func buildWhere(ctx context.Context, d dialect, request models.LoadRequest) (string, []interface{}, error) {
+	tenantWhere, args := tenantCondition(ctx)
+	conditions := []string{tenantWhere}
+
+	if !request.IncludeHidden {
+		conditions = append(conditions, `hidden = 0`)
+	}
+	// По умолчанию отдаются только активные IoC; пустой статус - записи lifecycle нет
+	statuses := request.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{models.StatusActive}
+	}
+	statusArgs := make([]string, 0, len(statuses)+1)
+	for _, status := range statuses {
+		status = strings.ToLower(strings.TrimSpace(status))
+		if status == models.StatusActive {
+			args = append(args, "")
+			statusArgs = append(statusArgs, "?")
+		}
+		args = append(args, status)
+		statusArgs = append(statusArgs, "?")
+	}
+	conditions = append(conditions, `lifecycle_status IN (`+strings.Join(statusArgs, ", ")+`)`)
+	if request.Filter != "" {
+		conditions = append(conditions, `(`+d.idText+` LIKE ? OR source LIKE ? OR type LIKE ? OR value LIKE ? OR tags LIKE ?)`)
+		filter := "%" + request.Filter + "%"
+		args = append(args, filter, filter, filter, filter, filter)
+	}
+	if request.Type != "" {
+		conditions = append(conditions, `type = ?`)
+		args = append(args, strings.ToLower(request.Type))
+	}
+	if request.Source != "" {
+		conditions = append(conditions, `source = ?`)
+		args = append(args, strings.ToLower(request.Source))
+	}
+	if request.AddedAfter != nil {
+		conditions = append(conditions, `added_at > ?`)
+		args = append(args, request.AddedAfter.UTC())
+	}
+	if request.AddedBefore != nil {
+		conditions = append(conditions, `added_at < ?`)
+		args = append(args, request.AddedBefore.UTC())
+	}
+
+	// Диапазонные фильтры работают по колонкам ip_start/ip_end, заполненным только у IP IoC
+	if len(request.IPWithin) > 0 {
+		var within []string
+		for _, value := range request.IPWithin {
+			r, err := iprange.Parse(value)
+			if err != nil {
+				return "", nil, err
+			}
+			within = append(within, `(ip_start >= `+d.ipParam+` AND ip_end <= `+d.ipParam+`)`)
+			args = append(args, r.Start.String(), r.End.String())
+		}
+		conditions = append(conditions, `type = 'ip'`, "("+strings.Join(within, " OR ")+")")
+	}
+	if request.IPContains != "" {
+		r, err := iprange.Parse(request.IPContains)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, `type = 'ip'`, `ip_start <= `+d.ipParam+` AND ip_end >= `+d.ipParam)
+		args = append(args, r.Start.String(), r.End.String())
+	}
+
+	// Иерархия доменов по колонкам host/registrable_domain/tld, заполненным у DOMAIN и URL IoC
+	if request.RegistrableDomain != "" {
+		conditions = append(conditions, `registrable_domain = ?`)
+		args = append(args, domains.RegistrableDomain(request.RegistrableDomain))
+	}
+	if request.SubdomainOf != "" {
+		parent := domains.Normalize(request.SubdomainOf)
+		conditions = append(conditions, `(host = ? OR `+d.hasSuffix+`)`)
+		args = append(args, parent, "."+parent)
+	}
+	if request.TLD != "" {
+		conditions = append(conditions, `tld = ?`)
+		args = append(args, domains.Normalize(request.TLD))
+	}
+	if request.Country != "" {
+		conditions = append(conditions, `country = ?`)
+		args = append(args, strings.ToUpper(request.Country))
+	}
+	if request.ASN != 0 {
+		conditions = append(conditions, `asn = ?`)
+		args = append(args, request.ASN)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// buildLoadQuery - собирает запрос на выборку IoC; limit = 0 означает выборку без ограничения
+func buildLoadQuery(ctx context.Context, d dialect, request models.LoadRequest) (string, []interface{}, error) {
+	// Записи аналитиков и sightings видны по тому же условию арендатора, что и строки ioc_data
+	joinWhere, joinArgs := tenantCondition(ctx)
+	var args []interface{}
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(selectIoCColumns)
+	if request.IncludeSightings {
+		queryBuilder.WriteString(d.withSightingColumns)
+		queryBuilder.WriteString(fmt.Sprintf(d.lifecycleJoin, joinWhere))
+		queryBuilder.WriteString(fmt.Sprintf(d.sightingsJoin, joinWhere))
+		args = append(append(args, joinArgs...), joinArgs...)
+	} else {
+		queryBuilder.WriteString(d.noSightingColumns)
+		queryBuilder.WriteString(fmt.Sprintf(d.lifecycleJoin, joinWhere))
+		args = append(args, joinArgs...)
+	}
+
+	where, whereArgs, err := buildWhere(ctx, d, request)
+	if err != nil {
+		return "", nil, err
+	}
+	queryBuilder.WriteString(where)
+	args = append(args, whereArgs...)
+
+	if request.SortByAdded {
+		queryBuilder.WriteString(d.orderBy)
+	}
+
+	if request.Limit > 0 {
+		queryBuilder.WriteString(` LIMIT ? OFFSET ?`)
+		args = append(args, request.Limit, request.Offset)
+	}
+
+	return queryBuilder.String(), args, nil
+}
+
+// scanIoC - читает строку, выбранную по selectIoCColumns
+func (s *ClickHouseStorage) scanIoC(rows *sql.Rows) (models.IoCDto, error) {
+	var ioc models.IoCDto
+	var tagsJSON, additionalDataJSON string
+	var hidden uint8
+	var status string
+	var manualTags []string
+	var sightingCount uint64
+	var firstSighted, lastSighted time.Time
+
+	if err := rows.Scan(&ioc.ID, &ioc.Source, &ioc.FirstSeen, &ioc.LastSeen, &ioc.Type, &ioc.Value, &tagsJSON, &additionalDataJSON, &ioc.AddedAt, &hidden,
+		&ioc.Country, &ioc.City, &ioc.ASN, &ioc.ASOrg, &ioc.Tenant, &status, &manualTags, &sightingCount, &firstSighted, &lastSighted); err != nil {
+		return ioc, err
+	}
+	ioc.Hidden = hidden == 1
+	ioc.Status = status
+	if ioc.Status == "" {
+		ioc.Status = models.StatusActive
+	}
+	if sightingCount > 0 {
+		ioc.SightingCount = int64(sightingCount)
+		ioc.FirstSighted, ioc.LastSighted = &firstSighted, &lastSighted
+	}
+
+	// Десериализуем JSON-поля
+	ioc.Tags = mergeTags(decodeTags(tagsJSON), manualTags)
+	if err := json.Unmarshal([]byte(additionalDataJSON), &ioc.AdditionalData); err != nil {
+		s.logger.Warn("Failed to unmarshal additional_data JSON", zap.Error(err))
+	}
+	return ioc, nil
+}
+
+// boolToUInt8 - ClickHouse хранит флаги в UInt8
+func boolToUInt8(value bool) uint8 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// ipColumns - значения ip_start и ip_end для записи IoC
+func ipColumns(ioc models.IoCDto) (netip.Addr, netip.Addr) {
+	r, _ := iprange.FromIoC(ioc.Type, ioc.Value)
+	return r.Start, r.End
+}
+
+// domainColumns - значения host, registrable_domain и tld для записи IoC
+func domainColumns(ioc models.IoCDto) (string, string, string) {
+	parts, _ := domains.FromIoC(ioc.Type, ioc.Value)
+	return parts.Host, parts.RegistrableDomain, parts.TLD
+}
+
+// decodeTags - разбирает колонку tags: UnaryStore пишет теги через запятую, StreamStore - JSON массивом
+func decodeTags(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var tags []string
+		if err := json.Unmarshal([]byte(raw), &tags); err == nil {
+			return tags
+		}
+	}
+	return strings.Split(raw, ",")
+}
+
+// mergeTags - теги фида и ручные теги аналитиков без повторов
+func mergeTags(tags, manual []string) []string {
+	for _, tag := range manual {
+		found := false
+		for _, existing := range tags {
+			if strings.EqualFold(existing, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
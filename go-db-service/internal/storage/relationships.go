@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// StoreRelationships - запись связей между индикаторами от имени арендатора вызова
+func (s *ClickHouseStorage) StoreRelationships(ctx context.Context, relationships []models.Relationship) error {
+	query := `INSERT INTO ioc_relationships (from_type, from_value, kind, to_type, to_value, source, updated_at, tenant) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	owner := tenant.FromContext(ctx)
+	for _, rel := range relationships {
+		_, err := stmt.ExecContext(ctx, rel.FromType, rel.FromValue, rel.Kind, rel.ToType, rel.ToValue, rel.Source, rel.UpdatedAt, owner)
+		if err != nil {
+			s.logger.Error("Failed to write relationship", zap.String("from", rel.FromValue), zap.String("kind", rel.Kind), zap.Error(err))
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RelationshipsOf - видимые вызову связи, у которых любой из концов входит в values
+func (s *ClickHouseStorage) RelationshipsOf(ctx context.Context, values []string, limit int) ([]models.Relationship, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	where, args := tenantCondition(ctx)
+	query := `SELECT from_type, from_value, kind, to_type, to_value, source, updated_at FROM ioc_relationships FINAL
+		WHERE ` + where + ` AND (from_value IN (` + placeholders + `) OR to_value IN (` + placeholders + `))
+		ORDER BY updated_at DESC LIMIT ?`
+	for i := 0; i < 2; i++ {
+		for _, value := range values {
+			args = append(args, value)
+		}
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Failed to load relationships", zap.Error(err))
+		return nil, fmt.Errorf("failed to load relationships: %v", err)
+	}
+	defer rows.Close()
+
+	var result []models.Relationship
+	for rows.Next() {
+		var rel models.Relationship
+		if err := rows.Scan(&rel.FromType, &rel.FromValue, &rel.Kind, &rel.ToType, &rel.ToValue, &rel.Source, &rel.UpdatedAt); err != nil {
+			s.logger.Error("Failed to scan relationship row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan relationship row: %v", err)
+		}
+		result = append(result, rel)
+	}
+	return result, rows.Err()
+}
+
+// IoCsByID - тип и значение IoC по id; отсутствующие id в результат не попадают
+func (s *ClickHouseStorage) IoCsByID(ctx context.Context, ids []string) (map[string]models.IoCDto, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	where, args := tenantCondition(ctx)
+	query := `SELECT toString(id), type, value FROM ioc_data WHERE ` + where + ` AND toString(id) IN (` + placeholders + `)`
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Failed to load IoCs by id", zap.Error(err))
+		return nil, fmt.Errorf("failed to load IoCs by id: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]models.IoCDto, len(ids))
+	for rows.Next() {
+		var ioc models.IoCDto
+		if err := rows.Scan(&ioc.ID, &ioc.Type, &ioc.Value); err != nil {
+			s.logger.Error("Failed to scan IoC row in IoCsByID", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan IoC row in IoCsByID: %v", err)
+		}
+		result[ioc.ID] = ioc
+	}
+	return result, rows.Err()
+}
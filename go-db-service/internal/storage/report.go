@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"awesomeProject/internal/iprange"
+	"awesomeProject/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sourcesTable - сведения источников о значениях для SourceReport: ключ (value, source, tenant), поэтому
+// значение от разных источников не схлопывается, как в ioc_data. Колонки - агрегаты min/max, и результат
+// не зависит от того, слиты ли куски и сколько раз записана строка.
+const sourcesTable = `CREATE TABLE IF NOT EXISTS ioc_sources (
+		value String,
+		source String,
+		tenant LowCardinality(String),
+		type SimpleAggregateFunction(any, String),
+		host SimpleAggregateFunction(any, String),
+		ip_start SimpleAggregateFunction(any, IPv6),
+		ip_end SimpleAggregateFunction(any, IPv6),
+		reported_at SimpleAggregateFunction(min, DateTime),
+		added_at SimpleAggregateFunction(min, DateTime),
+		last_seen SimpleAggregateFunction(max, DateTime)
+	) ENGINE = AggregatingMergeTree
+	ORDER BY (value, source, tenant)`
+
+// sourcesColumns - строка ioc_sources из строки ioc_data; reported_at - first_seen фида, если он есть, иначе время записи
+const sourcesColumns = `SELECT value, source, tenant, type, host, ip_start, ip_end,
+	if(first_seen > toDateTime(0), first_seen, added_at) AS reported_at, added_at, last_seen FROM ioc_data`
+
+// migrateSources - ioc_sources и materialized view, который заполняет ее при каждой вставке в ioc_data.
+// При создании view таблица один раз заполняется из ioc_data; строки, уже схлопнутые слиянием,
+// восстановить нельзя, поэтому пересечение по старым данным может быть занижено.
+func (s *ClickHouseStorage) migrateSources() error {
+	var exists uint64
+	err := s.db.QueryRow(`SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = 'ioc_sources_mv'`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check ioc_sources view: %v", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	for _, query := range []string{
+		sourcesTable,
+		`CREATE MATERIALIZED VIEW IF NOT EXISTS ioc_sources_mv TO ioc_sources AS ` + sourcesColumns,
+		// Повторы строк, попавших и через view, и через заполнение, не меняют min/max
+		`INSERT INTO ioc_sources ` + sourcesColumns,
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create ioc_sources: %v", err)
+		}
+	}
+	s.logger.Info("Source facts table created")
+	return nil
+}
+
+// sourceFacts - сведения источника о значении по ioc_sources: одна строка на значение и источник.
+// Строки собственного арендатора и общие с тем же значением и источником сводятся вместе.
+const sourceFacts = `(SELECT value, source, any(type) AS type, any(host) AS host, any(ip_start) AS ip_start, any(ip_end) AS ip_end,
+		min(reported_at) AS reported_at, min(added_at) AS added_at, max(last_seen) AS last_seen
+	FROM ioc_sources WHERE %s GROUP BY value, source) AS facts`
+
+// SourceReport - показатели качества источников; churnDays - глубина истории churn в днях.
+// Считается по ioc_sources, а не по ioc_data: в ioc_data строки одного значения от разных источников
+// схлопываются при слиянии, и пересечение зависело бы от того, успел ли ClickHouse слить куски.
+func (s *ClickHouseStorage) SourceReport(ctx context.Context, churnDays int) (models.SourceReport, error) {
+	report := models.SourceReport{Sources: make(map[string]*models.SourceStats), GeneratedAt: time.Now().UTC()}
+	stats := func(source string) *models.SourceStats {
+		if report.Sources[source] == nil {
+			report.Sources[source] = &models.SourceStats{}
+		}
+		return report.Sources[source]
+	}
+	entries, err := s.ListAllowlist(ctx)
+	if err != nil {
+		return report, err
+	}
+	allowlisted, allowlistArgs := allowlistCondition(clickhouseDialect, entries)
+
+	// Арендатор видит отчет по общим и своим IoC; условие повторяется в каждом чтении ioc_sources
+	where, tenantArgs := tenantCondition(ctx)
+	facts := fmt.Sprintf(sourceFacts, where)
+	twice := append(append([]interface{}{}, tenantArgs...), tenantArgs...)
+
+	// Уникальные и общие значения, совпадающие с текущим allowlist
+	err = s.queryReport(ctx, "totals", `SELECT source, count(*), countIf(n = 1), countIf(n > 1), countIf(allowlisted)
+		FROM (SELECT value, groupUniqArray(source) AS sources, length(sources) AS n, max(`+allowlisted+`) AS allowlisted
+			FROM `+facts+` GROUP BY value)
+		ARRAY JOIN sources AS source
+		GROUP BY source`, append(append([]interface{}{}, allowlistArgs...), tenantArgs...), func(rows *sql.Rows) error {
+		var source string
+		var total, unique, shared, allowlisted uint64
+		if err := rows.Scan(&source, &total, &unique, &shared, &allowlisted); err != nil {
+			return err
+		}
+		st := stats(source)
+		st.Total, st.Unique, st.Shared, st.Allowlisted = int64(total), int64(unique), int64(shared), int64(allowlisted)
+		if total > 0 {
+			st.AllowlistedShare = float64(allowlisted) / float64(total)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Попарное пересечение: пары источников (a < b) каждого общего значения
+	err = s.queryReport(ctx, "overlap", `SELECT pair.1, pair.2, count(*)
+		FROM (SELECT arraySort(groupUniqArray(source)) AS sources FROM `+facts+` GROUP BY value HAVING length(sources) > 1)
+		ARRAY JOIN arrayFlatten(arrayMap((x, i) -> arrayMap(y -> (x, y), arraySlice(sources, i + 1)), sources, arrayEnumerate(sources))) AS pair
+		GROUP BY pair.1, pair.2
+		ORDER BY count(*) DESC`, tenantArgs, func(rows *sql.Rows) error {
+		var overlap models.SourceOverlap
+		var shared uint64
+		if err := rows.Scan(&overlap.SourceA, &overlap.SourceB, &shared); err != nil {
+			return err
+		}
+		overlap.Shared = int64(shared)
+		report.Overlaps = append(report.Overlaps, overlap)
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Отставание от первого сообщившего по общим значениям
+	err = s.queryReport(ctx, "lag", `SELECT source, countIf(lag = 0), toFloat64(quantileExact(0.5)(lag))
+		FROM (
+			SELECT source, toInt64(reported_at) - toInt64(first) AS lag
+			FROM `+facts+`
+			INNER JOIN (SELECT value, min(reported_at) AS first FROM `+facts+` GROUP BY value HAVING count() > 1) AS f
+			USING (value)
+		)
+		GROUP BY source`, twice, func(rows *sql.Rows) error {
+		var source string
+		var firstReported uint64
+		var medianLag float64
+		if err := rows.Scan(&source, &firstReported, &medianLag); err != nil {
+			return err
+		}
+		st := stats(source)
+		st.FirstReported, st.MedianLag = int64(firstReported), medianLag
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Churn: новые значения по дню первой записи, пропавшие - по дню, когда источник сообщал о них последний раз
+	err = s.queryReport(ctx, "churn", `SELECT source, toString(day), sum(added), sum(removed)
+		FROM (
+			SELECT source, toDate(added_at) AS day, 1 AS added, 0 AS removed FROM `+facts+`
+			UNION ALL
+			SELECT source, toDate(last_seen) AS day, 0 AS added, 1 AS removed FROM `+facts+`
+			WHERE last_seen > toDateTime(0) AND day < today()
+		)
+		WHERE day >= today() - ?
+		GROUP BY source, day
+		ORDER BY source, day`, append(twice, churnDays), func(rows *sql.Rows) error {
+		var source string
+		var churn models.DailyChurn
+		var added, removed uint64
+		if err := rows.Scan(&source, &churn.Date, &added, &removed); err != nil {
+			return err
+		}
+		churn.Added, churn.Removed = int64(added), int64(removed)
+		st := stats(source)
+		st.Churn = append(st.Churn, churn)
+		return nil
+	})
+	return report, err
+}
+
+// allowlistCondition - значение совпадает с записью allowlist по тем же правилам, что allowlist.Matcher:
+// value - значение или хост, suffix - хост или его поддомен, cidr - IP IoC (адрес или сеть) внутри сети.
+// Условие строится по колонкам type, value, host, ip_start и ip_end; без записей оно ложно.
+func allowlistCondition(d dialect, entries []models.AllowlistEntry) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case models.AllowlistValue:
+			conditions = append(conditions, `value = ? OR host = ?`)
+			args = append(args, entry.Value, entry.Value)
+		case models.AllowlistSuffix:
+			conditions = append(conditions, `host = ? OR `+d.hasSuffix)
+			args = append(args, entry.Value, "."+entry.Value)
+		case models.AllowlistCIDR:
+			r, err := iprange.Parse(entry.Value)
+			if err != nil {
+				continue
+			}
+			conditions = append(conditions, `type = 'ip' AND ip_start >= `+d.ipParam+` AND ip_end <= `+d.ipParam)
+			args = append(args, r.Start.String(), r.End.String())
+		}
+	}
+	if len(conditions) == 0 {
+		return `1 = 0`, nil
+	}
+	return "((" + strings.Join(conditions, ") OR (") + "))", args
+}
+
+// queryReport - выполняет запрос отчета и передает строки в scan
+func (s *ClickHouseStorage) queryReport(ctx context.Context, name, query string, args []interface{}, scan func(rows *sql.Rows) error) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Failed to build source report", zap.String("part", name), zap.Error(err))
+		return fmt.Errorf("failed to build source report (%s): %v", name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			s.logger.Error("Failed to scan source report row", zap.String("part", name), zap.Error(err))
+			return fmt.Errorf("failed to scan source report row (%s): %v", name, err)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// RecordSightings - запись обнаружений индикаторов от имени арендатора вызова
+func (s *ClickHouseStorage) RecordSightings(ctx context.Context, sightings []models.Sighting) error {
+	query := `INSERT INTO ioc_sightings (value, type, observer, seen_at, count, tenant) VALUES (?, ?, ?, ?, ?, ?)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	owner := tenant.FromContext(ctx)
+	for _, sighting := range sightings {
+		_, err := stmt.ExecContext(ctx, sighting.Value, sighting.Type, sighting.Observer, sighting.SeenAt, sighting.Count, owner)
+		if err != nil {
+			s.logger.Error("Failed to write sighting", zap.String("value", sighting.Value), zap.Error(err))
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SightingsBySource - сколько IoC каждого источника встретилось в sightings и сколько было срабатываний
+func (s *ClickHouseStorage) SightingsBySource(ctx context.Context) (map[string]models.SourceSightings, error) {
+	where, args := tenantCondition(ctx)
+	query := `SELECT source, count(*), sum(sightings) FROM
+		(SELECT DISTINCT source, value FROM ioc_data WHERE ` + where + ` AND hidden = 0) AS iocs
+		INNER JOIN (SELECT value, sum(count) AS sightings FROM ioc_sightings WHERE ` + where + ` GROUP BY value) AS hits USING (value)
+		GROUP BY source`
+	args = append(args, args...)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Failed to count sightings by source", zap.Error(err))
+		return nil, fmt.Errorf("failed to count sightings by source: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]models.SourceSightings)
+	for rows.Next() {
+		var source string
+		var iocs, sightings uint64
+		if err := rows.Scan(&source, &iocs, &sightings); err != nil {
+			s.logger.Error("Failed to scan row in SightingsBySource", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row in SightingsBySource: %v", err)
+		}
+		result[source] = models.SourceSightings{IoCs: int64(iocs), Sightings: int64(sightings)}
+	}
+	return result, rows.Err()
+}
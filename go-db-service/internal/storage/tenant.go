@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"awesomeProject/internal/tenant"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// tenantCondition - строки, видимые вызову: общие и, если вызов идет от арендатора, его собственные.
+// Применяется к ioc_data и к таблицам аналитиков (lifecycle, заметки, связи, sightings).
+// Условие берется только из контекста, поэтому фильтры LoadRequest не могут его обойти.
+func tenantCondition(ctx context.Context) (string, []interface{}) {
+	if tenant.AllTenants(ctx) {
+		return `1 = 1`, nil
+	}
+	if id := tenant.FromContext(ctx); id != tenant.Shared {
+		return `tenant IN ('', ?)`, []interface{}{id}
+	}
+	return `tenant = ''`, nil
+}
+
+// tenantKeys - таблицы ReplacingMergeTree, в ключ сортировки которых входит арендатор: без него
+// собственная запись арендатора и общая запись с тем же ключом схлопывались бы при слиянии
+var tenantKeys = []struct{ table, orderBy string }{
+	{"ioc_data", "(value, tenant)"},
+	{"ioc_relationships", "(from_value, kind, to_value, from_type, to_type, source, tenant)"},
+	{"ioc_lifecycle", "(value, type, tenant)"},
+}
+
+// migrateTenant - колонка арендатора в таблице из tenantKeys вместе с ключом сортировки.
+// ClickHouse разрешает расширить ключ только колонкой, добавленной тем же ALTER, поэтому
+// миграция выполняется один раз, когда колонки еще нет.
+func (s *ClickHouseStorage) migrateTenant(table, orderBy string) error {
+	var exists uint64
+	err := s.db.QueryRow(`SELECT count() FROM system.columns WHERE database = currentDatabase() AND table = ? AND name = 'tenant'`, table).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check tenant column of %s: %v", table, err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := s.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN tenant LowCardinality(String) DEFAULT '', MODIFY ORDER BY ` + orderBy); err != nil {
+		return fmt.Errorf("failed to add tenant column to %s: %v", table, err)
+	}
+	s.logger.Info("Tenant column added", zap.String("table", table), zap.String("orderBy", orderBy))
+	return nil
+}
+
+// CountTenant - число собственных значений арендатора для проверки квоты
+func (s *ClickHouseStorage) CountTenant(ctx context.Context, id string) (int64, error) {
+	var count uint64
+	if err := s.db.QueryRowContext(ctx, `SELECT uniqExact(value) FROM ioc_data WHERE tenant = ?`, id).Scan(&count); err != nil {
+		s.logger.Error("Failed to count tenant IoCs", zap.String("tenant", id), zap.Error(err))
+		return 0, fmt.Errorf("failed to count tenant IoCs: %v", err)
+	}
+	return int64(count), nil
+}
@@ -0,0 +1,83 @@
+package tenant
+
+import (
+	"awesomeProject/internal/auth"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor - арендатор из metadata x-tenant-id; ставится после перехватчика аутентификации
+func (r *Resolver) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := r.fromMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor - арендатор из metadata x-tenant-id для стримов
+func (r *Resolver) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := r.fromMetadata(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &tenantStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (r *Resolver) fromMetadata(ctx context.Context) (context.Context, error) {
+	var requested string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataKey); len(values) > 0 {
+			requested = strings.TrimSpace(values[0])
+		}
+	}
+	id, err := r.Resolve(auth.IdentityFromContext(ctx), requested)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return WithTenant(ctx, id), nil
+}
+
+// Middleware - арендатор из заголовка X-Tenant-ID; ставится внутри проверки аутентификации
+func (r *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id, err := r.Resolve(auth.IdentityFromContext(req.Context()), strings.TrimSpace(req.Header.Get(HeaderKey)))
+		if err != nil {
+			code := http.StatusForbidden
+			if errors.Is(err, ErrInvalid) {
+				code = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(WithTenant(req.Context(), id)))
+	})
+}
+
+func grpcStatus(err error) error {
+	if errors.Is(err, ErrInvalid) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.PermissionDenied, err.Error())
+}
+
+// tenantStream - стрим с контекстом, в который добавлен арендатор
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,146 @@
+package tenant
+
+import (
+	"awesomeProject/internal/auth"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Shared - арендатор общих IoC из фидов, которые видны всем
+const Shared = ""
+
+// Ключи, в которых клиент передает арендатора
+const (
+	MetadataKey = "x-tenant-id" // gRPC metadata
+	HeaderKey   = "X-Tenant-ID" // HTTP заголовок
+)
+
+var (
+	ErrInvalid       = errors.New("invalid tenant")
+	ErrForbidden     = errors.New("tenant is not allowed for this client")
+	ErrQuotaExceeded = errors.New("tenant quota exceeded")
+)
+
+// idPattern - идентификатор арендатора подставляется в запросы и метаданные, поэтому набор символов ограничен
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Validate - проверка идентификатора арендатора
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q, expected [a-z0-9_-], up to 64 characters", ErrInvalid, id)
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+// WithTenant - кладет арендатора вызова в контекст
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext - арендатор вызова; Shared - вызов без арендатора, которому видны только общие IoC
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey{}).(string)
+	return id
+}
+
+type allTenantsKey struct{}
+
+// WithAllTenants - вызов видит записи всех арендаторов. Только для внутренних задач сервиса
+// (индекс статусов lifecycle); клиентские вызовы так не помечаются.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey{}, true)
+}
+
+// AllTenants - видит ли вызов записи всех арендаторов
+func AllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsKey{}).(bool)
+	return all
+}
+
+// Visible - виден ли IoC арендатора owner вызову арендатора viewer
+func Visible(viewer, owner string) bool {
+	return owner == Shared || owner == viewer
+}
+
+// Resolver - какие арендаторы разрешены клиентам из AUTH_CLIENTS
+type Resolver struct {
+	clients map[string][]string // имя клиента -> арендаторы
+}
+
+// NewResolver - разбирает список вида "siem-a:unit-a,analyst:unit-a|unit-b"
+func NewResolver(spec string) (*Resolver, error) {
+	r := &Resolver{clients: make(map[string][]string)}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, tenants, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || tenants == "" {
+			return nil, fmt.Errorf("invalid tenant client entry %q, expected client:tenant", pair)
+		}
+		for _, id := range strings.Split(tenants, "|") {
+			if err := Validate(id); err != nil {
+				return nil, err
+			}
+			r.clients[name] = append(r.clients[name], id)
+		}
+	}
+	return r, nil
+}
+
+// Resolve - арендатор вызова по проверенному имени клиента и запрошенному арендатору.
+// Без запроса клиент с единственным арендатором работает в нем, остальные - только с общими IoC.
+// Арендатор из запроса принимается только у клиента, которому он разрешен, поэтому
+// заявленное без токена имя (unverified:) арендатора не получает.
+func (r *Resolver) Resolve(identity, requested string) (string, error) {
+	allowed := r.clients[identity]
+	if strings.HasPrefix(identity, auth.UnverifiedPrefix) {
+		allowed = nil
+	}
+	if requested == "" {
+		if len(allowed) == 1 {
+			return allowed[0], nil
+		}
+		return Shared, nil
+	}
+	if err := Validate(requested); err != nil {
+		return "", err
+	}
+	for _, id := range allowed {
+		if id == requested {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrForbidden, requested)
+}
+
+// ParseQuotas - лимиты числа собственных IoC арендаторов вида "unit-a:1000000,unit-b:50000"
+func ParseQuotas(spec string) (map[string]int64, error) {
+	quotas := make(map[string]int64)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid tenant quota %q, expected tenant:limit", pair)
+		}
+		if err := Validate(id); err != nil {
+			return nil, err
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid tenant quota %q: limit must be a positive number", pair)
+		}
+		quotas[id] = limit
+	}
+	return quotas, nil
+}
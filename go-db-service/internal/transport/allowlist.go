@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"awesomeProject/internal/allowlist"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *Handler) ListAllowlist(ctx context.Context, _ *empty.Empty) (*protogen.AllowlistEntries, error) {
+	entries, err := h.service.ListAllowlist(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error listing allowlist: %v", err))
+		return nil, err
+	}
+	return models.ToProtoAllowlistEntries(entries), nil
+}
+
+// PutAllowlistEntries создает записи без id и изменяет записи с id; только для административных клиентов
+func (h *Handler) PutAllowlistEntries(ctx context.Context, req *protogen.AllowlistEntries) (*protogen.AllowlistEntries, error) {
+	entries := make([]models.AllowlistEntry, len(req.Entries))
+	for i, entry := range req.Entries {
+		entries[i] = models.ToModelAllowlistEntry(entry)
+	}
+	if err := requireAdmin(ctx, "PutAllowlistEntries"); err != nil {
+		h.audit.Record(ctx, "PutAllowlistEntries", entries, 0, err)
+		return nil, err
+	}
+
+	saved, err := h.service.PutAllowlistEntries(ctx, entries)
+	h.audit.Record(ctx, "PutAllowlistEntries", entries, len(saved), err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error saving allowlist entries: %v", err))
+		return nil, allowlistStatus(err)
+	}
+	return models.ToProtoAllowlistEntries(saved), nil
+}
+
+// DeleteAllowlistEntry удаляет запись allowlist; только для административных клиентов
+func (h *Handler) DeleteAllowlistEntry(ctx context.Context, req *protogen.DeleteAllowlistEntryRequest) (*empty.Empty, error) {
+	if err := requireAdmin(ctx, "DeleteAllowlistEntry"); err != nil {
+		h.audit.Record(ctx, "DeleteAllowlistEntry", map[string]string{"id": req.Id}, 0, err)
+		return nil, err
+	}
+	err := h.service.DeleteAllowlistEntry(ctx, req.Id)
+	h.audit.Record(ctx, "DeleteAllowlistEntry", map[string]string{"id": req.Id}, rowsAffected(err), err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error deleting allowlist entry: %v", err))
+		return nil, allowlistStatus(err)
+	}
+	return &empty.Empty{}, nil
+}
+
+func allowlistStatus(err error) error {
+	switch {
+	case errors.Is(err, allowlist.ErrInvalidEntry):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, allowlist.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
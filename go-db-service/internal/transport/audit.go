@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/service"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SetAudit - подключает журнал аудита записей и административных вызовов
+func (h *Handler) SetAudit(log *audit.Log) {
+	h.audit = log
+}
+
+// SetAudit - подключает журнал аудита выгрузок
+func (h *HTTPHandler) SetAudit(log *audit.Log) {
+	h.audit = log
+}
+
+// QueryAudit возвращает записи журнала аудита только административным клиентам;
+// сам запрос к журналу, в том числе отклоненный, тоже попадает в журнал
+func (h *Handler) QueryAudit(ctx context.Context, req *protogen.AuditQuery) (*protogen.AuditRecords, error) {
+	query := models.ToModelAuditQuery(req)
+	if err := requireAdmin(ctx, "QueryAudit"); err != nil {
+		h.audit.Record(ctx, "QueryAudit", query, 0, err)
+		return nil, err
+	}
+	records, err := h.service.QueryAudit(ctx, query)
+	h.audit.Record(ctx, "QueryAudit", query, len(records), err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error querying audit log: %v", err))
+		if errors.Is(err, service.ErrInvalidAuditQuery) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+	return models.ToProtoAuditRecords(records), nil
+}
+
+// requireAdmin - PermissionDenied для вызовов не от клиента из AUTH_ADMINS
+func requireAdmin(ctx context.Context, method string) error {
+	if auth.IsAdmin(ctx) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "%s requires an admin client token", method)
+}
+
+// batchParams - параметры записи пачки IoC для аудита: сами значения не пишутся, только источники и типы
+type batchParams struct {
+	Sources []string `json:"sources,omitempty"`
+	Types   []string `json:"types,omitempty"`
+}
+
+// batchCounter - собирает batchParams по мере получения IoC
+type batchCounter struct {
+	rows    int
+	sources map[string]struct{}
+	types   map[string]struct{}
+}
+
+func (c *batchCounter) add(ioc models.IoCDto) {
+	if c.sources == nil {
+		c.sources = make(map[string]struct{})
+		c.types = make(map[string]struct{})
+	}
+	c.rows++
+	c.sources[ioc.Source] = struct{}{}
+	c.types[ioc.Type] = struct{}{}
+}
+
+func (c *batchCounter) params() batchParams {
+	return batchParams{Sources: sortedKeys(c.sources), Types: sortedKeys(c.types)}
+}
+
+// countBatch - параметры и число строк готовой пачки
+func countBatch(iocs []models.IoCDto) (batchParams, int) {
+	var counter batchCounter
+	for _, ioc := range iocs {
+		counter.add(ioc)
+	}
+	return counter.params(), counter.rows
+}
+
+// rowsAffected - одна строка для успешных вызовов, меняющих одну запись
+func rowsAffected(err error) int {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,24 @@
+package transport
+
+import (
+	"awesomeProject/internal/auth"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/pkg/logger"
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQueryAuditRequiresAdmin(t *testing.T) {
+	h := NewHandler(newTestService(t), *logger.NewNop())
+
+	_, err := h.QueryAudit(auth.WithIdentity(context.Background(), "siem"), &protogen.AuditQuery{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("non-admin caller: got %v, want PermissionDenied", err)
+	}
+	if _, err := h.QueryAudit(auth.WithAdmin(context.Background()), &protogen.AuditQuery{}); err != nil {
+		t.Fatalf("admin caller: %v", err)
+	}
+}
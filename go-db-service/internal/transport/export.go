@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"awesomeProject/internal/export"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const exportChunkSize = 64 * 1024
+
+// Export выгружает IoC в запрошенном формате кусками по exportChunkSize
+func (h *Handler) Export(req *protogen.ExportRequest, stream protogen.Database_ExportServer) error {
+	request := models.ToModelLoadRequest(req.Query)
+	if err := export.Validate(req.Format, request); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := validateLoadRequest(request); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	writer := &chunkWriter{stream: stream}
+	count, err := exportIoCs(stream.Context(), h.service, req.Format, request, writer)
+	if err == nil {
+		err = writer.flush()
+	}
+	h.audit.Record(stream.Context(), "Export", exportParams{Format: req.Format, Query: request}, count, err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Export: failed after %d IoCs: %v", count, err))
+		return err
+	}
+
+	h.logger.Info(fmt.Sprintf("Export: %d IoCs exported as %s", count, req.Format))
+	return nil
+}
+
+// exportParams - параметры выгрузки для журнала аудита
+type exportParams struct {
+	Format string             `json:"format"`
+	Query  models.LoadRequest `json:"query"`
+}
+
+// exportIoCs - общая часть gRPC и HTTP выгрузки: читает поток из сервиса и кодирует его в w;
+// count - сколько IoC попало в выгрузку, без пропущенных форматом
+func exportIoCs(ctx context.Context, service Service, format string, request models.LoadRequest, w io.Writer) (int, error) {
+	encoder, err := export.NewWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	loadChannel, errChan, err := service.Load(ctx, request)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return count, ctx.Err()
+		case ioc, open := <-loadChannel:
+			if !open {
+				// Без этой проверки сбой хранилища выглядел бы как полная выгрузка
+				if err := <-errChan; err != nil {
+					return count, err
+				}
+				return count, encoder.Close()
+			}
+			written, err := encoder.Write(ioc)
+			if err != nil {
+				return count, err
+			}
+			if written {
+				count++
+			}
+		}
+	}
+}
+
+// chunkWriter - io.Writer, который копит байты и отправляет их в стрим кусками
+type chunkWriter struct {
+	stream protogen.Database_ExportServer
+	buf    []byte
+}
+
+// Write - каждый кусок уходит в стрим своим срезом: Send может держать сообщение после возврата,
+// поэтому общий буфер под отправленным куском не переиспользуется
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for len(c.buf) >= exportChunkSize {
+		chunk := make([]byte, exportChunkSize)
+		copy(chunk, c.buf)
+		if err := c.stream.Send(&protogen.ExportChunk{Data: chunk}); err != nil {
+			return 0, err
+		}
+		c.buf = append(c.buf[:0], c.buf[exportChunkSize:]...)
+	}
+	return len(p), nil
+}
+
+func (c *chunkWriter) flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	chunk := c.buf
+	c.buf = nil
+	return c.stream.Send(&protogen.ExportChunk{Data: chunk})
+}
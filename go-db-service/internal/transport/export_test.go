@@ -0,0 +1,84 @@
+package transport
+
+import (
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestHTTPExportNDJSON(t *testing.T) {
+	h := newTestHTTPHandler(t, newTestService(t,
+		newIoC("feed", models.TypeDomain, "a.example.com"),
+		newIoC("feed", models.TypeDomain, "b.example.com"),
+		newIoC("other", models.TypeDomain, "c.example.com"),
+	), nil)
+
+	w := get(h, "/api/v1/export?format=ndjson&source=feed")
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", w.Code, w.Body.String())
+	}
+	var values []string
+	decoder := json.NewDecoder(w.Body)
+	for decoder.More() {
+		var ioc models.IoCDto
+		if err := decoder.Decode(&ioc); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		values = append(values, ioc.Value)
+	}
+	sort.Strings(values)
+	if strings.Join(values, ",") != "a.example.com,b.example.com" {
+		t.Fatalf("export: got %v", values)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/export", nil)
+	unauthorized := httptest.NewRecorder()
+	h.ServeHTTP(unauthorized, r)
+	if unauthorized.Code != http.StatusUnauthorized {
+		t.Fatalf("export without token: status %d", unauthorized.Code)
+	}
+}
+
+// exportStream - gRPC стрим выгрузки, который запоминает отправленные куски
+type exportStream struct {
+	grpc.ServerStream
+	chunks [][]byte
+}
+
+func (s *exportStream) Send(chunk *protogen.ExportChunk) error {
+	s.chunks = append(s.chunks, chunk.Data)
+	return nil
+}
+
+func TestChunkWriterDoesNotReuseSentChunks(t *testing.T) {
+	stream := &exportStream{}
+	writer := &chunkWriter{stream: stream}
+
+	first := bytes.Repeat([]byte("a"), exportChunkSize+10)
+	second := bytes.Repeat([]byte("b"), exportChunkSize)
+	if _, err := writer.Write(first); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := writer.Write(second); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := writer.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	got := bytes.Join(stream.chunks, nil)
+	if !bytes.Equal(got, append(first, second...)) {
+		t.Fatalf("stream data differs from written data (%d chunks, %d bytes)", len(stream.chunks), len(got))
+	}
+	if !bytes.Equal(stream.chunks[0], first[:exportChunkSize]) {
+		t.Fatal("first chunk was overwritten by later writes")
+	}
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"awesomeProject/internal/graph"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StoreRelationships записывает связи между индикаторами
+func (h *Handler) StoreRelationships(ctx context.Context, req *protogen.StoreRelationshipsRequest) (*empty.Empty, error) {
+	relationships := make([]models.Relationship, len(req.Relationships))
+	for i, rel := range req.Relationships {
+		relationships[i] = models.ToModelRelationship(rel)
+	}
+
+	err := h.service.StoreRelationships(ctx, relationships)
+	h.audit.Record(ctx, "StoreRelationships", nil, len(relationships), err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error storing relationships: %v", err))
+		return nil, graphStatus(err)
+	}
+	h.logger.Info(fmt.Sprintf("Successfully stored %d relationships", len(relationships)))
+	return &empty.Empty{}, nil
+}
+
+// Neighbors возвращает подграф вокруг значения для пивотинга
+func (h *Handler) Neighbors(ctx context.Context, req *protogen.NeighborsRequest) (*protogen.NeighborsResponse, error) {
+	subgraph, err := h.service.Neighbors(ctx, models.NeighborsRequest{
+		Value: req.Value,
+		Depth: int(req.Depth),
+		Kinds: req.Kinds,
+		Limit: int(req.Limit),
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error loading neighbors: %v", err))
+		return nil, graphStatus(err)
+	}
+	return models.ToProtoSubgraph(subgraph), nil
+}
+
+func graphStatus(err error) error {
+	if errors.Is(err, graph.ErrInvalidRelationship) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
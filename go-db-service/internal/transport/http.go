@@ -0,0 +1,261 @@
+package transport
+
+import (
+	"awesomeProject/internal/alerts"
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/export"
+	"awesomeProject/internal/pipeline"
+	"awesomeProject/internal/spool"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	log "awesomeProject/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// HTTPHandler - HTTP ручки сервиса для внешних потребителей (выгрузки файлов, TAXII).
+// Все ручки, кроме /health, требуют аутентификации клиента.
+type HTTPHandler struct {
+	service       Service
+	authenticator *auth.Authenticator
+	logger        log.CustomZapLogger
+	mux           *http.ServeMux
+	tenants       *tenant.Resolver
+	limits        *Limits    // Ограничения выгрузок, TAXII и MISP фида, может быть nil
+	audit         *audit.Log // Журнал аудита выгрузок, может быть nil
+	taxiiSources  taxiiSources
+}
+
+func NewHTTPHandler(service Service, authenticator *auth.Authenticator, tenants *tenant.Resolver, limits *Limits, logger log.CustomZapLogger) *HTTPHandler {
+	h := &HTTPHandler{service: service, authenticator: authenticator, tenants: tenants, limits: limits, logger: logger, mux: http.NewServeMux()}
+
+	h.mux.Handle("GET /api/v1/export", h.protectLimited(LimitHTTPExport, h.Export))
+	h.mux.Handle("GET /api/v1/sources/report", h.protect(h.SourceReport))
+	h.mux.HandleFunc("GET /health", h.Health)
+	h.registerTaxii()
+	return h
+}
+
+// protect - оборачивает ручку проверкой аутентификации и выбором арендатора клиента
+func (h *HTTPHandler) protect(handler http.HandlerFunc) http.Handler {
+	return h.authenticator.Middleware(h.tenants.Middleware(handler))
+}
+
+// protectLimited - как protect, но еще с ограничениями клиента по правилам метода method
+func (h *HTTPHandler) protectLimited(method string, handler http.HandlerFunc) http.Handler {
+	return h.authenticator.Middleware(h.tenants.Middleware(h.limits.Middleware(method, handler)))
+}
+
+// ServeMISPFeed - раздача каталога MISP фида по /misp/feed/ (manifest.json, hashes.csv, <uuid>.json)
+func (h *HTTPHandler) ServeMISPFeed(dir string) {
+	files := http.StripPrefix("/misp/feed/", http.FileServer(http.Dir(dir)))
+	h.mux.Handle("GET /misp/feed/", h.protectLimited(LimitMISPFeed, func(w http.ResponseWriter, r *http.Request) {
+		// Служебные и временные файлы генератора начинаются с точки
+		if strings.HasPrefix(path.Base(r.URL.Path), ".") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+}
+
+// ServeAlertDeliveries - журнал доставки вебхуков: GET /api/v1/alerts/deliveries?limit=
+func (h *HTTPHandler) ServeAlertDeliveries(deliveryLog *alerts.DeliveryLog) {
+	h.mux.Handle("GET /api/v1/alerts/deliveries", h.protect(func(w http.ResponseWriter, r *http.Request) {
+		limit := 0 // Все записи, которые журнал держит в памяти
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				http.Error(w, fmt.Sprintf("invalid limit: %s", value), http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"deliveries": deliveryLog.Recent(limit)})
+	}))
+}
+
+// ServePipelineStats - метрики шагов обогащения: GET /api/v1/pipeline/stats
+func (h *HTTPHandler) ServePipelineStats(enrichment *pipeline.Pipeline) {
+	h.mux.Handle("GET /api/v1/pipeline/stats", h.protect(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"stages": enrichment.Stats()})
+	}))
+}
+
+// ServeExecutorStats - занятость пулов задач сервиса: GET /api/v1/executor/stats
+func (h *HTTPHandler) ServeExecutorStats() {
+	h.mux.Handle("GET /api/v1/executor/stats", h.protect(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"pools": h.service.ExecutorStats()})
+	}))
+}
+
+// ServeSpoolStats - размер и возраст spool неотправленных пачек: GET /api/v1/spool/stats
+func (h *HTTPHandler) ServeSpoolStats() {
+	h.mux.Handle("GET /api/v1/spool/stats", h.protect(func(w http.ResponseWriter, r *http.Request) {
+		stats, _ := h.service.SpoolStats()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"spool": stats})
+	}))
+}
+
+// ServeCacheStats - попадания и промахи кэша запросов: GET /api/v1/cache/stats
+func (h *HTTPHandler) ServeCacheStats() {
+	h.mux.Handle("GET /api/v1/cache/stats", h.protect(func(w http.ResponseWriter, r *http.Request) {
+		stats, invalidations, _ := h.service.CacheStats()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"methods": stats, "invalidations": invalidations})
+	}))
+}
+
+// ServeAuditStats - сколько записей аудита потеряно из-за очереди или ошибок хранилища: GET /api/v1/audit/stats
+func (h *HTTPHandler) ServeAuditStats() {
+	h.mux.Handle("GET /api/v1/audit/stats", h.protect(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"dropped": h.audit.Dropped()})
+	}))
+}
+
+// Health - GET /health для проверок живости без аутентификации.
+// Пока в spool есть неотправленные пачки, статус degraded: хранилище недоступно или еще догоняет.
+func (h *HTTPHandler) Health(w http.ResponseWriter, r *http.Request) {
+	response := struct {
+		Status string       `json:"status"`
+		Spool  *spool.Stats `json:"spool,omitempty"`
+	}{Status: "ok"}
+	if stats, ok := h.service.SpoolStats(); ok {
+		response.Spool = &stats
+		if stats.Batches > 0 {
+			response.Status = "degraded"
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Export - GET /api/v1/export?format=stix|csv|ndjson|blocklist&type=&source=&filter=&ip_within=&ip_contains=&registrable_domain=&subdomain_of=&tld=&country=&asn=&status=&limit=&offset=
+func (h *HTTPHandler) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+
+	request, err := parseLoadRequest(query.Get("limit"), query.Get("offset"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	request.Limit = clampHTTPRows(w, r, request.Limit)
+	request.Filter = query.Get("filter")
+	request.Type = query.Get("type")
+	request.Source = query.Get("source")
+	request.IPContains = query.Get("ip_contains")
+	request.RegistrableDomain = query.Get("registrable_domain")
+	request.SubdomainOf = query.Get("subdomain_of")
+	request.TLD = query.Get("tld")
+	request.Country = query.Get("country")
+	if asn := query.Get("asn"); asn != "" {
+		number, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(asn), "AS"), 10, 32)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid asn: %s", asn), http.StatusBadRequest)
+			return
+		}
+		request.ASN = uint32(number)
+	}
+	for _, value := range query["status"] {
+		request.Statuses = append(request.Statuses, strings.Split(value, ",")...)
+	}
+	for _, value := range query["ip_within"] {
+		request.IPWithin = append(request.IPWithin, strings.Split(value, ",")...)
+	}
+
+	if err := export.Validate(format, request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateLoadRequest(request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName(format, request.Type)))
+
+	writer := &flushWriter{w: w}
+	count, err := exportIoCs(r.Context(), h.service, format, request, writer)
+	h.audit.Record(audit.WithPeer(r.Context(), r.RemoteAddr), audit.MethodHTTPExport, exportParams{Format: format, Query: request}, count, err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("HTTP export failed after %d IoCs: %v", count, err))
+		if !writer.wrote {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, "export failed", http.StatusInternalServerError)
+			return
+		}
+		// Заголовки уже отправлены: обрываем соединение, чтобы клиент не принял часть выгрузки за всю
+		panic(http.ErrAbortHandler)
+	}
+	h.logger.Info(fmt.Sprintf("HTTP export: %d IoCs exported as %s", count, format))
+}
+
+// SourceReport - GET /api/v1/sources/report?churn_days=
+func (h *HTTPHandler) SourceReport(w http.ResponseWriter, r *http.Request) {
+	churnDays := 0
+	if value := r.URL.Query().Get("churn_days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			http.Error(w, fmt.Sprintf("invalid churn_days: %s", value), http.StatusBadRequest)
+			return
+		}
+		churnDays = days
+	}
+
+	report, err := h.service.SourceReport(r.Context(), churnDays)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("HTTP source report failed: %v", err))
+		http.Error(w, "failed to build source report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// parseLoadRequest - разбирает пагинацию из query параметров; пустые значения означают 0
+func parseLoadRequest(limit, offset string) (models.LoadRequest, error) {
+	var request models.LoadRequest
+	var err error
+	if limit != "" {
+		if request.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil || request.Limit < 0 {
+			return request, fmt.Errorf("invalid limit: %s", limit)
+		}
+	}
+	if offset != "" {
+		if request.Offset, err = strconv.ParseInt(offset, 10, 64); err != nil || request.Offset < 0 {
+			return request, fmt.Errorf("invalid offset: %s", offset)
+		}
+	}
+	return request, nil
+}
+
+// flushWriter - сбрасывает каждый записанный кусок клиенту, чтобы выгрузка не копилась в памяти
+type flushWriter struct {
+	w     http.ResponseWriter
+	wrote bool // Отправлено ли клиенту хоть что-то, после чего статус уже не поменять
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.wrote = true
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,17 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAlertDeliveriesRejectsInvalidLimit(t *testing.T) {
+	h := newTestHTTPHandler(t, newTestService(t), nil)
+	h.ServeAlertDeliveries(nil)
+
+	for _, limit := range []string{"-1", "ten"} {
+		if w := get(h, "/api/v1/alerts/deliveries?limit="+limit); w.Code != http.StatusBadRequest {
+			t.Fatalf("limit=%s: status %d, want 400", limit, w.Code)
+		}
+	}
+}
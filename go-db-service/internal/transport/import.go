@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"awesomeProject/internal/importer"
+	"awesomeProject/internal/tenant"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Import принимает файл кусками и импортирует его; опции импорта приходят в первом сообщении
+func (h *Handler) Import(stream protogen.Database_ImportServer) error {
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "empty import stream")
+	}
+	if err != nil {
+		return err
+	}
+	if first.Options == nil {
+		return status.Error(codes.InvalidArgument, "first import message must contain options")
+	}
+	opts := importer.Options{
+		Format:  first.Options.Format,
+		Source:  first.Options.Source,
+		Type:    first.Options.Type,
+		Columns: first.Options.Columns,
+		BatchID: first.Options.BatchId,
+	}
+
+	// Перекладываем куски из стрима в pipe, чтобы импорт читал файл как обычный io.Reader
+	reader, writer := io.Pipe()
+	go func() {
+		if _, err := writer.Write(first.Data); err != nil {
+			return
+		}
+		for {
+			req, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				writer.Close()
+				return
+			}
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if _, err := writer.Write(req.Data); err != nil {
+				return
+			}
+		}
+	}()
+
+	result, err := h.service.Import(stream.Context(), reader, opts)
+	opts.BatchID = result.BatchID // Сгенерированный идентификатор нужен в аудите, чтобы найти строки импорта
+	h.audit.Record(stream.Context(), "Import", opts, result.Accepted, err)
+	// Разблокируем горутину чтения, если импорт завершился раньше конца стрима
+	reader.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Import %s failed after %d IoCs: %v", result.BatchID, result.Accepted, err))
+		if errors.Is(err, tenant.ErrInvalid) || errors.Is(err, tenant.ErrForbidden) || errors.Is(err, tenant.ErrQuotaExceeded) {
+			return tenantStatus(err)
+		}
+		return status.Error(codes.Internal, fmt.Sprintf("import %s failed after %d IoCs: %v", result.BatchID, result.Accepted, err))
+	}
+
+	response := &protogen.ImportResponse{
+		BatchId:  result.BatchID,
+		Accepted: int64(result.Accepted),
+		Rejected: int64(result.Rejected),
+	}
+	for _, lineErr := range result.Errors {
+		response.Errors = append(response.Errors, &protogen.ImportLineError{Line: int64(lineErr.Line), Error: lineErr.Error})
+	}
+
+	h.logger.Info(fmt.Sprintf("Import %s: accepted %d, rejected %d", result.BatchID, result.Accepted, result.Rejected))
+	return stream.SendAndClose(response)
+}
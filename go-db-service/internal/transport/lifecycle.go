@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/lifecycle"
+	"awesomeProject/internal/tenant"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SetStatus меняет статус lifecycle IoC (false positive, отзыв, истечение или возврат в active)
+func (h *Handler) SetStatus(ctx context.Context, req *protogen.SetStatusRequest) (*protogen.Lifecycle, error) {
+	ref := req.GetIoc()
+	author, err := lifecycleAuthor(ctx, "SetStatus")
+	if err != nil {
+		h.audit.Record(ctx, "SetStatus", req, 0, err)
+		return nil, err
+	}
+	record, err := h.service.SetStatus(ctx, ref.GetType(), ref.GetValue(), req.Status, req.Reason, author)
+	h.audit.Record(ctx, "SetStatus", req, rowsAffected(err), err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error setting IoC status: %v", err))
+		return nil, lifecycleStatus(err)
+	}
+	return models.ToProtoLifecycle(record), nil
+}
+
+// SetManualTags заменяет ручные теги IoC
+func (h *Handler) SetManualTags(ctx context.Context, req *protogen.SetManualTagsRequest) (*protogen.Lifecycle, error) {
+	ref := req.GetIoc()
+	author, err := lifecycleAuthor(ctx, "SetManualTags")
+	if err != nil {
+		h.audit.Record(ctx, "SetManualTags", req, 0, err)
+		return nil, err
+	}
+	record, err := h.service.SetManualTags(ctx, ref.GetType(), ref.GetValue(), req.Tags, author)
+	h.audit.Record(ctx, "SetManualTags", req, rowsAffected(err), err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error setting manual tags: %v", err))
+		return nil, lifecycleStatus(err)
+	}
+	return models.ToProtoLifecycle(record), nil
+}
+
+// AddNote добавляет заметку аналитика к IoC
+func (h *Handler) AddNote(ctx context.Context, req *protogen.AddNoteRequest) (*protogen.Note, error) {
+	ref := req.GetIoc()
+	author, err := lifecycleAuthor(ctx, "AddNote")
+	if err != nil {
+		h.audit.Record(ctx, "AddNote", ref, 0, err)
+		return nil, err
+	}
+	note, err := h.service.AddNote(ctx, ref.GetType(), ref.GetValue(), author, req.Text)
+	h.audit.Record(ctx, "AddNote", ref, rowsAffected(err), err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error adding note: %v", err))
+		return nil, lifecycleStatus(err)
+	}
+	return models.ToProtoNote(note), nil
+}
+
+func (h *Handler) GetLifecycle(ctx context.Context, req *protogen.IoCRef) (*protogen.Lifecycle, error) {
+	record, err := h.service.GetLifecycle(ctx, req.Type, req.Value)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error loading lifecycle: %v", err))
+		return nil, lifecycleStatus(err)
+	}
+	return models.ToProtoLifecycle(record), nil
+}
+
+func (h *Handler) ListLifecycle(ctx context.Context, req *protogen.ListLifecycleRequest) (*protogen.LifecycleList, error) {
+	if req.Limit < 0 || req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+	records, err := h.service.ListLifecycle(ctx, req.Statuses, req.Limit, req.Offset)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error listing lifecycle: %v", err))
+		return nil, lifecycleStatus(err)
+	}
+	response := &protogen.LifecycleList{Records: make([]*protogen.Lifecycle, len(records))}
+	for i, record := range records {
+		response.Records[i] = models.ToProtoLifecycle(record)
+	}
+	return response, nil
+}
+
+// lifecycleAuthor - автор изменения lifecycle: только клиент, подтвержденный токеном
+// (Unauthenticated без него). Пометки без арендатора общие и скрывают IoC у всех арендаторов,
+// поэтому их ставят только административные клиенты. Поля changed_by и author из запроса
+// не используются, иначе автора можно подставить любого.
+func lifecycleAuthor(ctx context.Context, method string) (string, error) {
+	author, ok := auth.VerifiedIdentity(ctx)
+	if !ok {
+		return "", status.Errorf(codes.Unauthenticated, "%s requires a client token", method)
+	}
+	if tenant.FromContext(ctx) == "" {
+		if err := requireAdmin(ctx, method); err != nil {
+			return "", err
+		}
+	}
+	return author, nil
+}
+
+func lifecycleStatus(err error) error {
+	if errors.Is(err, lifecycle.ErrInvalid) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
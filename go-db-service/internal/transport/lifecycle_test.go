@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/tenant"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLifecycleWritesRequireVerifiedClient(t *testing.T) {
+	h := NewHandler(newTestService(t, newIoC("feed", models.TypeDomain, "fp.example.com")), *logger.NewNop())
+	ref := &protogen.IoCRef{Type: models.TypeDomain, Value: "fp.example.com"}
+	setStatus := &protogen.SetStatusRequest{Ioc: ref, Status: models.StatusFalsePositive, Reason: "internal host"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"anonymous", context.Background(), codes.Unauthenticated},
+		{"unverified client id", auth.WithIdentity(context.Background(), auth.UnverifiedPrefix+"analyst"), codes.Unauthenticated},
+		{"shared mark by non-admin", auth.WithIdentity(context.Background(), "analyst"), codes.PermissionDenied},
+		{"tenant mark", tenant.WithTenant(auth.WithIdentity(context.Background(), "analyst"), "unit-a"), codes.OK},
+		{"shared mark by admin", auth.WithAdmin(auth.WithIdentity(context.Background(), "admin")), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.SetStatus(tt.ctx, setStatus)
+			if status.Code(err) != tt.code {
+				t.Fatalf("SetStatus: got %v, want %v", err, tt.code)
+			}
+			_, err = h.SetManualTags(tt.ctx, &protogen.SetManualTagsRequest{Ioc: ref, Tags: []string{"reviewed"}})
+			if status.Code(err) != tt.code {
+				t.Fatalf("SetManualTags: got %v, want %v", err, tt.code)
+			}
+			_, err = h.AddNote(tt.ctx, &protogen.AddNoteRequest{Ioc: ref, Text: "checked"})
+			if status.Code(err) != tt.code {
+				t.Fatalf("AddNote: got %v, want %v", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestLifecycleAuthorIsVerifiedClient(t *testing.T) {
+	h := NewHandler(newTestService(t, newIoC("feed", models.TypeDomain, "fp.example.com")), *logger.NewNop())
+	ctx := tenant.WithTenant(auth.WithIdentity(context.Background(), "analyst"), "unit-a")
+
+	record, err := h.SetStatus(ctx, &protogen.SetStatusRequest{
+		Ioc:       &protogen.IoCRef{Type: models.TypeDomain, Value: "FP.example.com"},
+		Status:    models.StatusFalsePositive,
+		Reason:    "internal host",
+		ChangedBy: "someone-else",
+	})
+	if err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if record.ChangedBy != "analyst" || record.Value != "fp.example.com" {
+		t.Fatalf("SetStatus: got changed_by %q, value %q", record.ChangedBy, record.Value)
+	}
+}
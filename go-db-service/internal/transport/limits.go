@@ -0,0 +1,256 @@
+package transport
+
+import (
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/executor"
+	"awesomeProject/internal/ratelimit"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	log "awesomeProject/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	retryAfterKey = "retry-after" // Секунды до повтора в trailer metadata отклоненного вызова
+	maxRowsHeader = "x-max-rows"  // Лимит строк в trailer metadata и заголовке HTTP, если limit запроса был урезан
+
+	busyRetryAfter = time.Second // Когда повторять вызов, если занят стрим клиента или очередь задач
+)
+
+// Имена HTTP ручек в правилах ограничений, наравне с методами gRPC
+const (
+	LimitHTTPExport = "HTTPExport"
+	LimitTAXII      = "TAXII"
+	LimitMISPFeed   = "MISPFeed"
+)
+
+type maxRowsKey struct{}
+
+// Limits - ограничения клиентов gRPC API: частота вызовов, одновременные стримы и число строк в ответе.
+// Правила задаются по методу и клиенту, см. ratelimit.Rules.
+type Limits struct {
+	rates   *ratelimit.Limiter
+	streams *ratelimit.Concurrency
+	maxRows ratelimit.Rules[int64]
+	logger  log.CustomZapLogger
+}
+
+func NewLimits(rates, streams, maxRows string, logger log.CustomZapLogger) (*Limits, error) {
+	rateRules, err := ratelimit.ParseRates(rates)
+	if err != nil {
+		return nil, fmt.Errorf("rate limits: %w", err)
+	}
+	streamRules, err := ratelimit.ParseCounts(streams)
+	if err != nil {
+		return nil, fmt.Errorf("stream limits: %w", err)
+	}
+	rowRules, err := ratelimit.ParseCounts(maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("max result rows: %w", err)
+	}
+	return &Limits{
+		rates:   ratelimit.NewLimiter(rateRules),
+		streams: ratelimit.NewConcurrency(streamRules),
+		maxRows: rowRules,
+		logger:  logger,
+	}, nil
+}
+
+// UnaryInterceptor - частота вызовов и лимит строк Load; ставится после аутентификации
+func (l *Limits) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client, method := limitClient(ctx), path.Base(info.FullMethod)
+		if wait, ok := l.rates.Allow(client, method); !ok {
+			return nil, l.exhausted(ctx, grpc.SetTrailer, client, method, "rate limit exceeded", wait)
+		}
+		if request, ok := req.(*protogen.LoadRequest); ok {
+			l.clampRows(ctx, grpc.SetTrailer, client, method, request)
+		}
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, l.executorStatus(ctx, grpc.SetTrailer, client, method, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamInterceptor - частота вызовов, число одновременных стримов и лимит строк StreamLoad и Export
+func (l *Limits) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		setTrailer := func(_ context.Context, md metadata.MD) error { stream.SetTrailer(md); return nil }
+		client, method := limitClient(ctx), path.Base(info.FullMethod)
+		if wait, ok := l.rates.Allow(client, method); !ok {
+			return l.exhausted(ctx, setTrailer, client, method, "rate limit exceeded", wait)
+		}
+		release, ok := l.streams.Acquire(client, method)
+		if !ok {
+			return l.exhausted(ctx, setTrailer, client, method, "too many concurrent streams", busyRetryAfter)
+		}
+		defer release()
+
+		err := handler(srv, &limitedStream{ServerStream: stream, limits: l, client: client, method: method})
+		if err != nil {
+			return l.executorStatus(ctx, setTrailer, client, method, err)
+		}
+		return nil
+	}
+}
+
+// Middleware - те же правила для HTTP ручки: частота вызовов и одновременные запросы клиента
+// (отказ - 429 с Retry-After), а лимит строк кладется в контекст для clampHTTPRows.
+// Ставится после аутентификации; nil Limits ничего не ограничивает.
+func (l *Limits) Middleware(method string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := httpLimitClient(r)
+		if wait, ok := l.rates.Allow(client, method); !ok {
+			l.tooManyRequests(w, client, method, "rate limit exceeded", wait)
+			return
+		}
+		release, ok := l.streams.Acquire(client, method)
+		if !ok {
+			l.tooManyRequests(w, client, method, "too many concurrent requests", busyRetryAfter)
+			return
+		}
+		defer release()
+
+		if limit, ok := l.maxRows.Lookup(client, method); ok && limit > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), maxRowsKey{}, limit))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clampHTTPRows - урезает limit HTTP запроса до лимита из Middleware; 0 (все строки) тоже урезается
+func clampHTTPRows(w http.ResponseWriter, r *http.Request, limit int64) int64 {
+	maxRows, ok := r.Context().Value(maxRowsKey{}).(int64)
+	if !ok || (limit > 0 && limit <= maxRows) {
+		return limit
+	}
+	w.Header().Set(maxRowsHeader, strconv.FormatInt(maxRows, 10))
+	return maxRows
+}
+
+// tooManyRequests - 429 с временем повтора в Retry-After
+func (l *Limits) tooManyRequests(w http.ResponseWriter, client, method, reason string, wait time.Duration) {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	l.logger.Warn("Call rejected", zap.String("client", client), zap.String("method", method),
+		zap.String("reason", reason), zap.Int64("retryAfter", seconds))
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	http.Error(w, fmt.Sprintf("%s: %s, retry after %ds", method, reason, seconds), http.StatusTooManyRequests)
+}
+
+// executorStatus - переполненная очередь задач сервиса отдается как ResourceExhausted с retry-after,
+// остановка сервиса - как Unavailable, чтобы клиент повторил вызов на другой реплике
+func (l *Limits) executorStatus(ctx context.Context, setTrailer func(context.Context, metadata.MD) error, client, method string, err error) error {
+	switch {
+	case errors.Is(err, executor.ErrQueueFull):
+		return l.exhausted(ctx, setTrailer, client, method, err.Error(), busyRetryAfter)
+	case errors.Is(err, executor.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+// clampRows - урезает limit запроса до максимума метода; 0 в запросе (все строки) тоже урезается
+func (l *Limits) clampRows(ctx context.Context, setTrailer func(context.Context, metadata.MD) error, client, method string, request *protogen.LoadRequest) {
+	limit, ok := l.maxRows.Lookup(client, method)
+	if !ok || limit == 0 || request == nil || (request.Limit > 0 && request.Limit <= limit) {
+		return
+	}
+	l.logger.Debug("Result rows limited", zap.String("client", client), zap.String("method", method),
+		zap.Int64("requested", request.Limit), zap.Int64("max", limit))
+	request.Limit = limit
+	_ = setTrailer(ctx, metadata.Pairs(maxRowsHeader, strconv.FormatInt(limit, 10)))
+}
+
+// exhausted - ResourceExhausted с временем повтора в trailer retry-after и в RetryInfo
+func (l *Limits) exhausted(ctx context.Context, setTrailer func(context.Context, metadata.MD) error, client, method, reason string, wait time.Duration) error {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	l.logger.Warn("Call rejected", zap.String("client", client), zap.String("method", method),
+		zap.String("reason", reason), zap.Int64("retryAfter", seconds))
+	_ = setTrailer(ctx, metadata.Pairs(retryAfterKey, strconv.FormatInt(seconds, 10)))
+
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("%s: %s, retry after %ds", method, reason, seconds))
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// limitClient - ключ клиента для ограничений: проверенное имя, иначе адрес.
+// Имя из x-client-id без токена не используется, иначе его можно менять на каждый вызов.
+func limitClient(ctx context.Context) string {
+	if identity := auth.IdentityFromContext(ctx); identity != "" && !strings.HasPrefix(identity, auth.UnverifiedPrefix) {
+		return identity
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "peer:" + host
+		}
+		return "peer:" + p.Addr.String()
+	}
+	return "anonymous"
+}
+
+// httpLimitClient - ключ клиента HTTP: имя из аутентификации, иначе адрес
+func httpLimitClient(r *http.Request) string {
+	if identity := auth.IdentityFromContext(r.Context()); identity != "" {
+		return identity
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return "peer:" + host
+	}
+	return "peer:" + r.RemoteAddr
+}
+
+// limitedStream - урезает limit запросов StreamLoad и Export при их получении
+type limitedStream struct {
+	grpc.ServerStream
+	limits *Limits
+	client string
+	method string
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	setTrailer := func(_ context.Context, md metadata.MD) error { s.SetTrailer(md); return nil }
+	switch request := m.(type) {
+	case *protogen.LoadRequest:
+		s.limits.clampRows(s.Context(), setTrailer, s.client, s.method, request)
+	case *protogen.ExportRequest:
+		if request.Query == nil {
+			request.Query = &protogen.LoadRequest{}
+		}
+		s.limits.clampRows(s.Context(), setTrailer, s.client, s.method, request.Query)
+	}
+	return nil
+}
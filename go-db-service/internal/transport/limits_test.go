@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHTTPExportLimits(t *testing.T) {
+	limits, err := NewLimits(LimitHTTPExport+"=0.001:1", "", LimitHTTPExport+"=1", *logger.NewNop())
+	if err != nil {
+		t.Fatalf("limits: %v", err)
+	}
+	h := newTestHTTPHandler(t, newTestService(t,
+		newIoC("feed", models.TypeDomain, "a.example.com"),
+		newIoC("feed", models.TypeDomain, "b.example.com"),
+	), limits)
+
+	w := get(h, "/api/v1/export?format=ndjson")
+	if w.Code != http.StatusOK {
+		t.Fatalf("first export: status %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(maxRowsHeader); got != "1" {
+		t.Fatalf("first export: %s header %q, want 1", maxRowsHeader, got)
+	}
+	if lines := strings.Count(w.Body.String(), "\n"); lines != 1 {
+		t.Fatalf("first export: got %d IoCs, want 1", lines)
+	}
+
+	w = get(h, "/api/v1/export?format=ndjson")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second export: status %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("second export: no Retry-After header")
+	}
+}
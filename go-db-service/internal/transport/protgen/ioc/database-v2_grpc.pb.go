@@ -31,6 +31,31 @@ type DatabaseClient interface {
 	StreamStore(ctx context.Context, opts ...grpc.CallOption) (Database_StreamStoreClient, error)
 	// Стримовая загрузка данных
 	StreamLoad(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (Database_StreamLoadClient, error)
+	// Выгрузка данных в формате STIX 2.1, CSV, NDJSON или blocklist
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (Database_ExportClient, error)
+	// Импорт файла IoC (CSV, NDJSON, STIX 2.1, MISP)
+	Import(ctx context.Context, opts ...grpc.CallOption) (Database_ImportClient, error)
+	// Подписка на новые и обновленные IoC (at-least-once, с возобновлением по курсору)
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Database_SubscribeClient, error)
+	// Управление allowlist: список, создание/изменение (по id), удаление
+	ListAllowlist(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AllowlistEntries, error)
+	PutAllowlistEntries(ctx context.Context, in *AllowlistEntries, opts ...grpc.CallOption) (*AllowlistEntries, error)
+	DeleteAllowlistEntry(ctx context.Context, in *DeleteAllowlistEntryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Граф связей: запись связей и окрестность индикатора для пивотинга
+	StoreRelationships(ctx context.Context, in *StoreRelationshipsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Neighbors(ctx context.Context, in *NeighborsRequest, opts ...grpc.CallOption) (*NeighborsResponse, error)
+	// Lifecycle: статус (false positive, отзыв, истечение), ручные теги и заметки аналитиков
+	SetStatus(ctx context.Context, in *SetStatusRequest, opts ...grpc.CallOption) (*Lifecycle, error)
+	SetManualTags(ctx context.Context, in *SetManualTagsRequest, opts ...grpc.CallOption) (*Lifecycle, error)
+	AddNote(ctx context.Context, in *AddNoteRequest, opts ...grpc.CallOption) (*Note, error)
+	GetLifecycle(ctx context.Context, in *IoCRef, opts ...grpc.CallOption) (*Lifecycle, error)
+	ListLifecycle(ctx context.Context, in *ListLifecycleRequest, opts ...grpc.CallOption) (*LifecycleList, error)
+	// Sightings: запись срабатываний собственных сенсоров и попадания источников
+	RecordSighting(ctx context.Context, in *RecordSightingRequest, opts ...grpc.CallOption) (*RecordSightingResponse, error)
+	StreamRecordSighting(ctx context.Context, opts ...grpc.CallOption) (Database_StreamRecordSightingClient, error)
+	SightingsBySource(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SightingsBySourceResponse, error)
+	// Журнал аудита записей и административных действий, новые записи первыми
+	QueryAudit(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditRecords, error)
 	// Получение общего количества IoC
 	Count(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CountResponse, error)
 	// Получение количества IoC по всем типам
@@ -47,6 +72,12 @@ type DatabaseClient interface {
 	CountBySourceAndType(ctx context.Context, in *CountBySourceAndTypeRequest, opts ...grpc.CallOption) (*CountByTypeResponse, error)
 	// Получение количества IoC по типу и источнику
 	CountByTypeAndSource(ctx context.Context, in *CountByTypeAndSourceRequest, opts ...grpc.CallOption) (*CountBySourceResponse, error)
+	// Получение количества IP IoC по странам (карта мира в аналитике)
+	CountByCountry(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CountByCountryResponse, error)
+	// Получение количества IP IoC по автономным системам
+	CountByASN(ctx context.Context, in *CountByASNRequest, opts ...grpc.CallOption) (*CountByASNResponse, error)
+	// Качество источников: уникальные/общие значения, матрица пересечений, отставание, churn, allowlist
+	SourceReport(ctx context.Context, in *SourceReportRequest, opts ...grpc.CallOption) (*SourceReportResponse, error)
 }
 
 type databaseClient struct {
@@ -141,6 +172,255 @@ func (x *databaseStreamLoadClient) Recv() (*StreamLoadResponse, error) {
 	return m, nil
 }
 
+func (c *databaseClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (Database_ExportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Database_ServiceDesc.Streams[2], "/ioc.Database/Export", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &databaseExportClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Database_ExportClient interface {
+	Recv() (*ExportChunk, error)
+	grpc.ClientStream
+}
+
+type databaseExportClient struct {
+	grpc.ClientStream
+}
+
+func (x *databaseExportClient) Recv() (*ExportChunk, error) {
+	m := new(ExportChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *databaseClient) Import(ctx context.Context, opts ...grpc.CallOption) (Database_ImportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Database_ServiceDesc.Streams[3], "/ioc.Database/Import", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &databaseImportClient{stream}
+	return x, nil
+}
+
+type Database_ImportClient interface {
+	Send(*ImportRequest) error
+	CloseAndRecv() (*ImportResponse, error)
+	grpc.ClientStream
+}
+
+type databaseImportClient struct {
+	grpc.ClientStream
+}
+
+func (x *databaseImportClient) Send(m *ImportRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *databaseImportClient) CloseAndRecv() (*ImportResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *databaseClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Database_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Database_ServiceDesc.Streams[4], "/ioc.Database/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &databaseSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Database_SubscribeClient interface {
+	Recv() (*SubscribeResponse, error)
+	grpc.ClientStream
+}
+
+type databaseSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *databaseSubscribeClient) Recv() (*SubscribeResponse, error) {
+	m := new(SubscribeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *databaseClient) ListAllowlist(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AllowlistEntries, error) {
+	out := new(AllowlistEntries)
+	err := c.cc.Invoke(ctx, "/ioc.Database/ListAllowlist", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) PutAllowlistEntries(ctx context.Context, in *AllowlistEntries, opts ...grpc.CallOption) (*AllowlistEntries, error) {
+	out := new(AllowlistEntries)
+	err := c.cc.Invoke(ctx, "/ioc.Database/PutAllowlistEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) DeleteAllowlistEntry(ctx context.Context, in *DeleteAllowlistEntryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/ioc.Database/DeleteAllowlistEntry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) StoreRelationships(ctx context.Context, in *StoreRelationshipsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/ioc.Database/StoreRelationships", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) Neighbors(ctx context.Context, in *NeighborsRequest, opts ...grpc.CallOption) (*NeighborsResponse, error) {
+	out := new(NeighborsResponse)
+	err := c.cc.Invoke(ctx, "/ioc.Database/Neighbors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) SetStatus(ctx context.Context, in *SetStatusRequest, opts ...grpc.CallOption) (*Lifecycle, error) {
+	out := new(Lifecycle)
+	err := c.cc.Invoke(ctx, "/ioc.Database/SetStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) SetManualTags(ctx context.Context, in *SetManualTagsRequest, opts ...grpc.CallOption) (*Lifecycle, error) {
+	out := new(Lifecycle)
+	err := c.cc.Invoke(ctx, "/ioc.Database/SetManualTags", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) AddNote(ctx context.Context, in *AddNoteRequest, opts ...grpc.CallOption) (*Note, error) {
+	out := new(Note)
+	err := c.cc.Invoke(ctx, "/ioc.Database/AddNote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) GetLifecycle(ctx context.Context, in *IoCRef, opts ...grpc.CallOption) (*Lifecycle, error) {
+	out := new(Lifecycle)
+	err := c.cc.Invoke(ctx, "/ioc.Database/GetLifecycle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) ListLifecycle(ctx context.Context, in *ListLifecycleRequest, opts ...grpc.CallOption) (*LifecycleList, error) {
+	out := new(LifecycleList)
+	err := c.cc.Invoke(ctx, "/ioc.Database/ListLifecycle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) RecordSighting(ctx context.Context, in *RecordSightingRequest, opts ...grpc.CallOption) (*RecordSightingResponse, error) {
+	out := new(RecordSightingResponse)
+	err := c.cc.Invoke(ctx, "/ioc.Database/RecordSighting", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) StreamRecordSighting(ctx context.Context, opts ...grpc.CallOption) (Database_StreamRecordSightingClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Database_ServiceDesc.Streams[5], "/ioc.Database/StreamRecordSighting", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &databaseStreamRecordSightingClient{stream}
+	return x, nil
+}
+
+type Database_StreamRecordSightingClient interface {
+	Send(*Sighting) error
+	CloseAndRecv() (*RecordSightingResponse, error)
+	grpc.ClientStream
+}
+
+type databaseStreamRecordSightingClient struct {
+	grpc.ClientStream
+}
+
+func (x *databaseStreamRecordSightingClient) Send(m *Sighting) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *databaseStreamRecordSightingClient) CloseAndRecv() (*RecordSightingResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(RecordSightingResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *databaseClient) SightingsBySource(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SightingsBySourceResponse, error) {
+	out := new(SightingsBySourceResponse)
+	err := c.cc.Invoke(ctx, "/ioc.Database/SightingsBySource", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) QueryAudit(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditRecords, error) {
+	out := new(AuditRecords)
+	err := c.cc.Invoke(ctx, "/ioc.Database/QueryAudit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *databaseClient) Count(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CountResponse, error) {
 	out := new(CountResponse)
 	err := c.cc.Invoke(ctx, "/ioc.Database/Count", in, out, opts...)
@@ -213,6 +493,33 @@ func (c *databaseClient) CountByTypeAndSource(ctx context.Context, in *CountByTy
 	return out, nil
 }
 
+func (c *databaseClient) CountByCountry(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CountByCountryResponse, error) {
+	out := new(CountByCountryResponse)
+	err := c.cc.Invoke(ctx, "/ioc.Database/CountByCountry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) CountByASN(ctx context.Context, in *CountByASNRequest, opts ...grpc.CallOption) (*CountByASNResponse, error) {
+	out := new(CountByASNResponse)
+	err := c.cc.Invoke(ctx, "/ioc.Database/CountByASN", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) SourceReport(ctx context.Context, in *SourceReportRequest, opts ...grpc.CallOption) (*SourceReportResponse, error) {
+	out := new(SourceReportResponse)
+	err := c.cc.Invoke(ctx, "/ioc.Database/SourceReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DatabaseServer is the server API for Database service.
 // All implementations must embed UnimplementedDatabaseServer
 // for forward compatibility
@@ -225,6 +532,31 @@ type DatabaseServer interface {
 	StreamStore(Database_StreamStoreServer) error
 	// Стримовая загрузка данных
 	StreamLoad(*LoadRequest, Database_StreamLoadServer) error
+	// Выгрузка данных в формате STIX 2.1, CSV, NDJSON или blocklist
+	Export(*ExportRequest, Database_ExportServer) error
+	// Импорт файла IoC (CSV, NDJSON, STIX 2.1, MISP)
+	Import(Database_ImportServer) error
+	// Подписка на новые и обновленные IoC (at-least-once, с возобновлением по курсору)
+	Subscribe(*SubscribeRequest, Database_SubscribeServer) error
+	// Управление allowlist: список, создание/изменение (по id), удаление
+	ListAllowlist(context.Context, *emptypb.Empty) (*AllowlistEntries, error)
+	PutAllowlistEntries(context.Context, *AllowlistEntries) (*AllowlistEntries, error)
+	DeleteAllowlistEntry(context.Context, *DeleteAllowlistEntryRequest) (*emptypb.Empty, error)
+	// Граф связей: запись связей и окрестность индикатора для пивотинга
+	StoreRelationships(context.Context, *StoreRelationshipsRequest) (*emptypb.Empty, error)
+	Neighbors(context.Context, *NeighborsRequest) (*NeighborsResponse, error)
+	// Lifecycle: статус (false positive, отзыв, истечение), ручные теги и заметки аналитиков
+	SetStatus(context.Context, *SetStatusRequest) (*Lifecycle, error)
+	SetManualTags(context.Context, *SetManualTagsRequest) (*Lifecycle, error)
+	AddNote(context.Context, *AddNoteRequest) (*Note, error)
+	GetLifecycle(context.Context, *IoCRef) (*Lifecycle, error)
+	ListLifecycle(context.Context, *ListLifecycleRequest) (*LifecycleList, error)
+	// Sightings: запись срабатываний собственных сенсоров и попадания источников
+	RecordSighting(context.Context, *RecordSightingRequest) (*RecordSightingResponse, error)
+	StreamRecordSighting(Database_StreamRecordSightingServer) error
+	SightingsBySource(context.Context, *emptypb.Empty) (*SightingsBySourceResponse, error)
+	// Журнал аудита записей и административных действий, новые записи первыми
+	QueryAudit(context.Context, *AuditQuery) (*AuditRecords, error)
 	// Получение общего количества IoC
 	Count(context.Context, *emptypb.Empty) (*CountResponse, error)
 	// Получение количества IoC по всем типам
@@ -241,6 +573,12 @@ type DatabaseServer interface {
 	CountBySourceAndType(context.Context, *CountBySourceAndTypeRequest) (*CountByTypeResponse, error)
 	// Получение количества IoC по типу и источнику
 	CountByTypeAndSource(context.Context, *CountByTypeAndSourceRequest) (*CountBySourceResponse, error)
+	// Получение количества IP IoC по странам (карта мира в аналитике)
+	CountByCountry(context.Context, *emptypb.Empty) (*CountByCountryResponse, error)
+	// Получение количества IP IoC по автономным системам
+	CountByASN(context.Context, *CountByASNRequest) (*CountByASNResponse, error)
+	// Качество источников: уникальные/общие значения, матрица пересечений, отставание, churn, allowlist
+	SourceReport(context.Context, *SourceReportRequest) (*SourceReportResponse, error)
 	mustEmbedUnimplementedDatabaseServer()
 }
 
@@ -260,124 +598,512 @@ func (UnimplementedDatabaseServer) StreamStore(Database_StreamStoreServer) error
 func (UnimplementedDatabaseServer) StreamLoad(*LoadRequest, Database_StreamLoadServer) error {
 	return status.Errorf(codes.Unimplemented, "method StreamLoad not implemented")
 }
+func (UnimplementedDatabaseServer) Export(*ExportRequest, Database_ExportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedDatabaseServer) Import(Database_ImportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Import not implemented")
+}
+func (UnimplementedDatabaseServer) Subscribe(*SubscribeRequest, Database_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedDatabaseServer) ListAllowlist(context.Context, *emptypb.Empty) (*AllowlistEntries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAllowlist not implemented")
+}
+func (UnimplementedDatabaseServer) PutAllowlistEntries(context.Context, *AllowlistEntries) (*AllowlistEntries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutAllowlistEntries not implemented")
+}
+func (UnimplementedDatabaseServer) DeleteAllowlistEntry(context.Context, *DeleteAllowlistEntryRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAllowlistEntry not implemented")
+}
+func (UnimplementedDatabaseServer) StoreRelationships(context.Context, *StoreRelationshipsRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StoreRelationships not implemented")
+}
+func (UnimplementedDatabaseServer) Neighbors(context.Context, *NeighborsRequest) (*NeighborsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Neighbors not implemented")
+}
+func (UnimplementedDatabaseServer) SetStatus(context.Context, *SetStatusRequest) (*Lifecycle, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStatus not implemented")
+}
+func (UnimplementedDatabaseServer) SetManualTags(context.Context, *SetManualTagsRequest) (*Lifecycle, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetManualTags not implemented")
+}
+func (UnimplementedDatabaseServer) AddNote(context.Context, *AddNoteRequest) (*Note, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddNote not implemented")
+}
+func (UnimplementedDatabaseServer) GetLifecycle(context.Context, *IoCRef) (*Lifecycle, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLifecycle not implemented")
+}
+func (UnimplementedDatabaseServer) ListLifecycle(context.Context, *ListLifecycleRequest) (*LifecycleList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLifecycle not implemented")
+}
+func (UnimplementedDatabaseServer) RecordSighting(context.Context, *RecordSightingRequest) (*RecordSightingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordSighting not implemented")
+}
+func (UnimplementedDatabaseServer) StreamRecordSighting(Database_StreamRecordSightingServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRecordSighting not implemented")
+}
+func (UnimplementedDatabaseServer) SightingsBySource(context.Context, *emptypb.Empty) (*SightingsBySourceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SightingsBySource not implemented")
+}
+func (UnimplementedDatabaseServer) QueryAudit(context.Context, *AuditQuery) (*AuditRecords, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryAudit not implemented")
+}
 func (UnimplementedDatabaseServer) Count(context.Context, *emptypb.Empty) (*CountResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Count not implemented")
 }
 func (UnimplementedDatabaseServer) CountByType(context.Context, *emptypb.Empty) (*CountByTypeResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CountByType not implemented")
 }
-func (UnimplementedDatabaseServer) CountSpecificType(context.Context, *CountSpecificTypeRequest) (*CountResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CountSpecificType not implemented")
+func (UnimplementedDatabaseServer) CountSpecificType(context.Context, *CountSpecificTypeRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountSpecificType not implemented")
+}
+func (UnimplementedDatabaseServer) CountBySource(context.Context, *CountBySourceRequest) (*CountBySourceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountBySource not implemented")
+}
+func (UnimplementedDatabaseServer) CountSpecificSource(context.Context, *CountSpecificSourceRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountSpecificSource not implemented")
+}
+func (UnimplementedDatabaseServer) CountTypesBySource(context.Context, *emptypb.Empty) (*CountTypesBySourceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountTypesBySource not implemented")
+}
+func (UnimplementedDatabaseServer) CountBySourceAndType(context.Context, *CountBySourceAndTypeRequest) (*CountByTypeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountBySourceAndType not implemented")
+}
+func (UnimplementedDatabaseServer) CountByTypeAndSource(context.Context, *CountByTypeAndSourceRequest) (*CountBySourceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountByTypeAndSource not implemented")
+}
+func (UnimplementedDatabaseServer) CountByCountry(context.Context, *emptypb.Empty) (*CountByCountryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountByCountry not implemented")
+}
+func (UnimplementedDatabaseServer) CountByASN(context.Context, *CountByASNRequest) (*CountByASNResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountByASN not implemented")
+}
+func (UnimplementedDatabaseServer) SourceReport(context.Context, *SourceReportRequest) (*SourceReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SourceReport not implemented")
+}
+func (UnimplementedDatabaseServer) mustEmbedUnimplementedDatabaseServer() {}
+
+// UnsafeDatabaseServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DatabaseServer will
+// result in compilation errors.
+type UnsafeDatabaseServer interface {
+	mustEmbedUnimplementedDatabaseServer()
+}
+
+func RegisterDatabaseServer(s grpc.ServiceRegistrar, srv DatabaseServer) {
+	s.RegisterService(&Database_ServiceDesc, srv)
+}
+
+func _Database_Store_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Store(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/Store",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Store(ctx, req.(*StoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_Load_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Load(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/Load",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Load(ctx, req.(*LoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_StreamStore_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DatabaseServer).StreamStore(&databaseStreamStoreServer{stream})
+}
+
+type Database_StreamStoreServer interface {
+	SendAndClose(*emptypb.Empty) error
+	Recv() (*StreamStoreRequest, error)
+	grpc.ServerStream
+}
+
+type databaseStreamStoreServer struct {
+	grpc.ServerStream
+}
+
+func (x *databaseStreamStoreServer) SendAndClose(m *emptypb.Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *databaseStreamStoreServer) Recv() (*StreamStoreRequest, error) {
+	m := new(StreamStoreRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Database_StreamLoad_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LoadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DatabaseServer).StreamLoad(m, &databaseStreamLoadServer{stream})
+}
+
+type Database_StreamLoadServer interface {
+	Send(*StreamLoadResponse) error
+	grpc.ServerStream
+}
+
+type databaseStreamLoadServer struct {
+	grpc.ServerStream
+}
+
+func (x *databaseStreamLoadServer) Send(m *StreamLoadResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Database_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DatabaseServer).Export(m, &databaseExportServer{stream})
+}
+
+type Database_ExportServer interface {
+	Send(*ExportChunk) error
+	grpc.ServerStream
+}
+
+type databaseExportServer struct {
+	grpc.ServerStream
+}
+
+func (x *databaseExportServer) Send(m *ExportChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Database_Import_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DatabaseServer).Import(&databaseImportServer{stream})
+}
+
+type Database_ImportServer interface {
+	SendAndClose(*ImportResponse) error
+	Recv() (*ImportRequest, error)
+	grpc.ServerStream
+}
+
+type databaseImportServer struct {
+	grpc.ServerStream
+}
+
+func (x *databaseImportServer) SendAndClose(m *ImportResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *databaseImportServer) Recv() (*ImportRequest, error) {
+	m := new(ImportRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Database_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DatabaseServer).Subscribe(m, &databaseSubscribeServer{stream})
+}
+
+type Database_SubscribeServer interface {
+	Send(*SubscribeResponse) error
+	grpc.ServerStream
+}
+
+type databaseSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *databaseSubscribeServer) Send(m *SubscribeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Database_ListAllowlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).ListAllowlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/ListAllowlist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).ListAllowlist(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_PutAllowlistEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllowlistEntries)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).PutAllowlistEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/PutAllowlistEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).PutAllowlistEntries(ctx, req.(*AllowlistEntries))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedDatabaseServer) CountBySource(context.Context, *CountBySourceRequest) (*CountBySourceResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CountBySource not implemented")
+
+func _Database_DeleteAllowlistEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAllowlistEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).DeleteAllowlistEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/DeleteAllowlistEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).DeleteAllowlistEntry(ctx, req.(*DeleteAllowlistEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedDatabaseServer) CountSpecificSource(context.Context, *CountSpecificSourceRequest) (*CountResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CountSpecificSource not implemented")
+
+func _Database_StoreRelationships_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreRelationshipsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).StoreRelationships(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/StoreRelationships",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).StoreRelationships(ctx, req.(*StoreRelationshipsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedDatabaseServer) CountTypesBySource(context.Context, *emptypb.Empty) (*CountTypesBySourceResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CountTypesBySource not implemented")
+
+func _Database_Neighbors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NeighborsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Neighbors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/Neighbors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Neighbors(ctx, req.(*NeighborsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedDatabaseServer) CountBySourceAndType(context.Context, *CountBySourceAndTypeRequest) (*CountByTypeResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CountBySourceAndType not implemented")
+
+func _Database_SetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).SetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/SetStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).SetStatus(ctx, req.(*SetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedDatabaseServer) CountByTypeAndSource(context.Context, *CountByTypeAndSourceRequest) (*CountBySourceResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CountByTypeAndSource not implemented")
+
+func _Database_SetManualTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetManualTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).SetManualTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/SetManualTags",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).SetManualTags(ctx, req.(*SetManualTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedDatabaseServer) mustEmbedUnimplementedDatabaseServer() {}
 
-// UnsafeDatabaseServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to DatabaseServer will
-// result in compilation errors.
-type UnsafeDatabaseServer interface {
-	mustEmbedUnimplementedDatabaseServer()
+func _Database_AddNote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddNoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).AddNote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/AddNote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).AddNote(ctx, req.(*AddNoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterDatabaseServer(s grpc.ServiceRegistrar, srv DatabaseServer) {
-	s.RegisterService(&Database_ServiceDesc, srv)
+func _Database_GetLifecycle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IoCRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).GetLifecycle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/GetLifecycle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).GetLifecycle(ctx, req.(*IoCRef))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Database_Store_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StoreRequest)
+func _Database_ListLifecycle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLifecycleRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(DatabaseServer).Store(ctx, in)
+		return srv.(DatabaseServer).ListLifecycle(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/ioc.Database/Store",
+		FullMethod: "/ioc.Database/ListLifecycle",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DatabaseServer).Store(ctx, req.(*StoreRequest))
+		return srv.(DatabaseServer).ListLifecycle(ctx, req.(*ListLifecycleRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Database_Load_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(LoadRequest)
+func _Database_RecordSighting_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordSightingRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(DatabaseServer).Load(ctx, in)
+		return srv.(DatabaseServer).RecordSighting(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/ioc.Database/Load",
+		FullMethod: "/ioc.Database/RecordSighting",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DatabaseServer).Load(ctx, req.(*LoadRequest))
+		return srv.(DatabaseServer).RecordSighting(ctx, req.(*RecordSightingRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Database_StreamStore_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(DatabaseServer).StreamStore(&databaseStreamStoreServer{stream})
+func _Database_StreamRecordSighting_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DatabaseServer).StreamRecordSighting(&databaseStreamRecordSightingServer{stream})
 }
 
-type Database_StreamStoreServer interface {
-	SendAndClose(*emptypb.Empty) error
-	Recv() (*StreamStoreRequest, error)
+type Database_StreamRecordSightingServer interface {
+	SendAndClose(*RecordSightingResponse) error
+	Recv() (*Sighting, error)
 	grpc.ServerStream
 }
 
-type databaseStreamStoreServer struct {
+type databaseStreamRecordSightingServer struct {
 	grpc.ServerStream
 }
 
-func (x *databaseStreamStoreServer) SendAndClose(m *emptypb.Empty) error {
+func (x *databaseStreamRecordSightingServer) SendAndClose(m *RecordSightingResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
-func (x *databaseStreamStoreServer) Recv() (*StreamStoreRequest, error) {
-	m := new(StreamStoreRequest)
+func (x *databaseStreamRecordSightingServer) Recv() (*Sighting, error) {
+	m := new(Sighting)
 	if err := x.ServerStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func _Database_StreamLoad_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(LoadRequest)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func _Database_SightingsBySource_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	return srv.(DatabaseServer).StreamLoad(m, &databaseStreamLoadServer{stream})
-}
-
-type Database_StreamLoadServer interface {
-	Send(*StreamLoadResponse) error
-	grpc.ServerStream
-}
-
-type databaseStreamLoadServer struct {
-	grpc.ServerStream
+	if interceptor == nil {
+		return srv.(DatabaseServer).SightingsBySource(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/SightingsBySource",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).SightingsBySource(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (x *databaseStreamLoadServer) Send(m *StreamLoadResponse) error {
-	return x.ServerStream.SendMsg(m)
+func _Database_QueryAudit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).QueryAudit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/QueryAudit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).QueryAudit(ctx, req.(*AuditQuery))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
 func _Database_Count_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
@@ -524,6 +1250,60 @@ func _Database_CountByTypeAndSource_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Database_CountByCountry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).CountByCountry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/CountByCountry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).CountByCountry(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_CountByASN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountByASNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).CountByASN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/CountByASN",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).CountByASN(ctx, req.(*CountByASNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_SourceReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SourceReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).SourceReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ioc.Database/SourceReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).SourceReport(ctx, req.(*SourceReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Database_ServiceDesc is the grpc.ServiceDesc for Database service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -539,6 +1319,58 @@ var Database_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Load",
 			Handler:    _Database_Load_Handler,
 		},
+		{
+			MethodName: "ListAllowlist",
+			Handler:    _Database_ListAllowlist_Handler,
+		},
+		{
+			MethodName: "PutAllowlistEntries",
+			Handler:    _Database_PutAllowlistEntries_Handler,
+		},
+		{
+			MethodName: "DeleteAllowlistEntry",
+			Handler:    _Database_DeleteAllowlistEntry_Handler,
+		},
+		{
+			MethodName: "StoreRelationships",
+			Handler:    _Database_StoreRelationships_Handler,
+		},
+		{
+			MethodName: "Neighbors",
+			Handler:    _Database_Neighbors_Handler,
+		},
+		{
+			MethodName: "SetStatus",
+			Handler:    _Database_SetStatus_Handler,
+		},
+		{
+			MethodName: "SetManualTags",
+			Handler:    _Database_SetManualTags_Handler,
+		},
+		{
+			MethodName: "AddNote",
+			Handler:    _Database_AddNote_Handler,
+		},
+		{
+			MethodName: "GetLifecycle",
+			Handler:    _Database_GetLifecycle_Handler,
+		},
+		{
+			MethodName: "ListLifecycle",
+			Handler:    _Database_ListLifecycle_Handler,
+		},
+		{
+			MethodName: "RecordSighting",
+			Handler:    _Database_RecordSighting_Handler,
+		},
+		{
+			MethodName: "SightingsBySource",
+			Handler:    _Database_SightingsBySource_Handler,
+		},
+		{
+			MethodName: "QueryAudit",
+			Handler:    _Database_QueryAudit_Handler,
+		},
 		{
 			MethodName: "Count",
 			Handler:    _Database_Count_Handler,
@@ -571,6 +1403,18 @@ var Database_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CountByTypeAndSource",
 			Handler:    _Database_CountByTypeAndSource_Handler,
 		},
+		{
+			MethodName: "CountByCountry",
+			Handler:    _Database_CountByCountry_Handler,
+		},
+		{
+			MethodName: "CountByASN",
+			Handler:    _Database_CountByASN_Handler,
+		},
+		{
+			MethodName: "SourceReport",
+			Handler:    _Database_SourceReport_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -583,6 +1427,26 @@ var Database_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Database_StreamLoad_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Export",
+			Handler:       _Database_Export_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Import",
+			Handler:       _Database_Import_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Database_Subscribe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamRecordSighting",
+			Handler:       _Database_StreamRecordSighting_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "api/proto/database-v2.proto",
 }
@@ -0,0 +1,18 @@
+package transport
+
+import (
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"fmt"
+)
+
+// SourceReport возвращает показатели качества и пересечения источников
+func (h *Handler) SourceReport(ctx context.Context, req *protogen.SourceReportRequest) (*protogen.SourceReportResponse, error) {
+	report, err := h.service.SourceReport(ctx, int(req.ChurnDays))
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error building source report: %v", err))
+		return nil, err
+	}
+	return models.ToProtoSourceReport(report), nil
+}
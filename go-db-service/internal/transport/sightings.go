@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"awesomeProject/internal/service"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sightingBatchSize - размер пачки записи при стримовой передаче sightings
+const sightingBatchSize = 1000
+
+// RecordSighting записывает срабатывания собственных сенсоров
+func (h *Handler) RecordSighting(ctx context.Context, req *protogen.RecordSightingRequest) (*protogen.RecordSightingResponse, error) {
+	sightings := make([]models.Sighting, len(req.Sightings))
+	for i, sighting := range req.Sightings {
+		sightings[i] = models.ToModelSighting(sighting)
+	}
+
+	recorded, err := h.service.RecordSightings(ctx, sightings)
+	h.audit.Record(ctx, "RecordSighting", nil, recorded, err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error recording sightings: %v", err))
+		return nil, sightingStatus(err)
+	}
+	return &protogen.RecordSightingResponse{Recorded: int64(recorded)}, nil
+}
+
+// StreamRecordSighting принимает sightings стримом и пишет их пачками по sightingBatchSize
+func (h *Handler) StreamRecordSighting(stream protogen.Database_StreamRecordSightingServer) (err error) {
+	var recorded int64
+	defer func() {
+		h.audit.Record(stream.Context(), "StreamRecordSighting", nil, int(recorded), err)
+	}()
+	batch := make([]models.Sighting, 0, sightingBatchSize)
+	flush := func() error {
+		count, err := h.service.RecordSightings(stream.Context(), batch)
+		batch = batch[:0]
+		recorded += int64(count)
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, models.ToModelSighting(req))
+		if len(batch) >= sightingBatchSize {
+			if err := flush(); err != nil {
+				h.logger.Error(fmt.Sprintf("StreamRecordSighting failed after %d sightings: %v", recorded, err))
+				return sightingStatus(err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		h.logger.Error(fmt.Sprintf("StreamRecordSighting failed after %d sightings: %v", recorded, err))
+		return sightingStatus(err)
+	}
+
+	h.logger.Info(fmt.Sprintf("StreamRecordSighting: recorded %d sightings", recorded))
+	return stream.SendAndClose(&protogen.RecordSightingResponse{Recorded: recorded})
+}
+
+func (h *Handler) SightingsBySource(ctx context.Context, _ *empty.Empty) (*protogen.SightingsBySourceResponse, error) {
+	counts, err := h.service.SightingsBySource(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error counting sightings by source: %v", err))
+		return nil, err
+	}
+
+	response := &protogen.SightingsBySourceResponse{Sources: make(map[string]*protogen.SourceSightings, len(counts))}
+	for source, count := range counts {
+		response.Sources[source] = &protogen.SourceSightings{Iocs: count.IoCs, Sightings: count.Sightings}
+	}
+	return response, nil
+}
+
+func sightingStatus(err error) error {
+	if errors.Is(err, service.ErrInvalidSighting) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
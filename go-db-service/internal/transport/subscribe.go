@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"awesomeProject/internal/changes"
+	protogen "awesomeProject/internal/transport/protgen/ioc"
+	"awesomeProject/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultHeartbeat = 15 * time.Second
+	minHeartbeat     = time.Second
+
+	// replayOverlap - added_at проставляется ClickHouse при вставке, до публикации события,
+	// поэтому дочитывание из хранилища начинается чуть раньше курсора
+	replayOverlap = 5 * time.Second
+)
+
+// Subscribe отправляет клиенту IoC по мере их записи в хранилище.
+// Доставка at-least-once: после переподключения с курсором возможны повторы.
+func (h *Handler) Subscribe(req *protogen.SubscribeRequest, stream protogen.Database_SubscribeServer) error {
+	filter := changes.Filter{Types: req.Types, Sources: req.Sources, Tags: req.Tags}
+	heartbeat := defaultHeartbeat
+	if req.HeartbeatSeconds > 0 {
+		heartbeat = max(time.Duration(req.HeartbeatSeconds)*time.Second, minHeartbeat)
+	}
+
+	ctx := stream.Context()
+	sub, err := h.service.Subscribe(ctx, req.Cursor)
+	if errors.Is(err, changes.ErrClosed) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer sub.Close()
+
+	// Курсор старше буфера событий или выдан до рестарта - дочитываем пропущенное из хранилища
+	if sub.ReplayFrom != nil {
+		count, err := h.replayChanges(ctx, stream, filter, *sub.ReplayFrom)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Subscribe: replay failed after %d IoCs: %v", count, err))
+			return err
+		}
+		h.logger.Info(fmt.Sprintf("Subscribe: replayed %d IoCs from storage since %s", count, sub.ReplayFrom.Format(time.RFC3339)))
+	}
+
+	last := sub.Start
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, open := <-sub.Events():
+			if !open {
+				if err := sub.Err(); errors.Is(err, changes.ErrClosed) {
+					return status.Error(codes.Unavailable, err.Error())
+				} else if err != nil {
+					h.logger.Warn(fmt.Sprintf("Subscribe: subscriber dropped: %v", err))
+					return status.Error(codes.ResourceExhausted, err.Error())
+				}
+				return nil
+			}
+			last = sub.Cursor(event)
+			if !filter.Match(event.IoC) || !sub.Visible(event) {
+				continue
+			}
+			if err := stream.Send(&protogen.SubscribeResponse{Ioc: models.ToProtoIoC(event.IoC), Cursor: last.String()}); err != nil {
+				h.logger.Error(fmt.Sprintf("Subscribe: error sending event: %v", err))
+				return err
+			}
+			ticker.Reset(heartbeat)
+		case <-ticker.C:
+			// Heartbeat несет актуальный курсор, чтобы клиент с редким фильтром не отставал от буфера
+			if err := stream.Send(&protogen.SubscribeResponse{Cursor: last.String(), Heartbeat: true}); err != nil {
+				h.logger.Error(fmt.Sprintf("Subscribe: error sending heartbeat: %v", err))
+				return err
+			}
+		}
+	}
+}
+
+// replayChanges - отправляет IoC, записанные после since, в порядке записи
+func (h *Handler) replayChanges(ctx context.Context, stream protogen.Database_SubscribeServer, filter changes.Filter, since time.Time) (int, error) {
+	from := since.Add(-replayOverlap)
+	request := models.LoadRequest{AddedAfter: &from, SortByAdded: true}
+	if len(filter.Types) == 1 {
+		request.Type = filter.Types[0]
+	}
+	if len(filter.Sources) == 1 {
+		request.Source = filter.Sources[0]
+	}
+
+	loadChannel, errChan, err := h.service.Load(ctx, request)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return count, ctx.Err()
+		case ioc, open := <-loadChannel:
+			if !open {
+				return count, <-errChan
+			}
+			if ioc.AddedAt == nil {
+				return count, errors.New("stored IoC without added_at")
+			}
+			if !filter.Match(*ioc) {
+				continue
+			}
+			// Курсор без эпохи: при возобновлении дочитывание снова пойдет из хранилища по времени
+			cursor := changes.Cursor{Time: *ioc.AddedAt}
+			if err := stream.Send(&protogen.SubscribeResponse{Ioc: models.ToProtoIoC(*ioc), Cursor: cursor.String()}); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+}
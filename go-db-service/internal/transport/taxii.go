@@ -0,0 +1,355 @@
+package transport
+
+import (
+	"awesomeProject/internal/stix"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	taxiiMediaType   = "application/taxii+json;version=2.1"
+	stixMediaType    = "application/stix+json;version=2.1"
+	taxiiAPIRoot     = "/taxii2/api/"
+	taxiiPageSize    = 100  // Размер страницы по умолчанию
+	taxiiMaxPageSize = 1000 // Максимальный limit, который может запросить клиент
+
+	taxiiSourcesTTL = time.Minute // Как долго список коллекций источников не пересчитывается
+)
+
+// taxiiNamespace - namespace для детерминированных id коллекций
+var taxiiNamespace = uuid.MustParse("3f5c2d1e-7a4b-4c8e-9f10-6d2b8a9e0c41")
+
+// taxiiTypes - типы IoC, для которых публикуются отдельные коллекции
+var taxiiTypes = []string{models.TypeIP, models.TypeDomain, models.TypeURL, models.TypeSHA256, models.TypeMD5}
+
+// taxiiCollection - коллекция TAXII поверх ioc_data с фильтром по типу или источнику
+type taxiiCollection struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	CanRead     bool     `json:"can_read"`
+	CanWrite    bool     `json:"can_write"`
+	MediaTypes  []string `json:"media_types"`
+
+	iocType string
+	source  string
+}
+
+func newTaxiiCollection(key, title, description, iocType, source string) taxiiCollection {
+	return taxiiCollection{
+		ID:          uuid.NewSHA1(taxiiNamespace, []byte(key)).String(),
+		Title:       title,
+		Description: description,
+		CanRead:     true,
+		MediaTypes:  []string{stixMediaType},
+		iocType:     iocType,
+		source:      source,
+	}
+}
+
+// registerTaxii - ручки TAXII 2.1 (только чтение)
+func (h *HTTPHandler) registerTaxii() {
+	h.mux.Handle("GET /taxii2/{$}", h.protectLimited(LimitTAXII, h.taxiiDiscovery))
+	h.mux.Handle("GET "+taxiiAPIRoot+"{$}", h.protectLimited(LimitTAXII, h.taxiiAPIRootInfo))
+	h.mux.Handle("GET "+taxiiAPIRoot+"collections/{$}", h.protectLimited(LimitTAXII, h.taxiiListCollections))
+	h.mux.Handle("GET "+taxiiAPIRoot+"collections/{id}/{$}", h.protectLimited(LimitTAXII, h.taxiiGetCollection))
+	h.mux.Handle("GET "+taxiiAPIRoot+"collections/{id}/objects/{$}", h.protectLimited(LimitTAXII, h.taxiiGetObjects))
+	h.mux.Handle("GET "+taxiiAPIRoot+"collections/{id}/manifest/{$}", h.protectLimited(LimitTAXII, h.taxiiGetManifest))
+}
+
+func (h *HTTPHandler) taxiiDiscovery(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	apiRoot := fmt.Sprintf("%s://%s%s", scheme, r.Host, taxiiAPIRoot)
+	writeTaxii(w, http.StatusOK, map[string]interface{}{
+		"title":       "Threat Intelligence Platform TAXII Server",
+		"description": "Curated indicators from the platform IoC store",
+		"default":     apiRoot,
+		"api_roots":   []string{apiRoot},
+	})
+}
+
+func (h *HTTPHandler) taxiiAPIRootInfo(w http.ResponseWriter, r *http.Request) {
+	writeTaxii(w, http.StatusOK, map[string]interface{}{
+		"title":              "Threat Intelligence Platform indicators",
+		"versions":           []string{taxiiMediaType},
+		"max_content_length": 0,
+	})
+}
+
+func (h *HTTPHandler) taxiiListCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := h.taxiiCollections(r)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("TAXII: failed to list collections: %v", err))
+		writeTaxiiError(w, http.StatusInternalServerError, "failed to list collections")
+		return
+	}
+	writeTaxii(w, http.StatusOK, map[string]interface{}{"collections": collections})
+}
+
+func (h *HTTPHandler) taxiiGetCollection(w http.ResponseWriter, r *http.Request) {
+	collection, ok := h.taxiiFindCollection(w, r)
+	if !ok {
+		return
+	}
+	writeTaxii(w, http.StatusOK, collection)
+}
+
+func (h *HTTPHandler) taxiiGetObjects(w http.ResponseWriter, r *http.Request) {
+	iocs, more, next, ok := h.taxiiPage(w, r)
+	if !ok {
+		return
+	}
+
+	objects := make([]stix.Indicator, 0, len(iocs))
+	for _, ioc := range iocs {
+		if indicator, ok := stix.FromIoC(ioc); ok {
+			objects = append(objects, indicator)
+		}
+	}
+
+	envelope := map[string]interface{}{"more": more, "objects": objects}
+	if more {
+		envelope["next"] = next
+	}
+	setDateAddedHeaders(w, iocs)
+	writeTaxii(w, http.StatusOK, envelope)
+}
+
+func (h *HTTPHandler) taxiiGetManifest(w http.ResponseWriter, r *http.Request) {
+	iocs, more, next, ok := h.taxiiPage(w, r)
+	if !ok {
+		return
+	}
+
+	type manifestRecord struct {
+		ID        string `json:"id"`
+		DateAdded string `json:"date_added"`
+		Version   string `json:"version"`
+		MediaType string `json:"media_type"`
+	}
+	records := make([]manifestRecord, 0, len(iocs))
+	for _, ioc := range iocs {
+		indicator, ok := stix.FromIoC(ioc)
+		if !ok {
+			continue
+		}
+		records = append(records, manifestRecord{
+			ID:        indicator.ID,
+			DateAdded: formatTaxiiTime(ioc.AddedAt),
+			Version:   indicator.Modified,
+			MediaType: stixMediaType,
+		})
+	}
+
+	envelope := map[string]interface{}{"more": more, "objects": records}
+	if more {
+		envelope["next"] = next
+	}
+	setDateAddedHeaders(w, iocs)
+	writeTaxii(w, http.StatusOK, envelope)
+}
+
+// taxiiPage - читает страницу коллекции по added_after, limit и next (смещение от начала выборки)
+func (h *HTTPHandler) taxiiPage(w http.ResponseWriter, r *http.Request) ([]models.IoCDto, bool, string, bool) {
+	collection, ok := h.taxiiFindCollection(w, r)
+	if !ok {
+		return nil, false, "", false
+	}
+	query := r.URL.Query()
+
+	// В коллекциях только индикаторы: фильтр по другим типам объектов дает пустую страницу
+	if types := query.Get("match[type]"); types != "" && !containsValue(types, "indicator") {
+		return nil, false, "", true
+	}
+
+	limit := int64(taxiiPageSize)
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			writeTaxiiError(w, http.StatusBadRequest, "invalid limit")
+			return nil, false, "", false
+		}
+		limit = min(parsed, taxiiMaxPageSize)
+	}
+	limit = clampHTTPRows(w, r, limit)
+
+	var offset int64
+	if value := query.Get("next"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			writeTaxiiError(w, http.StatusBadRequest, "invalid next")
+			return nil, false, "", false
+		}
+		offset = parsed
+	}
+
+	request := models.LoadRequest{
+		Type:        collection.iocType,
+		Source:      collection.source,
+		SortByAdded: true,
+		Limit:       limit + 1, // Лишняя запись показывает, есть ли следующая страница
+		Offset:      offset,
+	}
+	if value := query.Get("added_after"); value != "" {
+		addedAfter, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			writeTaxiiError(w, http.StatusBadRequest, "invalid added_after")
+			return nil, false, "", false
+		}
+		request.AddedAfter = &addedAfter
+	}
+
+	iocs, err := h.service.UnaryLoad(r.Context(), request)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("TAXII: failed to load collection %s: %v", collection.ID, err))
+		writeTaxiiError(w, http.StatusInternalServerError, "failed to load objects")
+		return nil, false, "", false
+	}
+
+	more := int64(len(iocs)) > limit
+	if more {
+		iocs = iocs[:limit]
+	}
+	return iocs, more, strconv.FormatInt(offset+limit, 10), true
+}
+
+// taxiiStatic - коллекция со всеми индикаторами и по коллекции на тип; не зависят от данных
+var taxiiStatic = func() []taxiiCollection {
+	collections := []taxiiCollection{
+		newTaxiiCollection("all", "All indicators", "All indicators in the IoC store", "", ""),
+	}
+	for _, iocType := range taxiiTypes {
+		collections = append(collections, newTaxiiCollection("type:"+iocType,
+			strings.ToUpper(iocType)+" indicators", "Indicators of type "+iocType, iocType, ""))
+	}
+	return collections
+}()
+
+// taxiiSources - коллекции источников по арендаторам; список источников требует группировки
+// по всей ioc_data, поэтому пересчитывается не чаще раза в taxiiSourcesTTL
+type taxiiSources struct {
+	mu      sync.Mutex
+	entries map[string]taxiiSourcesEntry
+}
+
+type taxiiSourcesEntry struct {
+	collections []taxiiCollection
+	loadedAt    time.Time
+}
+
+// taxiiCollections - статические коллекции и по коллекции на каждый известный источник
+func (h *HTTPHandler) taxiiCollections(r *http.Request) ([]taxiiCollection, error) {
+	sources, err := h.taxiiSourceCollections(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]taxiiCollection{}, taxiiStatic...), sources...), nil
+}
+
+// taxiiSourceCollections - коллекции источников, видимых арендатору запроса, из кэша или по CountBySource
+func (h *HTTPHandler) taxiiSourceCollections(r *http.Request) ([]taxiiCollection, error) {
+	key := tenant.FromContext(r.Context())
+	h.taxiiSources.mu.Lock()
+	entry, ok := h.taxiiSources.entries[key]
+	h.taxiiSources.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < taxiiSourcesTTL {
+		return entry.collections, nil
+	}
+
+	sourceCounts, err := h.service.CountBySource(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]string, 0, len(sourceCounts))
+	for source := range sourceCounts {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	collections := make([]taxiiCollection, 0, len(sources))
+	for _, source := range sources {
+		collections = append(collections, newTaxiiCollection("source:"+source,
+			source+" indicators", "Indicators reported by "+source, "", source))
+	}
+
+	h.taxiiSources.mu.Lock()
+	if h.taxiiSources.entries == nil {
+		h.taxiiSources.entries = make(map[string]taxiiSourcesEntry)
+	}
+	h.taxiiSources.entries[key] = taxiiSourcesEntry{collections: collections, loadedAt: time.Now()}
+	h.taxiiSources.mu.Unlock()
+	return collections, nil
+}
+
+// taxiiFindCollection - коллекция по id из пути; статические находятся без обращения к хранилищу
+func (h *HTTPHandler) taxiiFindCollection(w http.ResponseWriter, r *http.Request) (taxiiCollection, bool) {
+	id := r.PathValue("id")
+	for _, collection := range taxiiStatic {
+		if collection.ID == id {
+			return collection, true
+		}
+	}
+	sources, err := h.taxiiSourceCollections(r)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("TAXII: failed to list collections: %v", err))
+		writeTaxiiError(w, http.StatusInternalServerError, "failed to list collections")
+		return taxiiCollection{}, false
+	}
+	for _, collection := range sources {
+		if collection.ID == id {
+			return collection, true
+		}
+	}
+	writeTaxiiError(w, http.StatusNotFound, "collection not found")
+	return taxiiCollection{}, false
+}
+
+func setDateAddedHeaders(w http.ResponseWriter, iocs []models.IoCDto) {
+	if len(iocs) == 0 {
+		return
+	}
+	w.Header().Set("X-TAXII-Date-Added-First", formatTaxiiTime(iocs[0].AddedAt))
+	w.Header().Set("X-TAXII-Date-Added-Last", formatTaxiiTime(iocs[len(iocs)-1].AddedAt))
+}
+
+func formatTaxiiTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(stix.TimeFormat)
+}
+
+func containsValue(list, value string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTaxii(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", taxiiMediaType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeTaxiiError - ошибка в формате TAXII error message
+func writeTaxiiError(w http.ResponseWriter, status int, title string) {
+	writeTaxii(w, status, map[string]interface{}{
+		"title":       title,
+		"http_status": status,
+	})
+}
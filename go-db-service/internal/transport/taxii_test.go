@@ -0,0 +1,29 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTaxiiUnknownCollection(t *testing.T) {
+	h := newTestHTTPHandler(t, newTestService(t), nil)
+
+	w := get(h, taxiiAPIRoot+"collections/"+uuid.NewString()+"/")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status %d, want 404", w.Code)
+	}
+	var body struct {
+		HTTPStatus json.Number `json:"http_status"`
+	}
+	decoder := json.NewDecoder(w.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.HTTPStatus != "404" {
+		t.Fatalf("http_status %q, want number 404", body.HTTPStatus)
+	}
+}
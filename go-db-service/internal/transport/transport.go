@@ -1,14 +1,26 @@
 package transport
 
 import (
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/cache"
+	"awesomeProject/internal/changes"
+	"awesomeProject/internal/executor"
+	"awesomeProject/internal/importer"
+	"awesomeProject/internal/iprange"
+	"awesomeProject/internal/lifecycle"
+	"awesomeProject/internal/spool"
+	"awesomeProject/internal/tenant"
 	protogen "awesomeProject/internal/transport/protgen/ioc"
 	"awesomeProject/models"
 	log "awesomeProject/pkg/logger"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const storeBufferSize = 100
@@ -16,7 +28,7 @@ const storeBufferSize = 100
 // Service
 type Service interface {
 	Store(ctx context.Context, stream chan models.IoCDto) error
-	Load(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, error)
+	Load(ctx context.Context, request models.LoadRequest) (chan *models.IoCDto, chan error, error)
 	UnaryLoad(ctx context.Context, request models.LoadRequest) ([]models.IoCDto, error)
 	UnaryStore(ctx context.Context, iocs []models.IoCDto) error
 	Count(ctx context.Context) (int64, error)
@@ -27,12 +39,34 @@ type Service interface {
 	CountTypesBySource(ctx context.Context) (map[string]map[string]int64, error)
 	CountBySourceAndType(ctx context.Context, sourceName string) (map[string]int64, error)
 	CountByTypeAndSource(ctx context.Context, typeName string) (map[string]int64, error)
+	CountByCountry(ctx context.Context) (map[string]int64, error)
+	CountByASN(ctx context.Context, limit int64) ([]models.ASNCount, error)
+	Import(ctx context.Context, r io.Reader, opts importer.Options) (importer.Result, error)
+	Subscribe(ctx context.Context, cursor string) (*changes.Subscription, error)
+	ListAllowlist(ctx context.Context) ([]models.AllowlistEntry, error)
+	PutAllowlistEntries(ctx context.Context, entries []models.AllowlistEntry) ([]models.AllowlistEntry, error)
+	DeleteAllowlistEntry(ctx context.Context, id string) error
+	StoreRelationships(ctx context.Context, relationships []models.Relationship) error
+	Neighbors(ctx context.Context, request models.NeighborsRequest) (models.Subgraph, error)
+	RecordSightings(ctx context.Context, sightings []models.Sighting) (int, error)
+	SightingsBySource(ctx context.Context) (map[string]models.SourceSightings, error)
+	SourceReport(ctx context.Context, churnDays int) (models.SourceReport, error)
+	SetStatus(ctx context.Context, iocType, value, status, reason, changedBy string) (models.Lifecycle, error)
+	SetManualTags(ctx context.Context, iocType, value string, tags []string, changedBy string) (models.Lifecycle, error)
+	AddNote(ctx context.Context, iocType, value, author, text string) (models.Note, error)
+	GetLifecycle(ctx context.Context, iocType, value string) (models.Lifecycle, error)
+	ListLifecycle(ctx context.Context, statuses []string, limit, offset int64) ([]models.Lifecycle, error)
+	QueryAudit(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, error)
+	ExecutorStats() []executor.Stats
+	SpoolStats() (spool.Stats, bool)
+	CacheStats() ([]cache.Stats, int64, bool)
 }
 
 type Handler struct {
 	protogen.UnimplementedDatabaseServer
 	service Service
 	logger  log.CustomZapLogger
+	audit   *audit.Log // Журнал аудита, может быть nil
 }
 
 func NewHandler(service Service, logger log.CustomZapLogger) *Handler {
@@ -42,9 +76,11 @@ func NewHandler(service Service, logger log.CustomZapLogger) *Handler {
 func (h *Handler) Store(ctx context.Context, req *protogen.StoreRequest) (*empty.Empty, error) {
 	var iocs []models.IoCDto = models.ToModelIoCs(req.IoCs)
 	err := h.service.UnaryStore(ctx, iocs)
+	params, rows := countBatch(iocs)
+	h.audit.Record(ctx, "Store", params, rows, err)
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to store IoCs: %v", err))
-		return nil, err
+		return nil, tenantStatus(err)
 	}
 
 	h.logger.Info(fmt.Sprintf("Successfully stored %d IoCs", len(iocs)))
@@ -54,12 +90,23 @@ func (h *Handler) Store(ctx context.Context, req *protogen.StoreRequest) (*empty
 
 func (h *Handler) StreamStore(stream protogen.Database_StreamStoreServer) error {
 	ch := make(chan models.IoCDto, storeBufferSize)
+	enqueued := make(chan error, 1) // Результат постановки записи в очередь для аудита
 
 	ctx, cancel := context.WithCancel(stream.Context()) // для того чтобы отозвать горутину если вылезла ошибка
 	defer cancel()
 
 	go func() {
-		defer close(ch)
+		// Число строк известно только после конца стрима, поэтому аудит пишет горутина чтения
+		var counter batchCounter
+		var recvErr error
+		defer func() {
+			close(ch)
+			err := <-enqueued
+			if err == nil {
+				err = recvErr
+			}
+			h.audit.Record(stream.Context(), "StreamStore", counter.params(), counter.rows, err)
+		}()
 		for {
 			select {
 			case <-ctx.Done():
@@ -78,17 +125,24 @@ func (h *Handler) StreamStore(stream protogen.Database_StreamStoreServer) error
 						return
 					}
 					h.logger.Error(fmt.Sprintf("StreamStore: Error receiving stream: %v", err))
+					recvErr = err
 					return
 				}
 				ioc := models.ToModelIoC(req.Ioc)
-				ch <- ioc
+				counter.add(ioc)
+				select {
+				case ch <- ioc:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
 
 	err := h.service.Store(stream.Context(), ch)
+	enqueued <- err
 	if err != nil {
-		close(ch)
+		// ch закрывает горутина чтения после отмены контекста
 		h.logger.Error(fmt.Sprintf("StreamStore: Failed to store IoCs: %v", err))
 		return err
 	}
@@ -98,6 +152,9 @@ func (h *Handler) StreamStore(stream protogen.Database_StreamStoreServer) error
 
 func (h *Handler) Load(ctx context.Context, req *protogen.LoadRequest) (*protogen.LoadResponse, error) {
 	modelReq := models.ToModelLoadRequest(req)
+	if err := validateLoadRequest(modelReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	response, err := h.service.UnaryLoad(ctx, modelReq)
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Error in loading: %v", err))
@@ -115,7 +172,10 @@ func (h *Handler) Load(ctx context.Context, req *protogen.LoadRequest) (*protoge
 // StreamLoad handles the bidirectional streaming load of data from the client to server
 func (h *Handler) StreamLoad(req *protogen.LoadRequest, stream protogen.Database_StreamLoadServer) error {
 	reqModel := models.ToModelLoadRequest(req)
-	loadChannel, err := h.service.Load(stream.Context(), reqModel)
+	if err := validateLoadRequest(reqModel); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	loadChannel, errChan, err := h.service.Load(stream.Context(), reqModel)
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Error start stream loading: %v", err))
 		return err
@@ -135,6 +195,10 @@ func (h *Handler) StreamLoad(req *protogen.LoadRequest, stream protogen.Database
 			return err
 		case ioc, open := <-loadChannel:
 			if !open {
+				if err := <-errChan; err != nil {
+					h.logger.Error(fmt.Sprintf("StreamLoad: storage failed: %v", err))
+					return err
+				}
 				h.logger.Info("Stream load is done") // тут может не считать остатки ioc если канал буфферезированный
 				return nil
 			}
@@ -148,6 +212,39 @@ func (h *Handler) StreamLoad(req *protogen.LoadRequest, stream protogen.Database
 	}
 }
 
+// tenantStatus - коды gRPC для отказов по арендатору и квоте
+func tenantStatus(err error) error {
+	switch {
+	case errors.Is(err, tenant.ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, tenant.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, tenant.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return err
+}
+
+// validateLoadRequest - проверка фильтров, ошибки в которых иначе всплывут только в хранилище
+func validateLoadRequest(request models.LoadRequest) error {
+	for _, value := range request.IPWithin {
+		if _, err := iprange.Parse(value); err != nil {
+			return err
+		}
+	}
+	if request.IPContains != "" {
+		if _, err := iprange.Parse(request.IPContains); err != nil {
+			return err
+		}
+	}
+	for _, status := range request.Statuses {
+		if _, err := lifecycle.ParseStatus(status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *Handler) Count(ctx context.Context, _ *empty.Empty) (*protogen.CountResponse, error) {
 	count, err := h.service.Count(ctx)
 	if err != nil {
@@ -251,3 +348,28 @@ func (h *Handler) CountByTypeAndSource(ctx context.Context, req *protogen.CountB
 		SourceCounts: sourceCounts,
 	}, nil
 }
+
+func (h *Handler) CountByCountry(ctx context.Context, _ *empty.Empty) (*protogen.CountByCountryResponse, error) {
+	countryCounts, err := h.service.CountByCountry(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error counting IoCs by country: %v", err))
+		return nil, err
+	}
+
+	return &protogen.CountByCountryResponse{
+		CountryCounts: countryCounts,
+	}, nil
+}
+
+func (h *Handler) CountByASN(ctx context.Context, req *protogen.CountByASNRequest) (*protogen.CountByASNResponse, error) {
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	asnCounts, err := h.service.CountByASN(ctx, req.Limit)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Error counting IoCs by ASN: %v", err))
+		return nil, err
+	}
+
+	return models.ToProtoASNCounts(asnCounts), nil
+}
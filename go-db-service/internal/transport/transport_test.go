@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"awesomeProject/internal/auth"
+	"awesomeProject/internal/executor"
+	"awesomeProject/internal/service"
+	"awesomeProject/internal/storage"
+	"awesomeProject/internal/tenant"
+	"awesomeProject/models"
+	"awesomeProject/pkg/logger"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const testToken = "secret-token"
+
+// newTestService - настоящий сервис поверх хранилища в памяти с уже записанными iocs
+func newTestService(t *testing.T, iocs ...models.IoCDto) *service.Service {
+	t.Helper()
+	exec, err := executor.New(executor.Config{
+		Workers:      map[executor.Class]int{executor.Interactive: 2, executor.Write: 2, executor.Bulk: 2},
+		QueueSize:    16,
+		QueueTimeout: time.Second,
+	}, *logger.NewNop())
+	if err != nil {
+		t.Fatalf("executor: %v", err)
+	}
+	s := service.NewService(*logger.NewNop(), storage.NewMemoryStorage(logger.NewNop()), exec)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.Drain(ctx)
+	})
+	if len(iocs) > 0 {
+		if err := s.UnaryStoreSync(context.Background(), iocs); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+	}
+	return s
+}
+
+// newTestHTTPHandler - HTTP ручки с одним клиентом siem по токену testToken
+func newTestHTTPHandler(t *testing.T, s Service, limits *Limits) *HTTPHandler {
+	t.Helper()
+	authenticator, err := auth.NewAuthenticator("siem:" + testToken)
+	if err != nil {
+		t.Fatalf("authenticator: %v", err)
+	}
+	tenants, err := tenant.NewResolver("")
+	if err != nil {
+		t.Fatalf("tenants: %v", err)
+	}
+	return NewHTTPHandler(s, authenticator, tenants, limits, *logger.NewNop())
+}
+
+func get(h http.Handler, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func newIoC(source, iocType, value string) models.IoCDto {
+	now := time.Now().UTC().Truncate(time.Second)
+	return models.IoCDto{
+		ID:        uuid.NewString(),
+		Source:    source,
+		FirstSeen: &now,
+		LastSeen:  &now,
+		Type:      iocType,
+		Value:     value,
+		Tags:      []string{},
+	}
+}
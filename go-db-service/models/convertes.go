@@ -8,7 +8,7 @@ import (
 
 // ToProtoIoC преобразует IoCDto в protobuf формат
 func ToProtoIoC(dto IoCDto) *ioc.IoCDto {
-	var protoFirstSeen, protoLastSeen *timestamppb.Timestamp
+	var protoFirstSeen, protoLastSeen, protoAddedAt, protoFirstSighted, protoLastSighted *timestamppb.Timestamp
 
 	if dto.FirstSeen != nil {
 		protoFirstSeen = timestamppb.New(*dto.FirstSeen)
@@ -16,6 +16,15 @@ func ToProtoIoC(dto IoCDto) *ioc.IoCDto {
 	if dto.LastSeen != nil {
 		protoLastSeen = timestamppb.New(*dto.LastSeen)
 	}
+	if dto.AddedAt != nil {
+		protoAddedAt = timestamppb.New(*dto.AddedAt)
+	}
+	if dto.FirstSighted != nil {
+		protoFirstSighted = timestamppb.New(*dto.FirstSighted)
+	}
+	if dto.LastSighted != nil {
+		protoLastSighted = timestamppb.New(*dto.LastSighted)
+	}
 
 	return &ioc.IoCDto{
 		Id:             dto.ID,
@@ -26,6 +35,17 @@ func ToProtoIoC(dto IoCDto) *ioc.IoCDto {
 		Value:          dto.Value,
 		Tags:           dto.Tags,
 		AdditionalData: dto.AdditionalData,
+		AddedAt:        protoAddedAt,
+		Hidden:         dto.Hidden,
+		Country:        dto.Country,
+		City:           dto.City,
+		Asn:            dto.ASN,
+		AsOrg:          dto.ASOrg,
+		SightingCount:  dto.SightingCount,
+		FirstSighted:   protoFirstSighted,
+		LastSighted:    protoLastSighted,
+		Status:         dto.Status,
+		Tenant:         dto.Tenant,
 	}
 }
 
@@ -51,6 +71,11 @@ func ToModelIoC(proto *ioc.IoCDto) IoCDto {
 		Value:          proto.Value,
 		Tags:           proto.Tags,
 		AdditionalData: proto.AdditionalData,
+		Country:        proto.Country,
+		City:           proto.City,
+		ASN:            proto.Asn,
+		ASOrg:          proto.AsOrg,
+		Tenant:         proto.Tenant,
 	}
 }
 
@@ -74,18 +99,252 @@ func ToProtoIoCs(dtos []IoCDto) []*ioc.IoCDto {
 
 // ToModelLoadRequest преобразует protobuf LoadRequest в модель LoadRequest
 func ToModelLoadRequest(proto *ioc.LoadRequest) LoadRequest {
-	return LoadRequest{
-		Limit:  proto.Limit,
-		Offset: proto.Offset,
-		Filter: proto.Filter,
+	if proto == nil {
+		return LoadRequest{}
+	}
+	request := LoadRequest{
+		Limit:         proto.Limit,
+		Offset:        proto.Offset,
+		Filter:        proto.Filter,
+		Type:          proto.Type,
+		Source:        proto.Source,
+		SortByAdded:   proto.SortByAdded,
+		IncludeHidden: proto.IncludeHidden,
+		IPWithin:      proto.IpWithin,
+		IPContains:    proto.IpContains,
+
+		RegistrableDomain: proto.RegistrableDomain,
+		SubdomainOf:       proto.SubdomainOf,
+		TLD:               proto.Tld,
+		Country:           proto.Country,
+		ASN:               proto.Asn,
+		IncludeSightings:  proto.IncludeSightings,
+		Statuses:          proto.Statuses,
+	}
+	if proto.AddedAfter != nil {
+		t := proto.AddedAfter.AsTime()
+		request.AddedAfter = &t
 	}
+	if proto.AddedBefore != nil {
+		t := proto.AddedBefore.AsTime()
+		request.AddedBefore = &t
+	}
+	return request
 }
 
 // ToProtoLoadRequest преобразует модель LoadRequest в protobuf LoadRequest
 func ToProtoLoadRequest(req LoadRequest) *ioc.LoadRequest {
-	return &ioc.LoadRequest{
-		Limit:  req.Limit,
-		Offset: req.Offset,
-		Filter: req.Filter,
+	protoReq := &ioc.LoadRequest{
+		Limit:         req.Limit,
+		Offset:        req.Offset,
+		Filter:        req.Filter,
+		Type:          req.Type,
+		Source:        req.Source,
+		SortByAdded:   req.SortByAdded,
+		IncludeHidden: req.IncludeHidden,
+		IpWithin:      req.IPWithin,
+		IpContains:    req.IPContains,
+
+		RegistrableDomain: req.RegistrableDomain,
+		SubdomainOf:       req.SubdomainOf,
+		Tld:               req.TLD,
+		Country:           req.Country,
+		Asn:               req.ASN,
+		IncludeSightings:  req.IncludeSightings,
+		Statuses:          req.Statuses,
+	}
+	if req.AddedAfter != nil {
+		protoReq.AddedAfter = timestamppb.New(*req.AddedAfter)
+	}
+	if req.AddedBefore != nil {
+		protoReq.AddedBefore = timestamppb.New(*req.AddedBefore)
+	}
+	return protoReq
+}
+
+// ToModelAllowlistEntry преобразует protobuf AllowlistEntry в модель
+func ToModelAllowlistEntry(proto *ioc.AllowlistEntry) AllowlistEntry {
+	entry := AllowlistEntry{
+		ID:      proto.Id,
+		Kind:    proto.Kind,
+		Value:   proto.Value,
+		Comment: proto.Comment,
+	}
+	if proto.CreatedAt != nil {
+		entry.CreatedAt = proto.CreatedAt.AsTime()
+	}
+	if proto.UpdatedAt != nil {
+		entry.UpdatedAt = proto.UpdatedAt.AsTime()
+	}
+	return entry
+}
+
+// ToProtoAllowlistEntries преобразует записи allowlist в protobuf
+func ToProtoAllowlistEntries(entries []AllowlistEntry) *ioc.AllowlistEntries {
+	result := &ioc.AllowlistEntries{Entries: make([]*ioc.AllowlistEntry, len(entries))}
+	for i, entry := range entries {
+		result.Entries[i] = &ioc.AllowlistEntry{
+			Id:        entry.ID,
+			Kind:      entry.Kind,
+			Value:     entry.Value,
+			Comment:   entry.Comment,
+			CreatedAt: timestamppb.New(entry.CreatedAt),
+			UpdatedAt: timestamppb.New(entry.UpdatedAt),
+		}
+	}
+	return result
+}
+
+// ToProtoASNCounts преобразует статистику по ASN в protobuf
+func ToProtoASNCounts(counts []ASNCount) *ioc.CountByASNResponse {
+	result := &ioc.CountByASNResponse{AsnCounts: make([]*ioc.ASNCount, len(counts))}
+	for i, count := range counts {
+		result.AsnCounts[i] = &ioc.ASNCount{Asn: count.ASN, Org: count.Org, Count: count.Count}
+	}
+	return result
+}
+
+// ToModelRelationship преобразует связь из protobuf
+func ToModelRelationship(proto *ioc.Relationship) Relationship {
+	rel := Relationship{
+		FromID:    proto.FromId,
+		FromType:  proto.FromType,
+		FromValue: proto.FromValue,
+		Kind:      proto.Kind,
+		ToID:      proto.ToId,
+		ToType:    proto.ToType,
+		ToValue:   proto.ToValue,
+		Source:    proto.Source,
+	}
+	if proto.UpdatedAt != nil {
+		rel.UpdatedAt = proto.UpdatedAt.AsTime()
+	}
+	return rel
+}
+
+// ToProtoSubgraph преобразует окрестность индикатора в protobuf
+func ToProtoSubgraph(graph Subgraph) *ioc.NeighborsResponse {
+	result := &ioc.NeighborsResponse{
+		Nodes:     make([]*ioc.GraphNode, len(graph.Nodes)),
+		Edges:     make([]*ioc.Relationship, len(graph.Edges)),
+		Truncated: graph.Truncated,
 	}
+	for i, node := range graph.Nodes {
+		result.Nodes[i] = &ioc.GraphNode{Type: node.Type, Value: node.Value, Depth: int32(node.Depth)}
+	}
+	for i, rel := range graph.Edges {
+		result.Edges[i] = &ioc.Relationship{
+			FromType:  rel.FromType,
+			FromValue: rel.FromValue,
+			Kind:      rel.Kind,
+			ToType:    rel.ToType,
+			ToValue:   rel.ToValue,
+			Source:    rel.Source,
+			UpdatedAt: timestamppb.New(rel.UpdatedAt),
+		}
+	}
+	return result
+}
+
+// ToModelSighting преобразует protobuf Sighting в модель
+func ToModelSighting(proto *ioc.Sighting) Sighting {
+	sighting := Sighting{
+		Value:    proto.Value,
+		Type:     proto.Type,
+		Observer: proto.Observer,
+		Count:    proto.Count,
+	}
+	if proto.SeenAt != nil {
+		sighting.SeenAt = proto.SeenAt.AsTime()
+	}
+	return sighting
+}
+
+// ToProtoSourceReport преобразует отчет по источникам в protobuf
+func ToProtoSourceReport(report SourceReport) *ioc.SourceReportResponse {
+	result := &ioc.SourceReportResponse{
+		Sources:     make(map[string]*ioc.SourceStats, len(report.Sources)),
+		Overlaps:    make([]*ioc.SourceOverlap, len(report.Overlaps)),
+		GeneratedAt: timestamppb.New(report.GeneratedAt),
+	}
+	for source, stats := range report.Sources {
+		protoStats := &ioc.SourceStats{
+			Total:            stats.Total,
+			Unique:           stats.Unique,
+			Shared:           stats.Shared,
+			FirstReported:    stats.FirstReported,
+			MedianLagSeconds: stats.MedianLag,
+			Allowlisted:      stats.Allowlisted,
+			AllowlistedShare: stats.AllowlistedShare,
+		}
+		for _, churn := range stats.Churn {
+			protoStats.Churn = append(protoStats.Churn, &ioc.DailyChurn{Date: churn.Date, Added: churn.Added, Removed: churn.Removed})
+		}
+		result.Sources[source] = protoStats
+	}
+	for i, overlap := range report.Overlaps {
+		result.Overlaps[i] = &ioc.SourceOverlap{SourceA: overlap.SourceA, SourceB: overlap.SourceB, Shared: overlap.Shared}
+	}
+	return result
+}
+
+// ToProtoLifecycle преобразует статус IoC в protobuf
+func ToProtoLifecycle(record Lifecycle) *ioc.Lifecycle {
+	result := &ioc.Lifecycle{
+		Type:       record.Type,
+		Value:      record.Value,
+		Status:     record.Status,
+		Reason:     record.Reason,
+		ChangedBy:  record.ChangedBy,
+		ManualTags: record.ManualTags,
+	}
+	if !record.ChangedAt.IsZero() {
+		result.ChangedAt = timestamppb.New(record.ChangedAt)
+	}
+	for _, note := range record.Notes {
+		result.Notes = append(result.Notes, ToProtoNote(note))
+	}
+	return result
+}
+
+// ToProtoNote преобразует заметку в protobuf
+func ToProtoNote(note Note) *ioc.Note {
+	return &ioc.Note{Id: note.ID, Author: note.Author, Text: note.Text, CreatedAt: timestamppb.New(note.CreatedAt)}
+}
+
+// ToModelAuditQuery преобразует protobuf фильтр журнала аудита в модель
+func ToModelAuditQuery(proto *ioc.AuditQuery) AuditQuery {
+	query := AuditQuery{
+		Actor:   proto.Actor,
+		Method:  proto.Method,
+		Outcome: proto.Outcome,
+		Limit:   proto.Limit,
+		Offset:  proto.Offset,
+	}
+	if proto.From != nil {
+		query.From = proto.From.AsTime()
+	}
+	if proto.To != nil {
+		query.To = proto.To.AsTime()
+	}
+	return query
+}
+
+// ToProtoAuditRecords преобразует записи журнала аудита в protobuf
+func ToProtoAuditRecords(records []AuditRecord) *ioc.AuditRecords {
+	result := &ioc.AuditRecords{Records: make([]*ioc.AuditRecord, len(records))}
+	for i, record := range records {
+		result.Records[i] = &ioc.AuditRecord{
+			Id:      record.ID,
+			Time:    timestamppb.New(record.Time),
+			Actor:   record.Actor,
+			Peer:    record.Peer,
+			Method:  record.Method,
+			Params:  record.Params,
+			Rows:    record.Rows,
+			Outcome: record.Outcome,
+			Error:   record.Error,
+		}
+	}
+	return result
 }
@@ -4,6 +4,15 @@ import (
 	"time"
 )
 
+// Типы IoC, которые пишет нормализатор (в нижнем регистре)
+const (
+	TypeIP     = "ip"
+	TypeDomain = "domain"
+	TypeURL    = "url"
+	TypeSHA256 = "sha256"
+	TypeMD5    = "md5"
+)
+
 // IoCDto представляет структуру данных для IOCs (Indicators of Compromise)
 type IoCDto struct {
 	ID             string            `json:"id"`
@@ -14,6 +23,23 @@ type IoCDto struct {
 	Value          string            `json:"value"`
 	Tags           []string          `json:"tags"`
 	AdditionalData map[string]string `json:"additional_data"`
+	AddedAt        *time.Time        `json:"added_at,omitempty"` // Время записи в хранилище, заполняется при чтении
+	Hidden         bool              `json:"hidden,omitempty"`   // Скрыт политикой allowlist, не отдается при чтении по умолчанию
+
+	// GeoIP/ASN обогащение IP IoC, заполняется при записи из локальных mmdb
+	Country string `json:"country,omitempty"` // ISO код страны
+	City    string `json:"city,omitempty"`
+	ASN     uint32 `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+
+	// Агрегаты sightings по значению, заполняются при чтении с IncludeSightings
+	SightingCount int64      `json:"sighting_count,omitempty"`
+	FirstSighted  *time.Time `json:"first_sighted,omitempty"`
+	LastSighted   *time.Time `json:"last_sighted,omitempty"`
+
+	Status string `json:"status,omitempty"` // Статус lifecycle, заполняется при чтении
+
+	Tenant string `json:"tenant,omitempty"` // Арендатор собственных IoC; пусто - общий IoC, виден всем
 }
 
 // StoreRequest представляет запрос для записи в базу данных
@@ -26,6 +52,35 @@ type LoadRequest struct {
 	Limit  int64  `json:"limit"`
 	Offset int64  `json:"offset"`
 	Filter string `json:"filter"`
+	Type   string `json:"type"`
+	Source string `json:"source"`
+
+	AddedAfter  *time.Time `json:"added_after,omitempty"`  // Только IoC, записанные позже этого момента
+	AddedBefore *time.Time `json:"added_before,omitempty"` // Только IoC, записанные раньше этого момента
+	SortByAdded bool       `json:"sort_by_added"`          // Сортировка по времени записи для стабильной пагинации
+
+	IncludeHidden bool `json:"include_hidden"` // Отдавать IoC, скрытые политикой allowlist
+
+	IPWithin   []string `json:"ip_within,omitempty"`   // IP IoC, целиком лежащие в одном из диапазонов (CIDR или start-end)
+	IPContains string   `json:"ip_contains,omitempty"` // IP IoC (адреса и сети), содержащие этот адрес
+
+	RegistrableDomain string `json:"registrable_domain,omitempty"` // DOMAIN/URL IoC с этим eTLD+1 (evil.com и все поддомены)
+	SubdomainOf       string `json:"subdomain_of,omitempty"`       // DOMAIN/URL IoC с хостом, равным домену или его поддоменом
+	TLD               string `json:"tld,omitempty"`                // DOMAIN/URL IoC в домене верхнего уровня (top)
+
+	Country string `json:"country,omitempty"` // IP IoC из страны (ISO код)
+	ASN     uint32 `json:"asn,omitempty"`     // IP IoC из автономной системы
+
+	IncludeSightings bool `json:"include_sightings"` // Присоединить агрегаты sightings к каждому IoC
+
+	Statuses []string `json:"statuses,omitempty"` // Статусы lifecycle в выдаче (пусто - только active)
+}
+
+// ASNCount - количество IoC в автономной системе
+type ASNCount struct {
+	ASN   uint32 `json:"asn"`
+	Org   string `json:"org"`
+	Count int64  `json:"count"`
 }
 
 // LoadResponse представляет ответ при загрузке данных из базы
@@ -42,3 +97,174 @@ type StreamStoreRequest struct {
 type StreamLoadResponse struct {
 	Ioc IoCDto `json:"ioc"`
 }
+
+// Виды записей allowlist
+const (
+	AllowlistValue  = "value"  // Точное значение IoC
+	AllowlistSuffix = "suffix" // Домен и все его поддомены (*.google.com)
+	AllowlistCIDR   = "cidr"   // Диапазон IP адресов
+)
+
+// AllowlistEntry - запись allowlist
+type AllowlistEntry struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Виды связей между индикаторами
+const (
+	RelationResolvesTo     = "resolves_to"      // Домен резолвится в IP
+	RelationHosts          = "hosts"            // URL, домен или IP раздает файл
+	RelationDrops          = "drops"            // Файл или URL загружает другой файл
+	RelationPartOfCampaign = "part_of_campaign" // IoC относится к кампании или семейству ВПО
+)
+
+// TypeCampaign - тип узла графа для кампании или семейства ВПО; в ioc_data таких IoC нет
+const TypeCampaign = "campaign"
+
+// Relationship - направленная связь между индикаторами (по типу и значению).
+// При записи конец можно задать id IoC вместо значения.
+type Relationship struct {
+	FromID    string    `json:"from_id,omitempty"`
+	FromType  string    `json:"from_type"`
+	FromValue string    `json:"from_value"`
+	Kind      string    `json:"kind"`
+	ToID      string    `json:"to_id,omitempty"`
+	ToType    string    `json:"to_type"`
+	ToValue   string    `json:"to_value"`
+	Source    string    `json:"source"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GraphNode - узел подграфа и расстояние до исходного значения
+type GraphNode struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Depth int    `json:"depth"`
+}
+
+// Subgraph - окрестность индикатора
+type Subgraph struct {
+	Nodes     []GraphNode    `json:"nodes"`
+	Edges     []Relationship `json:"edges"`
+	Truncated bool           `json:"truncated"` // Обход остановлен по лимиту узлов
+}
+
+// NeighborsRequest - запрос окрестности индикатора
+type NeighborsRequest struct {
+	Value string   `json:"value"`
+	Depth int      `json:"depth"` // 1 по умолчанию
+	Kinds []string `json:"kinds"` // Только связи этих видов (пусто - все)
+	Limit int      `json:"limit"` // Максимум узлов
+}
+
+// Sighting - обнаружение индикатора собственными средствами (EDR, IDS)
+type Sighting struct {
+	Value    string    `json:"value"`
+	Type     string    `json:"type"`     // Пусто - определяется по значению
+	Observer string    `json:"observer"` // Сенсор или система, увидевшая индикатор
+	SeenAt   time.Time `json:"seen_at"`  // Пусто - время записи
+	Count    uint32    `json:"count"`    // Число срабатываний, 0 считается как 1
+}
+
+// SourceSightings - сколько IoC источника встретилось в нашей инфраструктуре
+type SourceSightings struct {
+	IoCs      int64 `json:"iocs"`      // IoC источника хотя бы с одним sighting
+	Sightings int64 `json:"sightings"` // Сумма срабатываний по этим IoC
+}
+
+// SourceReport - качество и пересечение источников по ioc_data
+type SourceReport struct {
+	Sources     map[string]*SourceStats `json:"sources"`
+	Overlaps    []SourceOverlap         `json:"overlaps"`
+	GeneratedAt time.Time               `json:"generated_at"`
+}
+
+// SourceStats - показатели одного источника; значения считаются уникальными по value
+type SourceStats struct {
+	Total            int64        `json:"total"`              // Уникальных значений
+	Unique           int64        `json:"unique"`             // Значений, которых нет у других источников
+	Shared           int64        `json:"shared"`             // Значений, которые есть хотя бы у одного другого источника
+	FirstReported    int64        `json:"first_reported"`     // Общих значений, где источник сообщил первым
+	MedianLag        float64      `json:"median_lag_seconds"` // Медианное отставание от первого сообщившего по общим значениям
+	Allowlisted      int64        `json:"allowlisted"`        // Значений, совпадающих с текущим allowlist
+	AllowlistedShare float64      `json:"allowlisted_share"`  // Доля allowlisted от Total
+	Churn            []DailyChurn `json:"churn"`
+}
+
+// DailyChurn - новые и пропавшие значения источника за день
+type DailyChurn struct {
+	Date    string `json:"date"`    // YYYY-MM-DD
+	Added   int64  `json:"added"`   // Впервые записаны в этот день
+	Removed int64  `json:"removed"` // Источник последний раз сообщал о них в этот день
+}
+
+// SourceOverlap - число общих значений пары источников
+type SourceOverlap struct {
+	SourceA string `json:"source_a"`
+	SourceB string `json:"source_b"`
+	Shared  int64  `json:"shared"`
+}
+
+// Статусы lifecycle IoC
+const (
+	StatusActive        = "active"
+	StatusFalsePositive = "false_positive"
+	StatusRevoked       = "revoked"
+	StatusExpired       = "expired"
+)
+
+// Lifecycle - статус IoC, который ведут аналитики, ручные теги и заметки
+type Lifecycle struct {
+	Type       string    `json:"type"`
+	Value      string    `json:"value"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason"`
+	ChangedBy  string    `json:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at"`
+	ManualTags []string  `json:"manual_tags"`
+	Notes      []Note    `json:"notes,omitempty"`  // Заполняется только при чтении одного IoC
+	Tenant     string    `json:"tenant,omitempty"` // Арендатор, для которого действует запись; пусто - для всех
+}
+
+// Note - заметка аналитика к IoC
+type Note struct {
+	ID        string    `json:"id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Результаты действий в журнале аудита
+const (
+	AuditOK    = "ok"
+	AuditError = "error"
+)
+
+// AuditRecord - запись журнала аудита: кто, когда и с какими параметрами записал, изменил или выгрузил данные
+type AuditRecord struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`  // Клиент из аутентификации или gRPC metadata, пусто - анонимный
+	Peer    string    `json:"peer"`   // Адрес клиента
+	Method  string    `json:"method"` // RPC, HTTP ручка или broker
+	Params  string    `json:"params"` // Фильтр и параметры вызова в JSON
+	Rows    int64     `json:"rows"`   // Сколько строк записано, изменено или выгружено
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// AuditQuery - фильтр журнала аудита; пустые поля не ограничивают выборку
+type AuditQuery struct {
+	Actor   string    `json:"actor"`
+	Method  string    `json:"method"`
+	Outcome string    `json:"outcome"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Limit   int64     `json:"limit"`
+	Offset  int64     `json:"offset"`
+}
@@ -130,3 +130,8 @@ func (l *CustomZapLogger) Printf(format string, args ...interface{}) {
 	fmt.Println("[INFO] " + msg)
 	l.logger.Info(msg)
 }
+
+// NewNop - логгер без файла и без вывода zap, для тестов
+func NewNop() *CustomZapLogger {
+	return &CustomZapLogger{logger: zap.NewNop()}
+}
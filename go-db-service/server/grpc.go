@@ -9,7 +9,6 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"net"
-	"time"
 )
 
 // Server - структура для gRPC сервера
@@ -19,10 +18,10 @@ type Server struct {
 	handler    protogen.DatabaseServer // Хендлер для обработки запросов
 }
 
-// NewServer - конструктор для создания нового gRPC сервера
-func NewServer(handler *handlers.Handler, logger logger.CustomZapLogger) *Server {
+// NewServer - конструктор для создания нового gRPC сервера; opts - перехватчики и прочие настройки grpc
+func NewServer(handler *handlers.Handler, logger logger.CustomZapLogger, opts ...grpc.ServerOption) *Server {
 	return &Server{
-		grpcServer: grpc.NewServer(),
+		grpcServer: grpc.NewServer(opts...),
 		handler:    handler,
 		logger:     logger,
 	}
@@ -58,26 +57,23 @@ func (s *Server) Start(port string) error {
 	return nil
 }
 
-// Shutdown - завершение работы gRPC сервера
+// Shutdown - завершение работы gRPC сервера: ждет завершения активных вызовов и стримов,
+// а если ctx истек раньше, обрывает оставшиеся
 func (s *Server) Shutdown(ctx context.Context) error {
-	// Вызываем graceful stop с таймаутом
-	stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	// Завершаем работу gRPC сервера с учетом контекста
-	s.grpcServer.GracefulStop()
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
 
-	// Пример дополнительной операции с контекстом
-	// Например, закрытие базы данных или других сервисов
 	select {
-	case <-stopCtx.Done(): // Если контекст отменен из-за таймаута
-		s.logger.Printf("Shutdown timeout exceeded, force stopping.")
-		// В этом случае можно выполнить принудительное завершение работы.
-		return stopCtx.Err()
-	default:
-		// Если все завершилось в пределах таймаута, продолжаем выполнение
+	case <-stopped:
 		s.logger.Printf("Server gracefully stopped.")
+		return nil
+	case <-ctx.Done():
+		s.logger.Printf("Shutdown timeout exceeded, force stopping.")
+		s.grpcServer.Stop()
+		<-stopped
+		return ctx.Err()
 	}
-
-	return nil
 }
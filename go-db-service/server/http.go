@@ -0,0 +1,51 @@
+package server
+
+import (
+	"awesomeProject/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// HTTPServer - HTTP сервер для выгрузок и обмена данными
+type HTTPServer struct {
+	logger     logger.CustomZapLogger
+	httpServer *http.Server
+}
+
+// NewHTTPServer - конструктор HTTP сервера
+func NewHTTPServer(handler http.Handler, logger logger.CustomZapLogger) *HTTPServer {
+	return &HTTPServer{
+		httpServer: &http.Server{Handler: handler},
+		logger:     logger,
+	}
+}
+
+// Start - запуск HTTP сервера
+func (s *HTTPServer) Start(port string) error {
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Fatal(fmt.Sprintf("failed to serve http: %v", err))
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown - завершение работы HTTP сервера с ожиданием активных запросов;
+// если ctx истек раньше, оставшиеся соединения закрываются
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Printf("HTTP shutdown timeout exceeded, closing connections.")
+		_ = s.httpServer.Close()
+	}
+	return err
+}